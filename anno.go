@@ -0,0 +1,94 @@
+package exbana
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// SetAnno attaches an annotation to the match, later passes can store results (i.e. type info) on the match tree
+// without building a parallel tree. Annotations are included in the JSON output so values should be marshalable
+func (m *Match[T, P]) SetAnno(key string, value any) *Match[T, P] {
+	if m.annos == nil {
+		m.annos = map[string]any{}
+	}
+
+	m.annos[key] = value
+
+	return m
+}
+
+// Anno returns the annotation for key
+func (m *Match[T, P]) Anno(key string) (any, bool) {
+	value, ok := m.annos[key]
+	return value, ok
+}
+
+// DeleteAnno removes the annotation for key
+func (m *Match[T, P]) DeleteAnno(key string) {
+	delete(m.annos, key)
+}
+
+// AnnoKeys returns the sorted annotation keys
+func (m *Match[T, P]) AnnoKeys() []string {
+	keys := make([]string, 0, len(m.annos))
+	for key := range m.annos {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// AnnoAs returns the annotation for key if it has type A
+func AnnoAs[A, T, P any](m *Match[T, P], key string) (A, bool) {
+	value, ok := m.annos[key].(A)
+	return value, ok
+}
+
+// PrettyPrint writes the match tree with one match per line, indented by depth. A line has the ID (_ for anonymous
+// matches), the positions, the value and the annotations. value returns the string for the value of a match, if
+// value is nil DefaultValueString is used
+func (m *Match[T, P]) PrettyPrint(w io.Writer, value func(*Match[T, P]) string) error {
+	if value == nil {
+		value = DefaultValueString[T, P]
+	}
+
+	var err error
+
+	m.Walk(func(n *Match[T, P], depth int) bool {
+		if err != nil {
+			return false
+		}
+
+		var sb strings.Builder
+
+		sb.WriteString(strings.Repeat("  ", depth))
+
+		if id := n.ID(); id != NoID {
+			sb.WriteString(id)
+		} else {
+			sb.WriteString("_")
+		}
+
+		sb.WriteString(fmt.Sprintf(" %v - %v", n.Begin, n.End))
+
+		if v := value(n); v != "" {
+			sb.WriteString(fmt.Sprintf(" %q", v))
+		}
+
+		for _, key := range n.AnnoKeys() {
+			sb.WriteString(fmt.Sprintf(" %s=%v", key, n.annos[key]))
+		}
+
+		sb.WriteString("\n")
+
+		_, err = w.Write([]byte(sb.String()))
+
+		return err == nil
+	})
+
+	return err
+}
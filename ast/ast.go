@@ -0,0 +1,129 @@
+package ast
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+)
+
+// Span is the begin and end position of a match
+type Span[P any] struct {
+	Begin P
+	End   P
+}
+
+// Constructor builds an AST node from the nodes built for the sub matches
+type Constructor[P any] func(children []any, span Span[P]) (any, error)
+
+// TextConstructor builds an AST node from the matched objects, used for tokens
+type TextConstructor[T, P any] func(text []T, span Span[P]) (any, error)
+
+// Builder maps pattern IDs to node constructors and builds an AST from a match tree bottom-up. Matches without a
+// constructor splice the nodes of their sub matches into the children of the parent, so lists built by repetitions
+// and anonymous groups are flattened automatically. Unregistered matches without sub matches contribute their value
+// if it is not empty
+type Builder[T, P any] struct {
+	constructors map[string]Constructor[P]
+	texts        map[string]TextConstructor[T, P]
+}
+
+// New creates a new AST builder
+func New[T, P any]() *Builder[T, P] {
+	return &Builder[T, P]{
+		constructors: map[string]Constructor[P]{},
+		texts:        map[string]TextConstructor[T, P]{},
+	}
+}
+
+// Register registers a node constructor for a pattern ID
+func (b *Builder[T, P]) Register(id string, constructor Constructor[P]) *Builder[T, P] {
+	b.constructors[id] = constructor
+	return b
+}
+
+// RegisterText registers a node constructor for a pattern ID that receives the matched objects instead of children
+func (b *Builder[T, P]) RegisterText(id string, constructor TextConstructor[T, P]) *Builder[T, P] {
+	b.texts[id] = constructor
+	return b
+}
+
+// Build builds the AST for a match tree, if the root match has no constructor the root node is the list of children
+func (b *Builder[T, P]) Build(m *ebnf.Match[T, P], r ebnf.Reader[T, P]) (any, error) {
+	nodes, err := b.build(m, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nodes) == 1 && b.registered(m.ID()) {
+		return nodes[0], nil
+	}
+
+	return nodes, nil
+}
+
+func (b *Builder[T, P]) registered(id string) bool {
+	if id == ebnf.NoID {
+		return false
+	}
+
+	_, ok := b.constructors[id]
+	if !ok {
+		_, ok = b.texts[id]
+	}
+
+	return ok
+}
+
+// build returns the nodes a match contributes to the children of its parent
+func (b *Builder[T, P]) build(m *ebnf.Match[T, P], r ebnf.Reader[T, P]) ([]any, error) {
+	span := Span[P]{Begin: m.Begin, End: m.End}
+	id := m.ID()
+
+	if id != ebnf.NoID {
+		if constructor, ok := b.texts[id]; ok {
+			text, err := m.Objects(r)
+			if err != nil {
+				return nil, err
+			}
+
+			node, err := constructor(text, span)
+			if err != nil {
+				return nil, err
+			}
+
+			return []any{node}, nil
+		}
+	}
+
+	var children []any
+
+	for _, component := range m.Components {
+		if component == nil {
+			continue
+		}
+
+		nodes, err := b.build(component, r)
+		if err != nil {
+			return nil, err
+		}
+
+		children = append(children, nodes...)
+	}
+
+	if id != ebnf.NoID {
+		if constructor, ok := b.constructors[id]; ok {
+			node, err := constructor(children, span)
+			if err != nil {
+				return nil, err
+			}
+
+			return []any{node}, nil
+		}
+	}
+
+	if len(m.Components) == 0 {
+		if v, ok := m.Value.([]T); ok && len(v) > 0 {
+			return []any{v}, nil
+		}
+	}
+
+	return children, nil
+}
@@ -0,0 +1,339 @@
+package bind
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrTarget is returned when the decode target is not a non nil pointer to a struct
+var ErrTarget = errors.New("decode target must be a non nil pointer to a struct")
+
+// ErrSize is returned when the size of a match does not fit the field
+var ErrSize = errors.New("match size does not fit field")
+
+// ErrMissing is returned when a required field has no match
+var ErrMissing = errors.New("required match missing")
+
+// ErrUnsupported is returned for field types that can not be decoded
+var ErrUnsupported = errors.New("unsupported field type")
+
+// FieldError is returned when a field can not be decoded, Field is the path of the field from the decoded struct
+type FieldError struct {
+	Field string
+	ID    string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("field %s (%q): %v", e.Field, e.ID, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// options are the parsed field tag options
+type options struct {
+	id       string
+	order    binary.ByteOrder
+	size     int
+	cstr     bool
+	eval     bool
+	required bool
+}
+
+// Decode maps the match tree of a binary grammar onto the struct v points to. Each field is bound to the nearest sub
+// match with the pattern ID of the field, given by the field tag `exbana:"id,options"` or, without tag, the field name
+// compared case insensitive. Fields tagged with "-" and unexported fields are skipped. A field without match keeps
+// its value, so optional and conditional parts of a layout simply leave their fields unset.
+//
+// The matched bytes are decoded by field type: unsigned and signed integers (sign extended if the match is shorter
+// than the field), floats (4 or 8 bytes), bool (any non zero byte), string, []byte and byte arrays. Struct fields and
+// pointers to structs are decoded from the sub matches of their match, a pointer is only allocated if there is a
+// match. Slices of other types get an element per match with the ID, repeated layouts (i.e. records matched by a
+// repetition) are decoded this way. The options are:
+//
+//	le, be    byte order of integers and floats, big endian by default
+//	size=N    the match must be N bytes long
+//	cstr      strings end at the first NUL byte
+//	eval      the field is set to the result of Match.Eval, the result must be assignable or convertible
+//	required  a missing match is an error
+func Decode[P any](m *ebnf.Match[byte, P], r ebnf.Reader[byte, P], v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrTarget
+	}
+
+	return decodeStruct(m, r, rv.Elem(), "")
+}
+
+// decodeStruct decodes the fields of struct sv from the sub matches of m
+func decodeStruct[P any](m *ebnf.Match[byte, P], r ebnf.Reader[byte, P], sv reflect.Value, path string) error {
+	st := sv.Type()
+
+	for i := 0; i < st.NumField(); i++ {
+		field := st.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("exbana")
+		if tag == "-" {
+			continue
+		}
+
+		opts, err := parseTag(tag)
+		if err != nil {
+			return &FieldError{Field: path + field.Name, Err: err}
+		}
+
+		fieldPath := path + field.Name
+		fv := sv.Field(i)
+
+		matches := nearest(m, func(id string) bool {
+			if opts.id != "" {
+				return id == opts.id
+			}
+
+			return strings.EqualFold(id, field.Name)
+		})
+
+		if len(matches) == 0 {
+			if opts.required {
+				return &FieldError{Field: fieldPath, ID: opts.id, Err: ErrMissing}
+			}
+
+			continue
+		}
+
+		// Slices other than []byte get an element per match
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 && !opts.eval {
+			elems := reflect.MakeSlice(fv.Type(), len(matches), len(matches))
+
+			for j, sub := range matches {
+				err = decodeValue(sub, r, elems.Index(j), opts, fmt.Sprintf("%s[%d].", fieldPath, j))
+				if err != nil {
+					return wrap(err, fmt.Sprintf("%s[%d]", fieldPath, j), sub.ID())
+				}
+			}
+
+			fv.Set(elems)
+
+			continue
+		}
+
+		err = decodeValue(matches[0], r, fv, opts, fieldPath+".")
+		if err != nil {
+			return wrap(err, fieldPath, matches[0].ID())
+		}
+	}
+
+	return nil
+}
+
+// decodeValue decodes match m into value v
+func decodeValue[P any](m *ebnf.Match[byte, P], r ebnf.Reader[byte, P], v reflect.Value, opts options, path string) error {
+	if opts.eval {
+		result, err := m.Eval(r)
+		if err != nil {
+			return err
+		}
+
+		return assign(v, result)
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return decodeStruct(m, r, v, path)
+	case reflect.Pointer:
+		if v.Type().Elem().Kind() != reflect.Struct {
+			return fmt.Errorf("%w: %v", ErrUnsupported, v.Type())
+		}
+
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return decodeStruct(m, r, v.Elem(), path)
+	case reflect.Interface:
+		result, err := m.Eval(r)
+		if err != nil {
+			return err
+		}
+
+		return assign(v, result)
+	}
+
+	b, err := m.Objects(r)
+	if err != nil {
+		return err
+	}
+
+	if opts.size > 0 && len(b) != opts.size {
+		return fmt.Errorf("%w: %d bytes, expected %d", ErrSize, len(b), opts.size)
+	}
+
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if len(b) == 0 || len(b) > int(v.Type().Size()) {
+			return fmt.Errorf("%w: %d bytes for %v", ErrSize, len(b), v.Type())
+		}
+
+		v.SetUint(decodeUint(b, opts.order))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if len(b) == 0 || len(b) > int(v.Type().Size()) {
+			return fmt.Errorf("%w: %d bytes for %v", ErrSize, len(b), v.Type())
+		}
+
+		// Sign extend from the size of the match
+		shift := 64 - 8*len(b)
+		v.SetInt(int64(decodeUint(b, opts.order)<<shift) >> shift)
+	case reflect.Float32, reflect.Float64:
+		switch len(b) {
+		case 4:
+			v.SetFloat(float64(math.Float32frombits(uint32(decodeUint(b, opts.order)))))
+		case 8:
+			v.SetFloat(math.Float64frombits(decodeUint(b, opts.order)))
+		default:
+			return fmt.Errorf("%w: %d bytes for %v", ErrSize, len(b), v.Type())
+		}
+	case reflect.Bool:
+		v.SetBool(false)
+
+		for _, c := range b {
+			if c != 0 {
+				v.SetBool(true)
+				break
+			}
+		}
+	case reflect.String:
+		if i := strings.IndexByte(string(b), 0); opts.cstr && i >= 0 {
+			b = b[:i]
+		}
+
+		v.SetString(string(b))
+	case reflect.Slice:
+		v.SetBytes(append([]byte(nil), b...))
+	case reflect.Array:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("%w: %v", ErrUnsupported, v.Type())
+		}
+
+		if len(b) != v.Len() {
+			return fmt.Errorf("%w: %d bytes for %v", ErrSize, len(b), v.Type())
+		}
+
+		reflect.Copy(v, reflect.ValueOf(b))
+	default:
+		return fmt.Errorf("%w: %v", ErrUnsupported, v.Type())
+	}
+
+	return nil
+}
+
+// assign sets v to result, converting if needed
+func assign(v reflect.Value, result any) error {
+	if result == nil {
+		v.SetZero()
+		return nil
+	}
+
+	rv := reflect.ValueOf(result)
+
+	switch {
+	case rv.Type().AssignableTo(v.Type()):
+		v.Set(rv)
+	case rv.Type().ConvertibleTo(v.Type()):
+		v.Set(rv.Convert(v.Type()))
+	default:
+		return fmt.Errorf("%w: can not assign %T to %v", ebnf.ErrEvalType, result, v.Type())
+	}
+
+	return nil
+}
+
+// nearest returns the sub matches of m for which match returns true on their ID, the sub matches of a found match
+// are not searched
+func nearest[P any](m *ebnf.Match[byte, P], match func(string) bool) []*ebnf.Match[byte, P] {
+	var found []*ebnf.Match[byte, P]
+
+	m.Walk(func(sub *ebnf.Match[byte, P], depth int) bool {
+		if depth > 0 && sub.ID() != ebnf.NoID && match(sub.ID()) {
+			found = append(found, sub)
+			return false
+		}
+
+		return true
+	})
+
+	return found
+}
+
+// decodeUint decodes up to 8 bytes in byte order
+func decodeUint(b []byte, order binary.ByteOrder) uint64 {
+	var u uint64
+
+	if order == binary.LittleEndian {
+		for i := len(b) - 1; i >= 0; i-- {
+			u = u<<8 | uint64(b[i])
+		}
+
+		return u
+	}
+
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+
+	return u
+}
+
+// parseTag parses a field tag
+func parseTag(tag string) (options, error) {
+	opts := options{order: binary.BigEndian}
+
+	parts := strings.Split(tag, ",")
+	opts.id = parts[0]
+
+	for _, part := range parts[1:] {
+		switch {
+		case part == "le":
+			opts.order = binary.LittleEndian
+		case part == "be":
+			opts.order = binary.BigEndian
+		case part == "cstr":
+			opts.cstr = true
+		case part == "eval":
+			opts.eval = true
+		case part == "required":
+			opts.required = true
+		case strings.HasPrefix(part, "size="):
+			size, err := strconv.Atoi(strings.TrimPrefix(part, "size="))
+			if err != nil || size <= 0 {
+				return opts, fmt.Errorf("invalid size option %q", part)
+			}
+
+			opts.size = size
+		default:
+			return opts, fmt.Errorf("unknown tag option %q", part)
+		}
+	}
+
+	return opts, nil
+}
+
+// wrap wraps err in a FieldError unless it already is one
+func wrap(err error, field string, id string) error {
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) {
+		return err
+	}
+
+	return &FieldError{Field: field, ID: id, Err: err}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/almerlucke/exbana/v2/importers/goebnf"
+	"github.com/almerlucke/exbana/v2/lint"
+	"github.com/almerlucke/exbana/v2/patterns/reference"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	xebnf "golang.org/x/exp/ebnf"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/scanner"
+)
+
+var checkCommand = &command{
+	name:  "check",
+	args:  "grammar.ebnf",
+	short: "check a grammar for undefined rules, left recursion, empty loops and shadowed alternatives",
+	run:   runCheck,
+}
+
+// problem is a diagnostic of the check command
+type problem struct {
+	pos     scanner.Position
+	kind    lint.Kind
+	message string
+}
+
+func runCheck(fs *flag.FlagSet, args []string, e *env) error {
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+
+	path := fs.Arg(0)
+
+	if strings.EqualFold(filepath.Ext(path), ".abnf") {
+		return errABNF
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	// Syntax errors are reported with their positions by the parser
+	grammar, err := xebnf.Parse(path, f)
+	if err != nil {
+		return err
+	}
+
+	problems := checkGrammar(grammar)
+	if len(problems) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].pos.Offset < problems[j].pos.Offset
+	})
+
+	for _, p := range problems {
+		fmt.Fprintf(e.stdout, "%v: %s: %s\n", p.pos, p.kind, p.message)
+	}
+
+	if len(problems) == 1 {
+		return errors.New("1 problem")
+	}
+
+	return fmt.Errorf("%d problems", len(problems))
+}
+
+// checkGrammar returns the problems of a grammar, undefined names are reported at their position and imported as
+// undefined references so the other checks still run, the problems lint finds are reported at their production
+func checkGrammar(grammar xebnf.Grammar) []problem {
+	var problems []problem
+
+	pre := predefined()
+	undefined := map[string]bool{}
+
+	for _, production := range grammar {
+		names(production.Expr, func(name *xebnf.Name) {
+			if _, ok := grammar[name.String]; ok {
+				return
+			}
+
+			if _, ok := pre[name.String]; ok && !undefined[name.String] {
+				return
+			}
+
+			problems = append(problems, problem{
+				pos:     name.Pos(),
+				kind:    lint.UndefinedRule,
+				message: fmt.Sprintf("undefined rule %s", name.String),
+			})
+
+			if !undefined[name.String] {
+				pre[name.String] = reference.New[rune, runes.Pos](nil)
+				undefined[name.String] = true
+			}
+		})
+	}
+
+	g, err := goebnf.Import[runes.Pos](grammar, pre)
+	if err != nil {
+		// Only syntax errors the parser already reports make the import fail
+		return problems
+	}
+
+	for _, issue := range lint.Check(g) {
+		// Undefined rules are reported with the position of the name
+		if issue.Kind == lint.UndefinedRule {
+			continue
+		}
+
+		problems = append(problems, problem{
+			pos:     grammar[issue.Rule].Pos(),
+			kind:    issue.Kind,
+			message: fmt.Sprintf("%s: %s", issue.Rule, issue.Message),
+		})
+	}
+
+	return problems
+}
+
+// names calls f for every name in expr
+func names(expr xebnf.Expression, f func(*xebnf.Name)) {
+	switch x := expr.(type) {
+	case *xebnf.Name:
+		f(x)
+	case xebnf.Alternative:
+		for _, sub := range x {
+			names(sub, f)
+		}
+	case xebnf.Sequence:
+		for _, sub := range x {
+			names(sub, f)
+		}
+	case *xebnf.Group:
+		names(x.Body, f)
+	case *xebnf.Option:
+		names(x.Body, f)
+	case *xebnf.Repetition:
+		names(x.Body, f)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/exporters/diagram"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var diagramCommand = &command{
+	name:  "diagram",
+	args:  "grammar.ebnf",
+	short: "export railroad diagrams (SVG) or a rule graph (Graphviz DOT) of a grammar",
+	run:   runDiagram,
+}
+
+func runDiagram(fs *flag.FlagSet, args []string, e *env) error {
+	format := fs.String("format", "svg", "output format: svg or dot")
+	rules := fs.String("rules", "", "comma separated rules to export, all rules by default")
+	inline := fs.Bool("inline", false, "draw anonymous groups in the diagram of their rule instead of in diagrams of their own")
+	out := fs.String("out", "", "output directory for svg (one file per diagram, the current directory by default) or output file for dot (stdout by default)")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+
+	if *format != "svg" && *format != "dot" {
+		return fmt.Errorf("%w: unknown format %q", errUsage, *format)
+	}
+
+	g, err := loadGrammar(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var selected ebnf.Patterns[rune, runes.Pos]
+
+	if *rules != "" {
+		for _, name := range strings.Split(*rules, ",") {
+			rule, err := startRule(g, strings.TrimSpace(name))
+			if err != nil {
+				return err
+			}
+
+			selected = append(selected, rule)
+		}
+	}
+
+	diagrams := diagram.Build(g, selected, diagram.Options{Inline: *inline})
+
+	if *format == "dot" {
+		var buf bytes.Buffer
+
+		name := strings.TrimSuffix(filepath.Base(fs.Arg(0)), filepath.Ext(fs.Arg(0)))
+
+		err = diagram.WriteDOT(&buf, name, diagrams)
+		if err != nil {
+			return err
+		}
+
+		if *out == "" {
+			_, err = e.stdout.Write(buf.Bytes())
+			return err
+		}
+
+		return os.WriteFile(*out, buf.Bytes(), 0o644)
+	}
+
+	dir := *out
+	if dir == "" {
+		dir = "."
+	}
+
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range diagrams {
+		var buf bytes.Buffer
+
+		err = d.WriteSVG(&buf)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(filepath.Join(dir, d.Name+".svg"), buf.Bytes(), 0o644)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/almerlucke/exbana/v2/importers/goebnf"
+	"io"
+	"os"
+)
+
+var fmtCommand = &command{
+	name:  "fmt",
+	args:  "[grammar.ebnf ...]",
+	short: "reformat grammar files canonically, stdin is formatted to stdout if no files are given",
+	run:   runFmt,
+}
+
+// orders maps the -order flag values to production orders
+var orders = map[string]goebnf.Order{
+	"source": goebnf.SourceOrder,
+	"alpha":  goebnf.AlphaOrder,
+	"use":    goebnf.UseOrder,
+}
+
+func runFmt(fs *flag.FlagSet, args []string, e *env) error {
+	order := fs.String("order", "source", "order of the rules: source, alpha or use (first rule, then by first use)")
+	width := fs.Int("width", 80, "line width, alternatives of longer rules are wrapped one per line, 0 never wraps")
+	write := fs.Bool("w", false, "write the result to the file instead of stdout")
+	list := fs.Bool("l", false, "list the files whose formatting differs instead of printing them")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	o, ok := orders[*order]
+	if !ok {
+		return fmt.Errorf("%w: unknown order %q", errUsage, *order)
+	}
+
+	opts := goebnf.FormatOptions{Order: o, Width: *width}
+
+	if fs.NArg() == 0 {
+		if *write || *list {
+			return fmt.Errorf("%w: -w and -l need files", errUsage)
+		}
+
+		src, err := io.ReadAll(e.stdin)
+		if err != nil {
+			return err
+		}
+
+		out, err := goebnf.Format("-", src, opts)
+		if err != nil {
+			return err
+		}
+
+		_, err = e.stdout.Write(out)
+
+		return err
+	}
+
+	for _, path := range fs.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		out, err := goebnf.Format(path, src, opts)
+		if err != nil {
+			return err
+		}
+
+		changed := !bytes.Equal(src, out)
+
+		if *list && changed {
+			fmt.Fprintln(e.stdout, path)
+		}
+
+		if *write && changed {
+			err = os.WriteFile(path, out, 0o644)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !*list && !*write {
+			_, err = e.stdout.Write(out)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
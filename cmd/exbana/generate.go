@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/generate"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"github.com/almerlucke/exbana/v2/writers/limit"
+	runewriter "github.com/almerlucke/exbana/v2/writers/runes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var generateCommand = &command{
+	name:  "generate",
+	args:  "grammar.ebnf",
+	short: "generate random samples from a grammar",
+	run:   runGenerate,
+}
+
+// weightsFlag collects rule=w1,w2,... flags
+type weightsFlag map[string][]float64
+
+func (f weightsFlag) String() string {
+	return ""
+}
+
+func (f weightsFlag) Set(s string) error {
+	rule, list, ok := strings.Cut(s, "=")
+	if !ok || rule == "" {
+		return errors.New("expected rule=w1,w2,...")
+	}
+
+	var weights []float64
+
+	for _, w := range strings.Split(list, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(w), 64)
+		if err != nil {
+			return fmt.Errorf("invalid weight %q", w)
+		}
+
+		weights = append(weights, v)
+	}
+
+	f[rule] = weights
+
+	return nil
+}
+
+func runGenerate(fs *flag.FlagSet, args []string, e *env) error {
+	start := fs.String("start", "", "start rule, the first rule of the grammar by default")
+	n := fs.Int("n", 1, "number of samples")
+	seed := fs.Int64("seed", 0, "random seed, 0 for a random seed")
+	out := fs.String("out", "", "directory to write the samples to, one file per sample, stdout by default")
+	ext := fs.String("ext", ".txt", "file extension of samples written to a directory")
+	maxObjects := fs.Int("max-objects", 0, "soft maximum number of runes per sample, 0 is unlimited")
+	maxDepth := fs.Int("max-depth", 0, "soft maximum nesting depth, 0 is unlimited")
+	weights := weightsFlag{}
+	fs.Var(weights, "weights", "weights of the alternatives of a rule as rule=w1,w2,... (repeatable)")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 || *n < 0 {
+		return errUsage
+	}
+
+	g, err := loadGrammar(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	rule, err := startRule(g, *start)
+	if err != nil {
+		return err
+	}
+
+	config := generate.NewConfig[rune, runes.Pos]()
+
+	for name, w := range weights {
+		p, ok := g.Rule(name)
+		if !ok {
+			return fmt.Errorf("grammar has no rule %q", name)
+		}
+
+		if _, ok := p.(*alternation.Alternation[rune, runes.Pos]); !ok {
+			return fmt.Errorf("rule %q is not an alternation", name)
+		}
+
+		config.SetWeights(p, w...)
+	}
+
+	if *seed != 0 {
+		config.SetRand(rand.New(rand.NewSource(*seed)))
+	}
+
+	if *out != "" {
+		err = os.MkdirAll(*out, 0o755)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < *n; i++ {
+		sw := runewriter.NewStringWriter()
+		lw := limit.New[rune, runes.Pos](sw, limit.Limits{MaxObjects: *maxObjects, MaxDepth: *maxDepth})
+
+		err = ebnf.GeneratePattern[rune, runes.Pos](rule, generate.New[rune, runes.Pos](lw, config))
+		if err != nil {
+			return err
+		}
+
+		err = sw.Finish()
+		if err != nil {
+			return err
+		}
+
+		if *out == "" {
+			_, err = fmt.Fprintln(e.stdout, sw.String())
+		} else {
+			err = os.WriteFile(filepath.Join(*out, fmt.Sprintf("sample_%04d%s", i+1, *ext)), []byte(sw.String()), 0o644)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
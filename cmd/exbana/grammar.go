@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/importers/goebnf"
+	"github.com/almerlucke/exbana/v2/patterns/runeclass"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// printable is the generator table of unicode_char
+var printable = &unicode.RangeTable{R16: []unicode.Range16{{Lo: ' ', Hi: '~', Stride: 1}}}
+
+// errABNF is returned for ABNF grammar files, there is no ABNF importer
+var errABNF = errors.New("ABNF grammars are not supported, only EBNF in golang.org/x/exp/ebnf notation")
+
+// loadGrammar reads and imports a grammar file
+func loadGrammar(path string) (*ebnf.Grammar[rune, runes.Pos], error) {
+	if strings.EqualFold(filepath.Ext(path), ".abnf") {
+		return nil, errABNF
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return goebnf.Parse[runes.Pos](path, f, predefined())
+}
+
+// startRule returns the rule with name, or the first rule of the grammar if name is empty
+func startRule(g *ebnf.Grammar[rune, runes.Pos], name string) (ebnf.Pattern[rune, runes.Pos], error) {
+	if name == "" {
+		if len(g.Rules()) == 0 {
+			return nil, errors.New("grammar has no rules")
+		}
+
+		return g.Rules()[0], nil
+	}
+
+	rule, ok := g.Rule(name)
+	if !ok {
+		return nil, fmt.Errorf("grammar has no rule %q", name)
+	}
+
+	return rule, nil
+}
+
+// predefined returns the productions of the Go specification that are not defined in EBNF
+func predefined() goebnf.Predefined[runes.Pos] {
+	return goebnf.Predefined[runes.Pos]{
+		"unicode_letter": runeclass.Letter[runes.Pos](),
+		"unicode_digit":  runeclass.Digit[runes.Pos](),
+		"unicode_char": runeclass.FromFunc[runes.Pos](func(c rune) bool {
+			return c != '\n'
+		}, printable, "unicode_char", "character"),
+		"newline": runeclass.MustClass[runes.Pos](`[\n]`),
+	}
+}
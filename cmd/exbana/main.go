@@ -0,0 +1,106 @@
+// Command exbana works with EBNF grammar files from the command line.
+//
+// Usage:
+//
+//	exbana <command> [flags] [arguments]
+//
+// The commands are:
+//
+//	parse     parse an input file with a grammar and print the match tree
+//	check     check a grammar for undefined rules, left recursion, empty loops and shadowed alternatives
+//	diagram   export railroad diagrams (SVG) or a rule graph (Graphviz DOT) of a grammar
+//	fmt       reformat grammar files canonically
+//	generate  generate random samples from a grammar
+//
+// Grammar files are read in golang.org/x/exp/ebnf notation, the productions unicode_letter, unicode_digit,
+// unicode_char and newline of the Go specification are predefined. Run exbana <command> -h for the flags of a command.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// command is a subcommand of the CLI
+type command struct {
+	name  string
+	args  string
+	short string
+	run   func(fs *flag.FlagSet, args []string, env *env) error
+}
+
+// env is the environment of a command run
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// errUsage is returned by a command for invalid arguments
+var errUsage = errors.New("usage")
+
+var commands = []*command{
+	parseCommand,
+	checkCommand,
+	diagramCommand,
+	fmtCommand,
+	generateCommand,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], &env{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}))
+}
+
+// run runs the command given by args and returns the exit code: 0 on success, 1 on failure and 2 on a usage error
+func run(args []string, e *env) int {
+	if len(args) == 0 {
+		usage(e.stderr)
+		return 2
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+
+		fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+		fs.SetOutput(e.stderr)
+		fs.Usage = func() {
+			fmt.Fprintf(e.stderr, "usage: exbana %s [flags] %s\n\n%s\n\nflags:\n", cmd.name, cmd.args, cmd.short)
+			fs.PrintDefaults()
+		}
+
+		err := cmd.run(fs, args[1:], e)
+
+		switch {
+		case err == nil:
+			return 0
+		case errors.Is(err, flag.ErrHelp):
+			return 0
+		case errors.Is(err, errUsage):
+			fs.Usage()
+			return 2
+		}
+
+		fmt.Fprintf(e.stderr, "exbana %s: %v\n", cmd.name, err)
+
+		return 1
+	}
+
+	fmt.Fprintf(e.stderr, "exbana: unknown command %q\n", args[0])
+	usage(e.stderr)
+
+	return 2
+}
+
+// usage prints the commands
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: exbana <command> [flags] [arguments]\n\ncommands:\n")
+
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", cmd.name, cmd.short)
+	}
+}
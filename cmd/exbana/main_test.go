@@ -0,0 +1,230 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const listGrammar = `List = "[" [ Number { "," Number } ] "]" .
+Number = digit { digit } .
+digit = "0" … "9" .
+`
+
+// testRun runs the CLI with args, files are written to a temporary directory and {name} in args is replaced by the
+// path of the file
+func testRun(t *testing.T, files map[string]string, stdin string, args ...string) (int, string, string) {
+	dir := t.TempDir()
+
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, arg := range args {
+		for name := range files {
+			arg = strings.ReplaceAll(arg, "{"+name+"}", filepath.Join(dir, name))
+		}
+
+		args[i] = arg
+	}
+
+	var stdout, stderr strings.Builder
+
+	code := run(args, &env{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr})
+
+	return code, stdout.String(), stderr.String()
+}
+
+func TestParse(t *testing.T) {
+	files := map[string]string{"list.ebnf": listGrammar, "input": "[1,23]"}
+
+	code, out, errOut := testRun(t, files, "", "parse", "-format", "sexpr", "{list.ebnf}", "{input}")
+	if code != 0 || out != "(List (Number (digit \"1\")) (Number (digit \"2\") (digit \"3\")))\n" {
+		t.Errorf("unexpected result %d: %s%s", code, out, errOut)
+	}
+
+	code, out, _ = testRun(t, files, "12", "parse", "-start", "Number", "-format", "json", "{list.ebnf}", "-")
+	if code != 0 || !strings.Contains(out, `"id": "Number"`) || strings.Count(out, `"id": "digit"`) != 2 {
+		t.Errorf("unexpected json %d: %s", code, out)
+	}
+
+	code, out, _ = testRun(t, files, "[7]", "parse", "{list.ebnf}", "-")
+	if code != 0 || !strings.HasPrefix(out, "List ") || !strings.Contains(out, "    digit ") {
+		t.Errorf("unexpected pretty print %d: %s", code, out)
+	}
+
+	code, _, errOut = testRun(t, files, "[1,x]", "parse", "{list.ebnf}", "-")
+	if code != 1 || !strings.Contains(errOut, "-:1:4: expected digit, got \"x\"") || !strings.Contains(errOut, "   ^") {
+		t.Errorf("expected parse error, got %d: %s", code, errOut)
+	}
+
+	code, _, errOut = testRun(t, files, "", "parse", "{list.ebnf}")
+	if code != 2 || !strings.Contains(errOut, "usage: exbana parse") {
+		t.Errorf("expected usage error, got %d: %s", code, errOut)
+	}
+
+	code, _, _ = testRun(t, map[string]string{"g.abnf": "rule = %x41\n"}, "", "parse", "{g.abnf}", "-")
+	if code != 1 {
+		t.Errorf("expected ABNF to be rejected, got %d", code)
+	}
+
+	code, _, _ = testRun(t, nil, "", "unknown")
+	if code != 2 {
+		t.Errorf("expected unknown command to fail, got %d", code)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	files := map[string]string{"list.ebnf": listGrammar}
+
+	code, first, errOut := testRun(t, files, "", "generate", "-n", "5", "-seed", "42", "-max-depth", "10", "{list.ebnf}")
+	if code != 0 || strings.Count(first, "\n") != 5 {
+		t.Fatalf("unexpected result %d: %s%s", code, first, errOut)
+	}
+
+	_, second, _ := testRun(t, files, "", "generate", "-n", "5", "-seed", "42", "-max-depth", "10", "{list.ebnf}")
+	if first != second {
+		t.Errorf("expected the same samples for the same seed:\n%s\n%s", first, second)
+	}
+
+	// Every sample parses
+	for _, sample := range strings.Split(strings.TrimSuffix(first, "\n"), "\n") {
+		code, _, errOut = testRun(t, files, sample, "parse", "{list.ebnf}", "-")
+		if code != 0 {
+			t.Errorf("sample %q does not parse: %s", sample, errOut)
+		}
+	}
+
+	// Samples written to a directory
+	dir := t.TempDir()
+
+	code, _, errOut = testRun(t, files, "", "generate", "-n", "3", "-out", dir, "-ext", ".list", "{list.ebnf}")
+	if code != 0 {
+		t.Fatalf("unexpected result %d: %s", code, errOut)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 3 || entries[2].Name() != "sample_0003.list" {
+		t.Errorf("unexpected samples %v: %v", entries, err)
+	}
+
+	// Weights select the alternatives of a rule
+	weighted := map[string]string{"bit.ebnf": "Bits = Bit { Bit } .\nBit = \"0\" | \"1\" .\n"}
+
+	code, out, errOut := testRun(t, weighted, "", "generate", "-n", "10", "-weights", "Bit=0,1", "{bit.ebnf}")
+	if code != 0 || strings.Contains(out, "0") {
+		t.Errorf("expected only ones, got %d: %s%s", code, out, errOut)
+	}
+
+	code, _, _ = testRun(t, weighted, "", "generate", "-weights", "Bits=1", "{bit.ebnf}")
+	if code != 1 {
+		t.Errorf("expected weights on a non alternation to fail, got %d", code)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	code, out, errOut := testRun(t, map[string]string{"list.ebnf": listGrammar}, "", "check", "{list.ebnf}")
+	if code != 0 || out != "" {
+		t.Errorf("expected no problems, got %d: %s%s", code, out, errOut)
+	}
+
+	bad := `Expr = Expr "+" Term | Term .
+Term = { [ "x" ] } | Missing .
+Op = "+" | "++" | "+" .
+`
+
+	code, out, errOut = testRun(t, map[string]string{"bad.ebnf": bad}, "", "check", "{bad.ebnf}")
+	if code != 1 || !strings.Contains(errOut, "4 problems") {
+		t.Fatalf("expected 4 problems, got %d: %s%s", code, out, errOut)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+
+	expected := []string{
+		"bad.ebnf:1:1: left-recursion: Expr: Expr -> Expr",
+		"bad.ebnf:2:1: empty-loop: Term:",
+		"bad.ebnf:2:22: undefined-rule: undefined rule Missing",
+		"bad.ebnf:3:1: shadowed-branch: Op: alternative 3",
+	}
+
+	for i, line := range lines {
+		if i >= len(expected) || !strings.Contains(line, expected[i]) {
+			t.Errorf("unexpected problem %d: %s", i+1, line)
+		}
+	}
+
+	code, _, errOut = testRun(t, map[string]string{"syntax.ebnf": "Rule = \"a\" \n"}, "", "check", "{syntax.ebnf}")
+	if code != 1 || !strings.Contains(errOut, "syntax.ebnf:2:1") {
+		t.Errorf("expected syntax error, got %d: %s", code, errOut)
+	}
+}
+
+func TestDiagram(t *testing.T) {
+	files := map[string]string{"list.ebnf": listGrammar}
+	dir := t.TempDir()
+
+	code, _, errOut := testRun(t, files, "", "diagram", "-out", dir, "-rules", "List", "{list.ebnf}")
+	if code != 0 {
+		t.Fatalf("unexpected result %d: %s", code, errOut)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 3 || entries[0].Name() != "List.1.svg" || entries[2].Name() != "List.svg" {
+		t.Errorf("unexpected diagrams %v: %v", entries, err)
+	}
+
+	code, out, errOut := testRun(t, files, "", "diagram", "-format", "dot", "-inline", "{list.ebnf}")
+	if code != 0 || !strings.HasPrefix(out, `digraph "list" {`) || !strings.Contains(out, `"List" -> "Number";`) ||
+		strings.Contains(out, "List.1") {
+		t.Errorf("unexpected dot %d: %s%s", code, out, errOut)
+	}
+
+	code, _, _ = testRun(t, files, "", "diagram", "-rules", "Missing", "{list.ebnf}")
+	if code != 1 {
+		t.Errorf("expected unknown rule to fail, got %d", code)
+	}
+}
+
+func TestFmt(t *testing.T) {
+	messy := "digit=\"0\"…\"9\".\nNumber = digit{digit} .\n"
+	formatted := "digit  = \"0\" … \"9\" .\nNumber = digit { digit } .\n"
+
+	code, out, errOut := testRun(t, nil, messy, "fmt")
+	if code != 0 || out != formatted {
+		t.Errorf("unexpected result %d: %s%s", code, out, errOut)
+	}
+
+	code, out, _ = testRun(t, nil, messy, "fmt", "-order", "alpha")
+	if code != 0 || out != "Number = digit { digit } .\ndigit  = \"0\" … \"9\" .\n" {
+		t.Errorf("unexpected sorted result %d: %s", code, out)
+	}
+
+	files := map[string]string{"messy.ebnf": messy, "formatted.ebnf": formatted}
+
+	code, out, _ = testRun(t, files, "", "fmt", "-l", "{messy.ebnf}", "{formatted.ebnf}")
+	if code != 0 || !strings.HasSuffix(out, "messy.ebnf\n") || strings.Count(out, "\n") != 1 {
+		t.Errorf("expected only messy.ebnf to be listed, got %d: %s", code, out)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "messy.ebnf")
+
+	err := os.WriteFile(path, []byte(messy), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, _, errOut = testRun(t, nil, "", "fmt", "-w", path)
+	if src, _ := os.ReadFile(path); code != 0 || string(src) != formatted {
+		t.Errorf("expected the file to be formatted, got %d: %s%s", code, src, errOut)
+	}
+
+	code, _, errOut = testRun(t, nil, "A = \"a\"\n", "fmt")
+	if code != 1 || !strings.Contains(errOut, "-:2:1") {
+		t.Errorf("expected syntax error, got %d: %s", code, errOut)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/diagnostics"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var parseCommand = &command{
+	name:  "parse",
+	args:  "grammar.ebnf input",
+	short: "parse an input file (- for stdin) with a grammar and print the match tree",
+	run:   runParse,
+}
+
+func runParse(fs *flag.FlagSet, args []string, e *env) error {
+	start := fs.String("start", "", "start rule, the first rule of the grammar by default")
+	format := fs.String("format", "pretty", "output format: pretty, json or sexpr")
+	anonymous := fs.Bool("anonymous", false, "keep matches of patterns without ID")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 2 {
+		return errUsage
+	}
+
+	if *format != "pretty" && *format != "json" && *format != "sexpr" {
+		return fmt.Errorf("%w: unknown format %q", errUsage, *format)
+	}
+
+	g, err := loadGrammar(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	rule, err := startRule(g, *start)
+	if err != nil {
+		return err
+	}
+
+	name, input := fs.Arg(1), e.stdin
+
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		input = f
+	}
+
+	r, err := runes.New(input)
+	if err != nil {
+		return err
+	}
+
+	m, err := ebnf.MatchFull[rune, runes.Pos](r, rule)
+	if err != nil {
+		var failure *ebnf.Failure[rune, runes.Pos]
+		if errors.As(err, &failure) {
+			return errors.New(diagnostics.FromFailure(name, r, failure).Report())
+		}
+
+		return err
+	}
+
+	if !*anonymous {
+		m = m.Prune()
+	}
+
+	text := func(m *ebnf.Match[rune, runes.Pos]) string {
+		if len(m.Components) > 0 {
+			return ""
+		}
+
+		s, _ := ebnf.Text(m, r)
+
+		return s
+	}
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(e.stdout)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(m.JSON(text))
+	case "sexpr":
+		_, err = fmt.Fprintln(e.stdout, sexpr(m, text))
+		return err
+	}
+
+	return m.PrettyPrint(e.stdout, text)
+}
+
+// sexpr formats a match tree as S-expression: (id "text") for leaves and (id children...) otherwise, anonymous
+// matches have _ as ID
+func sexpr(m *ebnf.Match[rune, runes.Pos], text func(*ebnf.Match[rune, runes.Pos]) string) string {
+	var sb strings.Builder
+
+	id := m.ID()
+	if id == ebnf.NoID {
+		id = "_"
+	}
+
+	sb.WriteString("(")
+	sb.WriteString(id)
+
+	if len(m.Components) == 0 {
+		sb.WriteString(" ")
+		sb.WriteString(strconv.Quote(text(m)))
+	}
+
+	for _, c := range m.Components {
+		if c != nil {
+			sb.WriteString(" ")
+			sb.WriteString(sexpr(c, text))
+		}
+	}
+
+	sb.WriteString(")")
+
+	return sb.String()
+}
@@ -0,0 +1,110 @@
+package exbana
+
+// Cloner is implemented by patterns that can be snapshot by Compile. Clone returns a shallow copy of the pattern,
+// Rebind replaces the sub patterns of the copy with the result of f
+type Cloner[T, P any] interface {
+	Clone() Pattern[T, P]
+	Rebind(f func(Pattern[T, P]) Pattern[T, P])
+}
+
+// Options are the per call options of a compiled pattern
+type Options[T, P any] struct {
+	// Logger receives a mismatch for every failed (sub) pattern, nil means no logging
+	Logger Logger[T, P]
+	// Budget limits the number of match steps and the time spent, the zero budget is unlimited
+	Budget Budget
+	// Memoize enables packrat memoization for the call
+	Memoize bool
+	// SkipValues turns off the capture of matched objects as Match.Value
+	SkipValues bool
+}
+
+// Compiled is an immutable snapshot of a pattern tree, it is safe to match a compiled pattern from multiple
+// goroutines at the same time as long as each call uses its own reader
+type Compiled[T, P any] struct {
+	pattern Pattern[T, P]
+}
+
+// Compile snapshots the pattern tree, later changes to the patterns (i.e. SetLogger, SetEvalFunc) do not affect the
+// compiled pattern. Patterns that do not implement Cloner are shared with the original tree, the loggers of the
+// snapshot are replaced by void loggers, logging is done per call with Options
+func Compile[T, P any](pattern Pattern[T, P]) *Compiled[T, P] {
+	copies := map[Pattern[T, P]]Pattern[T, P]{}
+
+	var clone func(Pattern[T, P]) Pattern[T, P]
+
+	clone = func(p Pattern[T, P]) Pattern[T, P] {
+		if c, ok := copies[p]; ok {
+			return c
+		}
+
+		cloner, ok := p.(Cloner[T, P])
+		if !ok {
+			copies[p] = p
+			return p
+		}
+
+		c := cloner.Clone()
+		c.SetLogger(NewVoidLog[T, P]())
+		copies[p] = c
+
+		c.(Cloner[T, P]).Rebind(clone)
+
+		return c
+	}
+
+	return &Compiled[T, P]{
+		pattern: clone(pattern),
+	}
+}
+
+// Match matches the compiled pattern against reader r with the given options
+func (c *Compiled[T, P]) Match(r Reader[T, P], opts Options[T, P]) (bool, *Match[T, P], error) {
+	if opts.Memoize || opts.Budget.MaxSteps > 0 || !opts.Budget.Deadline.IsZero() {
+		s, err := NewSession(r)
+		if err != nil {
+			return false, nil, err
+		}
+
+		r = s.SetMemoize(opts.Memoize).SetBudget(opts.Budget)
+	}
+
+	if opts.Logger != nil {
+		r = &logReader[T, P]{Reader: r, logger: opts.Logger}
+	}
+
+	if opts.SkipValues {
+		r = WithoutValues(r)
+	}
+
+	return MatchPattern(c.pattern, r)
+}
+
+// logReader logs a mismatch for every failed pattern match to a per call logger
+type logReader[T, P any] struct {
+	Reader[T, P]
+	logger Logger[T, P]
+}
+
+func (l *logReader[T, P]) Unwrap() Reader[T, P] {
+	return l.Reader
+}
+
+func (l *logReader[T, P]) MatchPattern(pattern Pattern[T, P], r Reader[T, P]) (bool, *Match[T, P], error) {
+	begin, err := l.Reader.Position()
+	if IsStreamError(err) {
+		return false, nil, err
+	}
+
+	matched, result, err := MatchNext(pattern, l.Reader, r)
+	if err == nil && !matched {
+		end, err := l.Reader.Position()
+		if IsStreamError(err) {
+			return false, nil, err
+		}
+
+		l.logger.LogMismatch(NewMismatch[T, P](pattern, begin, end, nil, nil))
+	}
+
+	return matched, result, err
+}
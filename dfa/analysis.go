@@ -0,0 +1,282 @@
+package dfa
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/hint"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+)
+
+// MaxUnroll is the maximum number of repetitions of a bounded repetition that is compiled to a DFA
+const MaxUnroll = 64
+
+// Regular reports if a pattern can be executed by a DFA with exactly the same result as the tree engine. A DFA finds
+// the longest match, the tree engine does not backtrack into a finished sub pattern (i.e. a repetition is greedy), so
+// both only agree if every sub pattern that is followed by another one has a single possible match length. The
+// analysis is conservative, patterns that are not recognized are not regular
+func Regular[T, P any](pattern ebnf.Pattern[T, P]) bool {
+	switch pt := pattern.(type) {
+	case *entity.Entity[T, P], *vector.Vector[T, P]:
+		return true
+	case *hint.Hint[T, P]:
+		return Regular(pt.Pattern())
+	case *concatenation.Concatenation[T, P]:
+		patterns := pt.Patterns()
+
+		for i, pm := range patterns {
+			if !Regular(pm) || (i < len(patterns)-1 && !prefixFree(pm) && !greedy(pm, patterns[i+1:])) {
+				return false
+			}
+		}
+
+		return true
+	case *alternation.Alternation[T, P]:
+		patterns := pt.Patterns()
+		if len(patterns) == 0 {
+			return false
+		}
+
+		for _, pm := range patterns {
+			if !Regular(pm) {
+				return false
+			}
+		}
+
+		// The first match only equals the longest match if all alternatives that can match end at the same position
+		return !pt.IsOrthogonal() || separable(patterns)
+	case *repetition.Repetition[T, P]:
+		if pt.Max() > MaxUnroll || pt.Min() > MaxUnroll {
+			return false
+		}
+
+		return Regular(pt.Pattern()) && prefixFree(pt.Pattern()) && !nullable(pt.Pattern())
+	}
+
+	return false
+}
+
+// nullable reports if a pattern can match without reading
+func nullable[T, P any](pattern ebnf.Pattern[T, P]) bool {
+	switch pt := pattern.(type) {
+	case *entity.Entity[T, P]:
+		return false
+	case *vector.Vector[T, P]:
+		return len(pt.Vector()) == 0
+	case *hint.Hint[T, P]:
+		return nullable(pt.Pattern())
+	case *concatenation.Concatenation[T, P]:
+		for _, pm := range pt.Patterns() {
+			if !nullable(pm) {
+				return false
+			}
+		}
+
+		return true
+	case *alternation.Alternation[T, P]:
+		for _, pm := range pt.Patterns() {
+			if nullable(pm) {
+				return true
+			}
+		}
+
+		return false
+	case *repetition.Repetition[T, P]:
+		return pt.Min() == 0 || nullable(pt.Pattern())
+	}
+
+	return true
+}
+
+// fixedLength returns the length of all matches of a pattern if they have the same length
+func fixedLength[T, P any](pattern ebnf.Pattern[T, P]) (int, bool) {
+	switch pt := pattern.(type) {
+	case *entity.Entity[T, P]:
+		return 1, true
+	case *vector.Vector[T, P]:
+		return len(pt.Vector()), true
+	case *hint.Hint[T, P]:
+		return fixedLength(pt.Pattern())
+	case *concatenation.Concatenation[T, P]:
+		total := 0
+
+		for _, pm := range pt.Patterns() {
+			n, ok := fixedLength(pm)
+			if !ok {
+				return 0, false
+			}
+
+			total += n
+		}
+
+		return total, true
+	case *alternation.Alternation[T, P]:
+		length := -1
+
+		for _, pm := range pt.Patterns() {
+			n, ok := fixedLength(pm)
+			if !ok || (length >= 0 && n != length) {
+				return 0, false
+			}
+
+			length = n
+		}
+
+		return length, length >= 0
+	case *repetition.Repetition[T, P]:
+		if pt.Min() != pt.Max() || pt.Max() == 0 {
+			return 0, false
+		}
+
+		n, ok := fixedLength(pt.Pattern())
+
+		return n * pt.Min(), ok
+	}
+
+	return 0, false
+}
+
+// literal returns the objects all matches of a pattern start with and the function to compare them, complete is
+// true if the pattern only matches the literal objects
+func literal[T, P any](pattern ebnf.Pattern[T, P]) ([]T, func(T, T) bool, bool) {
+	switch pt := pattern.(type) {
+	case *vector.Vector[T, P]:
+		return pt.Vector(), pt.Equal(), true
+	case *hint.Hint[T, P]:
+		return literal(pt.Pattern())
+	case *concatenation.Concatenation[T, P]:
+		var (
+			objs []T
+			eq   func(T, T) bool
+		)
+
+		for _, pm := range pt.Patterns() {
+			l, e, complete := literal(pm)
+			if e == nil {
+				return objs, eq, false
+			}
+
+			objs, eq = append(objs, l...), e
+
+			if !complete {
+				return objs, eq, false
+			}
+		}
+
+		return objs, eq, true
+	case *repetition.Repetition[T, P]:
+		if pt.Min() > 0 {
+			l, e, _ := literal(pt.Pattern())
+			return l, e, false
+		}
+	}
+
+	return nil, nil, false
+}
+
+// separable reports if no two alternatives can match with a different length at the same position, either because
+// they have the same fixed length or because their literal objects differ
+func separable[T, P any](patterns ebnf.Patterns[T, P]) bool {
+	for i, p1 := range patterns {
+		for _, p2 := range patterns[i+1:] {
+			n1, fixed1 := fixedLength(p1)
+			n2, fixed2 := fixedLength(p2)
+
+			if fixed1 && fixed2 && n1 == n2 {
+				continue
+			}
+
+			if !differ(p1, p2) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// differ reports if the literal objects of two patterns differ, so they can not match at the same position
+func differ[T, P any](p1 ebnf.Pattern[T, P], p2 ebnf.Pattern[T, P]) bool {
+	l1, eq, _ := literal(p1)
+	l2, _, _ := literal(p2)
+
+	for i := 0; i < len(l1) && i < len(l2); i++ {
+		if !eq(l1[i], l2[i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// prefixFree reports if no match of a pattern can be the prefix of another (longer) match of the pattern, so the
+// pattern has at most one match length at every position
+func prefixFree[T, P any](pattern ebnf.Pattern[T, P]) bool {
+	if _, ok := fixedLength(pattern); ok {
+		return true
+	}
+
+	switch pt := pattern.(type) {
+	case *hint.Hint[T, P]:
+		return prefixFree(pt.Pattern())
+	case *concatenation.Concatenation[T, P]:
+		for _, pm := range pt.Patterns() {
+			if !prefixFree(pm) {
+				return false
+			}
+		}
+
+		return true
+	case *alternation.Alternation[T, P]:
+		for _, pm := range pt.Patterns() {
+			if !prefixFree(pm) {
+				return false
+			}
+		}
+
+		return separable(pt.Patterns())
+	case *repetition.Repetition[T, P]:
+		return pt.Min() == pt.Max() && pt.Max() != 0 && prefixFree(pt.Pattern())
+	}
+
+	return false
+}
+
+// greedy reports if the longest match of a regular repetition followed by the rest of a concatenation equals the
+// greedy match. This is the case if the repeated pattern starts with a literal object the rest can not start with,
+// after fewer repetitions the next object is that literal so the rest can only match without reading
+func greedy[T, P any](pattern ebnf.Pattern[T, P], rest ebnf.Patterns[T, P]) bool {
+	for {
+		h, ok := pattern.(*hint.Hint[T, P])
+		if !ok {
+			break
+		}
+
+		pattern = h.Pattern()
+	}
+
+	rep, ok := pattern.(*repetition.Repetition[T, P])
+	if !ok {
+		return false
+	}
+
+	objs, _, _ := literal(rep.Pattern())
+	if len(objs) == 0 {
+		return false
+	}
+
+	for _, pm := range rest {
+		first, nullable := ebnf.First(pm, objs[0])
+		if first {
+			return false
+		}
+
+		if !nullable {
+			break
+		}
+	}
+
+	return true
+}
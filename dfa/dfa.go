@@ -0,0 +1,335 @@
+package dfa
+
+import (
+	"errors"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/hint"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrNotRegular is returned when a pattern can not be compiled to a DFA
+var ErrNotRegular = errors.New("pattern is not regular")
+
+// state is a DFA state, the set of NFA nodes the automaton can be in
+type state struct {
+	nodes  []int
+	accept bool
+	next   map[any]*state
+}
+
+// DFA matches a regular pattern object by object without backtracking, the states are created lazily. The match has
+// the matched objects as value and no components, eval functions and loggers of the sub patterns are not used. Like
+// the tree engine the DFA stops at the end of the stream, but an entity never matches the end of the stream
+type DFA[T, P any] struct {
+	*ebnf.BasePattern[T, P]
+	source ebnf.Pattern[T, P]
+	nfa    *nfa[T]
+	final  int
+	class  func(T) any
+	start  *state
+	states map[string]*state
+	mu     sync.Mutex
+}
+
+// New compiles a regular pattern to a DFA. If class is not nil the transitions are cached per class of the next
+// object, all objects of the same class must be accepted by the same entities (for runes or bytes the object itself
+// can be used as class). Without class the transitions are computed for every object
+func New[T, P any](pattern ebnf.Pattern[T, P], class func(T) any) (*DFA[T, P], error) {
+	if !Regular(pattern) {
+		return nil, ErrNotRegular
+	}
+
+	n := &nfa[T]{}
+	start, final := build(n, pattern)
+
+	d := &DFA[T, P]{
+		BasePattern: ebnf.NewBasePattern[T, P](),
+		source:      pattern,
+		nfa:         n,
+		final:       final,
+		class:       class,
+		states:      map[string]*state{},
+	}
+
+	d.SetSelf(d)
+	d.SetID(pattern.ID())
+	d.SetPrintOutput(pattern.PrintOutput())
+
+	d.start = d.state(d.closure([]int{start}))
+
+	return d, nil
+}
+
+// Rewrite returns a copy of the pattern tree in which all maximal regular sub trees are replaced by a DFA, the
+// remaining patterns are matched by the tree engine. Single entities and vectors are not replaced, patterns that do
+// not implement ebnf.Cloner are shared with the original tree. Sub trees are only replaced if nothing but the match
+// tree is lost: no sub pattern has an ID and no pattern in the sub tree has an eval function, a logger or a lowering
+// hint, otherwise their children are rewritten
+func Rewrite[T, P any](pattern ebnf.Pattern[T, P], class func(T) any) ebnf.Pattern[T, P] {
+	copies := map[ebnf.Pattern[T, P]]ebnf.Pattern[T, P]{}
+
+	var rewrite func(ebnf.Pattern[T, P]) ebnf.Pattern[T, P]
+
+	rewrite = func(p ebnf.Pattern[T, P]) ebnf.Pattern[T, P] {
+		if c, ok := copies[p]; ok {
+			return c
+		}
+
+		switch p.(type) {
+		case *entity.Entity[T, P], *vector.Vector[T, P]:
+			copies[p] = p
+			return p
+		}
+
+		if plain(p, true) {
+			if d, err := New(p, class); err == nil {
+				copies[p] = d
+				return d
+			}
+		}
+
+		cloner, ok := p.(ebnf.Cloner[T, P])
+		if !ok {
+			copies[p] = p
+			return p
+		}
+
+		c := cloner.Clone()
+		copies[p] = c
+
+		c.(ebnf.Cloner[T, P]).Rebind(rewrite)
+
+		return c
+	}
+
+	return rewrite(pattern)
+}
+
+// plain reports if a pattern tree has no IDs below the root and no eval functions, loggers or lowering hints
+func plain[T, P any](pattern ebnf.Pattern[T, P], root bool) bool {
+	if (!root && pattern.ID() != ebnf.NoID) || pattern.HasEvalFunc() || pattern.Lowering() != ebnf.LowerNone {
+		return false
+	}
+
+	if _, void := pattern.Logger().(*ebnf.VoidLog[T, P]); !void && pattern.Logger() != nil {
+		return false
+	}
+
+	var children ebnf.Patterns[T, P]
+
+	switch pt := pattern.(type) {
+	case *hint.Hint[T, P]:
+		children = ebnf.Patterns[T, P]{pt.Pattern()}
+	case *concatenation.Concatenation[T, P]:
+		children = pt.Patterns()
+	case *alternation.Alternation[T, P]:
+		children = pt.Patterns()
+	case *repetition.Repetition[T, P]:
+		children = ebnf.Patterns[T, P]{pt.Pattern()}
+	}
+
+	for _, child := range children {
+		if !plain(child, false) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Source returns the compiled pattern
+func (d *DFA[T, P]) Source() ebnf.Pattern[T, P] {
+	return d.source
+}
+
+// States returns the number of DFA states created so far
+func (d *DFA[T, P]) States() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.states)
+}
+
+// closure returns the sorted set of nodes reachable from nodes without reading
+func (d *DFA[T, P]) closure(nodes []int) []int {
+	seen := map[int]bool{}
+	stack := append([]int{}, nodes...)
+
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if seen[i] {
+			continue
+		}
+
+		seen[i] = true
+		stack = append(stack, d.nfa.nodes[i].eps...)
+	}
+
+	set := make([]int, 0, len(seen))
+	for i := range seen {
+		set = append(set, i)
+	}
+
+	sort.Ints(set)
+
+	return set
+}
+
+// state returns the unique state for a set of nodes, must be called with the lock held or before the DFA is shared
+func (d *DFA[T, P]) state(nodes []int) *state {
+	var sb strings.Builder
+
+	for _, i := range nodes {
+		sb.WriteString(strconv.Itoa(i))
+		sb.WriteByte(',')
+	}
+
+	key := sb.String()
+
+	if s, ok := d.states[key]; ok {
+		return s
+	}
+
+	s := &state{
+		nodes: nodes,
+		next:  map[any]*state{},
+	}
+
+	for _, i := range nodes {
+		if i == d.final {
+			s.accept = true
+		}
+	}
+
+	d.states[key] = s
+
+	return s
+}
+
+// step returns the state after reading obj
+func (d *DFA[T, P]) step(s *state, obj T) *state {
+	var class any
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.class != nil {
+		class = d.class(obj)
+		if next, ok := s.next[class]; ok {
+			return next
+		}
+	}
+
+	var targets []int
+
+	for _, i := range s.nodes {
+		if n := d.nfa.nodes[i]; n.test != nil && n.test(obj) {
+			targets = append(targets, n.out)
+		}
+	}
+
+	next := d.state(d.closure(targets))
+
+	if d.class != nil {
+		s.next[class] = next
+	}
+
+	return next
+}
+
+// Match runs the DFA and returns the longest match
+func (d *DFA[T, P]) Match(r ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], error) {
+	begin, err := ebnf.NewMarker(r)
+	if ebnf.IsStreamError(err) {
+		return false, nil, err
+	}
+
+	defer begin.Discard()
+
+	beginPos := begin.Pos()
+
+	var (
+		current  = d.start
+		accepted = current.accept
+		endPos   = beginPos
+	)
+
+	for len(current.nodes) > 0 && !r.Finished() {
+		obj, err := r.Read1()
+		if err != nil {
+			if ebnf.IsStreamError(err) {
+				return false, nil, err
+			}
+
+			break
+		}
+
+		current = d.step(current, obj)
+
+		if current.accept {
+			endPos, err = r.Position()
+			if ebnf.IsStreamError(err) {
+				return false, nil, err
+			}
+
+			accepted = true
+		}
+	}
+
+	if !accepted {
+		err = begin.Reset()
+		if ebnf.IsStreamError(err) {
+			return false, nil, err
+		}
+
+		d.Logger().LogMismatch(ebnf.NewMismatch[T, P](d, beginPos, beginPos, nil, nil))
+
+		return false, nil, nil
+	}
+
+	err = r.SetPosition(endPos)
+	if ebnf.IsStreamError(err) {
+		return false, nil, err
+	}
+
+	if ebnf.IsValidating(r) {
+		return true, nil, nil
+	}
+
+	var val []T
+
+	if ebnf.IsCapturing(r) && r.Length(beginPos, endPos) > 0 {
+		val, err = r.Range(beginPos, endPos)
+		if err != nil {
+			return false, nil, err
+		}
+	}
+
+	return true, ebnf.NewMatch[T, P](d, beginPos, endPos, val, nil), nil
+}
+
+// First reports if obj can start the source pattern
+func (d *DFA[T, P]) First(obj T) (bool, bool) {
+	return ebnf.First(d.source, obj)
+}
+
+// Generate generates the source pattern
+func (d *DFA[T, P]) Generate(w ebnf.Writer[T]) error {
+	return ebnf.GeneratePattern(d.source, w)
+}
+
+// Print prints the source pattern
+func (d *DFA[T, P]) Print(w io.Writer) error {
+	return d.source.Print(w)
+}
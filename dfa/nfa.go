@@ -0,0 +1,120 @@
+package dfa
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/hint"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+)
+
+// node is a state of the NFA, a node either reads an object that passes test and moves to out, or moves to the eps
+// nodes without reading
+type node[T any] struct {
+	test func(T) bool
+	out  int
+	eps  []int
+}
+
+type nfa[T any] struct {
+	nodes []node[T]
+}
+
+func (n *nfa[T]) add() int {
+	n.nodes = append(n.nodes, node[T]{})
+	return len(n.nodes) - 1
+}
+
+func (n *nfa[T]) link(from int, to int) {
+	n.nodes[from].eps = append(n.nodes[from].eps, to)
+}
+
+// build adds the nodes of a regular pattern and returns the start and end node, the end node has no out going edges
+func build[T, P any](n *nfa[T], pattern ebnf.Pattern[T, P]) (int, int) {
+	switch pt := pattern.(type) {
+	case *entity.Entity[T, P]:
+		start, end := n.add(), n.add()
+		n.nodes[start].test = pt.MatchFunc()
+		n.nodes[start].out = end
+
+		return start, end
+	case *vector.Vector[T, P]:
+		start := n.add()
+		end := start
+		eq := pt.Equal()
+
+		for _, obj := range pt.Vector() {
+			next := n.add()
+			n.nodes[end].test = func(o T) bool { return eq(obj, o) }
+			n.nodes[end].out = next
+			end = next
+		}
+
+		return start, end
+	case *hint.Hint[T, P]:
+		return build(n, pt.Pattern())
+	case *concatenation.Concatenation[T, P]:
+		start := n.add()
+		end := start
+
+		for _, pm := range pt.Patterns() {
+			s, e := build(n, pm)
+			n.link(end, s)
+			end = e
+		}
+
+		return start, end
+	case *alternation.Alternation[T, P]:
+		start, end := n.add(), n.add()
+
+		for _, pm := range pt.Patterns() {
+			s, e := build(n, pm)
+			n.link(start, s)
+			n.link(e, end)
+		}
+
+		return start, end
+	case *repetition.Repetition[T, P]:
+		start := n.add()
+		end := start
+
+		for i := 0; i < pt.Min(); i++ {
+			s, e := build(n, pt.Pattern())
+			n.link(end, s)
+			end = e
+		}
+
+		if pt.Max() == 0 {
+			// Loop back to the start of the pattern after each repetition
+			loop := n.add()
+			n.link(end, loop)
+
+			s, e := build(n, pt.Pattern())
+			n.link(loop, s)
+			n.link(e, loop)
+
+			final := n.add()
+			n.link(loop, final)
+
+			return start, final
+		}
+
+		// Each optional repetition can skip to the final node
+		final := n.add()
+
+		for i := pt.Min(); i < pt.Max(); i++ {
+			s, e := build(n, pt.Pattern())
+			n.link(end, s)
+			n.link(end, final)
+			end = e
+		}
+
+		n.link(end, final)
+
+		return start, final
+	}
+
+	panic("dfa: pattern is not regular")
+}
@@ -0,0 +1,135 @@
+package diagnostics
+
+import (
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"strings"
+)
+
+// ParseError is a parse error with location and source excerpt, Error formats it as file:line:col: message and
+// Report adds the source line with a caret below the error position. The diagnostic code and severity are taken from
+// the failing pattern (see ebnf.Pattern.SetDiagnostic)
+type ParseError struct {
+	// File is the name of the source, left out of the location if empty
+	File string
+	// Pos is the error position
+	Pos runes.Pos
+	// Message describes the error, i.e. expected X, got "y"
+	Message string
+	// Line is the source line containing Pos without the line ending
+	Line string
+	// Caret is the offset in runes of Pos in Line
+	Caret int
+	// Code is the diagnostic code of the failing pattern, if any
+	Code string
+	// Severity is the severity of the failing pattern, errors by default
+	Severity ebnf.Severity
+	// Suggestion is the literal suggested by Suggest, if any
+	Suggestion string
+	// Err is the underlying error, if any
+	Err error
+}
+
+// New creates a parse error for a position of r
+func New(file string, r *runes.Reader, pos runes.Pos, message string) *ParseError {
+	line, begin, _ := r.LineAt(pos)
+
+	return &ParseError{
+		File:    file,
+		Pos:     pos,
+		Message: message,
+		Line:    string(line),
+		Caret:   min(max(pos.Index-begin.Index, 0), len(line)),
+	}
+}
+
+// FromFailure creates a parse error from the farthest failure of a session
+func FromFailure(file string, r *runes.Reader, f *ebnf.Failure[rune, runes.Pos]) *ParseError {
+	e := New(file, r, f.Pos, f.Error())
+	e.Err = f
+	e.Code, e.Severity = ebnf.DiagnosticOf(f.Patterns...)
+
+	return e
+}
+
+// FromMismatch creates a parse error from a logged mismatch, the error is located at the end of the mismatch where
+// the unmatched sub pattern (or the pattern itself if there is none) was expected
+func FromMismatch(file string, r *runes.Reader, m *ebnf.Mismatch[rune, runes.Pos]) *ParseError {
+	var unmatched ebnf.Pattern[rune, runes.Pos]
+
+	expected := ebnf.Expectation(m.Pattern)
+	if m.Unmatched != nil {
+		unmatched = m.Unmatched.Pattern
+		expected = ebnf.Expectation(unmatched)
+	}
+
+	var message string
+
+	if data := r.Data(); m.End.Index < len(data) {
+		message = fmt.Sprintf("expected %s, got %s", expected, ebnf.Quote(data[m.End.Index]))
+	} else {
+		message = fmt.Sprintf("expected %s, got end of input", expected)
+	}
+
+	e := New(file, r, m.End, message)
+	e.Code, e.Severity = ebnf.DiagnosticOf(unmatched, m.Pattern)
+
+	return e
+}
+
+// Location returns the one based position as file:line:col, or line:col without file
+func (e *ParseError) Location() string {
+	if e.File == "" {
+		return fmt.Sprintf("%d:%d", e.Pos.Line+1, e.Pos.Col+1)
+	}
+
+	return fmt.Sprintf("%s:%d:%d", e.File, e.Pos.Line+1, e.Pos.Col+1)
+}
+
+// Error formats the error as location: message, with a code as location: severity[code]: message and without code
+// but with a severity other than error as location: severity: message
+func (e *ParseError) Error() string {
+	switch {
+	case e.Code != "":
+		return fmt.Sprintf("%s: %s[%s]: %s", e.Location(), e.Severity, e.Code, e.Message)
+	case e.Severity != ebnf.SeverityError:
+		return fmt.Sprintf("%s: %s: %s", e.Location(), e.Severity, e.Message)
+	}
+
+	return fmt.Sprintf("%s: %s", e.Location(), e.Message)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Excerpt returns the source line and a caret below the error position, tabs before the position are kept so the
+// caret lines up
+func (e *ParseError) Excerpt() string {
+	var sb strings.Builder
+
+	sb.WriteString(e.Line)
+	sb.WriteString("\n")
+
+	for i, c := range []rune(e.Line) {
+		if i == e.Caret {
+			break
+		}
+
+		if c == '\t' {
+			sb.WriteRune('\t')
+		} else {
+			sb.WriteRune(' ')
+		}
+	}
+
+	sb.WriteString("^")
+
+	return sb.String()
+}
+
+// Report returns the error followed by the source excerpt
+func (e *ParseError) Report() string {
+	return e.Error() + "\n" + e.Excerpt()
+}
@@ -0,0 +1,139 @@
+package diagnostics
+
+import (
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/introspect"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"sort"
+	"strconv"
+	"unicode"
+)
+
+// Literals returns the sorted set of vector literals (keywords, operators) reachable from the patterns
+func Literals(patterns ...ebnf.Pattern[rune, runes.Pos]) []string {
+	var (
+		set     = map[string]bool{}
+		visited = map[ebnf.Pattern[rune, runes.Pos]]bool{}
+		visit   func(ebnf.Pattern[rune, runes.Pos])
+	)
+
+	visit = func(p ebnf.Pattern[rune, runes.Pos]) {
+		if visited[p] {
+			return
+		}
+
+		visited[p] = true
+
+		if v, ok := p.(*vector.Vector[rune, runes.Pos]); ok && len(v.Vector()) > 0 {
+			set[string(v.Vector())] = true
+		}
+
+		for _, child := range introspect.Children(p) {
+			visit(child)
+		}
+	}
+
+	for _, p := range patterns {
+		visit(p)
+	}
+
+	literals := make([]string, 0, len(set))
+	for literal := range set {
+		literals = append(literals, literal)
+	}
+
+	sort.Strings(literals)
+
+	return literals
+}
+
+// Distance returns the edit distance between a and b in runes, insertions, deletions, substitutions and
+// transpositions of adjacent runes count as one edit
+func Distance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
+
+// Suggest returns the candidate closest to word, candidates more than a third of the word length (at least one)
+// away are not suggested. An exact match is not a suggestion
+func Suggest(word string, candidates []string) (string, bool) {
+	var (
+		best     string
+		distance = max(1, len([]rune(word))/3) + 1
+	)
+
+	for _, candidate := range candidates {
+		if d := Distance(word, candidate); d > 0 && d < distance {
+			best, distance = candidate, d
+		}
+	}
+
+	return best, best != ""
+}
+
+// Suggest replaces the message with a did you mean suggestion if the word at the error position is close to one of
+// the literals, i.e. unknown "wihle", did you mean "while"? Returns true if a suggestion was made
+func (e *ParseError) Suggest(r *runes.Reader, literals []string) bool {
+	word := wordAt(r.Data(), e.Pos.Index)
+	if word == "" {
+		return false
+	}
+
+	suggestion, ok := Suggest(word, literals)
+	if !ok {
+		return false
+	}
+
+	e.Suggestion = suggestion
+	e.Message = fmt.Sprintf("unknown %s, did you mean %s?", strconv.Quote(word), strconv.Quote(suggestion))
+
+	return true
+}
+
+// wordAt returns the word (letters, digits and underscores) or the operator (other non space runes) at index
+func wordAt(data []rune, index int) string {
+	if index < 0 || index >= len(data) || unicode.IsSpace(data[index]) {
+		return ""
+	}
+
+	isWord := func(c rune) bool {
+		return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+	}
+
+	class := isWord(data[index])
+	end := index
+
+	for end < len(data) && !unicode.IsSpace(data[end]) && isWord(data[end]) == class {
+		end++
+	}
+
+	return string(data[index:end])
+}
@@ -0,0 +1,206 @@
+package diff
+
+import (
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/introspect"
+	"strings"
+)
+
+// ChangeType describes the kind of change of a node
+type ChangeType string
+
+const (
+	Added   ChangeType = "added"
+	Removed ChangeType = "removed"
+	Changed ChangeType = "changed"
+)
+
+// Change describes a single change in a tree, path is the list of child indices from the root of the old tree for
+// removed and changed nodes and from the root of the new tree for added nodes
+type Change struct {
+	Type ChangeType       `json:"type"`
+	Path []int            `json:"path"`
+	Old  *introspect.Node `json:"old,omitempty"`
+	New  *introspect.Node `json:"new,omitempty"`
+}
+
+// RuleChange lists the changes in the body of a rule
+type RuleChange struct {
+	Rule    string    `json:"rule"`
+	Changes []*Change `json:"changes"`
+}
+
+// Report is the result of a grammar diff
+type Report struct {
+	Added   []string      `json:"added,omitempty"`
+	Removed []string      `json:"removed,omitempty"`
+	Changed []*RuleChange `json:"changed,omitempty"`
+}
+
+// Empty returns true if there are no differences
+func (r *Report) Empty() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Changed) == 0
+}
+
+// String returns a human readable report
+func (r *Report) String() string {
+	var sb strings.Builder
+
+	for _, id := range r.Removed {
+		sb.WriteString(fmt.Sprintf("- %s\n", id))
+	}
+
+	for _, id := range r.Added {
+		sb.WriteString(fmt.Sprintf("+ %s\n", id))
+	}
+
+	for _, rc := range r.Changed {
+		sb.WriteString(fmt.Sprintf("~ %s\n", rc.Rule))
+		for _, c := range rc.Changes {
+			sb.WriteString(fmt.Sprintf("    %s %s", c.Type, formatPath(c.Path)))
+			if c.Old != nil {
+				sb.WriteString(fmt.Sprintf(" old: %v", c.Old))
+			}
+			if c.New != nil {
+				sb.WriteString(fmt.Sprintf(" new: %v", c.New))
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	return sb.String()
+}
+
+func formatPath(path []int) string {
+	elements := make([]string, len(path))
+	for i, index := range path {
+		elements[i] = fmt.Sprintf("%d", index)
+	}
+
+	return "/" + strings.Join(elements, "/")
+}
+
+// Grammars compares two grammars rule by rule, rules are matched by ID
+func Grammars[T, P any](a *ebnf.Grammar[T, P], b *ebnf.Grammar[T, P]) *Report {
+	report := &Report{}
+
+	for _, rule := range a.Rules() {
+		if _, ok := b.Rule(rule.ID()); !ok {
+			report.Removed = append(report.Removed, rule.ID())
+		}
+	}
+
+	for _, rule := range b.Rules() {
+		old, ok := a.Rule(rule.ID())
+		if !ok {
+			report.Added = append(report.Added, rule.ID())
+			continue
+		}
+
+		changes := Trees(introspect.Describe(old, a), introspect.Describe(rule, b))
+		if len(changes) > 0 {
+			report.Changed = append(report.Changed, &RuleChange{Rule: rule.ID(), Changes: changes})
+		}
+	}
+
+	return report
+}
+
+// Trees computes the changes between two node trees. Children are aligned with a longest common subsequence so
+// inserted or removed alternatives and elements are reported as such instead of as a cascade of changes
+func Trees(old *introspect.Node, new *introspect.Node) []*Change {
+	return diffNodes(old, new, nil, nil)
+}
+
+func diffNodes(old *introspect.Node, new *introspect.Node, oldPath []int, newPath []int) []*Change {
+	if old.Equal(new) {
+		return nil
+	}
+
+	if old.Kind != new.Kind || old.ID != new.ID || old.Label != new.Label {
+		return []*Change{{Type: Changed, Path: copyPath(oldPath), Old: old, New: new}}
+	}
+
+	return diffChildren(old.Children, new.Children, oldPath, newPath)
+}
+
+func diffChildren(old []*introspect.Node, new []*introspect.Node, oldPath []int, newPath []int) []*Change {
+	var changes []*Change
+
+	align(len(old), len(new), func(i, j int) bool { return old[i].Equal(new[j]) },
+		func(i, j int) {
+			changes = append(changes, diffNodes(old[i], new[j], append(oldPath, i), append(newPath, j))...)
+		},
+		func(i int) {
+			changes = append(changes, &Change{Type: Removed, Path: copyPath(append(oldPath, i)), Old: old[i]})
+		},
+		func(j int) {
+			changes = append(changes, &Change{Type: Added, Path: copyPath(append(newPath, j)), New: new[j]})
+		})
+
+	return changes
+}
+
+// align aligns two lists with a longest common subsequence of equal elements. Elements removed and added in a gap
+// between common elements are paired positionally, the remaining elements are reported as removed or added
+func align(n int, m int, equal func(i, j int) bool, pair func(i, j int), removed func(i int), added func(j int)) {
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if equal(i, j) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var gapRemoved, gapAdded []int
+
+	flush := func() {
+		k := min(len(gapRemoved), len(gapAdded))
+
+		for l := 0; l < k; l++ {
+			pair(gapRemoved[l], gapAdded[l])
+		}
+
+		for _, i := range gapRemoved[k:] {
+			removed(i)
+		}
+
+		for _, j := range gapAdded[k:] {
+			added(j)
+		}
+
+		gapRemoved = gapRemoved[:0]
+		gapAdded = gapAdded[:0]
+	}
+
+	i, j := 0, 0
+
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && equal(i, j):
+			flush()
+			i++
+			j++
+		case j < m && (i == n || lcs[i][j+1] >= lcs[i+1][j]):
+			gapAdded = append(gapAdded, j)
+			j++
+		default:
+			gapRemoved = append(gapRemoved, i)
+			i++
+		}
+	}
+
+	flush()
+}
+
+func copyPath(path []int) []int {
+	return append([]int{}, path...)
+}
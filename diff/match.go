@@ -0,0 +1,104 @@
+package diff
+
+import (
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"reflect"
+	"strings"
+)
+
+// MatchNode describes a node of a match tree
+type MatchNode[P any] struct {
+	ID    string `json:"id"`
+	Begin P      `json:"begin"`
+	End   P      `json:"end"`
+}
+
+func (n *MatchNode[P]) String() string {
+	return fmt.Sprintf("%s %v - %v", n.ID, n.Begin, n.End)
+}
+
+// MatchChange describes a single change between two match trees, path is the list of component indices from the
+// root of the old tree for removed and changed nodes and from the root of the new tree for added nodes
+type MatchChange[P any] struct {
+	Type ChangeType    `json:"type"`
+	Path []int         `json:"path"`
+	Old  *MatchNode[P] `json:"old,omitempty"`
+	New  *MatchNode[P] `json:"new,omitempty"`
+}
+
+func (c *MatchChange[P]) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("%s %s", c.Type, formatPath(c.Path)))
+
+	if c.Old != nil {
+		sb.WriteString(fmt.Sprintf(" old: %v", c.Old))
+	}
+
+	if c.New != nil {
+		sb.WriteString(fmt.Sprintf(" new: %v", c.New))
+	}
+
+	return sb.String()
+}
+
+// Matches computes the changes between two match trees, i.e. the results of an old and a new grammar on the same
+// input. Only named matches are compared (see Match.Prune), so refactoring anonymous groups does not show up as a
+// change. A node changes if its ID or positions change
+func Matches[T, P any](old *ebnf.Match[T, P], new *ebnf.Match[T, P]) []*MatchChange[P] {
+	return diffMatches(old.Prune(), new.Prune(), nil, nil)
+}
+
+func sameNode[T, P any](a *ebnf.Match[T, P], b *ebnf.Match[T, P]) bool {
+	return a.ID() == b.ID() && reflect.DeepEqual(a.Begin, b.Begin) && reflect.DeepEqual(a.End, b.End)
+}
+
+func sameTree[T, P any](a *ebnf.Match[T, P], b *ebnf.Match[T, P]) bool {
+	if !sameNode(a, b) || len(a.Components) != len(b.Components) {
+		return false
+	}
+
+	for i, component := range a.Components {
+		if !sameTree(component, b.Components[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchNode[T, P any](m *ebnf.Match[T, P]) *MatchNode[P] {
+	return &MatchNode[P]{ID: m.ID(), Begin: m.Begin, End: m.End}
+}
+
+func diffMatches[T, P any](old *ebnf.Match[T, P], new *ebnf.Match[T, P], oldPath []int, newPath []int) []*MatchChange[P] {
+	if sameTree(old, new) {
+		return nil
+	}
+
+	var changes []*MatchChange[P]
+
+	if !sameNode(old, new) {
+		changes = append(changes, &MatchChange[P]{Type: Changed, Path: copyPath(oldPath), Old: matchNode(old), New: matchNode(new)})
+
+		if old.ID() != new.ID() {
+			return changes
+		}
+	}
+
+	oldComponents, newComponents := old.Components, new.Components
+
+	align(len(oldComponents), len(newComponents), func(i, j int) bool { return sameTree(oldComponents[i], newComponents[j]) },
+		func(i, j int) {
+			changes = append(changes, diffMatches(oldComponents[i], newComponents[j], append(oldPath, i), append(newPath, j))...)
+		},
+		func(i int) {
+			changes = append(changes, &MatchChange[P]{Type: Removed, Path: copyPath(append(oldPath, i)), Old: matchNode(oldComponents[i])})
+		},
+		func(j int) {
+			changes = append(changes, &MatchChange[P]{Type: Added, Path: copyPath(append(newPath, j)), New: matchNode(newComponents[j])})
+		})
+
+	return changes
+}
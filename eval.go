@@ -0,0 +1,207 @@
+package exbana
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrEvalType is returned when an eval result does not have the expected type
+var ErrEvalType = errors.New("unexpected eval result type")
+
+// EvalAs evaluates a match and asserts the result has type R
+func EvalAs[R, T, P any](m *Match[T, P], r Reader[T, P]) (R, error) {
+	var zero R
+
+	v, err := m.Eval(r)
+	if err != nil {
+		return zero, err
+	}
+
+	result, ok := v.(R)
+	if !ok {
+		return zero, fmt.Errorf("%w: %q expected %T, got %T", ErrEvalType, m.ID(), zero, v)
+	}
+
+	return result, nil
+}
+
+// TypedPattern is a pattern with an eval function that returns a concrete type R, it can be used everywhere the
+// wrapped pattern can be used
+type TypedPattern[R, T, P any] struct {
+	Pattern[T, P]
+}
+
+// Typed sets a typed eval function on pattern
+func Typed[R, T, P any](pattern Pattern[T, P], eval func(*Match[T, P], Reader[T, P]) (R, error)) *TypedPattern[R, T, P] {
+	pattern.SetEvalFunc(func(m *Match[T, P], r Reader[T, P]) (any, error) {
+		return eval(m, r)
+	})
+
+	return &TypedPattern[R, T, P]{Pattern: pattern}
+}
+
+// Value evaluates a match of the pattern
+func (p *TypedPattern[R, T, P]) Value(m *Match[T, P], r Reader[T, P]) (R, error) {
+	return EvalAs[R](m, r)
+}
+
+// TransformFunc transforms a match, the table is passed so sub matches can be transformed
+type TransformFunc[T, P any] func(m *Match[T, P], t TransformTable[T, P], r Reader[T, P]) (any, error)
+
+// TransformTable maps pattern IDs to transform functions, this keeps evaluation separate from the grammar
+type TransformTable[T, P any] map[string]TransformFunc[T, P]
+
+// Transform unpacks the match and calls the transform function for the ID of the match, if the table has no
+// function for the ID the match is evaluated with Eval
+func (t TransformTable[T, P]) Transform(m *Match[T, P], r Reader[T, P]) (any, error) {
+	u := m.Unpack()
+
+	if f, ok := t[u.ID()]; ok {
+		return f(u, t, r)
+	}
+
+	return u.Eval(r)
+}
+
+// TransformAll transforms all matches
+func (t TransformTable[T, P]) TransformAll(ms []*Match[T, P], r Reader[T, P]) ([]any, error) {
+	results := make([]any, len(ms))
+
+	for i, m := range ms {
+		result, err := t.Transform(m, r)
+		if err != nil {
+			return nil, err
+		}
+
+		results[i] = result
+	}
+
+	return results, nil
+}
+
+// Transform transforms the match with a transform table
+func (m *Match[T, P]) Transform(t TransformTable[T, P], r Reader[T, P]) (any, error) {
+	return t.Transform(m, r)
+}
+
+// Contexter is an optional extension of Reader which carries a caller supplied context value (i.e. a symbol table or
+// options) through evaluation. Eval functions pass the reader on to the evaluation of sub matches, so the context is
+// available in all nested eval functions without closures created per parse
+type Contexter interface {
+	Context() any
+}
+
+// WithContext returns a reader middleware on top of r which carries ctx
+func WithContext[T, P any](r Reader[T, P], ctx any) Reader[T, P] {
+	return &withContext[T, P]{Reader: r, ctx: ctx}
+}
+
+// Context returns the context carried by r, nil if no reader in the middleware chain of r implements Contexter
+func Context[T, P any](r Reader[T, P]) any {
+	if c, ok := Lookup[Contexter](r); ok {
+		return c.Context()
+	}
+
+	return nil
+}
+
+// ContextAs returns the context carried by r if it has type C
+func ContextAs[C, T, P any](r Reader[T, P]) (C, bool) {
+	ctx, ok := Context(r).(C)
+	return ctx, ok
+}
+
+// EvalContext evaluates the match with ctx as context, eval functions can get the context with Context(r)
+func (m *Match[T, P]) EvalContext(r Reader[T, P], ctx any) (any, error) {
+	return m.Eval(WithContext(r, ctx))
+}
+
+// withContext is a reader middleware that carries a context value
+type withContext[T, P any] struct {
+	Reader[T, P]
+	ctx any
+}
+
+func (c *withContext[T, P]) Context() any {
+	return c.ctx
+}
+
+func (c *withContext[T, P]) Unwrap() Reader[T, P] {
+	return c.Reader
+}
+
+func (c *withContext[T, P]) MatchPattern(pattern Pattern[T, P], r Reader[T, P]) (bool, *Match[T, P], error) {
+	return MatchNext(pattern, c.Reader, r)
+}
+
+// EvalError is returned by Evaluate, it wraps the error of the failing match with its position and rule ID
+type EvalError[P any] struct {
+	// ID is the ID of the failing match or of its nearest ancestor with an ID
+	ID    string
+	Begin P
+	End   P
+	Err   error
+}
+
+func (e *EvalError[P]) Error() string {
+	return fmt.Sprintf("eval %q at %v - %v: %v", e.ID, e.Begin, e.End, e.Err)
+}
+
+func (e *EvalError[P]) Unwrap() error {
+	return e.Err
+}
+
+// Evaluate evaluates the match, if evaluation fails the sub matches are evaluated one by one to locate the failing
+// matches. Only the innermost failing matches are reported, so a parent failing because of a child is not reported
+// again. A single failure is returned as *EvalError, multiple failures are joined with errors.Join
+func Evaluate[T, P any](m *Match[T, P], r Reader[T, P]) (any, error) {
+	v, err := m.Eval(r)
+	if err == nil {
+		return v, nil
+	}
+
+	errs := locateEvalErrors(m, r, NoID)
+	if len(errs) == 0 {
+		// The match did not fail again when evaluated in isolation
+		errs = append(errs, newEvalError(m, m.ID(), err))
+	}
+
+	if len(errs) == 1 {
+		return nil, errs[0]
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// locateEvalErrors evaluates the sub matches of m depth first and returns the errors of the innermost failing matches
+func locateEvalErrors[T, P any](m *Match[T, P], r Reader[T, P], rule string) []error {
+	if id := m.ID(); id != NoID {
+		rule = id
+	}
+
+	var errs []error
+
+	for _, component := range m.Components {
+		errs = append(errs, locateEvalErrors(component, r, rule)...)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	if _, err := m.Eval(r); err != nil {
+		return []error{newEvalError(m, rule, err)}
+	}
+
+	return nil
+}
+
+func newEvalError[T, P any](m *Match[T, P], rule string, err error) error {
+	var evalErr *EvalError[P]
+	if errors.As(err, &evalErr) {
+		// Already located by a nested Evaluate
+		return err
+	}
+
+	return &EvalError[P]{ID: rule, Begin: m.Begin, End: m.End, Err: err}
+}
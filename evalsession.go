@@ -0,0 +1,85 @@
+package exbana
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrEvalCycle is returned when the evaluation of a match depends on itself
+var ErrEvalCycle = errors.New("eval cycle")
+
+// Evaluator is an optional extension of Reader which intercepts the evaluation of matches, Match.Eval delegates to
+// the first reader in the middleware chain that implements Evaluator
+type Evaluator[T, P any] interface {
+	EvalMatch(m *Match[T, P], r Reader[T, P]) (any, error)
+}
+
+// evalResult is a cached eval result
+type evalResult struct {
+	value any
+	err   error
+}
+
+// EvalSession is a reader middleware for a single evaluation, it caches the eval result per match node so a sub
+// match shared by multiple parents (i.e. served from the memo of a memoizing Session) is evaluated only once, and
+// detects matches whose evaluation depends on itself. The session is not safe for concurrent use
+type EvalSession[T, P any] struct {
+	Reader[T, P]
+	results map[*Match[T, P]]evalResult
+	active  map[*Match[T, P]]bool
+	hits    int
+}
+
+// NewEvalSession creates a new evaluation session on top of r
+func NewEvalSession[T, P any](r Reader[T, P]) *EvalSession[T, P] {
+	return &EvalSession[T, P]{
+		Reader:  r,
+		results: map[*Match[T, P]]evalResult{},
+		active:  map[*Match[T, P]]bool{},
+	}
+}
+
+// Eval evaluates a match in the session
+func (s *EvalSession[T, P]) Eval(m *Match[T, P]) (any, error) {
+	return m.Eval(s)
+}
+
+// EvalMatch returns the cached result of the match or evaluates it with the eval function of its pattern
+func (s *EvalSession[T, P]) EvalMatch(m *Match[T, P], r Reader[T, P]) (any, error) {
+	if result, ok := s.results[m]; ok {
+		s.hits++
+		return result.value, result.err
+	}
+
+	if s.active[m] {
+		return nil, fmt.Errorf("%w: %q at %v - %v", ErrEvalCycle, m.ID(), m.Begin, m.End)
+	}
+
+	s.active[m] = true
+	value, err := m.Pattern.Eval(m, r)
+	delete(s.active, m)
+
+	s.results[m] = evalResult{value: value, err: err}
+
+	return value, err
+}
+
+// Hits returns the number of evaluations served from the cache
+func (s *EvalSession[T, P]) Hits() int {
+	return s.hits
+}
+
+// Reset clears the cache
+func (s *EvalSession[T, P]) Reset() {
+	s.results = map[*Match[T, P]]evalResult{}
+	s.hits = 0
+}
+
+// Unwrap returns the source reader
+func (s *EvalSession[T, P]) Unwrap() Reader[T, P] {
+	return s.Reader
+}
+
+func (s *EvalSession[T, P]) MatchPattern(pattern Pattern[T, P], r Reader[T, P]) (bool, *Match[T, P], error) {
+	return MatchNext(pattern, s.Reader, r)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"sync"
 )
 
 // IsStreamError check if err is set and not io.EOF
@@ -13,32 +14,151 @@ func IsStreamError(err error) bool {
 
 // Scan stream for pattern and return all results
 func Scan[T, P any](stream Reader[T, P], pattern Pattern[T, P]) ([]*Match[T, P], error) {
+	return ScanN(stream, pattern, 0)
+}
+
+// FindFirst scans stream for the first match of pattern, returns nil if there is no match. The stream is positioned
+// after the match
+func FindFirst[T, P any](stream Reader[T, P], pattern Pattern[T, P]) (*Match[T, P], error) {
+	results, err := ScanN(stream, pattern, 1)
+	if err != nil || len(results) == 0 {
+		return nil, err
+	}
+
+	return results[0], nil
+}
+
+// ScanN scans stream for pattern and stops after n results, if n <= 0 all results are returned. The stream is
+// positioned after the last match. An empty match is followed by a skip of the next object, so patterns that can
+// match empty input do not stall the scan
+func ScanN[T, P any](stream Reader[T, P], pattern Pattern[T, P], n int) ([]*Match[T, P], error) {
 	var results []*Match[T, P]
 
-	for !stream.Finished() {
-		pos, err := stream.Position()
+	for !stream.Finished() && (n <= 0 || len(results) < n) {
+		mark, err := NewMarker(stream)
 		if IsStreamError(err) {
 			return nil, err
 		}
-		matched, result, err := pattern.Match(stream)
+		matched, result, err := MatchPattern(pattern, stream)
 		if err != nil {
 			return nil, err
 		}
 		if matched {
+			mark.Discard()
 			results = append(results, result)
+			if stream.Length(mark.Pos(), result.End) > 0 || (n > 0 && len(results) == n) {
+				continue
+			}
 		} else {
-			err = stream.SetPosition(pos)
+			err = mark.Reset()
+			mark.Discard()
 			if IsStreamError(err) {
 				return nil, err
 			}
-			_, err = stream.Skip(1)
+		}
+		_, err = stream.Skip(1)
+		if IsStreamError(err) {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// Replace scans stream for pattern and writes the transformed stream to w, for each match the output of replace is
+// written and unmatched objects are copied verbatim. An empty match is replaced as well and is followed by a copy of
+// the next object, so patterns that can match empty input do not stall the scan. Finish is called on w when the end of
+// the stream is reached
+func Replace[T, P any](stream Reader[T, P], pattern Pattern[T, P], w Writer[T], replace func(*Match[T, P], Reader[T, P]) ([]T, error)) error {
+	for !stream.Finished() {
+		mark, err := NewMarker(stream)
+		if IsStreamError(err) {
+			return err
+		}
+		matched, result, err := MatchPattern(pattern, stream)
+		if err != nil {
+			mark.Discard()
+			return err
+		}
+		if matched {
+			mark.Discard()
+			output, err := replace(result, stream)
+			if err != nil {
+				return err
+			}
+			err = w.Write(output...)
+			if err != nil {
+				return err
+			}
+			if stream.Length(mark.Pos(), result.End) > 0 {
+				continue
+			}
+		} else {
+			err = mark.Reset()
+			mark.Discard()
 			if IsStreamError(err) {
-				return nil, err
+				return err
 			}
 		}
+		obj, err := stream.Read1()
+		if IsStreamError(err) {
+			return err
+		}
+		err = w.Write(obj)
+		if err != nil {
+			return err
+		}
 	}
 
-	return results, nil
+	return w.Finish()
+}
+
+// ScanParallel scans chunks of the input for pattern on a pool of workers and returns all results in chunk order.
+// Chunks must be split at boundaries where no match can cross (see runes.Reader.Chunks for newline based chunking)
+// and must serve absolute positions so results have correct positions. Patterns are shared between workers, so
+// loggers and eval functions must be safe for concurrent use. If workers <= 0 a worker per chunk is used
+func ScanParallel[T, P any](chunks []Reader[T, P], pattern Pattern[T, P], workers int) ([]*Match[T, P], error) {
+	if workers <= 0 || workers > len(chunks) {
+		workers = len(chunks)
+	}
+
+	var (
+		results = make([][]*Match[T, P], len(chunks))
+		errs    = make([]error, len(chunks))
+		jobs    = make(chan int)
+		wg      sync.WaitGroup
+	)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				results[i], errs[i] = Scan(chunks[i], pattern)
+			}
+		}()
+	}
+
+	for i := range chunks {
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	var merged []*Match[T, P]
+
+	for i, chunkResults := range results {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+
+		merged = append(merged, chunkResults...)
+	}
+
+	return merged, nil
 }
 
 // PrintRules prints all rules and returns a string
@@ -0,0 +1,108 @@
+package exbana
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Expecter is an optional extension of Pattern which describes the input a terminal pattern expects, the
+// description is used in expected sets of failures
+type Expecter interface {
+	Expected() string
+}
+
+// Expectation returns the description of the input pattern expects, the Expected description if the pattern is an
+// Expecter, otherwise the ID or the print output
+func Expectation[T, P any](pattern Pattern[T, P]) string {
+	if e, ok := pattern.(Expecter); ok {
+		if expected := e.Expected(); expected != "" {
+			return expected
+		}
+	}
+
+	if id := pattern.ID(); id != NoID {
+		return id
+	}
+
+	return pattern.PrintOutput()
+}
+
+// Quote returns a quoted string for runes, bytes or strings, other types are formatted with %v
+func Quote[T any](objects ...T) string {
+	var sb strings.Builder
+
+	for _, obj := range objects {
+		switch v := any(obj).(type) {
+		case rune:
+			sb.WriteRune(v)
+		case byte:
+			sb.WriteByte(v)
+		case string:
+			sb.WriteString(v)
+		default:
+			if len(objects) == 1 {
+				return fmt.Sprintf("%v", obj)
+			}
+
+			return fmt.Sprintf("%v", objects)
+		}
+	}
+
+	return strconv.Quote(sb.String())
+}
+
+// Expected returns the sorted set of descriptions of the patterns that failed at the farthest position, patterns
+// without description are left out
+func (f *Failure[T, P]) Expected() []string {
+	set := map[string]bool{}
+
+	for _, pattern := range f.Patterns {
+		if expected := Expectation(pattern); expected != "" {
+			set[expected] = true
+		}
+	}
+
+	expected := make([]string, 0, len(set))
+	for e := range set {
+		expected = append(expected, e)
+	}
+
+	sort.Strings(expected)
+
+	return expected
+}
+
+// Error formats the failure as expected X, Y or Z, got W
+func (f *Failure[T, P]) Error() string {
+	var sb strings.Builder
+
+	if expected := f.Expected(); len(expected) > 0 {
+		sb.WriteString("expected ")
+
+		for i, e := range expected {
+			switch {
+			case i == 0:
+			case i == len(expected)-1:
+				sb.WriteString(" or ")
+			default:
+				sb.WriteString(", ")
+			}
+
+			sb.WriteString(e)
+		}
+
+		sb.WriteString(", got ")
+	} else {
+		sb.WriteString("unexpected ")
+	}
+
+	if f.AtEnd {
+		sb.WriteString("end of input")
+	} else {
+		sb.WriteString(Quote(f.Found))
+	}
+
+	return sb.String()
+}
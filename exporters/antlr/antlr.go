@@ -0,0 +1,496 @@
+package antlr
+
+import (
+	"bytes"
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/introspect"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/end"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/exception"
+	"github.com/almerlucke/exbana/v2/patterns/reference"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Export writes the grammar as an ANTLR4 combined grammar. Rules that only consist of terminal patterns (entities,
+// vectors and groups of those) become lexer rules, all other rules become parser rules. Lexer rules that are only
+// used by other lexer rules are exported as fragments. Anonymous terminal groups used inside parser rules are hoisted
+// into generated lexer rules because ANTLR only allows character sets in the lexer.
+func Export[T, P any](w io.Writer, name string, g *ebnf.Grammar[T, P]) error {
+	e := newExporter(g)
+
+	return e.export(w, name)
+}
+
+type exporter[T, P any] struct {
+	grammar  *ebnf.Grammar[T, P]
+	lexical  map[string]bool
+	visiting map[string]bool
+	hoisted  []string
+	hoistMap map[ebnf.Pattern[T, P]]string
+}
+
+func newExporter[T, P any](g *ebnf.Grammar[T, P]) *exporter[T, P] {
+	return &exporter[T, P]{
+		grammar:  g,
+		lexical:  map[string]bool{},
+		visiting: map[string]bool{},
+		hoistMap: map[ebnf.Pattern[T, P]]string{},
+	}
+}
+
+func (e *exporter[T, P]) export(w io.Writer, name string) error {
+	var (
+		parserRules bytes.Buffer
+		lexerRules  bytes.Buffer
+		fragments   = map[string]bool{}
+	)
+
+	rules := e.grammar.Rules()
+
+	// Lexer rules referenced from other lexer rules become fragments
+	for _, rule := range rules {
+		if e.isLexicalRule(rule) {
+			e.collectReferences(rule, true, func(ref ebnf.Pattern[T, P]) {
+				if e.isLexicalRule(ref) {
+					fragments[ref.ID()] = true
+				}
+			})
+		}
+	}
+
+	// A fragment that is also referenced by a parser rule must stay a token
+	for _, rule := range rules {
+		if !e.isLexicalRule(rule) {
+			e.collectReferences(rule, true, func(ref ebnf.Pattern[T, P]) {
+				delete(fragments, ref.ID())
+			})
+		}
+	}
+
+	for _, rule := range rules {
+		if e.isLexicalRule(rule) {
+			body, _, err := e.expression(rule, true, true)
+			if err != nil {
+				return err
+			}
+
+			if fragments[rule.ID()] {
+				_, err = lexerRules.WriteString("fragment ")
+				if err != nil {
+					return err
+				}
+			}
+
+			_, err = lexerRules.WriteString(fmt.Sprintf("%s : %s ;\n", lexerName(rule.ID()), body))
+			if err != nil {
+				return err
+			}
+		} else {
+			body, _, err := e.expression(rule, false, true)
+			if err != nil {
+				return err
+			}
+
+			_, err = parserRules.WriteString(fmt.Sprintf("%s : %s ;\n", parserName(rule.ID()), body))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, hoisted := range e.hoisted {
+		_, err := lexerRules.WriteString(hoisted)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := w.Write([]byte(fmt.Sprintf("grammar %s;\n\n", name)))
+	if err != nil {
+		return err
+	}
+
+	if parserRules.Len() > 0 {
+		_, err = w.Write(append(parserRules.Bytes(), '\n'))
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = w.Write(lexerRules.Bytes())
+
+	return err
+}
+
+// isRuleReference checks if a child pattern refers to a grammar rule instead of being inlined
+func (e *exporter[T, P]) isRuleReference(p ebnf.Pattern[T, P]) bool {
+	return e.grammar.IsRule(p)
+}
+
+// collectReferences calls f for each rule referenced from the body of pattern
+func (e *exporter[T, P]) collectReferences(p ebnf.Pattern[T, P], root bool, f func(ebnf.Pattern[T, P])) {
+	if !root && e.isRuleReference(p) {
+		f(p)
+		return
+	}
+
+	for _, child := range introspect.Children(p) {
+		e.collectReferences(child, false, f)
+	}
+}
+
+// isLexicalRule checks if a rule only consists of terminals and references to other lexical rules
+func (e *exporter[T, P]) isLexicalRule(rule ebnf.Pattern[T, P]) bool {
+	id := rule.ID()
+
+	if lexical, ok := e.lexical[id]; ok {
+		return lexical
+	}
+
+	// Recursive rules are always parser rules
+	if e.visiting[id] {
+		return false
+	}
+
+	e.visiting[id] = true
+	lexical := e.isLexical(rule, true)
+	delete(e.visiting, id)
+
+	e.lexical[id] = lexical
+
+	return lexical
+}
+
+// isLexical checks if a pattern only consists of terminals
+func (e *exporter[T, P]) isLexical(p ebnf.Pattern[T, P], root bool) bool {
+	if !root && e.isRuleReference(p) {
+		return e.isLexicalRule(p)
+	}
+
+	switch p.(type) {
+	case *entity.Entity[T, P], *vector.Vector[T, P]:
+		return true
+	case *end.End[T, P]:
+		return false
+	}
+
+	for _, child := range introspect.Children(p) {
+		if !e.isLexical(child, false) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hoist creates a lexer rule for an anonymous terminal group used in a parser rule
+func (e *exporter[T, P]) hoist(p ebnf.Pattern[T, P]) (string, error) {
+	if name, ok := e.hoistMap[p]; ok {
+		return name, nil
+	}
+
+	body, _, err := e.expression(p, true, true)
+	if err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("EXBANA_LIT_%d", len(e.hoisted))
+	e.hoistMap[p] = name
+	e.hoisted = append(e.hoisted, fmt.Sprintf("%s : %s ;\n", name, body))
+
+	return name, nil
+}
+
+// canHoist checks if an anonymous pattern tree contains an entity (which ANTLR only allows in lexer rules), no rule
+// references and can not match the empty string (which ANTLR does not allow for lexer rules)
+func (e *exporter[T, P]) canHoist(p ebnf.Pattern[T, P]) bool {
+	hasCharacterSet := false
+
+	var visit func(ebnf.Pattern[T, P]) bool
+
+	visit = func(p ebnf.Pattern[T, P]) bool {
+		if e.isRuleReference(p) {
+			return false
+		}
+
+		switch p.(type) {
+		case *entity.Entity[T, P]:
+			hasCharacterSet = true
+		case *vector.Vector[T, P]:
+		case *alternation.Alternation[T, P], *concatenation.Concatenation[T, P], *repetition.Repetition[T, P],
+			*reference.Reference[T, P]:
+		default:
+			return false
+		}
+
+		for _, child := range introspect.Children(p) {
+			if !visit(child) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return visit(p) && hasCharacterSet && !nullable(p)
+}
+
+type exprKind int
+
+const (
+	atomExpr exprKind = iota
+	sequenceExpr
+	choiceExpr
+)
+
+// subExpression converts a child pattern and groups it if needed in the context of the parent
+func (e *exporter[T, P]) subExpression(p ebnf.Pattern[T, P], lexer bool, groupKind exprKind) (string, error) {
+	expr, kind, err := e.expression(p, lexer, false)
+	if err != nil {
+		return "", err
+	}
+
+	if kind >= groupKind {
+		return "(" + expr + ")", nil
+	}
+
+	return expr, nil
+}
+
+// expression converts a pattern to an ANTLR expression
+func (e *exporter[T, P]) expression(p ebnf.Pattern[T, P], lexer bool, root bool) (string, exprKind, error) {
+	if !root && e.isRuleReference(p) {
+		if e.isLexicalRule(p) {
+			return lexerName(p.ID()), atomExpr, nil
+		}
+
+		if lexer {
+			return "", atomExpr, fmt.Errorf("lexer rule can not reference parser rule %q", p.ID())
+		}
+
+		return parserName(p.ID()), atomExpr, nil
+	}
+
+	// Anonymous terminal groups with character sets in parser rules are hoisted into the lexer
+	if !lexer && !root && e.canHoist(p) {
+		name, err := e.hoist(p)
+		return name, atomExpr, err
+	}
+
+	switch pt := p.(type) {
+	case *entity.Entity[T, P]:
+		if pt.PrintOutput() == "" {
+			return "", atomExpr, fmt.Errorf("entity %q has no print output to export", pt.ID())
+		}
+
+		return pt.PrintOutput(), atomExpr, nil
+	case *vector.Vector[T, P]:
+		lit, err := literal(pt.Vector())
+		return lit, atomExpr, err
+	case *end.End[T, P]:
+		if lexer {
+			return "", atomExpr, fmt.Errorf("end of stream can not be used in lexer rule %q", pt.ID())
+		}
+
+		return "EOF", atomExpr, nil
+	case *alternation.Alternation[T, P]:
+		alternatives := make([]string, len(pt.Patterns()))
+
+		for i, child := range pt.Patterns() {
+			expr, err := e.subExpression(child, lexer, choiceExpr)
+			if err != nil {
+				return "", atomExpr, err
+			}
+
+			alternatives[i] = expr
+		}
+
+		return strings.Join(alternatives, " | "), choiceExpr, nil
+	case *concatenation.Concatenation[T, P]:
+		elements := make([]string, len(pt.Patterns()))
+
+		for i, child := range pt.Patterns() {
+			expr, err := e.subExpression(child, lexer, choiceExpr)
+			if err != nil {
+				return "", atomExpr, err
+			}
+
+			elements[i] = expr
+		}
+
+		return strings.Join(elements, " "), sequenceExpr, nil
+	case *repetition.Repetition[T, P]:
+		expr, err := e.subExpression(pt.Pattern(), lexer, sequenceExpr)
+		if err != nil {
+			return "", atomExpr, err
+		}
+
+		return repeat(expr, pt.Min(), pt.Max())
+	case *reference.Reference[T, P]:
+		if pt.Pattern() == nil {
+			return "", atomExpr, ebnf.ErrUnresolvedReference
+		}
+
+		return e.expression(pt.Pattern(), lexer, false)
+	case *exception.Exception[T, P]:
+		return "", atomExpr, fmt.Errorf("exception %q can not be expressed in ANTLR", pt.ID())
+	}
+
+	return "", atomExpr, fmt.Errorf("unsupported pattern type %T", p)
+}
+
+// nullable checks if an anonymous terminal group can match the empty string
+func nullable[T, P any](p ebnf.Pattern[T, P]) bool {
+	switch pt := p.(type) {
+	case *vector.Vector[T, P]:
+		return len(pt.Vector()) == 0
+	case *alternation.Alternation[T, P]:
+		for _, child := range pt.Patterns() {
+			if nullable(child) {
+				return true
+			}
+		}
+
+		return false
+	case *concatenation.Concatenation[T, P]:
+		for _, child := range pt.Patterns() {
+			if !nullable(child) {
+				return false
+			}
+		}
+
+		return true
+	case *repetition.Repetition[T, P]:
+		return pt.Min() == 0 || nullable(pt.Pattern())
+	case *reference.Reference[T, P]:
+		return pt.Pattern() != nil && nullable(pt.Pattern())
+	}
+
+	return false
+}
+
+// repeat expands a bounded repetition
+func repeat(expr string, min int, max int) (string, exprKind, error) {
+	switch {
+	case min == 0 && max == 0:
+		return expr + "*", atomExpr, nil
+	case min == 0 && max == 1:
+		return expr + "?", atomExpr, nil
+	case min == 1 && max == 0:
+		return expr + "+", atomExpr, nil
+	case max != 0 && max < min:
+		return "", atomExpr, fmt.Errorf("invalid repetition bounds %d - %d", min, max)
+	}
+
+	elements := make([]string, 0, min+1)
+
+	for i := 0; i < min; i++ {
+		elements = append(elements, expr)
+	}
+
+	if max == 0 {
+		elements = append(elements, expr+"*")
+	} else if max > min {
+		// Nest optional repetitions to avoid ambiguity: (x (x)?)?
+		optional := ""
+		for i := 0; i < max-min; i++ {
+			if optional == "" {
+				optional = "(" + expr + ")?"
+			} else {
+				optional = "(" + expr + " " + optional + ")?"
+			}
+		}
+		elements = append(elements, optional)
+	}
+
+	if len(elements) == 1 {
+		return elements[0], atomExpr, nil
+	}
+
+	return strings.Join(elements, " "), sequenceExpr, nil
+}
+
+// literal converts a vector of runes, bytes or strings to a quoted ANTLR literal
+func literal[T any](vec []T) (string, error) {
+	var sb strings.Builder
+
+	sb.WriteByte('\'')
+
+	for _, e := range vec {
+		switch v := any(e).(type) {
+		case rune:
+			writeEscaped(&sb, v)
+		case byte:
+			writeEscaped(&sb, rune(v))
+		case string:
+			for _, r := range v {
+				writeEscaped(&sb, r)
+			}
+		default:
+			return "", fmt.Errorf("can not export vector element of type %T as literal", e)
+		}
+	}
+
+	sb.WriteByte('\'')
+
+	return sb.String(), nil
+}
+
+func writeEscaped(sb *strings.Builder, r rune) {
+	switch r {
+	case '\'':
+		sb.WriteString(`\'`)
+	case '\\':
+		sb.WriteString(`\\`)
+	case '\n':
+		sb.WriteString(`\n`)
+	case '\r':
+		sb.WriteString(`\r`)
+	case '\t':
+		sb.WriteString(`\t`)
+	default:
+		if unicode.IsPrint(r) {
+			sb.WriteRune(r)
+		} else if r <= 0xFFFF {
+			sb.WriteString(fmt.Sprintf(`\u%04X`, r))
+		} else {
+			sb.WriteString(fmt.Sprintf(`\u{%X}`, r))
+		}
+	}
+}
+
+// ruleName replaces characters that are not allowed in ANTLR rule names
+func ruleName(id string) []rune {
+	name := []rune(id)
+
+	for i, r := range name {
+		if !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') || r > unicode.MaxASCII {
+			name[i] = '_'
+		}
+	}
+
+	if len(name) == 0 || !unicode.IsLetter(name[0]) {
+		name = append([]rune{'r'}, name...)
+	}
+
+	return name
+}
+
+func lexerName(id string) string {
+	name := ruleName(id)
+	name[0] = unicode.ToUpper(name[0])
+	return string(name)
+}
+
+func parserName(id string) string {
+	name := ruleName(id)
+	name[0] = unicode.ToLower(name[0])
+	return string(name)
+}
@@ -0,0 +1,230 @@
+package diagram
+
+import (
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/introspect"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/end"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/reference"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	"strings"
+)
+
+// Node kinds
+const (
+	KindTerminal    = "terminal"
+	KindNonTerminal = "nonterminal"
+	KindSequence    = "sequence"
+	KindChoice      = "choice"
+	KindOptional    = "optional"
+	KindLoop        = "loop"
+	KindSkip        = "skip"
+)
+
+// Node is an element of a railroad diagram. Terminals and non terminals have a label, non terminals refer to the
+// diagram with the label as name. A loop matches its single child at least once, the label gives the bounds of the
+// loop if they are not one or more
+type Node struct {
+	Kind     string  `json:"kind"`
+	Label    string  `json:"label,omitempty"`
+	Children []*Node `json:"children,omitempty"`
+}
+
+// Diagram is the railroad diagram of a rule, or of an anonymous group of a rule (see Options). Rule is the name of
+// the rule the diagram belongs to, for the diagram of the rule itself it equals Name
+type Diagram struct {
+	Name string `json:"name"`
+	Rule string `json:"rule"`
+	Root *Node  `json:"root"`
+}
+
+// IsGroup reports if the diagram is the diagram of a group
+func (d *Diagram) IsGroup() bool {
+	return d.Name != d.Rule
+}
+
+// Options for building diagrams
+type Options struct {
+	// Inline draws anonymous groups (alternations and concatenations without ID nested in a rule) in the diagram of
+	// their rule, otherwise every group gets a diagram of its own named after the rule and a sequence number
+	// (i.e. expr.1) and is drawn as non terminal
+	Inline bool
+}
+
+// Build creates the diagrams of rules, rules that are part of grammar g are drawn as non terminals where they are
+// used. If rules is empty the diagrams of all rules of g are built. The diagram of a rule is followed by the diagrams
+// of its groups
+func Build[T, P any](g *ebnf.Grammar[T, P], rules ebnf.Patterns[T, P], opts Options) []*Diagram {
+	if len(rules) == 0 {
+		rules = g.Rules()
+	}
+
+	var diagrams []*Diagram
+
+	for _, rule := range rules {
+		b := &builder[T, P]{
+			g:        g,
+			opts:     opts,
+			rule:     rule.ID(),
+			groups:   map[ebnf.Pattern[T, P]]string{},
+			visiting: map[ebnf.Pattern[T, P]]bool{},
+		}
+
+		diagrams = append(diagrams, &Diagram{Name: rule.ID(), Rule: rule.ID(), Root: b.build(rule, true)})
+		diagrams = append(diagrams, b.diagrams...)
+	}
+
+	return diagrams
+}
+
+// builder builds the diagram of a rule and its groups
+type builder[T, P any] struct {
+	g        *ebnf.Grammar[T, P]
+	opts     Options
+	rule     string
+	groups   map[ebnf.Pattern[T, P]]string
+	diagrams []*Diagram
+	visiting map[ebnf.Pattern[T, P]]bool
+}
+
+func (b *builder[T, P]) build(p ebnf.Pattern[T, P], root bool) *Node {
+	if !root && b.g != nil && b.g.IsRule(p) {
+		return &Node{Kind: KindNonTerminal, Label: p.ID()}
+	}
+
+	// Anonymous recursion is drawn as a reference to the pattern
+	if b.visiting[p] {
+		return &Node{Kind: KindNonTerminal, Label: label(p)}
+	}
+
+	if !root && !b.opts.Inline && b.isGroup(p) {
+		return &Node{Kind: KindNonTerminal, Label: b.group(p)}
+	}
+
+	b.visiting[p] = true
+	defer delete(b.visiting, p)
+
+	switch pt := p.(type) {
+	case *reference.Reference[T, P]:
+		if pt.Pattern() == nil {
+			return &Node{Kind: KindNonTerminal, Label: "?"}
+		}
+
+		return b.build(pt.Pattern(), false)
+	case *concatenation.Concatenation[T, P]:
+		return b.list(KindSequence, pt.Patterns())
+	case *alternation.Alternation[T, P]:
+		return b.list(KindChoice, pt.Patterns())
+	case *repetition.Repetition[T, P]:
+		return b.repetition(pt)
+	case *entity.Entity[T, P], *vector.Vector[T, P], *end.End[T, P]:
+		return &Node{Kind: KindTerminal, Label: label(p)}
+	}
+
+	// Transparent wrappers (i.e. hints and lexemes) are drawn as their pattern, other patterns as their EBNF
+	if children := introspect.Children(p); len(children) == 1 {
+		return b.build(children[0], false)
+	}
+
+	return &Node{Kind: KindTerminal, Label: label(p)}
+}
+
+// list creates a sequence or choice node, a list of one pattern is the node of the pattern
+func (b *builder[T, P]) list(kind string, patterns ebnf.Patterns[T, P]) *Node {
+	if len(patterns) == 1 {
+		return b.build(patterns[0], false)
+	}
+
+	if len(patterns) == 0 {
+		return &Node{Kind: KindSkip}
+	}
+
+	node := &Node{Kind: kind}
+
+	for _, p := range patterns {
+		node.Children = append(node.Children, b.build(p, false))
+	}
+
+	return node
+}
+
+// repetition creates optional and loop nodes
+func (b *builder[T, P]) repetition(rep *repetition.Repetition[T, P]) *Node {
+	child := b.build(rep.Pattern(), false)
+	lo, hi := rep.Min(), rep.Max()
+
+	if lo == 0 && hi == 1 {
+		return &Node{Kind: KindOptional, Children: []*Node{child}}
+	}
+
+	loop := &Node{Kind: KindLoop, Children: []*Node{child}}
+
+	switch {
+	case hi == 0 && lo > 1:
+		loop.Label = fmt.Sprintf("%d+", lo)
+	case hi > 1:
+		loop.Label = fmt.Sprintf("%d-%d", max(lo, 1), hi)
+	}
+
+	if lo == 0 {
+		return &Node{Kind: KindOptional, Children: []*Node{loop}}
+	}
+
+	return loop
+}
+
+// isGroup reports if p is an anonymous group
+func (b *builder[T, P]) isGroup(p ebnf.Pattern[T, P]) bool {
+	if p.ID() != ebnf.NoID {
+		return false
+	}
+
+	switch pt := p.(type) {
+	case *concatenation.Concatenation[T, P]:
+		return len(pt.Patterns()) > 1
+	case *alternation.Alternation[T, P]:
+		return len(pt.Patterns()) > 1
+	}
+
+	return false
+}
+
+// group returns the diagram name of a group, the diagram is built on first use
+func (b *builder[T, P]) group(p ebnf.Pattern[T, P]) string {
+	if name, ok := b.groups[p]; ok {
+		return name
+	}
+
+	name := fmt.Sprintf("%s.%d", b.rule, len(b.groups)+1)
+	b.groups[p] = name
+
+	d := &Diagram{Name: name, Rule: b.rule}
+	b.diagrams = append(b.diagrams, d)
+	d.Root = b.build(p, true)
+
+	return name
+}
+
+// label returns the label of a terminal
+func label[T, P any](p ebnf.Pattern[T, P]) string {
+	switch pt := p.(type) {
+	case *vector.Vector[T, P]:
+		return introspect.Literal(pt.Vector())
+	case *end.End[T, P]:
+		return "EOF"
+	}
+
+	if p.ID() != ebnf.NoID {
+		return p.ID()
+	}
+
+	var sb strings.Builder
+
+	_ = p.Print(&sb)
+
+	return sb.String()
+}
@@ -0,0 +1,113 @@
+package diagram
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteDOT writes diagrams as Graphviz DOT graph with the given name. Every diagram is a node, the edges point from a
+// diagram to the diagrams it uses as non terminals. Rules are drawn as boxes, groups as dashed boxes labeled with
+// their elements. Non terminals without diagram (i.e. rules that were not selected) are drawn as plain text nodes
+func WriteDOT(w io.Writer, name string, diagrams []*Diagram) error {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "digraph %s {\n", strconv.Quote(name))
+	sb.WriteString("\trankdir=LR;\n")
+	sb.WriteString("\tnode [shape=box, fontname=\"monospace\"];\n")
+
+	known := map[string]bool{}
+	for _, d := range diagrams {
+		known[d.Name] = true
+	}
+
+	missing := map[string]bool{}
+
+	for _, d := range diagrams {
+		if d.IsGroup() {
+			fmt.Fprintf(&sb, "\t%s [style=dashed, label=%s];\n", strconv.Quote(d.Name), strconv.Quote(text(d.Root)))
+		} else {
+			fmt.Fprintf(&sb, "\t%s;\n", strconv.Quote(d.Name))
+		}
+	}
+
+	for _, d := range diagrams {
+		for _, ref := range references(d.Root) {
+			if !known[ref] && !missing[ref] {
+				missing[ref] = true
+				fmt.Fprintf(&sb, "\t%s [shape=plaintext];\n", strconv.Quote(ref))
+			}
+
+			fmt.Fprintf(&sb, "\t%s -> %s;\n", strconv.Quote(d.Name), strconv.Quote(ref))
+		}
+	}
+
+	sb.WriteString("}\n")
+
+	_, err := io.WriteString(w, sb.String())
+
+	return err
+}
+
+// references returns the distinct non terminals of a node tree in order of appearance
+func references(n *Node) []string {
+	var (
+		refs []string
+		seen = map[string]bool{}
+		walk func(n *Node)
+	)
+
+	walk = func(n *Node) {
+		if n.Kind == KindNonTerminal && !seen[n.Label] {
+			seen[n.Label] = true
+			refs = append(refs, n.Label)
+		}
+
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+
+	walk(n)
+
+	return refs
+}
+
+// text returns a node tree as EBNF like text
+func text(n *Node) string {
+	switch n.Kind {
+	case KindTerminal, KindNonTerminal:
+		return n.Label
+	case KindSequence, KindChoice:
+		sep := " "
+		if n.Kind == KindChoice {
+			sep = " | "
+		}
+
+		parts := make([]string, len(n.Children))
+		for i, c := range n.Children {
+			parts[i] = text(c)
+
+			if len(c.Children) > 1 {
+				parts[i] = "(" + parts[i] + ")"
+			}
+		}
+
+		return strings.Join(parts, sep)
+	case KindOptional:
+		if c := n.Children[0]; c.Kind == KindLoop && c.Label == "" {
+			return "{" + text(c.Children[0]) + "}"
+		}
+
+		return "[" + text(n.Children[0]) + "]"
+	case KindLoop:
+		if n.Label == "" {
+			return "(" + text(n.Children[0]) + ")+"
+		}
+
+		return "(" + text(n.Children[0]) + "){" + n.Label + "}"
+	}
+
+	return ""
+}
@@ -0,0 +1,264 @@
+package diagram
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Layout constants of the SVG railroad diagrams in pixels
+const (
+	radius   = 10 // radius of the rounded corners and horizontal space around choices and loops
+	gap      = 10 // space between the elements of a sequence and the branches of a choice
+	boxHalf  = 11 // half the height of terminal and non terminal boxes
+	charW    = 8  // width of a character of the monospace font
+	margin   = 20 // space around the diagram
+	marker   = 10 // length of the start and end marker lines
+	titleH   = 24 // height of the title line
+	labelH   = 14 // height of the label below a loop
+	fontSize = 13
+)
+
+// style is the style sheet of the SVG diagrams
+const style = `path { stroke: #333; stroke-width: 1.5; fill: none; }
+rect { stroke: #333; stroke-width: 1.5; fill: #fff; }
+rect.terminal { fill: #f2f7ff; }
+rect.nonterminal { fill: #fffbe6; }
+text { font-family: monospace; font-size: 13px; text-anchor: middle; }
+text.title { font-weight: bold; text-anchor: start; }
+text.label { font-size: 11px; }`
+
+// WriteSVG writes the diagram as SVG document with the name of the diagram as title. Terminals are drawn as rounded
+// boxes, non terminals as square boxes with the name of the referenced diagram
+func (d *Diagram) WriteSVG(w io.Writer) error {
+	root := layout(d.Root)
+
+	width := root.width + 2*margin + 2*marker
+	width = max(width, utf8.RuneCountInString(d.Name)*charW+2*margin)
+	height := titleH + root.up + root.down + 2*margin
+
+	s := &svg{}
+
+	y := margin + titleH + root.up
+	x := margin
+
+	s.path(point{x, y - 5}, point{x, y + 5})
+	s.path(point{x, y}, point{x + marker, y})
+	root.draw(s, x+marker, y)
+	s.path(point{x + marker + root.width, y}, point{x + 2*marker + root.width, y})
+	s.path(point{x + 2*marker + root.width, y - 5}, point{x + 2*marker + root.width, y + 5})
+
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">
+<style>
+%s
+</style>
+<text class="title" x="%d" y="%d">%s</text>
+%s</svg>
+`, width, height, width, height, style, margin, margin+fontSize, html.EscapeString(d.Name), s.sb.String())
+
+	return err
+}
+
+// point is a point of a path
+type point struct {
+	x, y int
+}
+
+// svg collects the elements of a diagram
+type svg struct {
+	sb strings.Builder
+}
+
+// path draws a line through points, corners are rounded with radius
+func (s *svg) path(points ...point) {
+	fmt.Fprintf(&s.sb, `<path d="M%d %d`, points[0].x, points[0].y)
+
+	for i := 1; i < len(points)-1; i++ {
+		prev, c, next := points[i-1], points[i], points[i+1]
+
+		in := toward(c, prev, radius)
+		out := toward(c, next, radius)
+
+		fmt.Fprintf(&s.sb, " L%d %d Q%d %d %d %d", in.x, in.y, c.x, c.y, out.x, out.y)
+	}
+
+	last := points[len(points)-1]
+
+	fmt.Fprintf(&s.sb, " L%d %d\"/>\n", last.x, last.y)
+}
+
+// rect draws a box with text centered on the line at y
+func (s *svg) rect(x, y, width int, class string, text string) {
+	rounded := 0
+	if class == KindTerminal {
+		rounded = boxHalf
+	}
+
+	fmt.Fprintf(&s.sb, `<rect class="%s" x="%d" y="%d" width="%d" height="%d" rx="%d"/>`+"\n",
+		class, x, y-boxHalf, width, 2*boxHalf, rounded)
+	s.text(x+width/2, y+fontSize/3, "", text)
+}
+
+// text draws text centered at x
+func (s *svg) text(x, y int, class string, text string) {
+	if class != "" {
+		class = ` class="` + class + `"`
+	}
+
+	fmt.Fprintf(&s.sb, `<text%s x="%d" y="%d">%s</text>`+"\n", class, x, y, html.EscapeString(text))
+}
+
+// toward returns the point at distance d from c in the direction of p, paths only have horizontal and vertical lines
+func toward(c, p point, d int) point {
+	switch {
+	case p.x > c.x:
+		return point{c.x + min(d, p.x-c.x), c.y}
+	case p.x < c.x:
+		return point{c.x - min(d, c.x-p.x), c.y}
+	case p.y > c.y:
+		return point{c.x, c.y + min(d, p.y-c.y)}
+	case p.y < c.y:
+		return point{c.x, c.y - min(d, c.y-p.y)}
+	}
+
+	return c
+}
+
+// box is the laid out size of a node, up and down are the extent above and below the line the node is drawn on
+type box struct {
+	node     *Node
+	width    int
+	up       int
+	down     int
+	children []*box
+}
+
+// layout computes the sizes of a node tree
+func layout(n *Node) *box {
+	b := &box{node: n}
+
+	for _, c := range n.Children {
+		b.children = append(b.children, layout(c))
+	}
+
+	switch n.Kind {
+	case KindTerminal, KindNonTerminal:
+		b.width = utf8.RuneCountInString(n.Label)*charW + 2*gap
+		b.up, b.down = boxHalf, boxHalf
+	case KindSequence:
+		for i, c := range b.children {
+			if i > 0 {
+				b.width += gap
+			}
+
+			b.width += c.width
+			b.up = max(b.up, c.up)
+			b.down = max(b.down, c.down)
+		}
+	case KindChoice, KindOptional:
+		branches := b.branches()
+
+		for i, c := range branches {
+			b.width = max(b.width, c.width)
+
+			if i == 0 {
+				b.up, b.down = c.up, c.down
+				continue
+			}
+
+			b.down = max(b.down+gap+c.up, 2*radius) + c.down
+		}
+
+		b.width += 4 * radius
+	case KindLoop:
+		c := b.children[0]
+
+		b.width = c.width + 2*radius
+		b.up = c.up
+		b.down = max(c.down+gap, 2*radius)
+
+		if n.Label != "" {
+			b.down += labelH
+		}
+	}
+
+	return b
+}
+
+// branches returns the branches of a choice, an optional node is a choice between its child and nothing
+func (b *box) branches() []*box {
+	if b.node.Kind == KindOptional {
+		return []*box{b.children[0], {node: &Node{Kind: KindSkip}}}
+	}
+
+	return b.children
+}
+
+// draw draws the node from (x, y) to (x + width, y)
+func (b *box) draw(s *svg, x, y int) {
+	switch b.node.Kind {
+	case KindTerminal, KindNonTerminal:
+		s.rect(x, y, b.width, b.node.Kind, b.node.Label)
+	case KindSequence:
+		for i, c := range b.children {
+			if i > 0 {
+				s.path(point{x, y}, point{x + gap, y})
+				x += gap
+			}
+
+			c.draw(s, x, y)
+			x += c.width
+		}
+	case KindChoice, KindOptional:
+		b.drawChoice(s, x, y)
+	case KindLoop:
+		c := b.children[0]
+		right := x + radius + c.width
+		bottom := y + b.down
+
+		if b.node.Label != "" {
+			bottom -= labelH
+			s.text(x+b.width/2, bottom+labelH-2, "label", b.node.Label)
+		}
+
+		s.path(point{x, y}, point{x + radius, y})
+		c.draw(s, x+radius, y)
+		s.path(point{right, y}, point{x + b.width, y})
+		s.path(point{right, y}, point{right + radius, y}, point{right + radius, bottom}, point{x, bottom},
+			point{x, y}, point{x + radius, y})
+	default:
+		s.path(point{x, y}, point{x + b.width, y})
+	}
+}
+
+// drawChoice draws the first branch on the line and the other branches below it
+func (b *box) drawChoice(s *svg, x, y int) {
+	right := x + b.width
+	branchY := y
+
+	for i, c := range b.branches() {
+		if i > 0 {
+			branchY = max(branchY+gap+c.up, y+2*radius)
+		}
+
+		if i == 0 {
+			s.path(point{x, y}, point{x + 2*radius, y})
+		} else {
+			s.path(point{x, y}, point{x + radius, y}, point{x + radius, branchY}, point{x + 2*radius, branchY})
+		}
+
+		c.draw(s, x+2*radius, branchY)
+
+		end := x + 2*radius + c.width
+
+		if i == 0 {
+			s.path(point{end, y}, point{right, y})
+		} else {
+			s.path(point{end, branchY}, point{right - radius, branchY}, point{right - radius, y}, point{right, y})
+		}
+
+		branchY += c.down
+	}
+}
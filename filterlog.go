@@ -0,0 +1,143 @@
+package exbana
+
+// FilterLog passes only the mismatches of selected patterns on to another logger, so logging can be enabled for a
+// single rule. It is also a Tracer, installed with a tracing reader (see readers/trace) it knows the depth and the
+// enclosing patterns of a mismatch and passes the selected match events on if the next logger is a Tracer. Without
+// tracer the depth of every mismatch is 0 and only the ID of the failing pattern is used
+type FilterLog[T, P any] struct {
+	next     Logger[T, P]
+	includes map[string]bool
+	excludes map[string]bool
+	minDepth int
+	maxDepth int
+	begin    P
+	end      P
+	cmp      CompareFunc[P]
+	stack    []Pattern[T, P]
+}
+
+// NewFilterLog creates a new filter on top of next, without options all mismatches are passed
+func NewFilterLog[T, P any](next Logger[T, P]) *FilterLog[T, P] {
+	return &FilterLog[T, P]{
+		next:     next,
+		maxDepth: -1,
+	}
+}
+
+// Include passes only mismatches of patterns with one of the IDs or inside patterns with one of the IDs
+func (l *FilterLog[T, P]) Include(ids ...string) *FilterLog[T, P] {
+	if l.includes == nil {
+		l.includes = map[string]bool{}
+	}
+
+	for _, id := range ids {
+		l.includes[id] = true
+	}
+
+	return l
+}
+
+// Exclude drops mismatches of patterns with one of the IDs or inside patterns with one of the IDs
+func (l *FilterLog[T, P]) Exclude(ids ...string) *FilterLog[T, P] {
+	if l.excludes == nil {
+		l.excludes = map[string]bool{}
+	}
+
+	for _, id := range ids {
+		l.excludes[id] = true
+	}
+
+	return l
+}
+
+// SetMinDepth drops mismatches of patterns nested less deep than depth
+func (l *FilterLog[T, P]) SetMinDepth(depth int) *FilterLog[T, P] {
+	l.minDepth = depth
+	return l
+}
+
+// SetMaxDepth drops mismatches of patterns nested deeper than depth, -1 means no maximum
+func (l *FilterLog[T, P]) SetMaxDepth(depth int) *FilterLog[T, P] {
+	l.maxDepth = depth
+	return l
+}
+
+// SetRange passes only mismatches that begin at or after begin and before end
+func (l *FilterLog[T, P]) SetRange(begin P, end P, cmp CompareFunc[P]) *FilterLog[T, P] {
+	l.begin = begin
+	l.end = end
+	l.cmp = cmp
+
+	return l
+}
+
+func (l *FilterLog[T, P]) LogMismatch(m *Mismatch[T, P]) {
+	depth := len(l.stack) - 1
+	if depth < 0 || l.stack[depth] != m.Pattern {
+		depth++
+	}
+
+	if l.pass(m.Pattern, depth, m.Begin) {
+		l.next.LogMismatch(m)
+	}
+}
+
+func (l *FilterLog[T, P]) OnEnter(pattern Pattern[T, P], depth int, pos P) {
+	l.stack = append(l.stack, pattern)
+
+	if tracer, ok := l.next.(Tracer[T, P]); ok && l.pass(pattern, depth, pos) {
+		tracer.OnEnter(pattern, depth, pos)
+	}
+}
+
+func (l *FilterLog[T, P]) OnSuccess(pattern Pattern[T, P], depth int, pos P, end P) {
+	if tracer, ok := l.next.(Tracer[T, P]); ok && l.pass(pattern, depth, pos) {
+		tracer.OnSuccess(pattern, depth, pos, end)
+	}
+
+	l.pop()
+}
+
+func (l *FilterLog[T, P]) OnFail(pattern Pattern[T, P], depth int, pos P, err error) {
+	if tracer, ok := l.next.(Tracer[T, P]); ok && l.pass(pattern, depth, pos) {
+		tracer.OnFail(pattern, depth, pos, err)
+	}
+
+	l.pop()
+}
+
+func (l *FilterLog[T, P]) pop() {
+	if n := len(l.stack); n > 0 {
+		l.stack = l.stack[:n-1]
+	}
+}
+
+// pass reports if an event of pattern at depth and pos passes the filter
+func (l *FilterLog[T, P]) pass(pattern Pattern[T, P], depth int, pos P) bool {
+	if depth < l.minDepth || (l.maxDepth >= 0 && depth > l.maxDepth) {
+		return false
+	}
+
+	if l.cmp != nil && (l.cmp(pos, l.begin) < 0 || l.cmp(pos, l.end) >= 0) {
+		return false
+	}
+
+	included := l.includes == nil
+
+	for _, p := range append(l.stack, pattern) {
+		id := p.ID()
+		if id == NoID {
+			continue
+		}
+
+		if l.excludes[id] {
+			return false
+		}
+
+		if l.includes[id] {
+			included = true
+		}
+	}
+
+	return included
+}
@@ -0,0 +1,18 @@
+package exbana
+
+// Firster is an optional extension of Pattern used for FIRST set analysis. First reports if obj can be the first
+// object of a match of the pattern and if the pattern can match without reading any object. The answer must be
+// conservative, a pattern that is not sure reports true
+type Firster[T any] interface {
+	First(obj T) (first bool, nullable bool)
+}
+
+// First reports if obj can be the first object of a match of pattern and if pattern can match without reading, see
+// Firster. Patterns that do not implement Firster can start with any object and can be empty
+func First[T, P any](pattern Pattern[T, P], obj T) (bool, bool) {
+	if f, ok := pattern.(Firster[T]); ok {
+		return f.First(obj)
+	}
+
+	return true, true
+}
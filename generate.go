@@ -0,0 +1,41 @@
+package exbana
+
+// sliceWriter collects generated objects in memory
+type sliceWriter[T any] struct {
+	objects []T
+}
+
+func (w *sliceWriter[T]) Write(objects ...T) error {
+	w.objects = append(w.objects, objects...)
+	return nil
+}
+
+func (w *sliceWriter[T]) Finish() error {
+	return nil
+}
+
+func (w *sliceWriter[T]) Offset() int {
+	return len(w.objects)
+}
+
+// GenerateSlice generates pattern and returns the generated objects
+func GenerateSlice[T, P any](pattern Pattern[T, P]) ([]T, error) {
+	w := &sliceWriter[T]{}
+
+	err := GeneratePattern[T, P](pattern, w)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.objects, nil
+}
+
+// GenerateString generates a rune pattern and returns the generated runes as string
+func GenerateString[P any](pattern Pattern[rune, P]) (string, error) {
+	runes, err := GenerateSlice(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	return string(runes), nil
+}
@@ -0,0 +1,163 @@
+package generate
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"math/rand"
+)
+
+// Bounds overrides the number of repetitions
+type Bounds struct {
+	Min int
+	Max int
+}
+
+// Config is a generation profile which is supplied at generation time instead of mutating patterns with SetMaxGen
+// and SetGenerateFunc, so one grammar can drive multiple generation profiles
+type Config[T, P any] struct {
+	weights    map[ebnf.Pattern[T, P]][]float64
+	repeats    map[ebnf.Pattern[T, P]]Bounds
+	generators map[string]func(*rand.Rand) T
+	rnd        *rand.Rand
+}
+
+// NewConfig creates a new empty generation profile
+func NewConfig[T, P any]() *Config[T, P] {
+	return &Config[T, P]{
+		weights:    map[ebnf.Pattern[T, P]][]float64{},
+		repeats:    map[ebnf.Pattern[T, P]]Bounds{},
+		generators: map[string]func(*rand.Rand) T{},
+	}
+}
+
+// SetWeights sets the relative weights of the alternatives of an alternation pattern
+func (c *Config[T, P]) SetWeights(pattern ebnf.Pattern[T, P], weights ...float64) *Config[T, P] {
+	c.weights[pattern] = weights
+	return c
+}
+
+// SetRepeat overrides the number of repetitions of a repetition pattern, the override is clamped to the bounds of
+// the pattern so the output stays valid
+func (c *Config[T, P]) SetRepeat(pattern ebnf.Pattern[T, P], min int, max int) *Config[T, P] {
+	c.repeats[pattern] = Bounds{Min: min, Max: max}
+	return c
+}
+
+// SetGenerator sets the generator for the pattern with id, the pattern is not generated, the generator output is
+// written instead. The generator draws from the random source of the profile, so the output can be reproduced
+func (c *Config[T, P]) SetGenerator(id string, generator func(*rand.Rand) T) *Config[T, P] {
+	c.generators[id] = generator
+	return c
+}
+
+// SetRand sets the random source of generation, all random choices of patterns generated with the profile draw from
+// it, so the output can be reproduced from a seeded source. Without source the global source of math/rand is used
+func (c *Config[T, P]) SetRand(rnd *rand.Rand) *Config[T, P] {
+	c.rnd = rnd
+	return c
+}
+
+// Writer applies a generation profile to the generation of patterns, it is a writer middleware on top of another
+// writer
+type Writer[T, P any] struct {
+	w      ebnf.Writer[T]
+	config *Config[T, P]
+}
+
+// New creates a new writer which applies config, output is written to w
+func New[T, P any](w ebnf.Writer[T], config *Config[T, P]) *Writer[T, P] {
+	return &Writer[T, P]{
+		w:      w,
+		config: config,
+	}
+}
+
+// GeneratePattern writes the output of the generator configured for the ID of pattern or lets the pattern generate
+func (g *Writer[T, P]) GeneratePattern(pattern ebnf.Pattern[T, P], w ebnf.Writer[T]) error {
+	if id := pattern.ID(); id != ebnf.NoID {
+		if generator, ok := g.config.generators[id]; ok {
+			return w.Write(generator(ebnf.Rand[T](g)))
+		}
+	}
+
+	return ebnf.GenerateNext(pattern, g.w, w)
+}
+
+// Choose chooses an alternative by weight, if the generation budget of the underlying writer is exhausted the
+// choice is left to the underlying writer
+func (g *Writer[T, P]) Choose(pattern ebnf.Pattern[T, P], alternatives ebnf.Patterns[T, P]) int {
+	weights, ok := g.config.weights[pattern]
+	if !ok || ebnf.Exhausted(g.w) {
+		return ebnf.Choose(g.w, pattern, alternatives)
+	}
+
+	total := 0.0
+
+	for i := range alternatives {
+		if i < len(weights) {
+			total += max(weights[i], 0)
+		}
+	}
+
+	if total <= 0 {
+		return ebnf.Choose(g.w, pattern, alternatives)
+	}
+
+	r := ebnf.Rand[T](g).Float64() * total
+
+	for i := range alternatives {
+		if i < len(weights) && weights[i] > 0 {
+			r -= weights[i]
+			if r < 0 {
+				return i
+			}
+		}
+	}
+
+	return len(alternatives) - 1
+}
+
+// Repeat chooses the number of repetitions within the configured bounds
+func (g *Writer[T, P]) Repeat(pattern ebnf.Pattern[T, P], repMin int, repMax int) int {
+	bounds, ok := g.config.repeats[pattern]
+	if !ok || ebnf.Exhausted(g.w) {
+		return ebnf.Repeat(g.w, pattern, repMin, repMax)
+	}
+
+	lo := max(bounds.Min, repMin)
+	hi := bounds.Max
+
+	if repMax > 0 {
+		hi = min(hi, repMax)
+	}
+
+	if hi < lo {
+		return lo
+	}
+
+	return ebnf.Rand[T](g).Intn(hi-lo+1) + lo
+}
+
+// Rand returns the random source of the profile, or the random source of the underlying writer if the profile has
+// none
+func (g *Writer[T, P]) Rand() *rand.Rand {
+	if g.config.rnd != nil {
+		return g.config.rnd
+	}
+
+	return ebnf.Rand(g.w)
+}
+
+// Write writes objects to the underlying writer
+func (g *Writer[T, P]) Write(objects ...T) error {
+	return g.w.Write(objects...)
+}
+
+// Finish finishes the underlying writer
+func (g *Writer[T, P]) Finish() error {
+	return g.w.Finish()
+}
+
+// Unwrap returns the underlying writer
+func (g *Writer[T, P]) Unwrap() ebnf.Writer[T] {
+	return g.w
+}
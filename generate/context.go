@@ -0,0 +1,163 @@
+package generate
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+)
+
+// frame is a rule being generated
+type frame struct {
+	id    string
+	start int
+}
+
+// Context is a writer middleware which carries user state through generation. Hooks are called when a rule, a
+// pattern with an ID, is entered and exited, and rules can be generated by functions with access to the state and
+// interned values, so generated output can be semantically consistent (i.e. only reference declared identifiers)
+type Context[T, P any] struct {
+	// State is user state
+	State      any
+	w          ebnf.Writer[T]
+	enter      map[string]func(*Context[T, P]) error
+	exit       map[string]func(*Context[T, P], []T) error
+	generators map[string]func(*Context[T, P]) ([]T, bool)
+	values     map[string][][]T
+	stack      []frame
+	output     []T
+}
+
+// NewContext creates a new generation context, output is written to w
+func NewContext[T, P any](w ebnf.Writer[T], state any) *Context[T, P] {
+	return &Context[T, P]{
+		State:      state,
+		w:          w,
+		enter:      map[string]func(*Context[T, P]) error{},
+		exit:       map[string]func(*Context[T, P], []T) error{},
+		generators: map[string]func(*Context[T, P]) ([]T, bool){},
+		values:     map[string][][]T{},
+	}
+}
+
+// OnRuleEnter sets the hook called before the rule with id is generated
+func (ctx *Context[T, P]) OnRuleEnter(id string, hook func(*Context[T, P]) error) *Context[T, P] {
+	ctx.enter[id] = hook
+	return ctx
+}
+
+// OnRuleExit sets the hook called after the rule with id is generated, the output of the rule is passed to the hook
+func (ctx *Context[T, P]) OnRuleExit(id string, hook func(*Context[T, P], []T) error) *Context[T, P] {
+	ctx.exit[id] = hook
+	return ctx
+}
+
+// SetGenerator sets a generator for the rule with id, if the generator returns false the rule generates as usual
+func (ctx *Context[T, P]) SetGenerator(id string, generator func(*Context[T, P]) ([]T, bool)) *Context[T, P] {
+	ctx.generators[id] = generator
+	return ctx
+}
+
+// Intern stores a generated value under kind
+func (ctx *Context[T, P]) Intern(kind string, value []T) {
+	ctx.values[kind] = append(ctx.values[kind], append([]T{}, value...))
+}
+
+// Values returns all values interned under kind
+func (ctx *Context[T, P]) Values(kind string) [][]T {
+	return ctx.values[kind]
+}
+
+// Pick returns a random value interned under kind, returns false if there are none
+func (ctx *Context[T, P]) Pick(kind string) ([]T, bool) {
+	values := ctx.values[kind]
+	if len(values) == 0 {
+		return nil, false
+	}
+
+	return values[ebnf.Rand[T](ctx).Intn(len(values))], true
+}
+
+// Path returns the IDs of the rules being generated, outermost first
+func (ctx *Context[T, P]) Path() []string {
+	path := make([]string, len(ctx.stack))
+
+	for i, f := range ctx.stack {
+		path[i] = f.id
+	}
+
+	return path
+}
+
+// InRule returns true if the rule with id is being generated
+func (ctx *Context[T, P]) InRule(id string) bool {
+	for _, f := range ctx.stack {
+		if f.id == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GeneratePattern calls the hooks for rules and lets the rule generator or the pattern generate
+func (ctx *Context[T, P]) GeneratePattern(pattern ebnf.Pattern[T, P], w ebnf.Writer[T]) error {
+	id := pattern.ID()
+	if id == ebnf.NoID {
+		return ebnf.GenerateNext(pattern, ctx.w, w)
+	}
+
+	ctx.stack = append(ctx.stack, frame{id: id, start: len(ctx.output)})
+	defer func() {
+		ctx.stack = ctx.stack[:len(ctx.stack)-1]
+		if len(ctx.stack) == 0 {
+			ctx.output = ctx.output[:0]
+		}
+	}()
+
+	if hook, ok := ctx.enter[id]; ok {
+		if err := hook(ctx); err != nil {
+			return err
+		}
+	}
+
+	generated := false
+
+	if generator, ok := ctx.generators[id]; ok {
+		if value, ok := generator(ctx); ok {
+			if err := w.Write(value...); err != nil {
+				return err
+			}
+
+			generated = true
+		}
+	}
+
+	if !generated {
+		if err := ebnf.GenerateNext(pattern, ctx.w, w); err != nil {
+			return err
+		}
+	}
+
+	if hook, ok := ctx.exit[id]; ok {
+		return hook(ctx, ctx.output[ctx.stack[len(ctx.stack)-1].start:])
+	}
+
+	return nil
+}
+
+// Write records the output of the rules being generated and writes objects to the underlying writer
+func (ctx *Context[T, P]) Write(objects ...T) error {
+	if len(ctx.stack) > 0 {
+		ctx.output = append(ctx.output, objects...)
+	}
+
+	return ctx.w.Write(objects...)
+}
+
+// Finish finishes the underlying writer
+func (ctx *Context[T, P]) Finish() error {
+	return ctx.w.Finish()
+}
+
+// Unwrap returns the underlying writer
+func (ctx *Context[T, P]) Unwrap() ebnf.Writer[T] {
+	return ctx.w
+}
@@ -0,0 +1,249 @@
+package generate
+
+import (
+	"errors"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	"math/rand"
+)
+
+// ErrNoNearMiss is returned when no near miss sample could be generated within the number of attempts
+var ErrNoNearMiss = errors.New("no near miss sample could be generated")
+
+// DefaultAttempts is the default number of attempts to generate a near miss sample
+const DefaultAttempts = 100
+
+// Mutation is the way a near miss sample violates a pattern
+type Mutation int
+
+const (
+	// MutateObject replaces one generated object of an entity or vector
+	MutateObject Mutation = iota
+	// DropElement drops a required element of a concatenation or repetition
+	DropElement
+	// ExceedRepetition repeats a repetition more than its maximum
+	ExceedRepetition
+)
+
+func (m Mutation) String() string {
+	switch m {
+	case DropElement:
+		return "drop element"
+	case ExceedRepetition:
+		return "exceed repetition"
+	}
+
+	return "mutate object"
+}
+
+// Sample is a near miss sample, Pattern is the violated sub pattern
+type Sample[T, P any] struct {
+	Output   []T
+	Mutation Mutation
+	Pattern  ebnf.Pattern[T, P]
+}
+
+// NearMiss generates samples that almost match a pattern, one generated object is mutated, a required element is
+// dropped or a repetition exceeds its maximum. The samples are labeled with the violated sub pattern, they are
+// meant to test error reporting and recovery
+type NearMiss[T, P any] struct {
+	// Mutate returns an object different from the given object, required for MutateObject
+	Mutate func(T) T
+	// Reader creates a reader for a sample, if set samples that still match the pattern are rejected
+	Reader func([]T) ebnf.Reader[T, P]
+	// Wrap optionally wraps the writer used for generation, i.e. to apply a generation profile or limits. The
+	// mutations draw from the random source of the wrapped writer
+	Wrap func(ebnf.Writer[T]) ebnf.Writer[T]
+	// Attempts is the maximum number of attempts, if <= 0 DefaultAttempts is used
+	Attempts int
+}
+
+// node is a generated pattern and the range of its output
+type node[T, P any] struct {
+	pattern  ebnf.Pattern[T, P]
+	parent   *node[T, P]
+	children []*node[T, P]
+	begin    int
+	end      int
+}
+
+// recorder records the generated output and the tree of generated patterns
+type recorder[T, P any] struct {
+	output []T
+	root   *node[T, P]
+	stack  []*node[T, P]
+}
+
+func (rec *recorder[T, P]) GeneratePattern(pattern ebnf.Pattern[T, P], w ebnf.Writer[T]) error {
+	n := &node[T, P]{pattern: pattern, begin: len(rec.output)}
+
+	if len(rec.stack) > 0 {
+		n.parent = rec.stack[len(rec.stack)-1]
+		n.parent.children = append(n.parent.children, n)
+	} else {
+		rec.root = n
+	}
+
+	rec.stack = append(rec.stack, n)
+	err := pattern.Generate(w)
+	rec.stack = rec.stack[:len(rec.stack)-1]
+	n.end = len(rec.output)
+
+	return err
+}
+
+func (rec *recorder[T, P]) Write(objects ...T) error {
+	rec.output = append(rec.output, objects...)
+	return nil
+}
+
+func (rec *recorder[T, P]) Finish() error {
+	return nil
+}
+
+// Generate generates a near miss sample for pattern using one of mutations, all mutations are used if none are
+// given
+func (nm *NearMiss[T, P]) Generate(pattern ebnf.Pattern[T, P], mutations ...Mutation) (*Sample[T, P], error) {
+	if len(mutations) == 0 {
+		mutations = []Mutation{MutateObject, DropElement, ExceedRepetition}
+	}
+
+	attempts := nm.Attempts
+	if attempts <= 0 {
+		attempts = DefaultAttempts
+	}
+
+	for i := 0; i < attempts; i++ {
+		rec := &recorder[T, P]{}
+
+		var w ebnf.Writer[T] = rec
+		if nm.Wrap != nil {
+			w = nm.Wrap(rec)
+		}
+
+		if err := ebnf.GeneratePattern(pattern, w); err != nil {
+			return nil, err
+		}
+
+		if err := w.Finish(); err != nil {
+			return nil, err
+		}
+
+		rnd := ebnf.Rand(w)
+
+		sample := nm.mutate(rec, mutations[rnd.Intn(len(mutations))], rnd)
+		if sample == nil || nm.matches(pattern, sample.Output) {
+			continue
+		}
+
+		return sample, nil
+	}
+
+	return nil, ErrNoNearMiss
+}
+
+// matches checks if output still matches pattern entirely
+func (nm *NearMiss[T, P]) matches(pattern ebnf.Pattern[T, P], output []T) bool {
+	if nm.Reader == nil {
+		return false
+	}
+
+	r := nm.Reader(output)
+
+	matched, _, err := ebnf.MatchPattern(pattern, r)
+
+	return err == nil && matched && r.Finished()
+}
+
+// mutate applies a mutation to a random site of the recorded generation, returns nil if there is no site
+func (nm *NearMiss[T, P]) mutate(rec *recorder[T, P], mutation Mutation, rnd *rand.Rand) *Sample[T, P] {
+	var sites []*node[T, P]
+
+	walk(rec.root, func(n *node[T, P]) {
+		if isSite(n, mutation) && (mutation != MutateObject || nm.Mutate != nil) {
+			sites = append(sites, n)
+		}
+	})
+
+	if len(sites) == 0 {
+		return nil
+	}
+
+	site := sites[rnd.Intn(len(sites))]
+	output := rec.output
+	sample := &Sample[T, P]{Mutation: mutation, Pattern: site.pattern}
+
+	switch mutation {
+	case MutateObject:
+		index := site.begin + rnd.Intn(site.end-site.begin)
+		sample.Output = append([]T{}, output...)
+		sample.Output[index] = nm.Mutate(output[index])
+	case DropElement:
+		sample.Pattern = site.parent.pattern
+		sample.Output = splice(output, site.begin, site.end, nil)
+	case ExceedRepetition:
+		rep := site.pattern.(*repetition.Repetition[T, P])
+		item := site.children[rnd.Intn(len(site.children))]
+		extra := make([]T, 0)
+
+		for j := len(site.children); j <= rep.Max(); j++ {
+			extra = append(extra, output[item.begin:item.end]...)
+		}
+
+		sample.Output = splice(output, site.end, site.end, extra)
+	}
+
+	return sample
+}
+
+// isSite checks if a generated node is a site for mutation
+func isSite[T, P any](n *node[T, P], mutation Mutation) bool {
+	switch mutation {
+	case MutateObject:
+		switch n.pattern.(type) {
+		case *entity.Entity[T, P], *vector.Vector[T, P]:
+			return n.end > n.begin
+		}
+	case DropElement:
+		if n.parent == nil || n.end == n.begin {
+			return false
+		}
+
+		switch pt := n.parent.pattern.(type) {
+		case *concatenation.Concatenation[T, P]:
+			return true
+		case *repetition.Repetition[T, P]:
+			return len(n.parent.children) <= pt.Min()
+		}
+	case ExceedRepetition:
+		if rep, ok := n.pattern.(*repetition.Repetition[T, P]); ok {
+			return rep.Max() > 0 && len(n.children) > 0
+		}
+	}
+
+	return false
+}
+
+func walk[T, P any](n *node[T, P], f func(*node[T, P])) {
+	if n == nil {
+		return
+	}
+
+	f(n)
+
+	for _, child := range n.children {
+		walk(child, f)
+	}
+}
+
+// splice replaces output[begin:end] with insert and returns a new slice
+func splice[T any](output []T, begin int, end int, insert []T) []T {
+	result := make([]T, 0, len(output)-(end-begin)+len(insert))
+	result = append(result, output[:begin]...)
+	result = append(result, insert...)
+
+	return append(result, output[end:]...)
+}
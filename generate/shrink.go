@@ -0,0 +1,106 @@
+package generate
+
+import (
+	"errors"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"sort"
+)
+
+// ErrSampleMismatch is returned when the sample to shrink does not match the pattern
+var ErrSampleMismatch = errors.New("sample does not match pattern")
+
+// span is a range of a sample that can be removed
+type span struct {
+	begin int
+	end   int
+}
+
+// Shrink shrinks a sample that triggers a failure to a minimal reproducer. Optional components and repetition items
+// beyond the minimum are removed as long as the sample still matches the pattern entirely and fails still reports
+// the failure. The reader function creates a reader for a sample
+func Shrink[T, P any](pattern ebnf.Pattern[T, P], sample []T, reader func([]T) ebnf.Reader[T, P], fails func([]T) bool) ([]T, error) {
+	spans, ok, err := removable(pattern, sample, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, ErrSampleMismatch
+	}
+
+	for shrunk := true; shrunk; {
+		shrunk = false
+
+		for _, s := range spans {
+			candidate := splice(sample, s.begin, s.end, nil)
+
+			candidateSpans, ok, err := removable(pattern, candidate, reader)
+			if err != nil {
+				return nil, err
+			}
+
+			if ok && fails(candidate) {
+				sample, spans, shrunk = candidate, candidateSpans, true
+				break
+			}
+		}
+	}
+
+	return sample, nil
+}
+
+// removable matches the sample and returns the spans that can be removed, largest first
+func removable[T, P any](pattern ebnf.Pattern[T, P], sample []T, reader func([]T) ebnf.Reader[T, P]) ([]span, bool, error) {
+	r := reader(sample)
+
+	begin, err := r.Position()
+	if ebnf.IsStreamError(err) {
+		return nil, false, err
+	}
+
+	matched, result, err := ebnf.MatchPattern(pattern, r)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !matched || !r.Finished() {
+		return nil, false, nil
+	}
+
+	var (
+		spans []span
+		visit func(*ebnf.Match[T, P])
+	)
+
+	index := func(p P) int {
+		return r.Length(begin, p)
+	}
+
+	visit = func(m *ebnf.Match[T, P]) {
+		if rep, ok := m.Pattern.(*repetition.Repetition[T, P]); ok && len(m.Components) > rep.Min() {
+			extra := m.Components[rep.Min():]
+
+			// All items beyond the minimum at once, then single items
+			if len(extra) > 1 {
+				spans = append(spans, span{begin: index(extra[0].Begin), end: index(extra[len(extra)-1].End)})
+			}
+
+			for _, item := range extra {
+				spans = append(spans, span{begin: index(item.Begin), end: index(item.End)})
+			}
+		}
+
+		for _, component := range m.Components {
+			visit(component)
+		}
+	}
+
+	visit(result)
+
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].end-spans[i].begin > spans[j].end-spans[j].begin
+	})
+
+	return spans, true, nil
+}
@@ -1,3 +1,9 @@
 module github.com/almerlucke/exbana/v2
 
 go 1.22.2
+
+require golang.org/x/exp v0.0.0-20240719175910-8a7402abbf56
+
+require golang.org/x/text v0.22.0
+
+require github.com/klauspost/compress v1.17.11
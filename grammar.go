@@ -0,0 +1,100 @@
+package exbana
+
+import "io"
+
+// Grammar is an ordered set of rule patterns, each rule is identified by its pattern ID
+type Grammar[T, P any] struct {
+	rules Patterns[T, P]
+	index map[string]Pattern[T, P]
+	skip  Pattern[T, P]
+}
+
+// NewGrammar creates a new grammar from a list of rules
+func NewGrammar[T, P any](rules ...Pattern[T, P]) *Grammar[T, P] {
+	g := &Grammar[T, P]{
+		index: map[string]Pattern[T, P]{},
+	}
+
+	for _, rule := range rules {
+		g.Add(rule)
+	}
+
+	return g
+}
+
+// Add adds a rule to the grammar, a rule with the same ID is replaced. Rules are identified by their ID, patterns
+// without ID are not added
+func (g *Grammar[T, P]) Add(rule Pattern[T, P]) *Grammar[T, P] {
+	if rule.ID() == NoID {
+		return g
+	}
+
+	if _, ok := g.index[rule.ID()]; ok {
+		for i, r := range g.rules {
+			if r.ID() == rule.ID() {
+				g.rules[i] = rule
+				break
+			}
+		}
+	} else {
+		g.rules = append(g.rules, rule)
+	}
+
+	g.index[rule.ID()] = rule
+
+	return g
+}
+
+// Rules returns the rules in order of addition
+func (g *Grammar[T, P]) Rules() Patterns[T, P] {
+	return g.rules
+}
+
+// Rule returns the rule with the given ID
+func (g *Grammar[T, P]) Rule(id string) (Pattern[T, P], bool) {
+	rule, ok := g.index[id]
+	return rule, ok
+}
+
+// IsRule checks if pattern is one of the grammar rules
+func (g *Grammar[T, P]) IsRule(pattern Pattern[T, P]) bool {
+	if pattern.ID() == NoID {
+		return false
+	}
+
+	rule, ok := g.index[pattern.ID()]
+
+	return ok && rule == pattern
+}
+
+// SetSkip sets the default skip pattern of the grammar for lexemes without their own skip pattern
+func (g *Grammar[T, P]) SetSkip(skip Pattern[T, P]) *Grammar[T, P] {
+	g.skip = skip
+	return g
+}
+
+// Skip returns the default skip pattern, nil if there is none
+func (g *Grammar[T, P]) Skip() Pattern[T, P] {
+	return g.skip
+}
+
+// Reader returns r with the default skip pattern of the grammar, r itself if the grammar has no skip pattern
+func (g *Grammar[T, P]) Reader(r Reader[T, P]) Reader[T, P] {
+	if g.skip == nil {
+		return r
+	}
+
+	return WithSkip(r, g.skip)
+}
+
+// Print prints all grammar rules as EBNF
+func (g *Grammar[T, P]) Print(w io.Writer) error {
+	output, err := PrintRules(g.rules)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write([]byte(output))
+
+	return err
+}
@@ -0,0 +1,367 @@
+package golang
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/lexer"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/exception"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/runeclass"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	"github.com/almerlucke/exbana/v2/readers/tokens"
+	"math/big"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Pattern IDs, the production names of the Go specification (https://go.dev/ref/spec#Lexical_elements). Comments,
+// keywords, operators and white space have no production in the specification
+const (
+	Identifier           = "identifier"
+	Keyword              = "keyword"
+	Operator             = "operator"
+	IntLit               = "int_lit"
+	DecimalLit           = "decimal_lit"
+	BinaryLit            = "binary_lit"
+	OctalLit             = "octal_lit"
+	HexLit               = "hex_lit"
+	FloatLit             = "float_lit"
+	DecimalFloatLit      = "decimal_float_lit"
+	HexFloatLit          = "hex_float_lit"
+	ImaginaryLit         = "imaginary_lit"
+	RuneLit              = "rune_lit"
+	StringLit            = "string_lit"
+	RawStringLit         = "raw_string_lit"
+	InterpretedStringLit = "interpreted_string_lit"
+	LineComment          = "line_comment"
+	GeneralComment       = "general_comment"
+	WhiteSpace           = "white_space"
+)
+
+// Keywords are the reserved keywords of Go
+var Keywords = []string{"break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough", "for",
+	"func", "go", "goto", "if", "import", "interface", "map", "package", "range", "return", "select", "struct",
+	"switch", "type", "var"}
+
+// Operators are the operators and punctuation of Go
+var Operators = []string{"+", "&", "+=", "&=", "&&", "==", "!=", "(", ")", "-", "|", "-=", "|=", "||", "<", "<=", "[",
+	"]", "*", "^", "*=", "^=", "<-", ">", ">=", "{", "}", "/", "<<", "/=", "<<=", "++", "=", ":=", ",", ";", "%", ">>",
+	"%=", ">>=", "--", "!", "...", ".", ":", "&^", "&^=", "~"}
+
+// Lexer contains the patterns of the Go lexical grammar, all patterns have the ID of their production
+type Lexer[P any] struct {
+	Identifier     ebnf.Pattern[rune, P]
+	Keyword        ebnf.Pattern[rune, P]
+	Operator       ebnf.Pattern[rune, P]
+	IntLit         ebnf.Pattern[rune, P]
+	FloatLit       ebnf.Pattern[rune, P]
+	ImaginaryLit   ebnf.Pattern[rune, P]
+	RuneLit        ebnf.Pattern[rune, P]
+	StringLit      ebnf.Pattern[rune, P]
+	LineComment    ebnf.Pattern[rune, P]
+	GeneralComment ebnf.Pattern[rune, P]
+	WhiteSpace     ebnf.Pattern[rune, P]
+	// Token matches a single token or comment, the longest token wins as in the Go scanner
+	Token ebnf.Pattern[rune, P]
+	// Skip matches white space and comments, use it as skip pattern of lexemes
+	Skip ebnf.Pattern[rune, P]
+	// Rules are all named productions
+	Rules ebnf.Patterns[rune, P]
+	// word matches identifiers and keywords, Tokens tells them apart with a keyword table
+	word ebnf.Pattern[rune, P]
+}
+
+// New creates the Go lexical grammar. Literals evaluate to their value: int_lit to *big.Int, float_lit to float64,
+// imaginary_lit to complex128, rune_lit to rune and string_lit to string. Identifiers, keywords, operators and
+// comments evaluate to their text
+func New[P any]() *Lexer[P] {
+	l := &Lexer[P]{}
+
+	rule := func(id string, p ebnf.Pattern[rune, P], eval func(string) (any, error)) ebnf.Pattern[rune, P] {
+		p.SetID(id)
+		p.SetEvalFunc(func(m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+			s, err := ebnf.Text(m, r)
+			if err != nil {
+				return nil, err
+			}
+
+			return eval(s)
+		})
+
+		l.Rules = append(l.Rules, p)
+
+		return p
+	}
+
+	text := func(s string) (any, error) { return s, nil }
+
+	letter := runeclass.FromFunc[P](func(c rune) bool {
+		return c == '_' || unicode.IsLetter(c)
+	}, letters, `(unicode_letter | "_")`, "letter")
+	letterOrDigit := runeclass.FromFunc[P](func(c rune) bool {
+		return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+	}, letters, `(letter | unicode_digit)`, "letter or digit")
+
+	identifier := concatenation.New[rune, P](letter, rep[P](letterOrDigit))
+	l.word = concatenation.New[rune, P](identifier).SetID(Identifier)
+
+	// Keywords are not matched as prefix of an identifier
+	var keywords []ebnf.Pattern[rune, P]
+
+	for _, kw := range Keywords {
+		keywords = append(keywords, exception.New[rune, P](lit[P](kw), concatenation.New[rune, P](lit[P](kw), letterOrDigit)))
+	}
+
+	l.Keyword = rule(Keyword, alternation.New[rune, P](keywords...), text)
+	// An exception returns the match of its must pattern, the concatenation keeps the identifier ID in the match
+	l.Identifier = rule(Identifier, concatenation.New[rune, P](exception.New[rune, P](identifier, l.Keyword)), text)
+
+	// Longest operators first
+	operators := append([]string{}, Operators...)
+	sort.SliceStable(operators, func(i, j int) bool { return len(operators[i]) > len(operators[j]) })
+
+	var ops []ebnf.Pattern[rune, P]
+
+	for _, op := range operators {
+		ops = append(ops, lit[P](op))
+	}
+
+	l.Operator = rule(Operator, alternation.New[rune, P](ops...), text)
+
+	// Integers
+	digits := func(class string) ebnf.Pattern[rune, P] {
+		digit := func() ebnf.Pattern[rune, P] { return runeclass.MustClass[P](class) }
+		return concatenation.New[rune, P](digit(), rep[P](concatenation.New[rune, P](opt[P](lit[P]("_")), digit())))
+	}
+
+	decimalDigits := func() ebnf.Pattern[rune, P] { return digits("[0-9]") }
+	hexDigits := func() ebnf.Pattern[rune, P] { return digits("[0-9A-Fa-f]") }
+
+	evalInt := func(s string) (any, error) {
+		i, ok := new(big.Int).SetString(s, 0)
+		if !ok {
+			return nil, strconv.ErrSyntax
+		}
+
+		return i, nil
+	}
+
+	decimalLit := rule(DecimalLit, alternation.New[rune, P](
+		concatenation.New[rune, P](runeclass.MustClass[P]("[1-9]"), opt[P](concatenation.New[rune, P](opt[P](lit[P]("_")), decimalDigits()))),
+		lit[P]("0"),
+	), evalInt)
+	binaryLit := rule(BinaryLit, concatenation.New[rune, P](lit[P]("0"), runeclass.MustClass[P]("[bB]"), opt[P](lit[P]("_")), digits("[01]")), evalInt)
+	octalLit := rule(OctalLit, concatenation.New[rune, P](lit[P]("0"), opt[P](runeclass.MustClass[P]("[oO]")), opt[P](lit[P]("_")), digits("[0-7]")), evalInt)
+	hexLit := rule(HexLit, concatenation.New[rune, P](lit[P]("0"), runeclass.MustClass[P]("[xX]"), opt[P](lit[P]("_")), hexDigits()), evalInt)
+
+	l.IntLit = rule(IntLit, alternation.New[rune, P](hexLit, binaryLit, octalLit, decimalLit), evalInt)
+
+	// Floats
+	evalFloat := func(s string) (any, error) {
+		return strconv.ParseFloat(s, 64)
+	}
+
+	sign := func() ebnf.Pattern[rune, P] { return opt[P](runeclass.MustClass[P]("[+-]")) }
+	decimalExponent := func() ebnf.Pattern[rune, P] {
+		return concatenation.New[rune, P](runeclass.MustClass[P]("[eE]"), sign(), decimalDigits())
+	}
+
+	decimalFloatLit := rule(DecimalFloatLit, alternation.New[rune, P](
+		concatenation.New[rune, P](decimalDigits(), lit[P]("."), opt[P](decimalDigits()), opt[P](decimalExponent())),
+		concatenation.New[rune, P](decimalDigits(), decimalExponent()),
+		concatenation.New[rune, P](lit[P]("."), decimalDigits(), opt[P](decimalExponent())),
+	), evalFloat)
+
+	hexMantissa := alternation.New[rune, P](
+		concatenation.New[rune, P](opt[P](lit[P]("_")), hexDigits(), lit[P]("."), opt[P](hexDigits())),
+		concatenation.New[rune, P](opt[P](lit[P]("_")), hexDigits()),
+		concatenation.New[rune, P](lit[P]("."), hexDigits()),
+	)
+	hexExponent := concatenation.New[rune, P](runeclass.MustClass[P]("[pP]"), sign(), decimalDigits())
+	hexFloatLit := rule(HexFloatLit, concatenation.New[rune, P](lit[P]("0"), runeclass.MustClass[P]("[xX]"), hexMantissa, hexExponent), evalFloat)
+
+	l.FloatLit = rule(FloatLit, alternation.New[rune, P](hexFloatLit, decimalFloatLit), evalFloat)
+
+	// Each alternative includes the "i", so 089i is matched by decimal_digits after int_lit matched 0
+	l.ImaginaryLit = rule(ImaginaryLit, alternation.New[rune, P](
+		concatenation.New[rune, P](l.FloatLit, lit[P]("i")),
+		concatenation.New[rune, P](l.IntLit, lit[P]("i")),
+		concatenation.New[rune, P](decimalDigits(), lit[P]("i")),
+	), func(s string) (any, error) {
+		// Decimal digits with leading zeros are decimal, not octal
+		s = strings.TrimSuffix(s, "i")
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return complex(0, f), nil
+		}
+
+		i, ok := new(big.Int).SetString(s, 0)
+		if !ok {
+			return nil, strconv.ErrSyntax
+		}
+
+		f, _ := new(big.Float).SetInt(i).Float64()
+
+		return complex(0, f), nil
+	})
+
+	// Runes and strings
+	hex := func(n int) ebnf.Pattern[rune, P] {
+		return repetition.New[rune, P](runeclass.HexDigit[P](), n, n)
+	}
+
+	value := func(quote rune) ebnf.Pattern[rune, P] {
+		escaped := concatenation.New[rune, P](lit[P](`\`), alternation.New[rune, P](
+			runeclass.MustClass[P](`[abfnrtv\\`+string(quote)+`]`),
+			concatenation.New[rune, P](lit[P]("x"), hex(2)),
+			concatenation.New[rune, P](lit[P]("u"), hex(4)),
+			concatenation.New[rune, P](lit[P]("U"), hex(8)),
+			repetition.New[rune, P](runeclass.OctDigit[P](), 3, 3),
+		))
+
+		char := runeclass.FromFunc[P](func(c rune) bool {
+			return c != '\n' && c != '\\' && c != quote
+		}, letters, "unicode_char", "character")
+
+		return alternation.New[rune, P](escaped, char)
+	}
+
+	l.RuneLit = rule(RuneLit, concatenation.New[rune, P](lit[P]("'"), value('\''), lit[P]("'")), func(s string) (any, error) {
+		c, _, _, err := strconv.UnquoteChar(s[1:len(s)-1], '\'')
+		return c, err
+	})
+
+	unquote := func(s string) (any, error) {
+		return strconv.Unquote(s)
+	}
+
+	rawChar := runeclass.FromFunc[P](func(c rune) bool { return c != '`' }, letters, "(unicode_char | newline)", "character")
+	rawStringLit := rule(RawStringLit, concatenation.New[rune, P](lit[P]("`"), rep[P](rawChar), lit[P]("`")), unquote)
+	interpretedStringLit := rule(InterpretedStringLit, concatenation.New[rune, P](lit[P](`"`), rep[P](value('"')), lit[P](`"`)), unquote)
+
+	l.StringLit = rule(StringLit, alternation.New[rune, P](rawStringLit, interpretedStringLit), unquote)
+
+	// Comments and white space
+	notNewline := runeclass.FromFunc[P](func(c rune) bool { return c != '\n' }, letters, "unicode_char", "character")
+	l.LineComment = rule(LineComment, concatenation.New[rune, P](lit[P]("//"), rep[P](notNewline)), text)
+
+	anyChar := runeclass.FromFunc[P](func(rune) bool { return true }, letters, "(unicode_char | newline)", "character")
+	l.GeneralComment = rule(GeneralComment, concatenation.New[rune, P](
+		lit[P]("/*"), rep[P](exception.New[rune, P](anyChar, lit[P]("*/"))), lit[P]("*/"),
+	), text)
+
+	l.WhiteSpace = rule(WhiteSpace, repetition.New[rune, P](runeclass.MustClass[P](`[ \t\r\n]`), 1, 0), text)
+
+	// Comments before operators so / does not win, imaginary before float before int so the longest literal wins
+	l.Token = alternation.New[rune, P](
+		l.LineComment, l.GeneralComment, l.Keyword, l.Identifier, l.ImaginaryLit, l.FloatLit, l.IntLit, l.RuneLit,
+		l.StringLit, l.Operator,
+	)
+
+	l.Skip = alternation.New[rune, P](l.WhiteSpace, l.LineComment, l.GeneralComment)
+
+	return l
+}
+
+// Tokens returns a token reader over src. The kinds of the tokens are the IDs of the token patterns, white space and
+// comments are kept as trivia. Semicolons are inserted as specified (https://go.dev/ref/spec#Semicolons): after the
+// last token of a line if it is an identifier, a literal, one of the keywords break, continue, fallthrough or return,
+// or one of the operators ++, --, ), ] or }. An inserted semicolon is an operator token without length at the end of
+// the token it follows. Keywords are told apart from identifiers with a keyword table
+func (l *Lexer[P]) Tokens(src ebnf.Reader[rune, P]) *tokens.Reader[rune, P] {
+	lex := lexer.New[rune, P](src, nil,
+		l.word, l.ImaginaryLit, l.FloatLit, l.IntLit, l.RuneLit, l.StringLit, l.Operator,
+	).SetTrivia(l.WhiteSpace, l.LineComment, l.GeneralComment).
+		SetKeywords(Identifier, lexer.NewKeywordTable(Keyword, Keywords...))
+
+	return tokens.New[rune, P](tokens.Transform[rune, P](lex, insertSemicolons[P]))
+}
+
+// insertSemicolons inserts a semicolon before a token that follows a newline and at the end of the stream, if the
+// previous token ends a statement
+func insertSemicolons[P any](state *tokens.State[rune, P], tok tokens.Token[rune, P], eof bool) []tokens.Token[rune, P] {
+	if state.Emitted == 0 || !endsStatement(state.Prev) {
+		if eof {
+			return nil
+		}
+
+		return []tokens.Token[rune, P]{tok}
+	}
+
+	if eof {
+		return []tokens.Token[rune, P]{semicolon(state.Prev)}
+	}
+
+	for _, trivia := range tok.Trivia {
+		// A general comment containing newlines acts like a newline
+		if slices.Contains(trivia.Value, '\n') {
+			return []tokens.Token[rune, P]{semicolon(state.Prev), tok}
+		}
+	}
+
+	return []tokens.Token[rune, P]{tok}
+}
+
+// endsStatement reports if a semicolon is inserted after tok at the end of a line
+func endsStatement[P any](tok tokens.Token[rune, P]) bool {
+	switch tok.Kind {
+	case Identifier, IntLit, FloatLit, ImaginaryLit, RuneLit, StringLit:
+		return true
+	case Keyword:
+		return slices.Contains([]string{"break", "continue", "fallthrough", "return"}, string(tok.Value))
+	case Operator:
+		return slices.Contains([]string{"++", "--", ")", "]", "}"}, string(tok.Value))
+	}
+
+	return false
+}
+
+// semicolon returns an inserted semicolon after tok
+func semicolon[P any](tok tokens.Token[rune, P]) tokens.Token[rune, P] {
+	return tokens.Token[rune, P]{Kind: Operator, Value: []rune(";"), Begin: tok.End, End: tok.End}
+}
+
+// Kind returns the production name of a token match, the innermost named production for literals (i.e. hex_lit
+// instead of int_lit)
+func Kind[P any](m *ebnf.Match[rune, P]) string {
+	m = m.Unpack()
+	kind := m.ID()
+
+	for len(m.Components) == 1 && m.Components[0].ID() != ebnf.NoID {
+		m = m.Components[0]
+		kind = m.ID()
+	}
+
+	return kind
+}
+
+// IsKeyword reports if s is a Go keyword
+func IsKeyword(s string) bool {
+	return slices.Contains(Keywords, s)
+}
+
+// letters is the generator table for identifiers and literal text
+var letters = &unicode.RangeTable{R16: []unicode.Range16{
+	{Lo: 'A', Hi: 'Z', Stride: 1},
+	{Lo: '_', Hi: '_', Stride: 1},
+	{Lo: 'a', Hi: 'z', Stride: 1},
+}}
+
+func rep[P any](p ebnf.Pattern[rune, P]) ebnf.Pattern[rune, P] {
+	return repetition.New[rune, P](p, 0, 0)
+}
+
+func opt[P any](p ebnf.Pattern[rune, P]) ebnf.Pattern[rune, P] {
+	return repetition.New[rune, P](p, 0, 1)
+}
+
+// lit matches the runes of s
+func lit[P any](s string) ebnf.Pattern[rune, P] {
+	v := vector.New[rune, P](func(a, b rune) bool { return a == b }, []rune(s)...)
+	v.SetPrintOutput(strconv.Quote(s))
+
+	return v
+}
@@ -0,0 +1,200 @@
+package ini
+
+import (
+	"errors"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/diagnostics"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/runeclass"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	"github.com/almerlucke/exbana/v2/readers/filter"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"io"
+	"strings"
+)
+
+// Pattern IDs of the INI grammar
+const (
+	DocumentID = "ini"
+	SectionID  = "section"
+	EntryID    = "entry"
+	CommentID  = "comment"
+)
+
+// Diagnostic code of malformed lines
+const CodeMalformedLine = "INI001"
+
+// Document is the result of evaluating an INI document, keys before the first section are in section ""
+type Document map[string]map[string]string
+
+// Entry is the result of evaluating an entry
+type Entry struct {
+	Key   string
+	Value string
+}
+
+// Skip matches comment lines and inline comments preceded by white space, it is the skip pattern for
+// filter.NewWithPattern. A comment line is matched together with the newline before it so the line structure of the
+// filtered input is kept
+func Skip[P any]() ebnf.Pattern[rune, P] {
+	return alternation.New[rune, P](
+		concatenation.New[rune, P](vector.Runes[P]("\n"), space[P](0), comment[P]()),
+		concatenation.New[rune, P](space[P](1), comment[P]()),
+	)
+}
+
+// Grammar returns the INI document pattern: lines with a [section] header, a key = value (or key: value) entry or a
+// comment. A value ending with a backslash continues on the next line. The document evaluates to a Document
+func Grammar[P any]() ebnf.Pattern[rune, P] {
+	section := concatenation.New[rune, P]().
+		Add("", vector.Runes[P]("[")).
+		Add("name", repetition.New[rune, P](runeclass.MustClass[P](`[^\]\n]`), 1, 0)).
+		Add("", vector.Runes[P]("]"))
+	section.SetID(SectionID)
+	section.SetEvalFunc(func(m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+		name, err := ebnf.Text(m.Component("name"), r)
+		return strings.TrimSpace(name), err
+	})
+
+	key := concatenation.New[rune, P](
+		runeclass.MustClass[P](`[^=:\[\n \t;#]`),
+		repetition.New[rune, P](runeclass.MustClass[P](`[^=:\n]`).SetExpectation("key"), 0, 0),
+	)
+
+	separator := runeclass.MustClass[P]("[=:]").SetExpectation(`"=" or ":"`)
+	separator.SetDiagnostic(CodeMalformedLine, ebnf.SeverityError)
+
+	value := repetition.New[rune, P](alternation.New[rune, P](
+		runeclass.MustClass[P](`[^\n\\]`),
+		concatenation.New[rune, P](vector.Runes[P](`\`), runeclass.Any[P]()),
+	), 0, 0)
+
+	entry := concatenation.New[rune, P]().
+		Add("key", key).
+		Add("", separator).
+		Add("", space[P](0)).
+		Add("value", value)
+	entry.SetID(EntryID)
+	entry.SetEvalFunc(func(m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+		k, err := ebnf.Text(m.Component("key"), r)
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := ebnf.Text(m.Component("value"), r)
+		if err != nil {
+			return nil, err
+		}
+
+		return Entry{Key: strings.TrimSpace(k), Value: unfold(v)}, nil
+	})
+
+	line := concatenation.New[rune, P](
+		space[P](0),
+		repetition.New[rune, P](alternation.New[rune, P](section, entry, comment[P]()), 0, 1),
+		space[P](0),
+	)
+
+	doc := concatenation.New[rune, P](line, repetition.New[rune, P](concatenation.New[rune, P](vector.Runes[P]("\n"), line), 0, 0))
+	doc.SetID(DocumentID)
+	doc.SetEvalFunc(func(m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+		var (
+			current string
+			err     error
+		)
+
+		d := Document{}
+
+		m.Walk(func(sub *ebnf.Match[rune, P], _ int) bool {
+			if err != nil {
+				return false
+			}
+
+			switch sub.ID() {
+			case SectionID:
+				current, err = ebnf.EvalAs[string](sub, r)
+				if _, ok := d[current]; !ok && err == nil {
+					d[current] = map[string]string{}
+				}
+
+				return false
+			case EntryID:
+				var e Entry
+
+				e, err = ebnf.EvalAs[Entry](sub, r)
+				if err == nil {
+					if d[current] == nil {
+						d[current] = map[string]string{}
+					}
+
+					d[current][e.Key] = e.Value
+				}
+
+				return false
+			}
+
+			return true
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return d, nil
+	})
+
+	return doc
+}
+
+// Parse parses an INI document, comments are dropped with a filter using Skip. A malformed line is reported as a
+// *diagnostics.ParseError
+func Parse(file string, r io.Reader) (Document, error) {
+	rd, err := runes.New(r)
+	if err != nil {
+		return nil, err
+	}
+
+	f := filter.NewWithPattern[rune, runes.Pos](rd, Skip[runes.Pos]())
+
+	m, err := ebnf.MatchFull[rune, filter.Pos[runes.Pos]](f, Grammar[filter.Pos[runes.Pos]]())
+	if err != nil {
+		var failure *ebnf.Failure[rune, filter.Pos[runes.Pos]]
+		if !errors.As(err, &failure) {
+			return nil, err
+		}
+
+		e := diagnostics.New(file, rd, failure.Pos.Source, failure.Error())
+		e.Err = failure
+		e.Code, e.Severity = ebnf.DiagnosticOf(failure.Patterns...)
+
+		return nil, e
+	}
+
+	return ebnf.EvalAs[Document](m, f)
+}
+
+// unfold joins continuation lines and trims trailing white space
+func unfold(value string) string {
+	lines := strings.Split(value, "\\\n")
+
+	for i := 1; i < len(lines); i++ {
+		lines[i] = strings.TrimLeft(lines[i], " \t")
+	}
+
+	return strings.TrimRight(strings.Join(lines, ""), " \t")
+}
+
+// comment matches a comment up to the end of the line
+func comment[P any]() ebnf.Pattern[rune, P] {
+	c := concatenation.New[rune, P](runeclass.MustClass[P]("[;#]"), repetition.New[rune, P](runeclass.MustClass[P](`[^\n]`), 0, 0))
+	c.SetID(CommentID)
+
+	return c
+}
+
+// space matches at least min spaces or tabs
+func space[P any](min int) ebnf.Pattern[rune, P] {
+	return repetition.New[rune, P](runeclass.MustClass[P](`[ \t]`), min, 0)
+}
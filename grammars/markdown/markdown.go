@@ -0,0 +1,318 @@
+package markdown
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/exception"
+	"github.com/almerlucke/exbana/v2/patterns/reference"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/runeclass"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"strings"
+)
+
+// Pattern IDs of the Markdown inline grammar. Delimiters (`*`, `**`, backticks, brackets and parentheses of links)
+// have the markup ID so a highlighter can style them apart from the content
+const (
+	InlineID   = "inline"
+	TextID     = "text"
+	EscapeID   = "escape"
+	CodeSpanID = "code_span"
+	EmphasisID = "emphasis"
+	StrongID   = "strong"
+	LinkID     = "link"
+	ImageID    = "image"
+	AutolinkID = "autolink"
+	MarkupID   = "markup"
+)
+
+// Node is the result of evaluating an inline element, Kind is the pattern ID of the element. Text holds the text of
+// text and code span nodes, Dest and Title the destination and title of links, images and autolinks. Escapes
+// evaluate to text nodes and adjacent text nodes are merged
+type Node struct {
+	Kind     string
+	Text     string
+	Dest     string
+	Title    string
+	Children []Node
+}
+
+// Grammar returns the pattern for a sequence of Markdown inline elements: backslash escapes, code spans, autolinks
+// (<scheme:...>), images, links with an optional title, strong (** or __) and emphasis (* or _). This is a
+// simplified subset of CommonMark: delimiters are matched innermost first without the delimiter run rules, an opening
+// delimiter must not be followed by white space and underscores inside words do not start emphasis. Characters that
+// do not start a complete element are text. The grammar evaluates to a []Node
+func Grammar[P any]() ebnf.Pattern[rune, P] {
+	element := reference.New[rune, P](nil)
+
+	// Text consumes words with inner underscores (snake_case) so they are not taken for emphasis
+	word := concatenation.New[rune, P](
+		alnum[P](),
+		repetition.New[rune, P](concatenation.New[rune, P](
+			repetition.New[rune, P](lit[P]("_"), 1, 0), alnum[P](),
+		), 0, 0),
+	)
+	text := repetition.New[rune, P](alternation.New[rune, P](word, runeclass.MustClass[P]("[^\\\\`*_\\[\\]!<]")), 1, 0)
+	text.SetID(TextID)
+	text.SetEvalFunc(evalText[P])
+
+	// Special characters which do not start a complete element
+	special := runeclass.MustClass[P]("[\\\\`*_\\[\\]!<]")
+	special.SetID(TextID)
+	special.SetEvalFunc(evalText[P])
+
+	escape := concatenation.New[rune, P]().
+		Add("", markup[P](`\`)).
+		Add("char", runeclass.ASCIIPunct[P]())
+	escape.SetID(EscapeID)
+	escape.SetEvalFunc(func(m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+		s, err := ebnf.Text(m.Component("char"), r)
+		return Node{Kind: TextID, Text: s}, err
+	})
+
+	// Code spans are closed by a backtick run of the same length, longer runs are tried first
+	var spans ebnf.Patterns[rune, P]
+
+	for _, ticks := range []string{"```", "``", "`"} {
+		span := concatenation.New[rune, P]().
+			Add("", markup[P](ticks)).
+			Add("code", repetition.New[rune, P](until[P](lit[P](ticks), runeclass.Any[P]()), 1, 0)).
+			Add("", markup[P](ticks))
+		span.SetID(CodeSpanID)
+		span.SetEvalFunc(func(m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+			s, err := ebnf.Text(m.Component("code"), r)
+			return Node{Kind: CodeSpanID, Text: codeText(s)}, err
+		})
+
+		spans = append(spans, span)
+	}
+
+	code := alternation.New[rune, P](spans...)
+
+	autolink := concatenation.New[rune, P]().
+		Add("", markup[P]("<")).
+		Add("uri", concatenation.New[rune, P](
+			runeclass.MustClass[P]("[A-Za-z]"),
+			repetition.New[rune, P](runeclass.MustClass[P]("[A-Za-z0-9+.\\-]"), 1, 31),
+			lit[P](":"),
+			repetition.New[rune, P](runeclass.MustClass[P]("[^ \\t\\n<>]"), 0, 0),
+		)).
+		Add("", markup[P](">"))
+	autolink.SetID(AutolinkID)
+	autolink.SetEvalFunc(func(m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+		s, err := ebnf.Text(m.Component("uri"), r)
+		return Node{Kind: AutolinkID, Text: s, Dest: s}, err
+	})
+
+	// The label may contain balanced brackets, the destination balanced parentheses
+	bracket := func(s string) ebnf.Pattern[rune, P] {
+		b := lit[P](s)
+		b.SetID(TextID)
+		b.SetEvalFunc(evalText[P])
+
+		return b
+	}
+	label := repetition.New[rune, P](balanced[P](bracket("["), bracket("]"),
+		exception.New[rune, P](element, runeclass.MustClass[P]("[\\[\\]]"))), 0, 0)
+
+	destChar := runeclass.MustClass[P]("[^ \\t\\n()<>]")
+	dest := alternation.New[rune, P](
+		concatenation.New[rune, P](lit[P]("<"), repetition.New[rune, P](runeclass.MustClass[P]("[^<>\\n]"), 0, 0), lit[P](">")),
+		repetition.New[rune, P](balanced[P](lit[P]("("), lit[P](")"), destChar), 1, 0),
+	)
+
+	title := concatenation.New[rune, P]().
+		Add("", repetition.New[rune, P](runeclass.MustClass[P]("[ \\t\\n]"), 1, 0)).
+		Add("", markup[P](`"`)).
+		Add("text", repetition.New[rune, P](runeclass.MustClass[P](`[^"]`), 0, 0)).
+		Add("", markup[P](`"`))
+
+	link := concatenation.New[rune, P]().
+		Add("", markup[P]("[")).
+		Add("label", label).
+		Add("", markup[P]("](")).
+		Add("dest", dest).
+		Add("title", repetition.New[rune, P](title, 0, 1)).
+		Add("", markup[P](")"))
+	link.SetID(LinkID)
+	link.SetEvalFunc(func(m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+		return evalLink(LinkID, m, r)
+	})
+
+	image := concatenation.New[rune, P]().
+		Add("", markup[P]("!")).
+		Add("link", link)
+	image.SetID(ImageID)
+	image.SetEvalFunc(func(m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+		return evalLink(ImageID, m.Component("link"), r)
+	})
+
+	strong := alternation.New[rune, P](
+		delimited[P](StrongID, "**", until[P](lit[P]("**"), element)),
+		delimited[P](StrongID, "__", until[P](lit[P]("__"), element)),
+	)
+
+	// Strong is tried first inside emphasis, otherwise its first delimiter would close the emphasis
+	emphasis := alternation.New[rune, P](
+		delimited[P](EmphasisID, "*", alternation.New[rune, P](strong, until[P](lit[P]("*"), element))),
+		delimited[P](EmphasisID, "_", alternation.New[rune, P](strong, until[P](lit[P]("_"), element))),
+	)
+
+	element.Set(alternation.New[rune, P](escape, code, autolink, image, link, strong, emphasis, text, special))
+
+	inline := repetition.New[rune, P](element, 0, 0)
+	inline.SetID(InlineID)
+	inline.SetEvalFunc(func(m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+		return nodes(m, r)
+	})
+
+	return inline
+}
+
+// Parse parses Markdown inline text
+func Parse(s string) ([]Node, error) {
+	rd, err := runes.New(strings.NewReader(s))
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := ebnf.MatchFull[rune, runes.Pos](rd, Grammar[runes.Pos]())
+	if err != nil {
+		return nil, err
+	}
+
+	return ebnf.EvalAs[[]Node](m, rd)
+}
+
+// delimited matches one or more items between two delimiters, items must not match the closing delimiter. The
+// content must not start with white space
+func delimited[P any](id string, delimiter string, item ebnf.Pattern[rune, P]) ebnf.Pattern[rune, P] {
+	body := exception.New[rune, P](repetition.New[rune, P](item, 1, 0), runeclass.MustClass[P]("[ \\t\\n]"))
+
+	d := concatenation.New[rune, P]().
+		Add("", markup[P](delimiter)).
+		Add("body", body).
+		Add("", markup[P](delimiter))
+	d.SetID(id)
+	d.SetEvalFunc(func(m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+		children, err := nodes(m, r)
+		return Node{Kind: id, Children: children}, err
+	})
+
+	return d
+}
+
+// until matches p if stop does not match at the current position, repeat it to match everything up to stop
+func until[P any](stop ebnf.Pattern[rune, P], p ebnf.Pattern[rune, P]) ebnf.Pattern[rune, P] {
+	return exception.New[rune, P](p, stop)
+}
+
+// balanced matches a group of open, nested groups and inner and close, or inner on its own. Repeat it to match a
+// sequence in which open and close are balanced, inner must not match open or close
+func balanced[P any](open ebnf.Pattern[rune, P], close ebnf.Pattern[rune, P], inner ebnf.Pattern[rune, P]) ebnf.Pattern[rune, P] {
+	ref := reference.New[rune, P](nil)
+	group := concatenation.New[rune, P](open, repetition.New[rune, P](ref, 0, 0), close)
+
+	return ref.Set(alternation.New[rune, P](group, inner))
+}
+
+// nodes evaluates the elements of m to nodes, matches without a node ID are searched for nodes
+func nodes[P any](m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) ([]Node, error) {
+	var result []Node
+
+	for _, c := range m.Components {
+		if c == nil {
+			continue
+		}
+
+		var children []Node
+
+		switch c.ID() {
+		case MarkupID:
+			continue
+		case TextID, EscapeID, CodeSpanID, EmphasisID, StrongID, LinkID, ImageID, AutolinkID:
+			n, err := ebnf.EvalAs[Node](c, r)
+			if err != nil {
+				return nil, err
+			}
+
+			children = []Node{n}
+		default:
+			var err error
+
+			children, err = nodes(c, r)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, n := range children {
+			if last := len(result) - 1; last >= 0 && n.Kind == TextID && result[last].Kind == TextID {
+				result[last].Text += n.Text
+			} else {
+				result = append(result, n)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// evalLink evaluates a link match to a node of kind
+func evalLink[P any](kind string, m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+	children, err := nodes(m.Component("label"), r)
+	if err != nil {
+		return nil, err
+	}
+
+	dest, err := ebnf.Text(m.Component("dest"), r)
+	if err != nil {
+		return nil, err
+	}
+
+	n := Node{Kind: kind, Dest: strings.TrimSuffix(strings.TrimPrefix(dest, "<"), ">"), Children: children}
+
+	if title, ok := m.Component("title").Optional(); ok {
+		n.Title, err = ebnf.Text(title.Component("text"), r)
+	}
+
+	return n, err
+}
+
+// codeText normalizes the content of a code span: line endings become spaces and a single space is stripped from
+// both ends if the content does not consist of spaces only
+func codeText(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+
+	if len(s) >= 2 && s[0] == ' ' && s[len(s)-1] == ' ' && strings.Trim(s, " ") != "" {
+		s = s[1 : len(s)-1]
+	}
+
+	return s
+}
+
+// evalText evaluates a text match to a text node
+func evalText[P any](m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+	s, err := ebnf.Text(m, r)
+	return Node{Kind: TextID, Text: s}, err
+}
+
+// alnum matches a letter or digit
+func alnum[P any]() ebnf.Pattern[rune, P] {
+	return runeclass.MustClass[P](`[\p{L}\p{N}]`)
+}
+
+// markup matches delimiter s
+func markup[P any](s string) ebnf.Pattern[rune, P] {
+	m := lit[P](s)
+	m.SetID(MarkupID)
+
+	return m
+}
+
+// lit matches the runes of s
+func lit[P any](s string) ebnf.Pattern[rune, P] {
+	return vector.New[rune, P](func(a, b rune) bool { return a == b }, []rune(s)...)
+}
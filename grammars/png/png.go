@@ -0,0 +1,189 @@
+package png
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/bind"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/signature"
+	"github.com/almerlucke/exbana/v2/patterns/tlv"
+	bytereader "github.com/almerlucke/exbana/v2/readers/bytes"
+	"hash/crc32"
+	"io"
+)
+
+// Pattern IDs of the PNG grammar
+const (
+	FileID   = "png"
+	ChunkID  = "chunk"
+	HeaderID = "ihdr"
+	CRCID    = "crc"
+)
+
+// ErrCRC is wrapped by CRCError
+var ErrCRC = errors.New("chunk CRC mismatch")
+
+// ErrLayout is returned by Decode if the file does not start with an IHDR or does not end with an IEND chunk
+var ErrLayout = errors.New("PNG must start with IHDR and end with IEND")
+
+// CRCError is returned when the CRC of a chunk does not match the CRC computed over its type and data
+type CRCError struct {
+	Type     string
+	Expected uint32
+	Actual   uint32
+}
+
+func (e *CRCError) Error() string {
+	return fmt.Sprintf("%v: %s chunk has CRC %08x, computed %08x", ErrCRC, e.Type, e.Expected, e.Actual)
+}
+
+func (e *CRCError) Unwrap() error {
+	return ErrCRC
+}
+
+// Chunk is the result of evaluating a chunk, the CRC is validated on evaluation
+type Chunk struct {
+	Type string
+	Data []byte
+	CRC  uint32
+}
+
+// Header is the IHDR chunk, it is decoded with bind.Decode from the ihdr match
+type Header struct {
+	Width             uint32 `exbana:"width"`
+	Height            uint32 `exbana:"height"`
+	BitDepth          uint8  `exbana:"bit_depth"`
+	ColorType         uint8  `exbana:"color_type"`
+	CompressionMethod uint8  `exbana:"compression_method"`
+	FilterMethod      uint8  `exbana:"filter_method"`
+	InterlaceMethod   uint8  `exbana:"interlace_method"`
+}
+
+// Image is a decoded PNG file, Chunks are all chunks including IHDR and IEND
+type Image struct {
+	Header Header
+	Chunks []Chunk
+}
+
+// Grammar returns the PNG file pattern: the PNG signature followed by chunks. A chunk is a big endian length, the
+// chunk type, the data and a CRC over type and data. The IHDR data has fields with IDs. The file evaluates to a
+// []Chunk, evaluation fails with a *CRCError if a CRC does not match
+func Grammar[P any]() ebnf.Pattern[byte, P] {
+	field := func(id string, size int) ebnf.Pattern[byte, P] {
+		return concatenation.New[byte, P](raw[P](size, size)).SetID(id)
+	}
+
+	header := concatenation.New[byte, P](
+		field("width", 4), field("height", 4), field("bit_depth", 1), field("color_type", 1),
+		field("compression_method", 1), field("filter_method", 1), field("interlace_method", 1),
+	)
+	header.SetID(HeaderID)
+
+	chunkType := raw[P](4, 4)
+	chunkType.SetEvalFunc(func(m *ebnf.Match[byte, P], r ebnf.Reader[byte, P]) (any, error) {
+		return ebnf.Text(m, r)
+	})
+
+	record := tlv.New[byte, P](chunkType, tlv.Uint[P](4, binary.BigEndian), nil, tlv.Table[byte, P]{
+		"IHDR": header,
+	}).SetLengthFirst(true)
+
+	crc := tlv.Uint[P](4, binary.BigEndian)
+	crc.SetID(CRCID)
+
+	chunk := concatenation.New[byte, P]().
+		Add("record", record).
+		Add("crc", crc)
+	chunk.SetID(ChunkID)
+	chunk.SetEvalFunc(evalChunk[P])
+
+	file := concatenation.New[byte, P](
+		signature.MustMagic[P]("89 50 4E 47 0D 0A 1A 0A"),
+		repetition.New[byte, P](chunk, 1, 0),
+	)
+	file.SetID(FileID)
+	file.SetEvalFunc(func(m *ebnf.Match[byte, P], r ebnf.Reader[byte, P]) (any, error) {
+		var chunks []Chunk
+
+		for _, c := range m.FindAll(ChunkID) {
+			v, err := ebnf.EvalAs[Chunk](c, r)
+			if err != nil {
+				return nil, err
+			}
+
+			chunks = append(chunks, v)
+		}
+
+		return chunks, nil
+	})
+
+	return file
+}
+
+// Decode decodes a PNG file, the CRCs of all chunks are validated and the header is bound from the IHDR match
+func Decode(r io.Reader) (*Image, error) {
+	rd, err := bytereader.New(r)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := ebnf.MatchFull[byte, int](rd, Grammar[int]())
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := ebnf.EvalAs[[]Chunk](m, rd)
+	if err != nil {
+		return nil, err
+	}
+
+	header := m.Find(HeaderID)
+	if header == nil || chunks[0].Type != "IHDR" || chunks[len(chunks)-1].Type != "IEND" {
+		return nil, ErrLayout
+	}
+
+	img := &Image{Chunks: chunks}
+
+	err = bind.Decode(header, rd, &img.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	return img, nil
+}
+
+// evalChunk evaluates a chunk match to a Chunk and validates the CRC
+func evalChunk[P any](m *ebnf.Match[byte, P], r ebnf.Reader[byte, P]) (any, error) {
+	record := m.Component("record")
+
+	typ, err := ebnf.Text(record.Component("tag"), r)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := record.Component("value").Objects(r)
+	if err != nil {
+		return nil, err
+	}
+
+	expected, err := ebnf.EvalAs[uint64](m.Component("crc"), r)
+	if err != nil {
+		return nil, err
+	}
+
+	actual := crc32.Update(crc32.ChecksumIEEE([]byte(typ)), crc32.IEEETable, data)
+	if uint32(expected) != actual {
+		return nil, &CRCError{Type: typ, Expected: uint32(expected), Actual: actual}
+	}
+
+	return Chunk{Type: typ, Data: data, CRC: actual}, nil
+}
+
+// raw matches min to max bytes, max 0 is unbounded
+func raw[P any](min int, max int) ebnf.Pattern[byte, P] {
+	return repetition.New[byte, P](entity.New[byte, P](func(byte) bool { return true }).SetExpectation("byte"), min, max)
+}
@@ -0,0 +1,206 @@
+package riff
+
+import (
+	"encoding/binary"
+	"errors"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/bind"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/padding"
+	"github.com/almerlucke/exbana/v2/patterns/reference"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/tlv"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	bytereader "github.com/almerlucke/exbana/v2/readers/bytes"
+	"io"
+)
+
+// Pattern IDs of the RIFF grammar
+const (
+	FileID   = "riff"
+	ChunkID  = "chunk"
+	ListID   = "list"
+	FormatID = "fmt"
+)
+
+// ErrNotWAV is returned by DecodeWAV if the RIFF form is not WAVE or a fmt or data chunk is missing
+var ErrNotWAV = errors.New("not a WAVE file")
+
+// Chunk is the result of evaluating a chunk, a LIST chunk has the list type and sub chunks instead of data. The
+// file evaluates to a RIFF chunk with the form type as list type
+type Chunk struct {
+	ID     string
+	Data   []byte
+	List   string
+	Chunks []Chunk
+}
+
+// Format is the WAVE fmt chunk, it is decoded with bind.Decode from the fmt match
+type Format struct {
+	AudioFormat   uint16 `exbana:"audio_format,le"`
+	Channels      uint16 `exbana:"channels,le"`
+	SampleRate    uint32 `exbana:"sample_rate,le"`
+	ByteRate      uint32 `exbana:"byte_rate,le"`
+	BlockAlign    uint16 `exbana:"block_align,le"`
+	BitsPerSample uint16 `exbana:"bits_per_sample,le"`
+}
+
+// WAV is a decoded WAVE file, Chunks are all top level chunks including fmt and data
+type WAV struct {
+	Format Format
+	Data   []byte
+	Chunks []Chunk
+}
+
+// Grammar returns the RIFF file pattern: "RIFF", the little endian file size, the form type and the chunks. A chunk
+// is a FourCC, a little endian size and the data, padded to an even offset. LIST chunks contain a list type and
+// nested chunks, the WAVE fmt chunk has fields with IDs. The file evaluates to a Chunk
+func Grammar[P any]() ebnf.Pattern[byte, P] {
+	chunk := reference.New[byte, P](nil)
+	chunks := repetition.New[byte, P](chunk, 0, 0)
+
+	list := concatenation.New[byte, P]().
+		Add("type", fourCC[P]()).
+		Add("chunks", chunks)
+	list.SetID(ListID)
+
+	field := func(id string, size int) ebnf.Pattern[byte, P] {
+		return concatenation.New[byte, P](raw[P](size, size)).SetID(id)
+	}
+
+	format := concatenation.New[byte, P](
+		field("audio_format", 2), field("channels", 2), field("sample_rate", 4), field("byte_rate", 4),
+		field("block_align", 2), field("bits_per_sample", 2),
+		// Extensible formats have extra fields
+		raw[P](0, 0),
+	)
+	format.SetID(FormatID)
+
+	record := tlv.New[byte, P](fourCC[P](), tlv.Uint[P](4, binary.LittleEndian), nil, tlv.Table[byte, P]{
+		"LIST": list,
+		"fmt ": format,
+	})
+	record.SetID(ChunkID)
+	record.SetEvalFunc(evalChunk[P])
+
+	padded := concatenation.New[byte, P](record, padding.Align[byte, P](2, nil))
+	padded.SetEvalFunc(func(m *ebnf.Match[byte, P], r ebnf.Reader[byte, P]) (any, error) {
+		return m.Components[0].Eval(r)
+	})
+	chunk.Set(padded)
+
+	riff := vector.New[byte, P](func(a, b byte) bool { return a == b }, 'R', 'I', 'F', 'F')
+	riff.SetEvalFunc(func(*ebnf.Match[byte, P], ebnf.Reader[byte, P]) (any, error) {
+		return "RIFF", nil
+	})
+
+	file := tlv.New[byte, P](riff, tlv.Uint[P](4, binary.LittleEndian), nil, tlv.Table[byte, P]{
+		"RIFF": concatenation.New[byte, P]().Add("type", fourCC[P]()).Add("chunks", chunks),
+	})
+	file.SetID(FileID)
+	file.SetEvalFunc(evalChunk[P])
+
+	return file
+}
+
+// Decode decodes a RIFF file
+func Decode(r io.Reader) (Chunk, error) {
+	rd, err := bytereader.New(r)
+	if err != nil {
+		return Chunk{}, err
+	}
+
+	m, err := ebnf.MatchFull[byte, int](rd, Grammar[int]())
+	if err != nil {
+		return Chunk{}, err
+	}
+
+	return ebnf.EvalAs[Chunk](m, rd)
+}
+
+// DecodeWAV decodes a WAVE file, the format is bound from the fmt chunk match
+func DecodeWAV(r io.Reader) (*WAV, error) {
+	rd, err := bytereader.New(r)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := ebnf.MatchFull[byte, int](rd, Grammar[int]())
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := ebnf.EvalAs[Chunk](m, rd)
+	if err != nil {
+		return nil, err
+	}
+
+	format := m.Find(FormatID)
+	if file.List != "WAVE" || format == nil {
+		return nil, ErrNotWAV
+	}
+
+	w := &WAV{Chunks: file.Chunks}
+
+	err = bind.Decode(format, rd, &w.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range file.Chunks {
+		if c.ID == "data" {
+			w.Data = c.Data
+			return w, nil
+		}
+	}
+
+	return nil, ErrNotWAV
+}
+
+// evalChunk evaluates a chunk match to a Chunk
+func evalChunk[P any](m *ebnf.Match[byte, P], r ebnf.Reader[byte, P]) (any, error) {
+	id, err := ebnf.Text(m.Component("tag"), r)
+	if err != nil {
+		return nil, err
+	}
+
+	c := Chunk{ID: id}
+	value := m.Component("value")
+
+	if id != "LIST" && id != "RIFF" {
+		c.Data, err = value.Objects(r)
+		return c, err
+	}
+
+	c.List, err = ebnf.Text(value.Component("type"), r)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sub := range value.Component("chunks").Components {
+		v, err := ebnf.EvalAs[Chunk](sub, r)
+		if err != nil {
+			return nil, err
+		}
+
+		c.Chunks = append(c.Chunks, v)
+	}
+
+	return c, nil
+}
+
+// fourCC matches a four character code, it evaluates to a string
+func fourCC[P any]() ebnf.Pattern[byte, P] {
+	f := raw[P](4, 4)
+	f.SetEvalFunc(func(m *ebnf.Match[byte, P], r ebnf.Reader[byte, P]) (any, error) {
+		return ebnf.Text(m, r)
+	})
+
+	return f
+}
+
+// raw matches min to max bytes, max 0 is unbounded
+func raw[P any](min int, max int) ebnf.Pattern[byte, P] {
+	return repetition.New[byte, P](entity.New[byte, P](func(byte) bool { return true }).SetExpectation("byte"), min, max)
+}
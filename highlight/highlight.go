@@ -0,0 +1,165 @@
+package highlight
+
+import (
+	"errors"
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"html"
+	"io"
+	"slices"
+)
+
+// ErrNoOffset is returned when a match position has no offset, see ebnf.PosOffset
+var ErrNoOffset = errors.New("position has no offset")
+
+// Span is a highlighted span of objects, Begin and End are offsets in objects (i.e. runes for the rune reader and
+// bytes for the byte reader)
+type Span struct {
+	Class string `json:"class"`
+	Begin int    `json:"begin"`
+	End   int    `json:"end"`
+}
+
+// ClassFunc returns the highlight class of a pattern ID, an empty class is not highlighted
+type ClassFunc func(id string) string
+
+// Classes returns a ClassFunc which looks up the class of a pattern ID in classes
+func Classes(classes map[string]string) ClassFunc {
+	return func(id string) string {
+		return classes[id]
+	}
+}
+
+// Spans converts match trees (i.e. a single match or the results of Scan) to ordered, non overlapping spans. The
+// class of a match is given by class, if class is nil every match with an ID is highlighted with the ID as class.
+// Inner matches take precedence over the matches containing them, adjacent spans of the same class are merged.
+// Offsets are taken from the positions with ebnf.PosOffset
+func Spans[T, P any](matches []*ebnf.Match[T, P], class ClassFunc) ([]Span, error) {
+	if class == nil {
+		class = func(id string) string { return id }
+	}
+
+	var spans []Span
+
+	for _, m := range matches {
+		sub, err := spansOf(m, class)
+		if err != nil {
+			return nil, err
+		}
+
+		spans = appendSpans(spans, sub...)
+	}
+
+	slices.SortStableFunc(spans, func(a, b Span) int {
+		return a.Begin - b.Begin
+	})
+
+	return spans, nil
+}
+
+// spansOf returns the spans of match m, the parts of m not covered by the spans of its components get the class of m
+func spansOf[T, P any](m *ebnf.Match[T, P], class ClassFunc) ([]Span, error) {
+	begin, ok := ebnf.PosOffset(m.Begin)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrNoOffset, m.Begin)
+	}
+
+	end, ok := ebnf.PosOffset(m.End)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrNoOffset, m.End)
+	}
+
+	own := ""
+	if id := m.ID(); id != ebnf.NoID {
+		own = class(id)
+	}
+
+	var spans []Span
+
+	cursor := begin
+
+	for _, c := range m.Components {
+		sub, err := spansOf(c, class)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range sub {
+			// Components are ordered, spans before the cursor would overlap
+			if s.Begin < cursor {
+				continue
+			}
+
+			if own != "" && s.Begin > cursor {
+				spans = appendSpans(spans, Span{Class: own, Begin: cursor, End: s.Begin})
+			}
+
+			spans = appendSpans(spans, s)
+			cursor = s.End
+		}
+	}
+
+	if own != "" && end > cursor {
+		spans = appendSpans(spans, Span{Class: own, Begin: cursor, End: end})
+	}
+
+	return spans, nil
+}
+
+// appendSpans appends spans, a span is merged with the last span if they have the same class and are adjacent
+func appendSpans(spans []Span, add ...Span) []Span {
+	for _, s := range add {
+		if s.End <= s.Begin {
+			continue
+		}
+
+		if n := len(spans); n > 0 && spans[n-1].Class == s.Class && spans[n-1].End == s.Begin {
+			spans[n-1].End = s.End
+			continue
+		}
+
+		spans = append(spans, s)
+	}
+
+	return spans
+}
+
+// WriteHTML writes text as HTML with every span in a span element with the class of the span prefixed by prefix
+// (i.e. "hl-"), text outside the spans is written escaped. The spans must be ordered and non overlapping as returned
+// by Spans
+func WriteHTML[T rune | byte](w io.Writer, text []T, spans []Span, prefix string) error {
+	cursor := 0
+
+	for _, s := range spans {
+		if s.Begin < cursor || s.End > len(text) {
+			return fmt.Errorf("span %v - %v out of order or out of bounds", s.Begin, s.End)
+		}
+
+		_, err := fmt.Fprintf(w, `%s<span class="%s">%s</span>`,
+			html.EscapeString(str(text[cursor:s.Begin])),
+			html.EscapeString(prefix+s.Class),
+			html.EscapeString(str(text[s.Begin:s.End])),
+		)
+		if err != nil {
+			return err
+		}
+
+		cursor = s.End
+	}
+
+	_, err := io.WriteString(w, html.EscapeString(str(text[cursor:])))
+
+	return err
+}
+
+// str converts runes or bytes to a string
+func str[T rune | byte](text []T) string {
+	switch v := any(text).(type) {
+	case []rune:
+		return string(v)
+	case []byte:
+		return string(v)
+	}
+
+	return ""
+}
@@ -0,0 +1,380 @@
+package goebnf
+
+import (
+	"bytes"
+	"fmt"
+	xebnf "golang.org/x/exp/ebnf"
+	"sort"
+	"strconv"
+	"strings"
+	"text/scanner"
+	"unicode/utf8"
+)
+
+// Order is the order of the productions of a formatted grammar
+type Order int
+
+const (
+	// SourceOrder keeps the productions in source order, blank lines between productions are kept
+	SourceOrder Order = iota
+	// AlphaOrder sorts the productions by name
+	AlphaOrder
+	// UseOrder starts with the first production followed by the productions in order of first use, depth first.
+	// Productions that are not used from the first production follow in source order
+	UseOrder
+)
+
+// FormatOptions configures Format
+type FormatOptions struct {
+	// Order is the order of the productions
+	Order Order
+	// Width is the line width, the alternatives of a production that does not fit are written on lines of their
+	// own. Zero never wraps
+	Width int
+}
+
+// Format parses an EBNF grammar in golang.org/x/exp/ebnf notation and prints it canonically: the names of
+// consecutive productions are padded so the "=" signs align, terms are separated by single spaces, groups, options
+// and repetitions are padded inside and tokens are double quoted. Comments before a production (or inside it) are
+// printed above the production and move with it, a comment after the "." of a production stays on its line.
+// Formatting is idempotent
+func Format(filename string, src []byte, opts FormatOptions) ([]byte, error) {
+	grammar, err := xebnf.Parse(filename, bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+
+	productions := make([]*xebnf.Production, 0, len(grammar))
+	for _, production := range grammar {
+		productions = append(productions, production)
+	}
+
+	sort.Slice(productions, func(i, j int) bool {
+		return productions[i].Pos().Offset < productions[j].Pos().Offset
+	})
+
+	header, layouts, footer := comments(filename, src, productions)
+
+	switch opts.Order {
+	case AlphaOrder:
+		sort.SliceStable(productions, func(i, j int) bool {
+			return productions[i].Name.String < productions[j].Name.String
+		})
+	case UseOrder:
+		productions = useOrder(grammar, productions)
+	}
+
+	f := &formatter{width: opts.Width}
+
+	for _, c := range header {
+		f.comment(c)
+	}
+
+	if len(header) > 0 && len(productions) > 0 {
+		f.blank()
+	}
+
+	var block []*xebnf.Production
+
+	for i, production := range productions {
+		l := layouts[production]
+
+		separate := i > 0 && (l.blank || len(l.leading) > 0)
+		if opts.Order != SourceOrder {
+			separate = i > 0 && len(l.leading) > 0
+		}
+
+		if separate || len(l.leading) > 0 {
+			f.block(block, layouts)
+			block = nil
+		}
+
+		if separate {
+			f.blank()
+		}
+
+		for _, c := range l.leading {
+			f.comment(c)
+		}
+
+		block = append(block, production)
+	}
+
+	f.block(block, layouts)
+
+	if len(footer) > 0 && len(productions) > 0 {
+		f.blank()
+	}
+
+	for _, c := range footer {
+		f.comment(c)
+	}
+
+	return f.buf.Bytes(), nil
+}
+
+// layout holds the comments of a production and if it was preceded by a blank line in the source
+type layout struct {
+	leading  []string
+	trailing string
+	blank    bool
+}
+
+// comment is a comment of the source with its first and last line
+type comment struct {
+	text  string
+	first int
+	last  int
+}
+
+// comments collects the comments of the source. Header comments are the comments before the first production that
+// are separated from it by a blank line, footer comments the comments after the last production
+func comments(filename string, src []byte, productions []*xebnf.Production) ([]string, map[*xebnf.Production]*layout, []string) {
+	var (
+		s       scanner.Scanner
+		header  []string
+		pending []comment
+		layouts = map[*xebnf.Production]*layout{}
+		index   = 0     // index of the next production
+		inBody  = false // scanning the production at index-1
+		endLine = 0     // line of the end of the last production including its trailing comment
+	)
+
+	for _, production := range productions {
+		layouts[production] = &layout{}
+	}
+
+	s.Init(bytes.NewReader(src))
+	s.Filename = filename
+	s.Mode = scanner.GoTokens &^ scanner.SkipComments
+	s.Error = func(*scanner.Scanner, string) {}
+
+	for tok := s.Scan(); tok != scanner.EOF; tok = s.Scan() {
+		pos := s.Position
+
+		if tok == scanner.Comment {
+			c := comment{text: s.TokenText(), first: pos.Line, last: pos.Line + strings.Count(s.TokenText(), "\n")}
+
+			switch {
+			case inBody:
+				// Comments inside a production are printed above it
+				l := layouts[productions[index-1]]
+				l.leading = append(l.leading, c.text)
+			case index > 0 && c.first == endLine && len(pending) == 0 && layouts[productions[index-1]].trailing == "":
+				layouts[productions[index-1]].trailing = c.text
+				endLine = c.last
+			default:
+				pending = append(pending, c)
+			}
+
+			continue
+		}
+
+		if index < len(productions) && pos.Offset == productions[index].Pos().Offset {
+			l := layouts[productions[index]]
+
+			// Comments up to the last blank line before the first production are the header
+			split := 0
+
+			if index == 0 {
+				for i := range pending {
+					if i > 0 && pending[i].first > pending[i-1].last+1 {
+						split = i
+					}
+				}
+
+				if len(pending) > 0 && pos.Line > pending[len(pending)-1].last+1 {
+					split = len(pending)
+				}
+			}
+
+			for i, c := range pending {
+				if i < split {
+					header = append(header, c.text)
+				} else {
+					l.leading = append(l.leading, c.text)
+				}
+			}
+
+			first := pos.Line
+			if split < len(pending) {
+				first = pending[split].first
+			}
+
+			l.blank = index > 0 && first > endLine+1
+
+			pending = nil
+			inBody = true
+			index++
+
+			continue
+		}
+
+		if inBody && tok == '.' {
+			inBody = false
+			endLine = pos.Line
+		}
+	}
+
+	var footer []string
+
+	for _, c := range pending {
+		footer = append(footer, c.text)
+	}
+
+	return header, layouts, footer
+}
+
+// useOrder orders productions by first use from the first production
+func useOrder(grammar xebnf.Grammar, productions []*xebnf.Production) []*xebnf.Production {
+	if len(productions) == 0 {
+		return productions
+	}
+
+	var (
+		ordered []*xebnf.Production
+		seen    = map[*xebnf.Production]bool{}
+		visit   func(p *xebnf.Production)
+	)
+
+	visit = func(p *xebnf.Production) {
+		if seen[p] {
+			return
+		}
+
+		seen[p] = true
+		ordered = append(ordered, p)
+
+		names(p.Expr, func(name *xebnf.Name) {
+			if used, ok := grammar[name.String]; ok {
+				visit(used)
+			}
+		})
+	}
+
+	visit(productions[0])
+
+	for _, p := range productions {
+		if !seen[p] {
+			ordered = append(ordered, p)
+		}
+	}
+
+	return ordered
+}
+
+// names calls f for the names in expr in order of appearance
+func names(expr xebnf.Expression, f func(*xebnf.Name)) {
+	switch x := expr.(type) {
+	case *xebnf.Name:
+		f(x)
+	case xebnf.Alternative:
+		for _, sub := range x {
+			names(sub, f)
+		}
+	case xebnf.Sequence:
+		for _, sub := range x {
+			names(sub, f)
+		}
+	case *xebnf.Group:
+		names(x.Body, f)
+	case *xebnf.Option:
+		names(x.Body, f)
+	case *xebnf.Repetition:
+		names(x.Body, f)
+	}
+}
+
+// formatter writes the formatted grammar
+type formatter struct {
+	buf   bytes.Buffer
+	width int
+}
+
+func (f *formatter) blank() {
+	f.buf.WriteByte('\n')
+}
+
+func (f *formatter) comment(text string) {
+	f.buf.WriteString(text)
+	f.buf.WriteByte('\n')
+}
+
+// block writes consecutive productions with aligned "=" signs
+func (f *formatter) block(block []*xebnf.Production, layouts map[*xebnf.Production]*layout) {
+	width := 0
+	for _, p := range block {
+		width = max(width, utf8.RuneCountInString(p.Name.String))
+	}
+
+	for _, p := range block {
+		name := p.Name.String
+		name += strings.Repeat(" ", width-utf8.RuneCountInString(name))
+		indent := strings.Repeat(" ", width+3)
+
+		line := name + " ="
+		if p.Expr != nil {
+			line += " " + expression(p.Expr)
+		}
+
+		line += " ."
+
+		if alt, ok := p.Expr.(xebnf.Alternative); ok && f.width > 0 && utf8.RuneCountInString(line) > f.width {
+			var sb strings.Builder
+
+			sb.WriteString(name + " = ")
+
+			for i, x := range alt {
+				if i > 0 {
+					sb.WriteString(" |\n" + indent)
+				}
+
+				sb.WriteString(expression(x))
+			}
+
+			sb.WriteString(" .")
+
+			line = sb.String()
+		}
+
+		if trailing := layouts[p].trailing; trailing != "" {
+			line += " " + trailing
+		}
+
+		f.buf.WriteString(line)
+		f.buf.WriteByte('\n')
+	}
+}
+
+// expression returns the canonical text of an expression
+func expression(expr xebnf.Expression) string {
+	switch x := expr.(type) {
+	case *xebnf.Name:
+		return x.String
+	case *xebnf.Token:
+		return strconv.Quote(x.String)
+	case *xebnf.Range:
+		return strconv.Quote(x.Begin.String) + " … " + strconv.Quote(x.End.String)
+	case xebnf.Alternative:
+		return list(x, " | ")
+	case xebnf.Sequence:
+		return list(x, " ")
+	case *xebnf.Group:
+		return "( " + expression(x.Body) + " )"
+	case *xebnf.Option:
+		return "[ " + expression(x.Body) + " ]"
+	case *xebnf.Repetition:
+		return "{ " + expression(x.Body) + " }"
+	}
+
+	return fmt.Sprintf("%v", expr)
+}
+
+func list(exprs []xebnf.Expression, sep string) string {
+	parts := make([]string, len(exprs))
+	for i, x := range exprs {
+		parts[i] = expression(x)
+	}
+
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,208 @@
+package goebnf
+
+import (
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/reference"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	xebnf "golang.org/x/exp/ebnf"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Predefined maps production names that are not defined in the grammar (i.e. unicode_letter in the Go spec) to
+// patterns
+type Predefined[P any] map[string]ebnf.Pattern[rune, P]
+
+// Parse parses an EBNF grammar in golang.org/x/exp/ebnf notation and imports it
+func Parse[P any](filename string, src io.Reader, predefined Predefined[P]) (*ebnf.Grammar[rune, P], error) {
+	grammar, err := xebnf.Parse(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	return Import[P](grammar, predefined)
+}
+
+// Import builds rune patterns from a grammar parsed with golang.org/x/exp/ebnf. Tokens become entities (single rune)
+// or vectors, ranges become entities, alternatives become alternations, sequences become concatenations and
+// options and repetitions become repetitions. Each production is a rule with the production name as ID, rules are
+// ordered by their position in the source
+func Import[P any](grammar xebnf.Grammar, predefined Predefined[P]) (*ebnf.Grammar[rune, P], error) {
+	imp := &importer[P]{
+		grammar:    grammar,
+		predefined: predefined,
+		references: map[string]*reference.Reference[rune, P]{},
+	}
+
+	productions := make([]*xebnf.Production, 0, len(grammar))
+	for _, production := range grammar {
+		productions = append(productions, production)
+	}
+
+	sort.Slice(productions, func(i, j int) bool {
+		return productions[i].Pos().Offset < productions[j].Pos().Offset
+	})
+
+	rules := make([]ebnf.Pattern[rune, P], 0, len(productions))
+
+	for _, production := range productions {
+		rule, err := imp.expression(production.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("%v: production %s: %w", production.Pos(), production.Name.String, err)
+		}
+
+		// A production that only consists of a name would rename the referred pattern, so wrap it
+		if _, isName := production.Expr.(*xebnf.Name); isName {
+			rule = concatenation.New[rune, P](rule)
+		}
+
+		rule.SetID(production.Name.String)
+		imp.reference(production.Name.String).Set(rule)
+		rules = append(rules, rule)
+	}
+
+	return ebnf.NewGrammar[rune, P](rules...), nil
+}
+
+type importer[P any] struct {
+	grammar    xebnf.Grammar
+	predefined Predefined[P]
+	references map[string]*reference.Reference[rune, P]
+}
+
+func (imp *importer[P]) reference(name string) *reference.Reference[rune, P] {
+	ref, ok := imp.references[name]
+	if !ok {
+		ref = reference.New[rune, P](nil)
+		imp.references[name] = ref
+	}
+
+	return ref
+}
+
+func (imp *importer[P]) expression(expr xebnf.Expression) (ebnf.Pattern[rune, P], error) {
+	switch x := expr.(type) {
+	case nil:
+		// Empty production
+		return concatenation.New[rune, P](), nil
+	case *xebnf.Name:
+		if _, ok := imp.grammar[x.String]; ok {
+			return imp.reference(x.String), nil
+		}
+
+		if pattern, ok := imp.predefined[x.String]; ok {
+			return pattern, nil
+		}
+
+		return nil, fmt.Errorf("%v: undefined production %s", x.Pos(), x.String)
+	case *xebnf.Token:
+		return token[P](x.String), nil
+	case *xebnf.Range:
+		return characterRange[P](x.Begin.String, x.End.String)
+	case xebnf.Alternative:
+		return imp.list(x, func(patterns ...ebnf.Pattern[rune, P]) ebnf.Pattern[rune, P] {
+			return alternation.New[rune, P](patterns...)
+		})
+	case xebnf.Sequence:
+		return imp.list(x, func(patterns ...ebnf.Pattern[rune, P]) ebnf.Pattern[rune, P] {
+			return concatenation.New[rune, P](patterns...)
+		})
+	case *xebnf.Group:
+		return imp.expression(x.Body)
+	case *xebnf.Option:
+		body, err := imp.expression(x.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		return repetition.Optional[rune, P](body), nil
+	case *xebnf.Repetition:
+		body, err := imp.expression(x.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		return repetition.Any[rune, P](body), nil
+	case *xebnf.Bad:
+		return nil, fmt.Errorf("%v: %s", x.Pos(), x.Error)
+	}
+
+	return nil, fmt.Errorf("%v: unsupported expression %T", expr.Pos(), expr)
+}
+
+func (imp *importer[P]) list(exprs []xebnf.Expression, f func(...ebnf.Pattern[rune, P]) ebnf.Pattern[rune, P]) (ebnf.Pattern[rune, P], error) {
+	patterns := make([]ebnf.Pattern[rune, P], len(exprs))
+
+	for i, expr := range exprs {
+		pattern, err := imp.expression(expr)
+		if err != nil {
+			return nil, err
+		}
+
+		patterns[i] = pattern
+	}
+
+	return f(patterns...), nil
+}
+
+func runeEq(r1 rune, r2 rune) bool {
+	return r1 == r2
+}
+
+// token creates an entity for a single rune token and a vector for longer tokens
+func token[P any](str string) ebnf.Pattern[rune, P] {
+	rs := []rune(str)
+
+	if len(rs) == 1 {
+		r := rs[0]
+		return entity.New[rune, P](func(c rune) bool {
+			return c == r
+		}).SetGenerateFunc(func() rune {
+			return r
+		}).SetPrintOutput(strconv.Quote(str))
+	}
+
+	return vector.New[rune, P](runeEq, rs...).SetPrintOutput(strconv.Quote(str))
+}
+
+// characterRange creates an entity for a "a" … "z" range
+func characterRange[P any](begin string, end string) (ebnf.Pattern[rune, P], error) {
+	b := []rune(begin)
+	e := []rune(end)
+
+	if len(b) != 1 || len(e) != 1 {
+		return nil, fmt.Errorf("range bounds must be single characters: %q … %q", begin, end)
+	}
+
+	low, high := b[0], e[0]
+
+	if low > high {
+		return nil, fmt.Errorf("invalid range %q … %q", begin, end)
+	}
+
+	return entity.New[rune, P](func(c rune) bool {
+		return c >= low && c <= high
+	}).SetRandGenerateFunc(func(rnd *rand.Rand) rune {
+		return low + rnd.Int31n(high-low+1)
+	}).SetPrintOutput(fmt.Sprintf("[%s-%s]", escapeClassRune(low), escapeClassRune(high))), nil
+}
+
+// escapeClassRune escapes runes that have a special meaning in a character class
+func escapeClassRune(r rune) string {
+	switch r {
+	case ']', '[', '\\', '-', '^':
+		return `\` + string(r)
+	}
+
+	s := strconv.QuoteRune(r)
+
+	return strings.TrimSuffix(strings.TrimPrefix(s, "'"), "'")
+}
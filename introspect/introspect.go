@@ -0,0 +1,237 @@
+package introspect
+
+import (
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/end"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/exception"
+	"github.com/almerlucke/exbana/v2/patterns/hint"
+	"github.com/almerlucke/exbana/v2/patterns/lexeme"
+	"github.com/almerlucke/exbana/v2/patterns/padding"
+	"github.com/almerlucke/exbana/v2/patterns/precedence"
+	"github.com/almerlucke/exbana/v2/patterns/reference"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/tlv"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	"strconv"
+	"strings"
+)
+
+// Node kinds
+const (
+	KindAlternation   = "alternation"
+	KindConcatenation = "concatenation"
+	KindEnd           = "end"
+	KindEntity        = "entity"
+	KindException     = "exception"
+	KindReference     = "reference"
+	KindRepetition    = "repetition"
+	KindVector        = "vector"
+)
+
+// Node is a structural description of a pattern tree which can be compared, printed and serialized
+type Node struct {
+	Kind     string  `json:"kind"`
+	ID       string  `json:"id,omitempty"`
+	Label    string  `json:"label,omitempty"`
+	Children []*Node `json:"children,omitempty"`
+}
+
+// Equal checks if two node trees are structurally equal
+func (n *Node) Equal(o *Node) bool {
+	if n == nil || o == nil {
+		return n == o
+	}
+
+	if n.Kind != o.Kind || n.ID != o.ID || n.Label != o.Label || len(n.Children) != len(o.Children) {
+		return false
+	}
+
+	for i, child := range n.Children {
+		if !child.Equal(o.Children[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String returns a compact single line representation of the node tree
+func (n *Node) String() string {
+	var sb strings.Builder
+
+	n.write(&sb)
+
+	return sb.String()
+}
+
+func (n *Node) write(sb *strings.Builder) {
+	sb.WriteString(n.Kind)
+
+	if n.ID != "" {
+		sb.WriteString("#" + n.ID)
+	}
+
+	if n.Label != "" {
+		sb.WriteString(" " + n.Label)
+	}
+
+	if len(n.Children) > 0 {
+		sb.WriteString("(")
+		for i, child := range n.Children {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			child.write(sb)
+		}
+		sb.WriteString(")")
+	}
+}
+
+// Children returns the sub patterns of the known pattern types
+func Children[T, P any](p ebnf.Pattern[T, P]) ebnf.Patterns[T, P] {
+	switch pt := p.(type) {
+	case *alternation.Alternation[T, P]:
+		return pt.Patterns()
+	case *concatenation.Concatenation[T, P]:
+		return pt.Patterns()
+	case *repetition.Repetition[T, P]:
+		return ebnf.Patterns[T, P]{pt.Pattern()}
+	case *exception.Exception[T, P]:
+		return ebnf.Patterns[T, P]{pt.Must(), pt.Exception()}
+	case *reference.Reference[T, P]:
+		if pt.Pattern() != nil {
+			return ebnf.Patterns[T, P]{pt.Pattern()}
+		}
+	case *hint.Hint[T, P]:
+		return ebnf.Patterns[T, P]{pt.Pattern()}
+	case *lexeme.Lexeme[T, P]:
+		if pt.Skip() != nil {
+			return ebnf.Patterns[T, P]{pt.Pattern(), pt.Skip()}
+		}
+
+		return ebnf.Patterns[T, P]{pt.Pattern()}
+	case *padding.Padding[T, P]:
+		return ebnf.Patterns[T, P]{pt.Pad()}
+	case *tlv.TLV[T, P]:
+		return append(ebnf.Patterns[T, P]{pt.Tag(), pt.Length()}, pt.Values()...)
+	case *precedence.Precedence[T, P]:
+		children := ebnf.Patterns[T, P]{pt.Operand()}
+
+		for _, op := range pt.Operators() {
+			children = append(children, op.Pattern)
+		}
+
+		return children
+	}
+
+	return nil
+}
+
+// Describe creates a node tree for a rule of a grammar, sub patterns that are rules of the grammar are described as
+// references to the rule. The grammar can be nil, in that case only references are described by ID
+func Describe[T, P any](rule ebnf.Pattern[T, P], g *ebnf.Grammar[T, P]) *Node {
+	d := &describer[T, P]{
+		grammar:  g,
+		visiting: map[ebnf.Pattern[T, P]]bool{},
+	}
+
+	return d.describe(rule, true)
+}
+
+type describer[T, P any] struct {
+	grammar  *ebnf.Grammar[T, P]
+	visiting map[ebnf.Pattern[T, P]]bool
+}
+
+func (d *describer[T, P]) isRule(p ebnf.Pattern[T, P]) bool {
+	return d.grammar != nil && d.grammar.IsRule(p)
+}
+
+func (d *describer[T, P]) describe(p ebnf.Pattern[T, P], root bool) *Node {
+	if !root && d.isRule(p) {
+		return &Node{Kind: KindReference, Label: p.ID()}
+	}
+
+	// Anonymous recursion
+	if d.visiting[p] {
+		return &Node{Kind: KindReference, Label: p.ID()}
+	}
+
+	d.visiting[p] = true
+	defer delete(d.visiting, p)
+
+	node := &Node{}
+
+	if !root {
+		node.ID = p.ID()
+	}
+
+	switch pt := p.(type) {
+	case *alternation.Alternation[T, P]:
+		node.Kind = KindAlternation
+		if pt.IsOrthogonal() {
+			node.Label = "orthogonal"
+		}
+	case *concatenation.Concatenation[T, P]:
+		node.Kind = KindConcatenation
+	case *repetition.Repetition[T, P]:
+		node.Kind = KindRepetition
+		node.Label = fmt.Sprintf("{%d,%d}", pt.Min(), pt.Max())
+	case *exception.Exception[T, P]:
+		node.Kind = KindException
+	case *entity.Entity[T, P]:
+		node.Kind = KindEntity
+		node.Label = pt.PrintOutput()
+	case *vector.Vector[T, P]:
+		node.Kind = KindVector
+		node.Label = Literal(pt.Vector())
+	case *end.End[T, P]:
+		node.Kind = KindEnd
+	case *reference.Reference[T, P]:
+		if pt.Pattern() == nil {
+			return &Node{Kind: KindReference}
+		}
+
+		// References are transparent
+		return d.describe(pt.Pattern(), false)
+	case *hint.Hint[T, P]:
+		// Hints are transparent
+		return d.describe(pt.Pattern(), false)
+	case *lexeme.Lexeme[T, P]:
+		// Lexemes are transparent, trivia is not part of the grammar description
+		return d.describe(pt.Pattern(), false)
+	default:
+		node.Kind = fmt.Sprintf("%T", p)
+		node.Label = p.PrintOutput()
+	}
+
+	for _, child := range Children(p) {
+		node.Children = append(node.Children, d.describe(child, false))
+	}
+
+	return node
+}
+
+// Literal returns a quoted string for a vector of runes, bytes or strings, other types are formatted with %v
+func Literal[T any](vec []T) string {
+	var sb strings.Builder
+
+	for _, e := range vec {
+		switch v := any(e).(type) {
+		case rune:
+			sb.WriteRune(v)
+		case byte:
+			sb.WriteByte(v)
+		case string:
+			sb.WriteString(v)
+		default:
+			return fmt.Sprintf("%v", vec)
+		}
+	}
+
+	return strconv.Quote(sb.String())
+}
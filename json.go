@@ -0,0 +1,78 @@
+package exbana
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// JSONMatch is the JSON representation of a match tree
+type JSONMatch[P any] struct {
+	ID          string          `json:"id,omitempty"`
+	Begin       P               `json:"begin"`
+	End         P               `json:"end"`
+	Value       string          `json:"value,omitempty"`
+	Components  []*JSONMatch[P] `json:"components,omitempty"`
+	Annotations map[string]any  `json:"annotations,omitempty"`
+}
+
+// JSON converts the match tree to its JSON representation, value returns the string for the value of a match, if
+// value is nil DefaultValueString is used
+func (m *Match[T, P]) JSON(value func(*Match[T, P]) string) *JSONMatch[P] {
+	if value == nil {
+		value = DefaultValueString[T, P]
+	}
+
+	j := &JSONMatch[P]{
+		ID:    m.ID(),
+		Begin: m.Begin,
+		End:   m.End,
+		Value: value(m),
+	}
+
+	if len(m.annos) > 0 {
+		j.Annotations = map[string]any{}
+
+		for key, anno := range m.annos {
+			j.Annotations[key] = anno
+		}
+	}
+
+	for _, component := range m.Components {
+		if component != nil {
+			j.Components = append(j.Components, component.JSON(value))
+		}
+	}
+
+	return j
+}
+
+// MarshalJSON marshals the match tree with the default value strings
+func (m *Match[T, P]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.JSON(nil))
+}
+
+// ToJSON marshals the match tree with a custom value string function
+func ToJSON[T, P any](m *Match[T, P], value func(*Match[T, P]) string) ([]byte, error) {
+	return json.Marshal(m.JSON(value))
+}
+
+// DefaultValueString returns rune, byte and string values as string, other values are formatted with %v, empty
+// values give an empty string
+func DefaultValueString[T, P any](m *Match[T, P]) string {
+	switch v := m.Value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []rune:
+		return string(v)
+	case []byte:
+		return string(v)
+	case []T:
+		if len(v) == 0 {
+			return ""
+		}
+	}
+
+	return fmt.Sprint(m.Value)
+}
@@ -0,0 +1,216 @@
+package lexer
+
+import (
+	"errors"
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/readers/tokens"
+	"io"
+)
+
+// ErrNoToken is returned when none of the token patterns matches the source at the current position
+var ErrNoToken = errors.New("no token matches")
+
+// Lexer is a token source which matches tokens from a source reader, use NewReader or tokens.New to read the tokens
+// with a token reader, so parsers can be written in two phases: token patterns over the source and grammar patterns
+// over the tokens. Tokens are matched lazily, the source is only read as far as the parser looks ahead
+type Lexer[T, P any] struct {
+	src      ebnf.Reader[T, P]
+	patterns ebnf.Patterns[T, P]
+	skip     ebnf.Pattern[T, P]
+	trivia   ebnf.Patterns[T, P]
+	trailing []tokens.Token[T, P]
+	keywords KeywordTable
+	ident    string
+}
+
+// KeywordTable maps keywords to their token kind
+type KeywordTable map[string]string
+
+// NewKeywordTable creates a keyword table where all words have kind, if kind is empty the kind of a keyword is the
+// keyword itself
+func NewKeywordTable(kind string, words ...string) KeywordTable {
+	table := KeywordTable{}
+
+	for _, word := range words {
+		if kind == "" {
+			table[word] = word
+		} else {
+			table[word] = kind
+		}
+	}
+
+	return table
+}
+
+// New creates a lexer over src. At each position the skip pattern (i.e. whitespace and comments) is skipped, after
+// that the token pattern with the longest match wins, a tie goes to the pattern that comes first. The kind of a token
+// is the ID of its pattern. Skip may be nil
+func New[T, P any](src ebnf.Reader[T, P], skip ebnf.Pattern[T, P], patterns ...ebnf.Pattern[T, P]) *Lexer[T, P] {
+	return &Lexer[T, P]{
+		src:      src,
+		patterns: patterns,
+		skip:     skip,
+	}
+}
+
+// SetTrivia sets the trivia patterns (i.e. whitespace and comments), trivia is not discarded like the input matched by
+// the skip pattern but kept on a side channel: the trivia before a token is attached to the token as Trivia, the
+// trivia after the last token is returned by Trailing. The kind of a trivia token is the ID of its pattern, the
+// longest match wins as for tokens. Trivia patterns are matched after the skip pattern, so the skip pattern should
+// not match trivia that has to be kept
+func (l *Lexer[T, P]) SetTrivia(patterns ...ebnf.Pattern[T, P]) *Lexer[T, P] {
+	l.trivia = patterns
+	return l
+}
+
+// SetKeywords sets a keyword table for identifiers: a token of kind identifier whose text is in the table gets the
+// kind of the keyword. This is faster than matching keywords with patterns and needs no exception to keep keywords
+// from matching as prefix of an identifier. Keywords are looked up for rune and byte tokens only
+func (l *Lexer[T, P]) SetKeywords(identifier string, table KeywordTable) *Lexer[T, P] {
+	l.ident = identifier
+	l.keywords = table
+
+	return l
+}
+
+// NewReader creates a token reader over a new lexer
+func NewReader[T, P any](src ebnf.Reader[T, P], skip ebnf.Pattern[T, P], patterns ...ebnf.Pattern[T, P]) *tokens.Reader[T, P] {
+	return tokens.New[T, P](New(src, skip, patterns...))
+}
+
+// Position returns the source position
+func (l *Lexer[T, P]) Position() (P, error) {
+	return l.src.Position()
+}
+
+// Next matches the next token from the source, io.EOF is returned if only trivia is left
+func (l *Lexer[T, P]) Next() (tokens.Token[T, P], error) {
+	var trivia []tokens.Token[T, P]
+
+	for {
+		err := ebnf.SkipTrivia(l.skip, l.src)
+		if ebnf.IsStreamError(err) {
+			return tokens.Token[T, P]{}, err
+		}
+
+		if l.src.Finished() {
+			l.trailing = trivia
+			return tokens.Token[T, P]{}, io.EOF
+		}
+
+		tok, ok, err := l.longest(l.trivia)
+		if err != nil {
+			return tok, err
+		}
+
+		if !ok {
+			break
+		}
+
+		trivia = append(trivia, tok)
+	}
+
+	tok, ok, err := l.longest(l.patterns)
+	if err != nil {
+		return tok, err
+	}
+
+	if !ok {
+		pos, err := l.src.Position()
+		if ebnf.IsStreamError(err) {
+			return tok, err
+		}
+
+		return tok, fmt.Errorf("%w at %v", ErrNoToken, pos)
+	}
+
+	tok.Trivia = trivia
+
+	if tok.Kind == l.ident && l.keywords != nil {
+		if kind, ok := l.keywords[text(tok.Value)]; ok {
+			tok.Kind = kind
+		}
+	}
+
+	return tok, nil
+}
+
+// text returns the text of rune and byte values
+func text[T any](value []T) string {
+	switch v := any(value).(type) {
+	case []rune:
+		return string(v)
+	case []byte:
+		return string(v)
+	}
+
+	return ""
+}
+
+// Trailing returns the trivia after the last token, it is set when Next returns io.EOF
+func (l *Lexer[T, P]) Trailing() []tokens.Token[T, P] {
+	return l.trailing
+}
+
+// longest matches the pattern with the longest match at the current position, ties go to the pattern that comes
+// first. The source is positioned after the match, false is returned if no pattern matches
+func (l *Lexer[T, P]) longest(patterns ebnf.Patterns[T, P]) (tokens.Token[T, P], bool, error) {
+	var tok tokens.Token[T, P]
+
+	if len(patterns) == 0 {
+		return tok, false, nil
+	}
+
+	begin, err := ebnf.NewMarker(l.src)
+	if ebnf.IsStreamError(err) {
+		return tok, false, err
+	}
+
+	defer begin.Discard()
+
+	var (
+		best   ebnf.Pattern[T, P]
+		end    P
+		length int
+	)
+
+	for _, pattern := range patterns {
+		matched, err := ebnf.Matches(pattern, l.src)
+		if err != nil {
+			return tok, false, err
+		}
+
+		if matched {
+			pos, err := l.src.Position()
+			if ebnf.IsStreamError(err) {
+				return tok, false, err
+			}
+
+			if n := l.src.Length(begin.Pos(), pos); n > length {
+				best, end, length = pattern, pos, n
+			}
+		}
+
+		err = begin.Reset()
+		if err != nil {
+			return tok, false, err
+		}
+	}
+
+	if best == nil {
+		return tok, false, nil
+	}
+
+	value, err := l.src.Range(begin.Pos(), end)
+	if err != nil {
+		return tok, false, err
+	}
+
+	err = l.src.SetPosition(end)
+	if err != nil {
+		return tok, false, err
+	}
+
+	return tokens.Token[T, P]{Kind: best.ID(), Value: value, Begin: begin.Pos(), End: end}, true, nil
+}
@@ -0,0 +1,101 @@
+package exbana
+
+import "io"
+
+// WithLimit returns a reader middleware on top of r which serves at most n objects from the current position of r,
+// after that the reader is finished and reads return io.EOF. This confines a sub pattern to a length prefixed part of
+// the input, i.e. the value of a type-length-value record
+func WithLimit[T, P any](r Reader[T, P], n int) (Reader[T, P], error) {
+	begin, err := r.Position()
+	if IsStreamError(err) {
+		return nil, err
+	}
+
+	return &withLimit[T, P]{Reader: r, begin: begin, n: n}, nil
+}
+
+// withLimit is a reader middleware that limits the number of objects served
+type withLimit[T, P any] struct {
+	Reader[T, P]
+	begin P
+	n     int
+}
+
+// remaining returns the number of objects that can still be served
+func (l *withLimit[T, P]) remaining() int {
+	pos, err := l.Reader.Position()
+	if IsStreamError(err) {
+		return 0
+	}
+
+	return l.n - l.Reader.Length(l.begin, pos)
+}
+
+func (l *withLimit[T, P]) Peek1() (T, error) {
+	if l.remaining() <= 0 {
+		var zero T
+		return zero, io.EOF
+	}
+
+	return l.Reader.Peek1()
+}
+
+func (l *withLimit[T, P]) Read1() (T, error) {
+	if l.remaining() <= 0 {
+		var zero T
+		return zero, io.EOF
+	}
+
+	return l.Reader.Read1()
+}
+
+func (l *withLimit[T, P]) Peek(n int, buf []T) (int, error) {
+	if rem := l.remaining(); n > rem {
+		i, err := l.Reader.Peek(max(rem, 0), buf)
+		if err == nil {
+			err = io.EOF
+		}
+
+		return i, err
+	}
+
+	return l.Reader.Peek(n, buf)
+}
+
+func (l *withLimit[T, P]) Read(n int, buf []T) (int, error) {
+	if rem := l.remaining(); n > rem {
+		i, err := l.Reader.Read(max(rem, 0), buf)
+		if err == nil {
+			err = io.EOF
+		}
+
+		return i, err
+	}
+
+	return l.Reader.Read(n, buf)
+}
+
+func (l *withLimit[T, P]) Skip(n int) (int, error) {
+	if rem := l.remaining(); n > rem {
+		i, err := l.Reader.Skip(max(rem, 0))
+		if err == nil {
+			err = io.EOF
+		}
+
+		return i, err
+	}
+
+	return l.Reader.Skip(n)
+}
+
+func (l *withLimit[T, P]) Finished() bool {
+	return l.remaining() <= 0 || l.Reader.Finished()
+}
+
+func (l *withLimit[T, P]) Unwrap() Reader[T, P] {
+	return l.Reader
+}
+
+func (l *withLimit[T, P]) MatchPattern(pattern Pattern[T, P], r Reader[T, P]) (bool, *Match[T, P], error) {
+	return MatchNext(pattern, l.Reader, r)
+}
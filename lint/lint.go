@@ -0,0 +1,326 @@
+package lint
+
+import (
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/introspect"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/exception"
+	"github.com/almerlucke/exbana/v2/patterns/precedence"
+	"github.com/almerlucke/exbana/v2/patterns/reference"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	"strings"
+)
+
+// Kind is the kind of an issue
+type Kind string
+
+const (
+	// UndefinedRule is a reference that was never set
+	UndefinedRule Kind = "undefined-rule"
+	// LeftRecursion is a rule that can reach itself without reading, matching it never terminates
+	LeftRecursion Kind = "left-recursion"
+	// EmptyLoop is an unbounded repetition of a pattern that can match without reading
+	EmptyLoop Kind = "empty-loop"
+	// ShadowedBranch is an alternative that can never be chosen because an earlier alternative always matches first
+	ShadowedBranch Kind = "shadowed-branch"
+)
+
+// Issue is a problem found in a rule of a grammar
+type Issue struct {
+	Kind    Kind   `json:"kind"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Rule, i.Kind, i.Message)
+}
+
+// Check checks all rules of a grammar for undefined references, left recursion, empty loops and shadowed branches of
+// alternations. The issues are ordered by rule. The analysis is conservative, it only reports what it can prove for
+// the known pattern types
+func Check[T, P any](g *ebnf.Grammar[T, P]) []Issue {
+	c := &checker[T, P]{
+		g:        g,
+		nullable: map[ebnf.Pattern[T, P]]bool{},
+		visiting: map[ebnf.Pattern[T, P]]bool{},
+	}
+
+	var issues []Issue
+
+	for _, rule := range g.Rules() {
+		c.rule = rule.ID()
+		c.issues = nil
+
+		c.leftRecursion(rule)
+		c.walk(rule, map[ebnf.Pattern[T, P]]bool{}, true)
+
+		issues = append(issues, c.issues...)
+	}
+
+	return issues
+}
+
+// checker holds the state of a check
+type checker[T, P any] struct {
+	g        *ebnf.Grammar[T, P]
+	rule     string
+	issues   []Issue
+	nullable map[ebnf.Pattern[T, P]]bool
+	visiting map[ebnf.Pattern[T, P]]bool
+}
+
+func (c *checker[T, P]) report(kind Kind, format string, args ...any) {
+	c.issues = append(c.issues, Issue{Kind: kind, Rule: c.rule, Message: fmt.Sprintf(format, args...)})
+}
+
+// walk checks the patterns of a rule body, other rules are not entered
+func (c *checker[T, P]) walk(p ebnf.Pattern[T, P], seen map[ebnf.Pattern[T, P]]bool, root bool) {
+	if seen[p] || (!root && c.g.IsRule(p)) {
+		return
+	}
+
+	seen[p] = true
+
+	switch pt := p.(type) {
+	case *reference.Reference[T, P]:
+		if pt.Pattern() == nil {
+			c.report(UndefinedRule, "reference to an undefined rule")
+		}
+	case *repetition.Repetition[T, P]:
+		if pt.Max() == 0 && c.isNullable(pt.Pattern()) {
+			c.report(EmptyLoop, "repeated pattern %s can match empty input", c.describe(pt.Pattern()))
+		}
+	case *alternation.Alternation[T, P]:
+		c.shadowed(pt)
+	}
+
+	for _, child := range introspect.Children(p) {
+		c.walk(child, seen, false)
+	}
+}
+
+// shadowed reports alternatives that can not be chosen. An orthogonal alternation stops at the first alternative that
+// matches, otherwise the longest match wins and a tie goes to the alternative that comes first
+func (c *checker[T, P]) shadowed(alt *alternation.Alternation[T, P]) {
+	alternatives := alt.Patterns()
+	first := alt.IsOrthogonal()
+
+	for i, a := range alternatives {
+		if first && c.isNullable(a) {
+			switch rest := len(alternatives) - i - 1; {
+			case rest == 1:
+				c.report(ShadowedBranch, "alternative %d (%s) can match empty input, alternative %d is never chosen",
+					i+1, c.describe(a), i+2)
+			case rest > 1:
+				c.report(ShadowedBranch, "alternative %d (%s) can match empty input, alternatives %d to %d are never chosen",
+					i+1, c.describe(a), i+2, len(alternatives))
+			}
+
+			return
+		}
+
+		for j := i + 1; j < len(alternatives); j++ {
+			if c.shadows(a, alternatives[j], first) {
+				c.report(ShadowedBranch, "alternative %d (%s) is never chosen, alternative %d (%s) matches first",
+					j+1, c.describe(alternatives[j]), i+1, c.describe(a))
+			}
+		}
+	}
+}
+
+// shadows reports if a matches whenever b matches, at least as long as b if first is false, so b is never chosen if
+// a comes first
+func (c *checker[T, P]) shadows(a ebnf.Pattern[T, P], b ebnf.Pattern[T, P], first bool) bool {
+	a, b = resolve(a), resolve(b)
+
+	if a == b || introspect.Describe(a, c.g).Equal(introspect.Describe(b, c.g)) {
+		return true
+	}
+
+	vb, ok := b.(*vector.Vector[T, P])
+	if !ok || len(vb.Vector()) == 0 {
+		return false
+	}
+
+	objs := vb.Vector()
+
+	switch pa := a.(type) {
+	case *entity.Entity[T, P]:
+		// A single object that accepts the first object of b
+		return (first || len(objs) == 1) && pa.MatchFunc()(objs[0])
+	case *vector.Vector[T, P]:
+		// A prefix of b
+		prefix := pa.Vector()
+		if len(prefix) > len(objs) || (!first && len(prefix) != len(objs)) {
+			return false
+		}
+
+		for i, obj := range prefix {
+			if !pa.Equal()(obj, objs[i]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// leftRecursion reports if the rule can reach itself without reading
+func (c *checker[T, P]) leftRecursion(rule ebnf.Pattern[T, P]) {
+	seen := map[ebnf.Pattern[T, P]]bool{}
+
+	var visit func(p ebnf.Pattern[T, P], path []string) bool
+
+	visit = func(p ebnf.Pattern[T, P], path []string) bool {
+		if p == nil {
+			return false
+		}
+
+		if p == rule && len(path) > 0 {
+			c.report(LeftRecursion, "%s", strings.Join(append(path, rule.ID()), " -> "))
+			return true
+		}
+
+		if seen[p] {
+			return false
+		}
+
+		seen[p] = true
+
+		if c.g.IsRule(p) {
+			path = append(path, p.ID())
+		}
+
+		for _, child := range c.leftmost(p) {
+			if visit(child, path) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	visit(rule, nil)
+}
+
+// leftmost returns the sub patterns that can be matched at the start of a match of p
+func (c *checker[T, P]) leftmost(p ebnf.Pattern[T, P]) ebnf.Patterns[T, P] {
+	switch pt := p.(type) {
+	case *concatenation.Concatenation[T, P]:
+		var first ebnf.Patterns[T, P]
+
+		for _, child := range pt.Patterns() {
+			first = append(first, child)
+
+			if !c.isNullable(child) {
+				break
+			}
+		}
+
+		return first
+	case *exception.Exception[T, P]:
+		return ebnf.Patterns[T, P]{pt.Must()}
+	case *precedence.Precedence[T, P]:
+		return ebnf.Patterns[T, P]{pt.Operand()}
+	}
+
+	return introspect.Children(p)
+}
+
+// isNullable reports if p can match without reading, patterns of unknown type are not nullable
+func (c *checker[T, P]) isNullable(p ebnf.Pattern[T, P]) bool {
+	if n, ok := c.nullable[p]; ok {
+		return n
+	}
+
+	// Recursion through a pattern being analyzed
+	if c.visiting[p] {
+		return false
+	}
+
+	c.visiting[p] = true
+	defer delete(c.visiting, p)
+
+	n := false
+
+	switch pt := p.(type) {
+	case *vector.Vector[T, P]:
+		n = len(pt.Vector()) == 0
+	case *concatenation.Concatenation[T, P]:
+		n = true
+
+		for _, child := range pt.Patterns() {
+			if !c.isNullable(child) {
+				n = false
+				break
+			}
+		}
+	case *alternation.Alternation[T, P]:
+		for _, child := range pt.Patterns() {
+			if c.isNullable(child) {
+				n = true
+				break
+			}
+		}
+	case *repetition.Repetition[T, P]:
+		n = pt.Min() == 0 || c.isNullable(pt.Pattern())
+	case *exception.Exception[T, P]:
+		n = c.isNullable(pt.Must())
+	case *reference.Reference[T, P]:
+		n = pt.Pattern() != nil && c.isNullable(pt.Pattern())
+	case *entity.Entity[T, P], *precedence.Precedence[T, P]:
+		n = false
+	default:
+		// Transparent wrappers (i.e. hints and lexemes) are nullable if their pattern is
+		children := introspect.Children(p)
+		n = len(children) > 0 && c.isNullable(children[0])
+	}
+
+	c.nullable[p] = n
+
+	return n
+}
+
+// resolve follows references and concatenations of a single pattern
+func resolve[T, P any](p ebnf.Pattern[T, P]) ebnf.Pattern[T, P] {
+	for i := 0; i < 100; i++ {
+		switch pt := p.(type) {
+		case *reference.Reference[T, P]:
+			if pt.Pattern() == nil {
+				return p
+			}
+
+			p = pt.Pattern()
+		case *concatenation.Concatenation[T, P]:
+			if len(pt.Patterns()) != 1 {
+				return p
+			}
+
+			p = pt.Patterns()[0]
+		default:
+			return p
+		}
+	}
+
+	return p
+}
+
+// describe returns the EBNF of a pattern as child, or the introspection of the pattern if it prints without content
+func (c *checker[T, P]) describe(p ebnf.Pattern[T, P]) string {
+	var sb strings.Builder
+
+	_ = p.PrintAsChild(&sb)
+
+	if strings.Trim(sb.String(), "()[]{}?*+|, ") == "" {
+		return introspect.Describe(p, c.g).String()
+	}
+
+	return sb.String()
+}
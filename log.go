@@ -14,14 +14,107 @@ func (l *VoidLog[T, P]) LogMismatch(_ *Mismatch[T, P]) {
 	/* void */
 }
 
+// StackLog records mismatches in a list. By default the list grows unbounded, options limit it to the interesting
+// entries: a cap, deduplication of failures of the same pattern at the same position and keeping only the entries
+// with the deepest end positions
 type StackLog[T, P any] struct {
 	Stack []*Mismatch[T, P]
+	// Dropped is the number of mismatches that were not recorded due to the options
+	Dropped int
+	max     int
+	seen    map[stackKey[T, P]]bool
+	deepest int
+	cmp     CompareFunc[P]
+}
+
+// stackKey identifies a failure by pattern and begin position
+type stackKey[T, P any] struct {
+	pattern Pattern[T, P]
+	pos     any
 }
 
 func NewStackLog[T, P any]() *StackLog[T, P] {
 	return &StackLog[T, P]{}
 }
 
+// SetMax caps the stack size, mismatches logged when the stack is full are dropped. 0 means no cap
+func (s *StackLog[T, P]) SetMax(max int) *StackLog[T, P] {
+	s.max = max
+	return s
+}
+
+// SetDedup turns deduplication of failures of the same pattern at the same begin position on or off, the position
+// type must be comparable
+func (s *StackLog[T, P]) SetDedup(dedup bool) *StackLog[T, P] {
+	if dedup {
+		s.seen = map[stackKey[T, P]]bool{}
+	} else {
+		s.seen = nil
+	}
+
+	return s
+}
+
+// SetDeepest keeps only the n entries with the deepest end positions, a shallower entry is evicted when a deeper
+// mismatch is logged. 0 keeps all entries
+func (s *StackLog[T, P]) SetDeepest(n int, cmp CompareFunc[P]) *StackLog[T, P] {
+	s.deepest = n
+	s.cmp = cmp
+
+	return s
+}
+
 func (s *StackLog[T, P]) LogMismatch(m *Mismatch[T, P]) {
+	if s.seen != nil {
+		key := stackKey[T, P]{pattern: m.Pattern, pos: m.Begin}
+		if s.seen[key] {
+			s.Dropped++
+			return
+		}
+
+		s.seen[key] = true
+	}
+
+	if s.deepest > 0 && len(s.Stack) >= s.deepest {
+		shallowest := 0
+
+		for i, e := range s.Stack {
+			if s.cmp(e.End, s.Stack[shallowest].End) < 0 {
+				shallowest = i
+			}
+		}
+
+		if s.cmp(m.End, s.Stack[shallowest].End) <= 0 {
+			s.Dropped++
+			return
+		}
+
+		s.Stack = append(s.Stack[:shallowest], s.Stack[shallowest+1:]...)
+		s.Dropped++
+	}
+
+	if s.max > 0 && len(s.Stack) >= s.max {
+		s.Dropped++
+		return
+	}
+
 	s.Stack = append(s.Stack, m)
 }
+
+// Reset clears the stack
+func (s *StackLog[T, P]) Reset() {
+	s.Stack = nil
+	s.Dropped = 0
+
+	if s.seen != nil {
+		s.seen = map[stackKey[T, P]]bool{}
+	}
+}
+
+// Tracer receives structured match events for every (sub) pattern matched through a tracing reader (see
+// readers/trace), depth is the nesting depth of the pattern, pos is the position the match started at
+type Tracer[T, P any] interface {
+	OnEnter(pattern Pattern[T, P], depth int, pos P)
+	OnSuccess(pattern Pattern[T, P], depth int, pos P, end P)
+	OnFail(pattern Pattern[T, P], depth int, pos P, err error)
+}
@@ -0,0 +1,56 @@
+package exbana
+
+// Lowering tells a parent pattern how to add the match of a sub pattern to its components, this yields lean match
+// trees without post-processing passes. The root match of a parse is never lowered
+type Lowering int
+
+const (
+	// LowerNone adds the match as is
+	LowerNone Lowering = iota
+	// LowerToken adds the match with the matched objects as value and without components
+	LowerToken
+	// LowerList adds the components of the match instead of the match itself
+	LowerList
+	// LowerDiscard does not add the match
+	LowerDiscard
+)
+
+// Lower appends the match of a sub pattern to components according to the lowering of its pattern
+func Lower[T, P any](components []*Match[T, P], m *Match[T, P], r Reader[T, P]) ([]*Match[T, P], error) {
+	if m == nil {
+		return components, nil
+	}
+
+	switch m.Pattern.Lowering() {
+	case LowerToken:
+		var val []T
+
+		if IsCapturing(r) {
+			objs, err := m.Objects(r)
+			if err != nil {
+				return nil, err
+			}
+
+			val = objs
+		}
+
+		return append(components, NewMatch(m.Pattern, m.Begin, m.End, val, nil)), nil
+	case LowerList:
+		return append(components, m.Components...), nil
+	case LowerDiscard:
+		return components, nil
+	}
+
+	return append(components, m), nil
+}
+
+// MatchComponent matches a sub pattern of a concatenation or repetition, if the pattern does not capture it is only
+// validated and the returned match is nil
+func MatchComponent[T, P any](pattern Pattern[T, P], r Reader[T, P]) (bool, *Match[T, P], error) {
+	if !pattern.Captures() {
+		matched, err := Matches(pattern, r)
+		return matched, nil, err
+	}
+
+	return MatchPattern(pattern, r)
+}
@@ -1,5 +1,7 @@
 package exbana
 
+import "maps"
+
 // Match contains matched pattern, position, optional value and optional components
 type Match[T, P any] struct {
 	Pattern    Pattern[T, P]
@@ -7,6 +9,8 @@ type Match[T, P any] struct {
 	End        P
 	Value      any
 	Components []*Match[T, P]
+	indices    []int
+	annos      map[string]any
 }
 
 // NewMatch creates a new pattern match result
@@ -54,6 +58,170 @@ func (m *Match[T, P]) ID() string {
 	return m.Pattern.ID()
 }
 
+// Eval evaluates the match with the eval function of its pattern, if a reader in the middleware chain of r implements
+// Evaluator the evaluation is delegated to that reader
 func (m *Match[T, P]) Eval(r Reader[T, P]) (any, error) {
+	if e, ok := Lookup[Evaluator[T, P]](r); ok {
+		return e.EvalMatch(m, r)
+	}
+
 	return m.Pattern.Eval(m, r)
 }
+
+// Walk visits the match and its components in pre-order, depth is 0 for the match itself. If fn returns false the
+// components of the visited match are skipped
+func (m *Match[T, P]) Walk(fn func(*Match[T, P], int) bool) {
+	m.walk(fn, 0)
+}
+
+func (m *Match[T, P]) walk(fn func(*Match[T, P], int) bool, depth int) {
+	if !fn(m, depth) {
+		return
+	}
+
+	for _, component := range m.Components {
+		if component != nil {
+			component.walk(fn, depth+1)
+		}
+	}
+}
+
+// WalkPost visits the components of the match before the match itself (post-order), depth is 0 for the match itself
+func (m *Match[T, P]) WalkPost(fn func(*Match[T, P], int)) {
+	m.walkPost(fn, 0)
+}
+
+func (m *Match[T, P]) walkPost(fn func(*Match[T, P], int), depth int) {
+	for _, component := range m.Components {
+		if component != nil {
+			component.walkPost(fn, depth+1)
+		}
+	}
+
+	fn(m, depth)
+}
+
+// Find returns the first sub match (pre-order) with the given pattern ID, nil if there is none
+func (m *Match[T, P]) Find(id string) *Match[T, P] {
+	var found *Match[T, P]
+
+	m.Walk(func(sub *Match[T, P], depth int) bool {
+		if found != nil {
+			return false
+		}
+
+		if depth > 0 && sub.ID() == id {
+			found = sub
+			return false
+		}
+
+		return true
+	})
+
+	return found
+}
+
+// FindAll returns all sub matches (pre-order) with the given pattern ID, including sub matches of found matches
+func (m *Match[T, P]) FindAll(id string) []*Match[T, P] {
+	var found []*Match[T, P]
+
+	m.Walk(func(sub *Match[T, P], depth int) bool {
+		if depth > 0 && sub.ID() == id {
+			found = append(found, sub)
+		}
+
+		return true
+	})
+
+	return found
+}
+
+// Prune returns a copy of the match tree in which all components whose pattern has no ID are replaced by their
+// (pruned) components, so the tree only contains named rules. The match itself is always kept
+func (m *Match[T, P]) Prune() *Match[T, P] {
+	return m.PruneFunc(func(sub *Match[T, P]) bool {
+		return sub.ID() != NoID
+	})
+}
+
+// PruneFunc returns a copy of the match tree in which all components for which keep returns false are replaced by
+// their (pruned) components. The match itself is always kept
+func (m *Match[T, P]) PruneFunc(keep func(*Match[T, P]) bool) *Match[T, P] {
+	c := *m
+	c.Components = nil
+	c.annos = maps.Clone(m.annos)
+
+	for _, component := range m.Components {
+		if component == nil {
+			continue
+		}
+
+		pruned := component.PruneFunc(keep)
+
+		if keep(component) {
+			c.Components = append(c.Components, pruned)
+		} else {
+			c.Components = append(c.Components, pruned.Components...)
+		}
+	}
+
+	return &c
+}
+
+// ComponentNamer is implemented by patterns whose components can be accessed by name, ComponentIndex returns -1 for
+// an unknown name
+type ComponentNamer interface {
+	ComponentIndex(name string) int
+}
+
+// Component returns the named component, nil if the pattern has no component with the name
+func (m *Match[T, P]) Component(name string) *Match[T, P] {
+	namer, ok := m.Pattern.(ComponentNamer)
+	if !ok {
+		return nil
+	}
+
+	i := namer.ComponentIndex(name)
+	if i >= 0 && m.indices != nil {
+		i = m.indices[i]
+	}
+
+	if i < 0 || i >= len(m.Components) {
+		return nil
+	}
+
+	return m.Components[i]
+}
+
+// SetComponentIndices sets the component index of each named sub pattern, -1 if the sub pattern has no component.
+// This is used by patterns whose components do not match their sub patterns one to one due to lowering
+func (m *Match[T, P]) SetComponentIndices(indices []int) *Match[T, P] {
+	m.indices = indices
+	return m
+}
+
+// Objects returns the matched objects, read from r with Range
+func (m *Match[T, P]) Objects(r Reader[T, P]) ([]T, error) {
+	if r.Length(m.Begin, m.End) <= 0 {
+		return nil, nil
+	}
+
+	return r.Range(m.Begin, m.End)
+}
+
+// Text returns the matched objects of a rune or byte stream as string
+func Text[T rune | byte, P any](m *Match[T, P], r Reader[T, P]) (string, error) {
+	objs, err := m.Objects(r)
+	if err != nil {
+		return "", err
+	}
+
+	switch v := any(objs).(type) {
+	case []rune:
+		return string(v), nil
+	case []byte:
+		return string(v), nil
+	}
+
+	return "", nil
+}
@@ -0,0 +1,29 @@
+package exbana
+
+// Offsetter is an optional extension of positions and writers which reports the offset in objects from the start
+// of the stream, patterns that depend on the offset (i.e. alignment) use it
+type Offsetter interface {
+	Offset() int
+}
+
+// PosOffset returns the offset of position p, an int position is the offset itself, other positions must implement
+// Offsetter
+func PosOffset[P any](p P) (int, bool) {
+	switch pos := any(p).(type) {
+	case int:
+		return pos, true
+	case Offsetter:
+		return pos.Offset(), true
+	}
+
+	return 0, false
+}
+
+// WriterOffset returns the number of objects written to w if w implements Offsetter
+func WriterOffset[T any](w Writer[T]) (int, bool) {
+	if o, ok := w.(Offsetter); ok {
+		return o.Offset(), true
+	}
+
+	return 0, false
+}
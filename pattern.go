@@ -1,11 +1,17 @@
 package exbana
 
-import "io"
+import (
+	"errors"
+	"io"
+)
 
 const (
 	NoID = ""
 )
 
+// ErrUnresolvedReference is returned when a reference pattern is used before the referred pattern is set
+var ErrUnresolvedReference = errors.New("unresolved pattern reference")
+
 // Pattern can match objects from a stream, generate objects to write to a stream, print and has an identifier
 type Pattern[T, P any] interface {
 	Match(Reader[T, P]) (bool, *Match[T, P], error)
@@ -17,12 +23,21 @@ type Pattern[T, P any] interface {
 	Self() Pattern[T, P]
 	SetSelf(Pattern[T, P]) Pattern[T, P]
 	SetEvalFunc(func(*Match[T, P], Reader[T, P]) (any, error)) Pattern[T, P]
+	HasEvalFunc() bool
 	Eval(*Match[T, P], Reader[T, P]) (any, error)
 	Generate(Writer[T]) error
 	Print(io.Writer) error
 	PrintAsChild(io.Writer) error
 	PrintOutput() string
 	SetPrintOutput(string) Pattern[T, P]
+	Lowering() Lowering
+	MarkToken() Pattern[T, P]
+	MarkList() Pattern[T, P]
+	MarkDiscard() Pattern[T, P]
+	Captures() bool
+	SetCapture(bool) Pattern[T, P]
+	Diagnostic() (string, Severity)
+	SetDiagnostic(string, Severity) Pattern[T, P]
 }
 
 // Patterns is a convenience type for a slice of pattern interfaces
@@ -36,6 +51,10 @@ type BasePattern[T, P any] struct {
 	logger      Logger[T, P]
 	printOutput string
 	evalFunc    func(*Match[T, P], Reader[T, P]) (any, error)
+	lowering    Lowering
+	noCapture   bool
+	code        string
+	severity    Severity
 }
 
 func NewBasePattern[T, P any]() *BasePattern[T, P] {
@@ -113,6 +132,11 @@ func (p *BasePattern[T, P]) SetEvalFunc(f func(*Match[T, P], Reader[T, P]) (any,
 	return p.self
 }
 
+// HasEvalFunc reports if an eval function is set
+func (p *BasePattern[T, P]) HasEvalFunc() bool {
+	return p.evalFunc != nil
+}
+
 func (p *BasePattern[T, P]) Eval(m *Match[T, P], r Reader[T, P]) (any, error) {
 	if p.evalFunc != nil {
 		return p.evalFunc(m, r)
@@ -129,3 +153,59 @@ func (p *BasePattern[T, P]) SetPrintOutput(output string) Pattern[T, P] {
 	p.printOutput = output
 	return p.self
 }
+
+// Lowering returns how a parent pattern adds the match of the pattern to its components
+func (p *BasePattern[T, P]) Lowering() Lowering {
+	return p.lowering
+}
+
+// MarkToken makes parent patterns capture the matched objects of the pattern as value but no components
+func (p *BasePattern[T, P]) MarkToken() Pattern[T, P] {
+	p.lowering = LowerToken
+	return p.self
+}
+
+// MarkList makes parent patterns flatten the components of the pattern into their own components
+func (p *BasePattern[T, P]) MarkList() Pattern[T, P] {
+	p.lowering = LowerList
+	return p.self
+}
+
+// MarkDiscard makes parent patterns drop the match of the pattern
+func (p *BasePattern[T, P]) MarkDiscard() Pattern[T, P] {
+	p.lowering = LowerDiscard
+	return p.self
+}
+
+// Captures returns false if the pattern produces no match inside concatenation and repetition results
+func (p *BasePattern[T, P]) Captures() bool {
+	return !p.noCapture
+}
+
+// SetCapture turns the match of the pattern inside concatenation and repetition results on or off, without capture
+// the pattern is only validated so no match tree is built (i.e. for white space and separators)
+func (p *BasePattern[T, P]) SetCapture(capture bool) Pattern[T, P] {
+	p.noCapture = !capture
+	return p.self
+}
+
+// Diagnostic returns the diagnostic code and severity of failures of the pattern
+func (p *BasePattern[T, P]) Diagnostic() (string, Severity) {
+	return p.code, p.severity
+}
+
+// SetDiagnostic sets a diagnostic code (i.e. E001 for a missing semicolon) and severity for failures of the pattern,
+// so tooling can categorize, suppress or document specific failures
+func (p *BasePattern[T, P]) SetDiagnostic(code string, severity Severity) Pattern[T, P] {
+	p.code = code
+	p.severity = severity
+
+	return p.self
+}
+
+// Copy returns a copy of the base pattern, used by patterns implementing Cloner. The self of the copy still refers to
+// the original pattern, the cloned pattern must call SetSelf
+func (p *BasePattern[T, P]) Copy() *BasePattern[T, P] {
+	c := *p
+	return &c
+}
@@ -3,7 +3,7 @@ package alternation
 import (
 	ebnf "github.com/almerlucke/exbana/v2"
 	"io"
-	"math/rand"
+	"sync"
 )
 
 // Alternation matches a series of patterns OR style in order (alternation)
@@ -11,6 +11,8 @@ type Alternation[T, P any] struct {
 	*ebnf.BasePattern[T, P]
 	patterns     ebnf.Patterns[T, P]
 	isOrthogonal bool // if orthogonal we stop at first match as we know input is not related
+	dispatch     func(T) any
+	index        *sync.Map
 }
 
 // New creates a new Alternation pattern
@@ -31,24 +33,106 @@ func (a *Alternation[T, P]) SetOrthogonal(ortho bool) *Alternation[T, P] {
 	return a
 }
 
+// SetDispatch enables FIRST set dispatch, only the alternatives that can start with the next object (or can be
+// empty) are tried. The alternatives per next object are cached by the class the dispatch function returns for the
+// object, all objects of the same class must be accepted by the same alternatives. The class must be comparable,
+// for runes or bytes the object itself can be used as class. A nil function disables dispatch
+func (a *Alternation[T, P]) SetDispatch(class func(T) any) *Alternation[T, P] {
+	a.dispatch = class
+	a.index = &sync.Map{}
+
+	return a
+}
+
+// candidates returns the alternatives that can match the next object, all alternatives if dispatch is disabled
+func (a *Alternation[T, P]) candidates(r ebnf.Reader[T, P]) (ebnf.Patterns[T, P], error) {
+	if a.dispatch == nil || r.Finished() {
+		return a.patterns, nil
+	}
+
+	obj, err := r.Peek1()
+	if err != nil {
+		if ebnf.IsStreamError(err) {
+			return nil, err
+		}
+
+		return a.patterns, nil
+	}
+
+	class := a.dispatch(obj)
+
+	if patterns, ok := a.index.Load(class); ok {
+		return patterns.(ebnf.Patterns[T, P]), nil
+	}
+
+	var patterns ebnf.Patterns[T, P]
+
+	for _, pm := range a.patterns {
+		if first, nullable := ebnf.First(pm, obj); first || nullable {
+			patterns = append(patterns, pm)
+		}
+	}
+
+	a.index.Store(class, patterns)
+
+	return patterns, nil
+}
+
+// First reports if obj can start any of the alternatives
+func (a *Alternation[T, P]) First(obj T) (bool, bool) {
+	first, nullable := false, false
+
+	for _, pm := range a.patterns {
+		f, n := ebnf.First(pm, obj)
+		first = first || f
+		nullable = nullable || n
+	}
+
+	return first, nullable
+}
+
+// IsOrthogonal returns true if the alternation stops at the first match
+func (a *Alternation[T, P]) IsOrthogonal() bool {
+	return a.isOrthogonal
+}
+
+// Patterns returns the alternatives
+func (a *Alternation[T, P]) Patterns() ebnf.Patterns[T, P] {
+	return a.patterns
+}
+
 // Match matches the Alternation sub patterns against a stream, fails if there is no match. If there are more than one match,
 // the longest match returns, if two or more matches are the longest, the first of those is returned. So order of the sub
 // patterns matters when creating an Alternation pattern
 func (a *Alternation[T, P]) Match(r ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], error) {
+	if ebnf.IsValidating(r) {
+		ok, err := a.validate(r)
+		return ok, nil, err
+	}
+
 	var matches []*ebnf.Match[T, P]
 
-	beginPos, err := r.Position()
+	begin, err := ebnf.NewMarker(r)
 	if ebnf.IsStreamError(err) {
 		return false, nil, err
 	}
 
-	for _, pm := range a.patterns {
-		err = r.SetPosition(beginPos)
+	defer begin.Discard()
+
+	beginPos := begin.Pos()
+
+	patterns, err := a.candidates(r)
+	if err != nil {
+		return false, nil, err
+	}
+
+	for _, pm := range patterns {
+		err = begin.Reset()
 		if ebnf.IsStreamError(err) {
 			return false, nil, err
 		}
 
-		matched, result, err := pm.Match(r)
+		matched, result, err := ebnf.MatchPattern(pm, r)
 		if err != nil {
 			return false, nil, err
 		}
@@ -59,7 +143,12 @@ func (a *Alternation[T, P]) Match(r ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P],
 				return false, nil, err
 			}
 
-			match := ebnf.NewMatch(a, beginPos, endPos, nil, []*ebnf.Match[T, P]{result})
+			components, err := ebnf.Lower(nil, result, r)
+			if err != nil {
+				return false, nil, err
+			}
+
+			match := ebnf.NewMatch(a, beginPos, endPos, nil, components)
 
 			// if set of alternations is orthogonal we know there is no relation between the entities in the set
 			// so we can stop at first match
@@ -105,13 +194,78 @@ func (a *Alternation[T, P]) Match(r ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P],
 	return false, nil, nil
 }
 
+// validate matches the alternation without creating a match, like Match the longest alternative wins
+func (a *Alternation[T, P]) validate(r ebnf.Reader[T, P]) (bool, error) {
+	begin, err := ebnf.NewMarker(r)
+	if ebnf.IsStreamError(err) {
+		return false, err
+	}
+
+	defer begin.Discard()
+
+	var (
+		longest P
+		length  = -1
+	)
+
+	patterns, err := a.candidates(r)
+	if err != nil {
+		return false, err
+	}
+
+	for _, pm := range patterns {
+		err = begin.Reset()
+		if ebnf.IsStreamError(err) {
+			return false, err
+		}
+
+		matched, _, err := ebnf.MatchPattern(pm, r)
+		if err != nil {
+			return false, err
+		}
+
+		if !matched {
+			continue
+		}
+
+		if a.isOrthogonal {
+			return true, nil
+		}
+
+		endPos, err := r.Position()
+		if ebnf.IsStreamError(err) {
+			return false, err
+		}
+
+		if l := r.Length(begin.Pos(), endPos); l > length {
+			longest, length = endPos, l
+		}
+	}
+
+	if length < 0 {
+		return false, nil
+	}
+
+	err = r.SetPosition(longest)
+	if ebnf.IsStreamError(err) {
+		return false, err
+	}
+
+	return true, nil
+}
+
 func (a *Alternation[T, P]) CanUnpack() bool {
 	return true
 }
 
-// Generate writes an alternation of patterns to a writer, randomly chosen
+// Generate writes an alternation of patterns to a writer, randomly chosen unless the writer chooses
 func (a *Alternation[T, P]) Generate(w ebnf.Writer[T]) error {
-	return a.patterns[rand.Intn(len(a.patterns))].Generate(w)
+	i := ebnf.Choose[T, P](w, a, a.patterns)
+	if i < 0 || i >= len(a.patterns) {
+		i = ebnf.Rand(w).Intn(len(a.patterns))
+	}
+
+	return ebnf.GeneratePattern(a.patterns[i], w)
 }
 
 // Print EBNF alternation group
@@ -143,3 +297,27 @@ func (a *Alternation[T, P]) Print(w io.Writer) error {
 
 	return err
 }
+
+// Clone returns a shallow copy of the alternation
+func (a *Alternation[T, P]) Clone() ebnf.Pattern[T, P] {
+	c := *a
+	c.BasePattern = a.BasePattern.Copy()
+	c.patterns = append(ebnf.Patterns[T, P]{}, a.patterns...)
+
+	if c.dispatch != nil {
+		c.index = &sync.Map{}
+	}
+
+	return c.SetSelf(&c)
+}
+
+// Rebind replaces the alternatives with the result of f
+func (a *Alternation[T, P]) Rebind(f func(ebnf.Pattern[T, P]) ebnf.Pattern[T, P]) {
+	for i, pattern := range a.patterns {
+		a.patterns[i] = f(pattern)
+	}
+
+	if a.dispatch != nil {
+		a.index = &sync.Map{}
+	}
+}
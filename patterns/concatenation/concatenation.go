@@ -9,6 +9,7 @@ import (
 type Concatenation[T, P any] struct {
 	*ebnf.BasePattern[T, P]
 	patterns ebnf.Patterns[T, P]
+	names    []string
 }
 
 // New creates a new concatenation pattern
@@ -23,9 +24,46 @@ func New[T, P any](patterns ...ebnf.Pattern[T, P]) *Concatenation[T, P] {
 	return c
 }
 
+// Add appends a named pattern, the match of the pattern can be accessed by name with Match.Component
+func (c *Concatenation[T, P]) Add(name string, pattern ebnf.Pattern[T, P]) *Concatenation[T, P] {
+	for len(c.names) < len(c.patterns) {
+		c.names = append(c.names, "")
+	}
+
+	c.patterns = append(c.patterns, pattern)
+	c.names = append(c.names, name)
+
+	return c
+}
+
+// ComponentIndex returns the index of the named pattern, -1 if there is no pattern with the name
+func (c *Concatenation[T, P]) ComponentIndex(name string) int {
+	for i, n := range c.names {
+		if n == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Patterns returns the concatenated patterns
+func (c *Concatenation[T, P]) Patterns() ebnf.Patterns[T, P] {
+	return c.patterns
+}
+
 // Match matches AND against a stream, fails if any of the sub patterns mismatches
 func (c *Concatenation[T, P]) Match(rd ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], error) {
-	var matches []*ebnf.Match[T, P]
+	if ebnf.IsValidating(rd) {
+		ok, err := c.validate(rd)
+		return ok, nil, err
+	}
+
+	var (
+		matches []*ebnf.Match[T, P]
+		indices []int
+		lowered bool
+	)
 
 	beginPos, err := rd.Position()
 	if ebnf.IsStreamError(err) {
@@ -38,13 +76,28 @@ func (c *Concatenation[T, P]) Match(rd ebnf.Reader[T, P]) (bool, *ebnf.Match[T,
 			return false, nil, err
 		}
 
-		matched, result, err := pm.Match(rd)
+		matched, result, err := ebnf.MatchComponent(pm, rd)
 		if err != nil {
 			return false, nil, err
 		}
 
 		if matched {
-			matches = append(matches, result)
+			n := len(matches)
+
+			matches, err = ebnf.Lower(matches, result, rd)
+			if err != nil {
+				return false, nil, err
+			}
+
+			if len(c.names) > 0 {
+				if result != nil && len(matches) == n+1 && matches[n].Pattern == result.Pattern {
+					indices = append(indices, n)
+				} else {
+					indices = append(indices, -1)
+				}
+
+				lowered = lowered || indices[len(indices)-1] != len(indices)-1
+			}
 		} else {
 			subEndPos, err := rd.Position()
 			if ebnf.IsStreamError(err) {
@@ -62,13 +115,32 @@ func (c *Concatenation[T, P]) Match(rd ebnf.Reader[T, P]) (bool, *ebnf.Match[T,
 		return false, nil, err
 	}
 
-	return true, ebnf.NewMatch(c, beginPos, endPos, nil, matches), nil
+	match := ebnf.NewMatch(c, beginPos, endPos, nil, matches)
+
+	// Keep the named components accessible if lowering or capture changed the components
+	if lowered {
+		match.SetComponentIndices(indices)
+	}
+
+	return true, match, nil
+}
+
+// validate matches the concatenation without creating a match
+func (c *Concatenation[T, P]) validate(rd ebnf.Reader[T, P]) (bool, error) {
+	for _, pm := range c.patterns {
+		matched, _, err := ebnf.MatchPattern(pm, rd)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+
+	return true, nil
 }
 
 // Generate writes a concatenation of patterns to a writer
 func (c *Concatenation[T, P]) Generate(w ebnf.Writer[T]) error {
 	for _, child := range c.patterns {
-		err := child.Generate(w)
+		err := ebnf.GeneratePattern(child, w)
 		if err != nil {
 			return err
 		}
@@ -106,3 +178,36 @@ func (c *Concatenation[T, P]) Print(w io.Writer) error {
 
 	return err
 }
+
+// Clone returns a shallow copy of the concatenation
+func (c *Concatenation[T, P]) Clone() ebnf.Pattern[T, P] {
+	cc := *c
+	cc.BasePattern = c.BasePattern.Copy()
+	cc.patterns = append(ebnf.Patterns[T, P]{}, c.patterns...)
+	cc.names = append([]string{}, c.names...)
+
+	return cc.SetSelf(&cc)
+}
+
+// Rebind replaces the concatenated patterns with the result of f
+func (c *Concatenation[T, P]) Rebind(f func(ebnf.Pattern[T, P]) ebnf.Pattern[T, P]) {
+	for i, pattern := range c.patterns {
+		c.patterns[i] = f(pattern)
+	}
+}
+
+// First reports if obj can start a sub pattern up to and including the first sub pattern that can not be empty
+func (c *Concatenation[T, P]) First(obj T) (bool, bool) {
+	first := false
+
+	for _, pm := range c.patterns {
+		f, nullable := ebnf.First(pm, obj)
+		first = first || f
+
+		if !nullable {
+			return first, false
+		}
+	}
+
+	return first, true
+}
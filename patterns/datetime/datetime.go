@@ -0,0 +1,217 @@
+package datetime
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/runeclass"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	"strings"
+	"time"
+)
+
+// Pattern IDs of the date and time patterns
+const (
+	DateID      = "date"
+	TimeID      = "time"
+	RFC3339ID   = "rfc3339"
+	CommonLogID = "common_log_time"
+	SyslogID    = "syslog_time"
+	DurationID  = "duration"
+)
+
+// Layouts used to evaluate the matched text
+const (
+	CommonLogLayout = "02/Jan/2006:15:04:05 -0700"
+	SyslogLayout    = time.Stamp
+)
+
+var months = []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+
+// Date matches a full date YYYY-MM-DD, it evaluates to a time.Time. Generated dates are syntactically valid but days
+// 29-31 may not exist in the generated month
+func Date[P any]() ebnf.Pattern[rune, P] {
+	d := date[P]()
+	d.SetID(DateID)
+	d.SetEvalFunc(layout[P](time.DateOnly))
+
+	return d
+}
+
+// Time matches a time of day HH:MM:SS with optional fraction, it evaluates to a time.Time on January 1, year 0
+func Time[P any]() ebnf.Pattern[rune, P] {
+	t := clock[P](true)
+	t.SetID(TimeID)
+	t.SetEvalFunc(layout[P](time.TimeOnly))
+
+	return t
+}
+
+// RFC3339 matches an RFC 3339 timestamp (2006-01-02T15:04:05.999Z07:00), the separator may be T, t or a space and
+// the zone Z, z or a numeric offset. It evaluates to a time.Time
+func RFC3339[P any]() ebnf.Pattern[rune, P] {
+	zone := alternation.New[rune, P](
+		runeclass.MustClass[P]("[Zz]"),
+		concatenation.New[rune, P](runeclass.MustClass[P]("[+-]"), hour[P](), char[P](':'), sixty[P]()),
+	)
+
+	ts := concatenation.New[rune, P](date[P](), runeclass.MustClass[P]("[Tt ]"), clock[P](true), zone)
+	ts.SetID(RFC3339ID)
+	ts.SetEvalFunc(func(m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+		s, err := ebnf.Text(m, r)
+		if err != nil {
+			return nil, err
+		}
+
+		// time.RFC3339Nano only accepts the upper case separator and zone
+		s = strings.ToUpper(s[:10]) + "T" + strings.ToUpper(s[11:])
+
+		return time.Parse(time.RFC3339Nano, s)
+	})
+
+	return ts
+}
+
+// CommonLog matches the timestamp of the common log format without brackets (10/Oct/2000:13:55:36 -0700), it
+// evaluates to a time.Time
+func CommonLog[P any]() ebnf.Pattern[rune, P] {
+	ts := concatenation.New[rune, P](
+		day[P](), char[P]('/'), month[P](), char[P]('/'), digits[P](4), char[P](':'), clock[P](false),
+		char[P](' '), runeclass.MustClass[P]("[+-]"), hour[P](), sixty[P](),
+	)
+	ts.SetID(CommonLogID)
+	ts.SetEvalFunc(layout[P](CommonLogLayout))
+
+	return ts
+}
+
+// Syslog matches an RFC 3164 syslog timestamp with a space padded day (Jan  2 15:04:05), it evaluates to a
+// time.Time in year 0 because the format has no year
+func Syslog[P any]() ebnf.Pattern[rune, P] {
+	paddedDay := alternation.New[rune, P](
+		concatenation.New[rune, P](char[P](' '), runeclass.MustClass[P]("[1-9]")),
+		concatenation.New[rune, P](runeclass.MustClass[P]("[12]"), runeclass.ASCIIDigit[P]()),
+		concatenation.New[rune, P](char[P]('3'), runeclass.MustClass[P]("[01]")),
+	)
+
+	ts := concatenation.New[rune, P](month[P](), char[P](' '), paddedDay, char[P](' '), clock[P](false))
+	ts.SetID(SyslogID)
+	ts.SetEvalFunc(layout[P](SyslogLayout))
+
+	return ts
+}
+
+// Duration matches a Go duration such as 1h30m, 1.5s, -300ms or 2µs, it evaluates to a time.Duration
+func Duration[P any]() ebnf.Pattern[rune, P] {
+	number := alternation.New[rune, P](
+		concatenation.New[rune, P](digitsPlus[P](), repetition.New[rune, P](
+			concatenation.New[rune, P](char[P]('.'), digitsPlus[P]()), 0, 1),
+		),
+		concatenation.New[rune, P](char[P]('.'), digitsPlus[P]()),
+	)
+
+	var units []ebnf.Pattern[rune, P]
+
+	// Two rune units first so ms is not matched as m
+	for _, unit := range []string{"ns", "us", "µs", "μs", "ms", "s", "m", "h"} {
+		units = append(units, vector.Runes[P](unit))
+	}
+
+	d := concatenation.New[rune, P](
+		repetition.New[rune, P](runeclass.MustClass[P]("[+-]"), 0, 1),
+		repetition.New[rune, P](concatenation.New[rune, P](number, alternation.New[rune, P](units...)), 1, 0),
+	)
+	d.SetID(DurationID)
+	d.SetEvalFunc(func(m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+		s, err := ebnf.Text(m, r)
+		if err != nil {
+			return nil, err
+		}
+
+		return time.ParseDuration(s)
+	})
+
+	return d
+}
+
+// layout returns an eval function parsing the matched text with a time layout
+func layout[P any](layout string) func(*ebnf.Match[rune, P], ebnf.Reader[rune, P]) (any, error) {
+	return func(m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+		s, err := ebnf.Text(m, r)
+		if err != nil {
+			return nil, err
+		}
+
+		return time.Parse(layout, s)
+	}
+}
+
+// date matches YYYY-MM-DD
+func date[P any]() ebnf.Pattern[rune, P] {
+	month := alternation.New[rune, P](
+		concatenation.New[rune, P](char[P]('0'), runeclass.MustClass[P]("[1-9]")),
+		concatenation.New[rune, P](char[P]('1'), runeclass.MustClass[P]("[0-2]")),
+	)
+
+	return concatenation.New[rune, P](digits[P](4), char[P]('-'), month, char[P]('-'), day[P]())
+}
+
+// clock matches HH:MM:SS with an optional fraction
+func clock[P any](fraction bool) ebnf.Pattern[rune, P] {
+	c := concatenation.New[rune, P](hour[P](), char[P](':'), sixty[P](), char[P](':'), sixty[P]())
+
+	if fraction {
+		c.Add("", repetition.New[rune, P](concatenation.New[rune, P](char[P]('.'), digitsPlus[P]()), 0, 1))
+	}
+
+	return c
+}
+
+// day matches a day of the month 01-31
+func day[P any]() ebnf.Pattern[rune, P] {
+	return alternation.New[rune, P](
+		concatenation.New[rune, P](char[P]('0'), runeclass.MustClass[P]("[1-9]")),
+		concatenation.New[rune, P](runeclass.MustClass[P]("[12]"), runeclass.ASCIIDigit[P]()),
+		concatenation.New[rune, P](char[P]('3'), runeclass.MustClass[P]("[01]")),
+	)
+}
+
+// month matches an English three letter month abbreviation
+func month[P any]() ebnf.Pattern[rune, P] {
+	var names []ebnf.Pattern[rune, P]
+
+	for _, name := range months {
+		names = append(names, vector.Runes[P](name))
+	}
+
+	return alternation.New[rune, P](names...)
+}
+
+// hour matches 00-23
+func hour[P any]() ebnf.Pattern[rune, P] {
+	return alternation.New[rune, P](
+		concatenation.New[rune, P](runeclass.MustClass[P]("[01]"), runeclass.ASCIIDigit[P]()),
+		concatenation.New[rune, P](char[P]('2'), runeclass.MustClass[P]("[0-3]")),
+	)
+}
+
+// sixty matches 00-59
+func sixty[P any]() ebnf.Pattern[rune, P] {
+	return concatenation.New[rune, P](runeclass.MustClass[P]("[0-5]"), runeclass.ASCIIDigit[P]())
+}
+
+// digits matches exactly n decimal digits
+func digits[P any](n int) ebnf.Pattern[rune, P] {
+	return repetition.New[rune, P](runeclass.ASCIIDigit[P](), n, n)
+}
+
+// digitsPlus matches one or more decimal digits
+func digitsPlus[P any]() ebnf.Pattern[rune, P] {
+	return repetition.New[rune, P](runeclass.ASCIIDigit[P](), 1, 0)
+}
+
+// char matches a single rune
+func char[P any](c rune) ebnf.Pattern[rune, P] {
+	return vector.Runes[P](string(c))
+}
@@ -20,8 +20,21 @@ func New[T, P any]() *End[T, P] {
 	return e
 }
 
+// Expected describes the end of stream
+func (e *End[T, P]) Expected() string {
+	if output := e.PrintOutput(); output != "" {
+		return output
+	}
+
+	return "end of input"
+}
+
 // Match matches a end of stream pattern against a stream
 func (e *End[T, P]) Match(r ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], error) {
+	if ebnf.IsValidating(r) {
+		return r.Finished(), nil, nil
+	}
+
 	pos, err := r.Position()
 	if ebnf.IsStreamError(err) {
 		return false, nil, err
@@ -40,3 +53,21 @@ func (e *End[T, P]) Match(r ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], error)
 func (e *End[T, P]) Generate(w ebnf.Writer[T]) error {
 	return w.Finish()
 }
+
+// Clone returns a copy of the end pattern
+func (e *End[T, P]) Clone() ebnf.Pattern[T, P] {
+	c := *e
+	c.BasePattern = e.BasePattern.Copy()
+
+	return c.SetSelf(&c)
+}
+
+// Rebind does nothing, end has no sub patterns
+func (e *End[T, P]) Rebind(_ func(ebnf.Pattern[T, P]) ebnf.Pattern[T, P]) {
+	/* no sub patterns */
+}
+
+// First reports false, if there is a next object the stream is not at the end
+func (e *End[T, P]) First(_ T) (bool, bool) {
+	return false, false
+}
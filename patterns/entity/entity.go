@@ -2,13 +2,15 @@ package entity
 
 import (
 	ebnf "github.com/almerlucke/exbana/v2"
+	"math/rand"
 )
 
 // Entity represents a single entity pattern
 type Entity[T, P any] struct {
 	*ebnf.BasePattern[T, P]
-	matchFunc func(T) bool
-	genFunc   func() T
+	matchFunc   func(T) bool
+	genFunc     func(*rand.Rand) T
+	expectation string
 }
 
 // New creates a new entity pattern
@@ -24,13 +26,62 @@ func New[T, P any](matchFunc func(T) bool) *Entity[T, P] {
 	return e
 }
 
+// MatchFunc returns the function testing a single object
+func (e *Entity[T, P]) MatchFunc() func(T) bool {
+	return e.matchFunc
+}
+
+// SetExpectation sets a human description of the expected object (i.e. "decimal digit") used in error messages
+func (e *Entity[T, P]) SetExpectation(expectation string) *Entity[T, P] {
+	e.expectation = expectation
+	return e
+}
+
+// Expected returns the expectation, the ID or the print output
+func (e *Entity[T, P]) Expected() string {
+	if e.expectation != "" {
+		return e.expectation
+	}
+
+	if id := e.ID(); id != ebnf.NoID {
+		return id
+	}
+
+	return e.PrintOutput()
+}
+
 func (e *Entity[T, P]) SetGenerateFunc(f func() T) *Entity[T, P] {
+	if f == nil {
+		e.genFunc = nil
+		return e
+	}
+
+	e.genFunc = func(*rand.Rand) T {
+		return f()
+	}
+
+	return e
+}
+
+// SetRandGenerateFunc sets a generate function which draws from the random source of the writer (see ebnf.Rand), so
+// the generated objects can be reproduced from a seed
+func (e *Entity[T, P]) SetRandGenerateFunc(f func(*rand.Rand) T) *Entity[T, P] {
 	e.genFunc = f
 	return e
 }
 
 // Match matches the entity to a stream
 func (e *Entity[T, P]) Match(rd ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], error) {
+	if ebnf.IsValidating(rd) {
+		obj, err := rd.Read1()
+		if ebnf.IsStreamError(err) {
+			return false, nil, err
+		}
+
+		// The zero object returned at the end of the stream is not matched
+		return err == nil && e.matchFunc(obj), nil, nil
+	}
+
 	pos, err := rd.Position()
 	if ebnf.IsStreamError(err) {
 		return false, nil, err
@@ -41,15 +92,19 @@ func (e *Entity[T, P]) Match(rd ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], err
 		return false, nil, err
 	}
 
-	if e.matchFunc(obj) {
+	if err == nil && e.matchFunc(obj) {
 		endPos, err := rd.Position()
 		if ebnf.IsStreamError(err) {
 			return false, nil, err
 		}
 
-		val, err := rd.Range(pos, endPos)
-		if err != nil {
-			return false, nil, err
+		var val []T
+
+		if ebnf.IsCapturing(rd) {
+			val, err = rd.Range(pos, endPos)
+			if err != nil {
+				return false, nil, err
+			}
 		}
 
 		return true, ebnf.NewMatch(e, pos, endPos, val, nil), nil
@@ -68,8 +123,26 @@ func (e *Entity[T, P]) Match(rd ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], err
 // Generate writes an entity to a writer
 func (e *Entity[T, P]) Generate(w ebnf.Writer[T]) error {
 	if e.genFunc != nil {
-		return w.Write(e.genFunc())
+		return w.Write(e.genFunc(ebnf.Rand(w)))
 	}
 
 	return nil
 }
+
+// Clone returns a copy of the entity
+func (e *Entity[T, P]) Clone() ebnf.Pattern[T, P] {
+	c := *e
+	c.BasePattern = e.BasePattern.Copy()
+
+	return c.SetSelf(&c)
+}
+
+// Rebind does nothing, an entity has no sub patterns
+func (e *Entity[T, P]) Rebind(_ func(ebnf.Pattern[T, P]) ebnf.Pattern[T, P]) {
+	/* no sub patterns */
+}
+
+// First reports if obj is matched by the entity
+func (e *Entity[T, P]) First(obj T) (bool, bool) {
+	return e.matchFunc(obj), false
+}
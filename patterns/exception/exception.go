@@ -25,15 +25,34 @@ func New[T, P any](must ebnf.Pattern[T, P], exception ebnf.Pattern[T, P]) *Excep
 	return e
 }
 
+// Must returns the pattern that must match
+func (e *Exception[T, P]) Must() ebnf.Pattern[T, P] {
+	return e.must
+}
+
+// Exception returns the pattern that must not match
+func (e *Exception[T, P]) Exception() ebnf.Pattern[T, P] {
+	return e.exception
+}
+
 // Match matches the exception against a stream
 func (e *Exception[T, P]) Match(r ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], error) {
-	beginPos, err := r.Position()
+	if ebnf.IsValidating(r) {
+		ok, err := e.validate(r)
+		return ok, nil, err
+	}
+
+	begin, err := ebnf.NewMarker(r)
 	if ebnf.IsStreamError(err) {
 		return false, nil, err
 	}
 
+	defer begin.Discard()
+
+	beginPos := begin.Pos()
+
 	// First check for the exception match, we do not want to match the exception
-	matched, result, err := e.exception.Match(r)
+	matched, result, err := ebnf.MatchPattern(e.exception, r)
 	if err != nil {
 		return false, nil, err
 	}
@@ -50,17 +69,41 @@ func (e *Exception[T, P]) Match(r ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], e
 	}
 
 	// Reset the position and return must match result
-	err = r.SetPosition(beginPos)
+	err = begin.Reset()
 	if ebnf.IsStreamError(err) {
 		return false, nil, err
 	}
 
-	return e.must.Match(r)
+	return ebnf.MatchPattern(e.must, r)
+}
+
+// validate matches the exception without creating a match
+func (e *Exception[T, P]) validate(r ebnf.Reader[T, P]) (bool, error) {
+	begin, err := ebnf.NewMarker(r)
+	if ebnf.IsStreamError(err) {
+		return false, err
+	}
+
+	defer begin.Discard()
+
+	matched, _, err := ebnf.MatchPattern(e.exception, r)
+	if err != nil || matched {
+		return false, err
+	}
+
+	err = begin.Reset()
+	if ebnf.IsStreamError(err) {
+		return false, err
+	}
+
+	matched, _, err = ebnf.MatchPattern(e.must, r)
+
+	return matched, err
 }
 
 // Generate let's MustMatch generate to writer
 func (e *Exception[T, P]) Generate(w ebnf.Writer[T]) error {
-	return e.must.Generate(w)
+	return ebnf.GeneratePattern(e.must, w)
 }
 
 // Print EBNF exception pattern
@@ -78,3 +121,22 @@ func (e *Exception[T, P]) Print(w io.Writer) error {
 
 	return err
 }
+
+// Clone returns a shallow copy of the exception
+func (e *Exception[T, P]) Clone() ebnf.Pattern[T, P] {
+	c := *e
+	c.BasePattern = e.BasePattern.Copy()
+
+	return c.SetSelf(&c)
+}
+
+// Rebind replaces the must and exception patterns with the result of f
+func (e *Exception[T, P]) Rebind(f func(ebnf.Pattern[T, P]) ebnf.Pattern[T, P]) {
+	e.must = f(e.must)
+	e.exception = f(e.exception)
+}
+
+// First reports if obj can start the pattern that must match
+func (e *Exception[T, P]) First(obj T) (bool, bool) {
+	return ebnf.First(e.must, obj)
+}
@@ -0,0 +1,80 @@
+package hint
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"io"
+)
+
+// Hint tags a pattern with a formatting hint (i.e. "statement" or "block"), matching and printing are transparent,
+// on generation the hint is passed to the writer so a formatting writer can insert separators and indentation
+type Hint[T, P any] struct {
+	*ebnf.BasePattern[T, P]
+	pattern ebnf.Pattern[T, P]
+	hint    string
+}
+
+// New creates a new hint pattern
+func New[T, P any](hint string, pattern ebnf.Pattern[T, P]) *Hint[T, P] {
+	h := &Hint[T, P]{
+		BasePattern: ebnf.NewBasePattern[T, P](),
+		pattern:     pattern,
+		hint:        hint,
+	}
+
+	h.SetSelf(h)
+
+	return h
+}
+
+// Hint returns the hint
+func (h *Hint[T, P]) Hint() string {
+	return h.hint
+}
+
+// Pattern returns the hinted pattern
+func (h *Hint[T, P]) Pattern() ebnf.Pattern[T, P] {
+	return h.pattern
+}
+
+// Match matches the hinted pattern, the match of the hinted pattern is returned as is
+func (h *Hint[T, P]) Match(r ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], error) {
+	return ebnf.MatchPattern(h.pattern, r)
+}
+
+// Generate lets the hinted pattern generate to writer surrounded by the hint
+func (h *Hint[T, P]) Generate(w ebnf.Writer[T]) error {
+	err := ebnf.BeginHint(w, h.hint)
+	if err != nil {
+		return err
+	}
+
+	err = ebnf.GeneratePattern(h.pattern, w)
+	if err != nil {
+		return err
+	}
+
+	return ebnf.EndHint(w, h.hint)
+}
+
+// Print prints the hinted pattern as child
+func (h *Hint[T, P]) Print(w io.Writer) error {
+	return h.pattern.PrintAsChild(w)
+}
+
+// Clone returns a shallow copy of the hint
+func (h *Hint[T, P]) Clone() ebnf.Pattern[T, P] {
+	c := *h
+	c.BasePattern = h.BasePattern.Copy()
+
+	return c.SetSelf(&c)
+}
+
+// Rebind replaces the hinted pattern with the result of f
+func (h *Hint[T, P]) Rebind(f func(ebnf.Pattern[T, P]) ebnf.Pattern[T, P]) {
+	h.pattern = f(h.pattern)
+}
+
+// First reports if obj can start the hinted pattern
+func (h *Hint[T, P]) First(obj T) (bool, bool) {
+	return ebnf.First(h.pattern, obj)
+}
@@ -0,0 +1,155 @@
+package ipaddr
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/runeclass"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	"net/netip"
+)
+
+// Pattern IDs of the address patterns
+const (
+	IPv4ID     = "ipv4"
+	IPv6ID     = "ipv6"
+	IPv4CIDRID = "ipv4_cidr"
+	IPv6CIDRID = "ipv6_cidr"
+)
+
+// IPv4 matches a dotted decimal IPv4 address without leading zeros, it evaluates to a netip.Addr
+func IPv4[P any]() ebnf.Pattern[rune, P] {
+	a := ipv4[P]()
+	a.SetID(IPv4ID)
+	a.SetEvalFunc(evalAddr[P])
+
+	return a
+}
+
+// IPv6 matches an IPv6 address in full or compressed (::) form, the last 32 bits may be written as IPv4 address. It
+// follows the IPv6address rule of RFC 3986, zones are not matched. It evaluates to a netip.Addr
+func IPv6[P any]() ebnf.Pattern[rune, P] {
+	a := ipv6[P]()
+	a.SetID(IPv6ID)
+	a.SetEvalFunc(evalAddr[P])
+
+	return a
+}
+
+// IP matches an IPv4 or IPv6 address, it evaluates to a netip.Addr
+func IP[P any]() ebnf.Pattern[rune, P] {
+	return alternation.New[rune, P](IPv4[P](), IPv6[P]())
+}
+
+// CIDR matches an IPv4 or IPv6 address followed by a prefix length (10.0.0.0/8, 2001:db8::/32), it evaluates to a
+// netip.Prefix
+func CIDR[P any]() ebnf.Pattern[rune, P] {
+	v4 := concatenation.New[rune, P](ipv4[P](), vector.Runes[P]("/"), alternation.New[rune, P](
+		concatenation.New[rune, P](vector.Runes[P]("3"), class[P]("[0-2]")),
+		concatenation.New[rune, P](class[P]("[12]"), digit[P]()),
+		digit[P](),
+	))
+	v4.SetID(IPv4CIDRID)
+	v4.SetEvalFunc(evalPrefix[P])
+
+	v6 := concatenation.New[rune, P](ipv6[P](), vector.Runes[P]("/"), alternation.New[rune, P](
+		concatenation.New[rune, P](vector.Runes[P]("12"), class[P]("[0-8]")),
+		concatenation.New[rune, P](vector.Runes[P]("1"), class[P]("[01]"), digit[P]()),
+		concatenation.New[rune, P](class[P]("[1-9]"), digit[P]()),
+		digit[P](),
+	))
+	v6.SetID(IPv6CIDRID)
+	v6.SetEvalFunc(evalPrefix[P])
+
+	return alternation.New[rune, P](v4, v6)
+}
+
+func evalAddr[P any](m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+	s, err := ebnf.Text(m, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return netip.ParseAddr(s)
+}
+
+func evalPrefix[P any](m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+	s, err := ebnf.Text(m, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return netip.ParsePrefix(s)
+}
+
+// ipv4 matches four decimal octets
+func ipv4[P any]() *concatenation.Concatenation[rune, P] {
+	return concatenation.New[rune, P](
+		octet[P](), vector.Runes[P]("."), octet[P](), vector.Runes[P]("."), octet[P](), vector.Runes[P]("."), octet[P](),
+	)
+}
+
+// octet matches a decimal octet 0-255, longer forms are tried first
+func octet[P any]() ebnf.Pattern[rune, P] {
+	return alternation.New[rune, P](
+		concatenation.New[rune, P](vector.Runes[P]("25"), class[P]("[0-5]")),
+		concatenation.New[rune, P](vector.Runes[P]("2"), class[P]("[0-4]"), digit[P]()),
+		concatenation.New[rune, P](vector.Runes[P]("1"), digit[P](), digit[P]()),
+		concatenation.New[rune, P](class[P]("[1-9]"), digit[P]()),
+		digit[P](),
+	)
+}
+
+// ipv6 matches the alternatives of the RFC 3986 IPv6address rule. The optional h16 groups before "::" are matched
+// as h16 *(":" h16) instead of *(h16 ":") h16, so the repetition does not consume the first colon of "::"
+func ipv6[P any]() *alternation.Alternation[rune, P] {
+	h16 := func() ebnf.Pattern[rune, P] {
+		return repetition.New[rune, P](runeclass.HexDigit[P](), 1, 4)
+	}
+
+	// n times h16 ":"
+	groups := func(n int) ebnf.Pattern[rune, P] {
+		return repetition.New[rune, P](concatenation.New[rune, P](h16(), vector.Runes[P](":")), n, n)
+	}
+
+	ls32 := func() ebnf.Pattern[rune, P] {
+		return alternation.New[rune, P](concatenation.New[rune, P](h16(), vector.Runes[P](":"), h16()), ipv4[P]())
+	}
+
+	// Up to n + 1 groups before "::"
+	prefix := func(n int) ebnf.Pattern[rune, P] {
+		if n == 0 {
+			// A repetition max of 0 is unbounded
+			return repetition.New[rune, P](h16(), 0, 1)
+		}
+
+		return repetition.New[rune, P](concatenation.New[rune, P](h16(), repetition.New[rune, P](
+			concatenation.New[rune, P](vector.Runes[P](":"), h16()), 0, n,
+		)), 0, 1)
+	}
+
+	compressed := func(n int, tail ...ebnf.Pattern[rune, P]) ebnf.Pattern[rune, P] {
+		return concatenation.New[rune, P](append([]ebnf.Pattern[rune, P]{prefix(n), vector.Runes[P]("::")}, tail...)...)
+	}
+
+	return alternation.New[rune, P](
+		concatenation.New[rune, P](groups(6), ls32()),
+		concatenation.New[rune, P](vector.Runes[P]("::"), groups(5), ls32()),
+		compressed(0, groups(4), ls32()),
+		compressed(1, groups(3), ls32()),
+		compressed(2, groups(2), ls32()),
+		compressed(3, groups(1), ls32()),
+		compressed(4, ls32()),
+		compressed(5, h16()),
+		compressed(6),
+	)
+}
+
+func digit[P any]() ebnf.Pattern[rune, P] {
+	return runeclass.ASCIIDigit[P]()
+}
+
+func class[P any](c string) ebnf.Pattern[rune, P] {
+	return runeclass.MustClass[P](c)
+}
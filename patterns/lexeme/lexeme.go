@@ -0,0 +1,113 @@
+package lexeme
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"io"
+)
+
+// Lexeme matches its pattern surrounded by trivia, the skip pattern is matched repeatedly before and after the
+// pattern. Skipped input is validated only, it is not captured in the match tree. Without skip pattern the default
+// skip pattern of the reader is used (see ebnf.WithSkip and ebnf.Grammar.SetSkip)
+type Lexeme[T, P any] struct {
+	*ebnf.BasePattern[T, P]
+	pattern ebnf.Pattern[T, P]
+	skip    ebnf.Pattern[T, P]
+}
+
+// New creates a new lexeme pattern, skip can be nil to use the default skip pattern of the reader
+func New[T, P any](pattern ebnf.Pattern[T, P], skip ebnf.Pattern[T, P]) *Lexeme[T, P] {
+	l := &Lexeme[T, P]{
+		BasePattern: ebnf.NewBasePattern[T, P](),
+		pattern:     pattern,
+		skip:        skip,
+	}
+
+	l.SetSelf(l)
+
+	return l
+}
+
+// Pattern returns the pattern of the lexeme
+func (l *Lexeme[T, P]) Pattern() ebnf.Pattern[T, P] {
+	return l.pattern
+}
+
+// Skip returns the skip pattern, nil if the default skip pattern of the reader is used
+func (l *Lexeme[T, P]) Skip() ebnf.Pattern[T, P] {
+	return l.skip
+}
+
+// Match skips trivia, matches the pattern and skips trivia again. The match of the pattern is returned as is, so it
+// does not include the skipped input
+func (l *Lexeme[T, P]) Match(r ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], error) {
+	skip := l.skip
+	if skip == nil {
+		skip = ebnf.DefaultSkip(r)
+	}
+
+	err := ebnf.SkipTrivia(skip, r)
+	if err != nil {
+		return false, nil, err
+	}
+
+	matched, result, err := ebnf.MatchPattern(l.pattern, r)
+	if err != nil || !matched {
+		return false, nil, err
+	}
+
+	err = ebnf.SkipTrivia(skip, r)
+	if err != nil {
+		return false, nil, err
+	}
+
+	return true, result, nil
+}
+
+// Generate lets the pattern generate to writer followed by the skip pattern, if set, to separate lexemes
+func (l *Lexeme[T, P]) Generate(w ebnf.Writer[T]) error {
+	err := ebnf.GeneratePattern(l.pattern, w)
+	if err != nil {
+		return err
+	}
+
+	if l.skip == nil {
+		return nil
+	}
+
+	return ebnf.GeneratePattern(l.skip, w)
+}
+
+// Print prints the pattern as child, trivia is not part of the EBNF
+func (l *Lexeme[T, P]) Print(w io.Writer) error {
+	return l.pattern.PrintAsChild(w)
+}
+
+// Clone returns a shallow copy of the lexeme
+func (l *Lexeme[T, P]) Clone() ebnf.Pattern[T, P] {
+	c := *l
+	c.BasePattern = l.BasePattern.Copy()
+
+	return c.SetSelf(&c)
+}
+
+// Rebind replaces the pattern and skip pattern with the result of f
+func (l *Lexeme[T, P]) Rebind(f func(ebnf.Pattern[T, P]) ebnf.Pattern[T, P]) {
+	l.pattern = f(l.pattern)
+
+	if l.skip != nil {
+		l.skip = f(l.skip)
+	}
+}
+
+// First reports if obj can start the skip pattern or the pattern, without own skip pattern any object can start the
+// lexeme because the default skip pattern is not known
+func (l *Lexeme[T, P]) First(obj T) (bool, bool) {
+	if l.skip == nil {
+		return true, true
+	}
+
+	skipFirst, _ := ebnf.First(l.skip, obj)
+	first, nullable := ebnf.First(l.pattern, obj)
+
+	return skipFirst || first, nullable
+}
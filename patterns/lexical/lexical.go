@@ -0,0 +1,212 @@
+package lexical
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/exception"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/runeclass"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Pattern IDs of the literal patterns
+const (
+	IdentifierID   = "identifier"
+	IntID          = "int_lit"
+	FloatID        = "float_lit"
+	StringID       = "string_lit"
+	LineCommentID  = "line_comment"
+	BlockCommentID = "block_comment"
+	NumberID       = "number"
+)
+
+// text is the generator table for free text (strings and comments), it contains no quotes, escapes or comment
+// delimiters so generated text never ends a literal early
+var text = &unicode.RangeTable{R16: []unicode.Range16{
+	{Lo: ' ', Hi: ' ', Stride: 1},
+	{Lo: '0', Hi: '9', Stride: 1},
+	{Lo: 'A', Hi: 'Z', Stride: 1},
+	{Lo: 'a', Hi: 'z', Stride: 1},
+}}
+
+// word is the generator table for identifiers
+var word = &unicode.RangeTable{R16: []unicode.Range16{
+	{Lo: 'A', Hi: 'Z', Stride: 1},
+	{Lo: '_', Hi: '_', Stride: 1},
+	{Lo: 'a', Hi: 'z', Stride: 1},
+}}
+
+// Identifier matches a letter or underscore followed by letters, digits and underscores, it evaluates to a string
+func Identifier[P any]() ebnf.Pattern[rune, P] {
+	first := runeclass.FromFunc[P](func(c rune) bool {
+		return c == '_' || unicode.IsLetter(c)
+	}, word, `[\p{L}_]`, "letter")
+	next := runeclass.FromFunc[P](func(c rune) bool {
+		return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+	}, word, `[\p{L}\p{Nd}_]`, "letter or digit")
+
+	id := concatenation.New[rune, P](first, repetition.New[rune, P](next, 0, 0))
+	id.SetID(IdentifierID)
+	id.SetEvalFunc(evalText[P])
+
+	return id
+}
+
+// Int matches a decimal, hexadecimal (0x), octal (0o or 0) or binary (0b) integer with optional underscores between
+// digits as in Go, it evaluates to an int64
+func Int[P any]() ebnf.Pattern[rune, P] {
+	i := alternation.New[rune, P](
+		prefixed[P]("xX", runeclass.HexDigit[P]()),
+		prefixed[P]("bB", digits[P](binDigit[P]())),
+		prefixed[P]("oO", runeclass.OctDigit[P]()),
+		decimal[P](),
+	)
+	i.SetID(IntID)
+	i.SetEvalFunc(func(m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+		s, err := ebnf.Text(m, r)
+		if err != nil {
+			return nil, err
+		}
+
+		// Base 0 handles the prefixes and underscores, a leading 0 without prefix is octal
+		return strconv.ParseInt(s, 0, 64)
+	})
+
+	return i
+}
+
+// Float matches a decimal float with a fraction and/or an exponent (1.5, .5, 1., 1e3, 1_000.5e-3), it evaluates to
+// a float64
+func Float[P any]() ebnf.Pattern[rune, P] {
+	dot := char[P]('.')
+	exponent := concatenation.New[rune, P](
+		set[P]("eE"),
+		repetition.New[rune, P](set[P]("+-"), 0, 1),
+		digits[P](runeclass.ASCIIDigit[P]()),
+	)
+
+	f := alternation.New[rune, P](
+		concatenation.New[rune, P](
+			digits[P](runeclass.ASCIIDigit[P]()), dot,
+			repetition.New[rune, P](digits[P](runeclass.ASCIIDigit[P]()), 0, 1),
+			repetition.New[rune, P](exponent, 0, 1),
+		),
+		concatenation.New[rune, P](digits[P](runeclass.ASCIIDigit[P]()), exponent),
+		concatenation.New[rune, P](dot, digits[P](runeclass.ASCIIDigit[P]()), repetition.New[rune, P](exponent, 0, 1)),
+	)
+	f.SetID(FloatID)
+	f.SetEvalFunc(func(m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+		s, err := ebnf.Text(m, r)
+		if err != nil {
+			return nil, err
+		}
+
+		return strconv.ParseFloat(s, 64)
+	})
+
+	return f
+}
+
+// Number matches a float or an integer, floats are tried first so the integer part of a float is not matched on its
+// own
+func Number[P any]() ebnf.Pattern[rune, P] {
+	return alternation.New[rune, P](Float[P](), Int[P]())
+}
+
+// LineComment matches prefix followed by the rest of the line, the newline is not part of the comment. It evaluates
+// to the comment text including prefix
+func LineComment[P any](prefix string) ebnf.Pattern[rune, P] {
+	rest := runeclass.FromFunc[P](func(c rune) bool { return c != '\n' }, text, `[^\n]`, "character")
+
+	c := concatenation.New[rune, P](vector.Runes[P](prefix), repetition.New[rune, P](rest, 0, 0))
+	c.SetID(LineCommentID)
+	c.SetEvalFunc(evalText[P])
+
+	return c
+}
+
+// BlockComment matches open followed by anything up to and including the first close, block comments do not nest.
+// It evaluates to the comment text including delimiters
+func BlockComment[P any](open string, close string) ebnf.Pattern[rune, P] {
+	body := exception.New[rune, P](runeclass.FromFunc[P](func(rune) bool { return true }, text, ".", "character"), vector.Runes[P](close))
+
+	c := concatenation.New[rune, P](vector.Runes[P](open), repetition.New[rune, P](body, 0, 0), vector.Runes[P](close))
+	c.SetID(BlockCommentID)
+	c.SetEvalFunc(evalText[P])
+
+	return c
+}
+
+// evalText evaluates a match to the matched text
+func evalText[P any](m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+	return ebnf.Text(m, r)
+}
+
+// decimal matches 0 or a decimal number not starting with 0
+func decimal[P any]() ebnf.Pattern[rune, P] {
+	nonZero := runeclass.FromTable[P](&unicode.RangeTable{R16: []unicode.Range16{{Lo: '1', Hi: '9', Stride: 1}}}, "[1-9]", "digit")
+
+	return alternation.New[rune, P](
+		concatenation.New[rune, P](nonZero, repetition.New[rune, P](concatenation.New[rune, P](
+			repetition.New[rune, P](char[P]('_'), 0, 1), runeclass.ASCIIDigit[P](),
+		), 0, 0)),
+		char[P]('0'),
+	)
+}
+
+// prefixed matches 0 followed by one of the prefix runes and digits, octal also matches a 0 followed by digits
+// without prefix
+func prefixed[P any](prefix string, digit ebnf.Pattern[rune, P]) ebnf.Pattern[rune, P] {
+	body := concatenation.New[rune, P](repetition.New[rune, P](char[P]('_'), 0, 1), digits[P](digit))
+
+	if prefix == "oO" {
+		return concatenation.New[rune, P](char[P]('0'), alternation.New[rune, P](
+			concatenation.New[rune, P](set[P](prefix), body),
+			body,
+		))
+	}
+
+	return concatenation.New[rune, P](char[P]('0'), set[P](prefix), body)
+}
+
+// digits matches one or more digits with optional single underscores between them
+func digits[P any](digit ebnf.Pattern[rune, P]) ebnf.Pattern[rune, P] {
+	return concatenation.New[rune, P](digit, repetition.New[rune, P](concatenation.New[rune, P](
+		repetition.New[rune, P](char[P]('_'), 0, 1), digit,
+	), 0, 0))
+}
+
+func binDigit[P any]() ebnf.Pattern[rune, P] {
+	return set[P]("01")
+}
+
+// char matches a single rune
+func char[P any](c rune) *entity.Entity[rune, P] {
+	e := entity.New[rune, P](func(obj rune) bool { return obj == c }).SetGenerateFunc(func() rune { return c })
+	e.SetPrintOutput(strconv.Quote(string(c)))
+
+	return e
+}
+
+// set matches one of the runes of s
+func set[P any](s string) *entity.Entity[rune, P] {
+	rs := []rune(s)
+	table := &unicode.RangeTable{}
+
+	for _, c := range rs {
+		if c > unicode.MaxLatin1 {
+			table.R32 = append(table.R32, unicode.Range32{Lo: uint32(c), Hi: uint32(c), Stride: 1})
+		} else {
+			table.R16 = append(table.R16, unicode.Range16{Lo: uint16(c), Hi: uint16(c), Stride: 1})
+		}
+	}
+
+	return runeclass.FromFunc[P](func(c rune) bool {
+		return strings.ContainsRune(s, c)
+	}, table, "["+strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), "]", `\]`)+"]", "one of "+strconv.Quote(s))
+}
@@ -0,0 +1,198 @@
+package lexical
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/runeclass"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// NumberConfig configures a number pattern created with LocaleNumber
+type NumberConfig struct {
+	groups       string
+	decimal      rune
+	plus         string
+	minus        string
+	exponent     bool
+	strictGroups bool
+	trailingSign bool
+	rational     bool
+}
+
+// NewNumberConfig creates a number configuration with a . decimal mark, + and - signs, exponents and no group
+// separators
+func NewNumberConfig() *NumberConfig {
+	return &NumberConfig{
+		decimal:  '.',
+		plus:     "+",
+		minus:    "-",
+		exponent: true,
+	}
+}
+
+// SetGroupSeparators sets the runes that separate digit groups in the integer part (i.e. ',' or '.' or ' ' and
+// '\u00a0')
+func (c *NumberConfig) SetGroupSeparators(separators ...rune) *NumberConfig {
+	c.groups = string(separators)
+	return c
+}
+
+// SetDecimalMark sets the rune that separates the integer part from the fraction
+func (c *NumberConfig) SetDecimalMark(mark rune) *NumberConfig {
+	c.decimal = mark
+	return c
+}
+
+// SetSigns sets the runes accepted as plus and minus sign (i.e. "-−" to accept the Unicode minus sign)
+func (c *NumberConfig) SetSigns(plus string, minus string) *NumberConfig {
+	c.plus = plus
+	c.minus = minus
+
+	return c
+}
+
+// SetExponent turns exponents (e or E followed by an optionally signed integer) on or off
+func (c *NumberConfig) SetExponent(exponent bool) *NumberConfig {
+	c.exponent = exponent
+	return c
+}
+
+// SetStrictGroups requires groups of exactly three digits after the first group of one to three digits
+func (c *NumberConfig) SetStrictGroups(strict bool) *NumberConfig {
+	c.strictGroups = strict
+	return c
+}
+
+// SetTrailingSign accepts the sign after the number instead of before it (123-), as exported by some accounting
+// systems
+func (c *NumberConfig) SetTrailingSign(trailing bool) *NumberConfig {
+	c.trailingSign = trailing
+	return c
+}
+
+// SetRational makes the pattern evaluate to a *big.Rat instead of a float64, so decimal fractions are exact
+func (c *NumberConfig) SetRational(rational bool) *NumberConfig {
+	c.rational = rational
+	return c
+}
+
+// LocaleNumber creates a number pattern from a configuration, it evaluates to a float64 or to a *big.Rat if the
+// configuration is rational. Later changes to the configuration do not affect the pattern
+func LocaleNumber[P any](config *NumberConfig) ebnf.Pattern[rune, P] {
+	c := *config
+
+	digit := func() ebnf.Pattern[rune, P] { return runeclass.ASCIIDigit[P]() }
+	digits := func() ebnf.Pattern[rune, P] { return repetition.New[rune, P](digit(), 1, 0) }
+
+	var integer ebnf.Pattern[rune, P] = digits()
+
+	if c.groups != "" {
+		var grouped ebnf.Pattern[rune, P]
+
+		if c.strictGroups {
+			grouped = concatenation.New[rune, P](repetition.New[rune, P](digit(), 1, 3), repetition.New[rune, P](
+				concatenation.New[rune, P](set[P](c.groups), repetition.New[rune, P](digit(), 3, 3)), 1, 0,
+			))
+		} else {
+			grouped = concatenation.New[rune, P](digits(), repetition.New[rune, P](
+				concatenation.New[rune, P](set[P](c.groups), digits()), 1, 0,
+			))
+		}
+
+		// Numbers without separators are always accepted
+		integer = alternation.New[rune, P](grouped, digits())
+	}
+
+	fraction := concatenation.New[rune, P](char[P](c.decimal), digits())
+
+	mantissa := alternation.New[rune, P](
+		concatenation.New[rune, P](integer, repetition.New[rune, P](fraction, 0, 1)),
+		fraction,
+	)
+
+	var signs ebnf.Pattern[rune, P]
+	if s := c.plus + c.minus; s != "" {
+		signs = repetition.New[rune, P](set[P](s), 0, 1)
+	}
+
+	n := concatenation.New[rune, P]()
+
+	if signs != nil && !c.trailingSign {
+		n.Add("", signs)
+	}
+
+	n.Add("", mantissa)
+
+	if c.exponent {
+		n.Add("", repetition.New[rune, P](concatenation.New[rune, P](
+			set[P]("eE"), repetition.New[rune, P](set[P]("+-"), 0, 1), digits(),
+		), 0, 1))
+	}
+
+	if signs != nil && c.trailingSign {
+		n.Add("", signs)
+	}
+
+	n.SetID(NumberID)
+	n.SetEvalFunc(func(m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+		s, err := ebnf.Text(m, r)
+		if err != nil {
+			return nil, err
+		}
+
+		s = c.Normalize(s)
+
+		if c.rational {
+			v, ok := new(big.Rat).SetString(s)
+			if !ok {
+				return nil, strconv.ErrSyntax
+			}
+
+			return v, nil
+		}
+
+		return strconv.ParseFloat(s, 64)
+	})
+
+	return n
+}
+
+// Normalize converts a number matched by a pattern with this configuration to the notation of strconv.ParseFloat:
+// group separators are removed, the decimal mark becomes . and the sign a leading -
+func (c *NumberConfig) Normalize(s string) string {
+	var (
+		b        strings.Builder
+		negative bool
+		exponent bool
+		prev     rune
+	)
+
+	for _, r := range s {
+		switch {
+		case r == 'e' || r == 'E':
+			exponent = true
+			b.WriteRune('e')
+		case exponent && (prev == 'e' || prev == 'E') && (r == '+' || r == '-'):
+			b.WriteRune(r)
+		case strings.ContainsRune(c.minus, r):
+			negative = true
+		case strings.ContainsRune(c.plus, r), !exponent && strings.ContainsRune(c.groups, r):
+		case !exponent && r == c.decimal:
+			b.WriteRune('.')
+		default:
+			b.WriteRune(r)
+		}
+
+		prev = r
+	}
+
+	if negative {
+		return "-" + b.String()
+	}
+
+	return b.String()
+}
@@ -0,0 +1,339 @@
+package lexical
+
+import (
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/runeclass"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// StringConfig configures a quoted string pattern created with QuotedString
+type StringConfig struct {
+	open      rune
+	close     rune
+	escape    rune
+	escapes   map[rune]rune
+	hex       bool
+	unicode   bool
+	octal     bool
+	multiline bool
+	doubling  bool
+}
+
+// NewStringConfig creates a string configuration for strings delimited by quote with the Go escapes: \a \b \f \n \r
+// \t \v \\ \quote, \x with 2, \u with 4 and \U with 8 hex digits and \ with 3 octal digits. Strings are single line
+func NewStringConfig(quote rune) *StringConfig {
+	return &StringConfig{
+		open:   quote,
+		close:  quote,
+		escape: '\\',
+		escapes: map[rune]rune{
+			'a': '\a', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t', 'v': '\v', '\\': '\\', quote: quote,
+		},
+		hex:     true,
+		unicode: true,
+		octal:   true,
+	}
+}
+
+// SetQuotes sets different open and close quotes (i.e. « and »), the escape sequence for the close quote replaces
+// the one for the previous close quote
+func (c *StringConfig) SetQuotes(open rune, close rune) *StringConfig {
+	delete(c.escapes, c.close)
+
+	if c.escape != 0 {
+		c.escapes[close] = close
+	}
+
+	c.open = open
+	c.close = close
+
+	return c
+}
+
+// SetEscape sets the escape rune, 0 turns off escape sequences. If the previous escape rune could be escaped the
+// new escape rune can be escaped instead
+func (c *StringConfig) SetEscape(escape rune) *StringConfig {
+	if _, ok := c.escapes[c.escape]; ok {
+		delete(c.escapes, c.escape)
+
+		if escape != 0 {
+			c.escapes[escape] = escape
+		}
+	}
+
+	c.escape = escape
+
+	return c
+}
+
+// SetEscapes replaces the single rune escape sequences, each key following the escape rune is replaced by its value
+func (c *StringConfig) SetEscapes(escapes map[rune]rune) *StringConfig {
+	c.escapes = map[rune]rune{}
+
+	for k, v := range escapes {
+		c.escapes[k] = v
+	}
+
+	return c
+}
+
+// SetHex turns \x escapes with 2 hex digits on or off, the value is a byte
+func (c *StringConfig) SetHex(hex bool) *StringConfig {
+	c.hex = hex
+	return c
+}
+
+// SetUnicode turns \u escapes with 4 and \U escapes with 8 hex digits on or off, the value is a Unicode code point
+func (c *StringConfig) SetUnicode(unicode bool) *StringConfig {
+	c.unicode = unicode
+	return c
+}
+
+// SetOctal turns escapes with 3 octal digits on or off, the value is a byte
+func (c *StringConfig) SetOctal(octal bool) *StringConfig {
+	c.octal = octal
+	return c
+}
+
+// SetMultiline allows newlines in strings
+func (c *StringConfig) SetMultiline(multiline bool) *StringConfig {
+	c.multiline = multiline
+	return c
+}
+
+// SetDoubling allows a doubled close quote as escape for the quote, as in SQL strings
+func (c *StringConfig) SetDoubling(doubling bool) *StringConfig {
+	c.doubling = doubling
+	return c
+}
+
+// String matches a single line string delimited by quote with the Go escapes (see NewStringConfig), it evaluates to
+// the unescaped string
+func String[P any](quote rune) ebnf.Pattern[rune, P] {
+	return QuotedString[P](NewStringConfig(quote))
+}
+
+// Unquote removes the quotes from a string matched by String and replaces the escape sequences
+func Unquote(s string, quote rune) (string, error) {
+	return NewStringConfig(quote).Unquote(s)
+}
+
+// QuotedString creates a quoted string pattern from a configuration, it evaluates to the unescaped string. Later
+// changes to the configuration do not affect the pattern
+func QuotedString[P any](config *StringConfig) ebnf.Pattern[rune, P] {
+	c := *config
+	c.escapes = map[rune]rune{}
+
+	for k, v := range config.escapes {
+		c.escapes[k] = v
+	}
+
+	var alternatives []ebnf.Pattern[rune, P]
+
+	if c.doubling {
+		alternatives = append(alternatives, concatenation.New[rune, P](char[P](c.close), char[P](c.close)))
+	}
+
+	if c.escape != 0 {
+		var sequences []ebnf.Pattern[rune, P]
+
+		if len(c.escapes) > 0 {
+			sequences = append(sequences, set[P](string(c.escapeKeys())))
+		}
+
+		hex := func(n int) ebnf.Pattern[rune, P] {
+			return repetition.New[rune, P](runeclass.HexDigit[P](), n, n)
+		}
+
+		if c.hex {
+			sequences = append(sequences, concatenation.New[rune, P](char[P]('x'), hex(2)))
+		}
+
+		if c.unicode {
+			// \U only matches code points up to 10FFFF, the first digits are generated so surrogates are never
+			// generated
+			sequences = append(sequences,
+				concatenation.New[rune, P](char[P]('u'), hexDigit[P]("Dd"), hex(3)),
+				concatenation.New[rune, P](char[P]('U'), char[P]('0'), char[P]('0'), alternation.New[rune, P](
+					concatenation.New[rune, P](char[P]('1'), char[P]('0'), hex(4)),
+					concatenation.New[rune, P](char[P]('0'), hexDigit[P]("0"), hex(4)),
+				)),
+			)
+		}
+
+		if c.octal {
+			sequences = append(sequences, repetition.New[rune, P](runeclass.OctDigit[P](), 3, 3))
+		}
+
+		if len(sequences) > 0 {
+			alternatives = append(alternatives, concatenation.New[rune, P](char[P](c.escape), alternation.New[rune, P](sequences...)))
+		}
+	}
+
+	excluded := []rune{c.close}
+	if c.escape != 0 {
+		excluded = append(excluded, c.escape)
+	}
+
+	if !c.multiline {
+		excluded = append(excluded, '\n')
+	}
+
+	plain := runeclass.FromFunc[P](func(r rune) bool {
+		for _, e := range excluded {
+			if r == e {
+				return false
+			}
+		}
+
+		return true
+	}, text, fmt.Sprintf("[^%s]", strings.Trim(strconv.Quote(string(excluded)), `"`)), "character")
+
+	alternatives = append(alternatives, plain)
+
+	s := concatenation.New[rune, P](char[P](c.open), repetition.New[rune, P](alternation.New[rune, P](alternatives...), 0, 0), char[P](c.close))
+	s.SetID(StringID)
+	s.SetEvalFunc(func(m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+		s, err := ebnf.Text(m, r)
+		if err != nil {
+			return nil, err
+		}
+
+		return c.Unquote(s)
+	})
+
+	return s
+}
+
+// Unquote removes the quotes from a string matched by a pattern with this configuration and replaces the escape
+// sequences
+func (c *StringConfig) Unquote(s string) (string, error) {
+	rs := []rune(s)
+	if len(rs) < 2 || rs[0] != c.open || rs[len(rs)-1] != c.close {
+		return "", strconv.ErrSyntax
+	}
+
+	rs = rs[1 : len(rs)-1]
+
+	var b strings.Builder
+
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+
+		switch {
+		case c.doubling && r == c.close:
+			if i+1 >= len(rs) || rs[i+1] != c.close {
+				return "", strconv.ErrSyntax
+			}
+
+			b.WriteRune(c.close)
+			i++
+		case c.escape != 0 && r == c.escape:
+			n, err := c.unescape(rs[i+1:], &b)
+			if err != nil {
+				return "", err
+			}
+
+			i += n
+		case r == '\n' && !c.multiline, r == c.close:
+			return "", strconv.ErrSyntax
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String(), nil
+}
+
+// unescape writes the value of the escape sequence at the start of rs, it returns the number of runes consumed
+func (c *StringConfig) unescape(rs []rune, b *strings.Builder) (int, error) {
+	if len(rs) == 0 {
+		return 0, strconv.ErrSyntax
+	}
+
+	if v, ok := c.escapes[rs[0]]; ok {
+		b.WriteRune(v)
+		return 1, nil
+	}
+
+	number := func(n int, base int) (uint64, error) {
+		if len(rs) < n+1 {
+			return 0, strconv.ErrSyntax
+		}
+
+		return strconv.ParseUint(string(rs[1:n+1]), base, 32)
+	}
+
+	switch {
+	case c.hex && rs[0] == 'x':
+		v, err := number(2, 16)
+		if err != nil {
+			return 0, strconv.ErrSyntax
+		}
+
+		b.WriteByte(byte(v))
+
+		return 3, nil
+	case c.unicode && (rs[0] == 'u' || rs[0] == 'U'):
+		n := 4
+		if rs[0] == 'U' {
+			n = 8
+		}
+
+		v, err := number(n, 16)
+		if err != nil || !utf8.ValidRune(rune(v)) {
+			return 0, strconv.ErrSyntax
+		}
+
+		b.WriteRune(rune(v))
+
+		return n + 1, nil
+	case c.octal && rs[0] >= '0' && rs[0] <= '7':
+		// The first digit is part of the number
+		rs = append([]rune{0}, rs...)
+
+		v, err := number(3, 8)
+		if err != nil || v > 255 {
+			return 0, strconv.ErrSyntax
+		}
+
+		b.WriteByte(byte(v))
+
+		return 3, nil
+	}
+
+	return 0, strconv.ErrSyntax
+}
+
+// hexDigit matches a hex digit, generated digits are not in exclude
+func hexDigit[P any](exclude string) ebnf.Pattern[rune, P] {
+	gen := &unicode.RangeTable{}
+
+	for _, r := range "0123456789ABCDEFabcdef" {
+		if !strings.ContainsRune(exclude, r) {
+			gen.R16 = append(gen.R16, unicode.Range16{Lo: uint16(r), Hi: uint16(r), Stride: 1})
+		}
+	}
+
+	return runeclass.FromFunc[P](runeclass.HexDigit[P]().MatchFunc(), gen, "[0-9A-Fa-f]", "hexadecimal digit")
+}
+
+// escapeKeys returns the single rune escapes in order
+func (c *StringConfig) escapeKeys() []rune {
+	keys := make([]rune, 0, len(c.escapes))
+	for k := range c.escapes {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	return keys
+}
@@ -0,0 +1,176 @@
+package padding
+
+import (
+	"errors"
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"io"
+)
+
+// ErrNoOffset is returned when the offset of the reader position or the writer is not known, see ebnf.Offsetter
+var ErrNoOffset = errors.New("offset is not known")
+
+// CountFunc returns the number of padding objects at offset
+type CountFunc func(offset int) int
+
+// Padding matches a number of padding objects computed from the offset of the reader position, the pad pattern must
+// match a single object. The offset is taken from the position with ebnf.PosOffset, so int positions (i.e. the byte
+// reader) and positions implementing ebnf.Offsetter are supported. Generation takes the offset from the writer with
+// ebnf.WriterOffset
+type Padding[T, P any] struct {
+	*ebnf.BasePattern[T, P]
+	pad   ebnf.Pattern[T, P]
+	count CountFunc
+	label string
+}
+
+// New creates a new padding pattern, if pad is nil any object is padding
+func New[T, P any](count CountFunc, pad ebnf.Pattern[T, P]) *Padding[T, P] {
+	if pad == nil {
+		pad = entity.New[T, P](func(T) bool { return true }).SetExpectation("padding")
+	}
+
+	p := &Padding[T, P]{
+		BasePattern: ebnf.NewBasePattern[T, P](),
+		pad:         pad,
+		count:       count,
+		label:       "? padding ? * ",
+	}
+
+	p.SetSelf(p)
+
+	return p
+}
+
+// Align matches padding up to the next multiple of boundary, no padding is matched if the offset is already aligned
+func Align[T, P any](boundary int, pad ebnf.Pattern[T, P]) *Padding[T, P] {
+	p := New[T, P](func(offset int) int {
+		return (boundary - offset%boundary) % boundary
+	}, pad)
+	p.label = fmt.Sprintf("? align %d ? * ", boundary)
+
+	return p
+}
+
+// Fixed matches exactly k padding objects
+func Fixed[T, P any](k int, pad ebnf.Pattern[T, P]) *Padding[T, P] {
+	p := New[T, P](func(int) int { return k }, pad)
+	p.label = fmt.Sprintf("%d * ", k)
+
+	return p
+}
+
+// Pad returns the pad pattern
+func (p *Padding[T, P]) Pad() ebnf.Pattern[T, P] {
+	return p.pad
+}
+
+// Count returns the number of padding objects at offset
+func (p *Padding[T, P]) Count(offset int) int {
+	return max(p.count(offset), 0)
+}
+
+// Match matches the number of padding objects for the offset of the current position
+func (p *Padding[T, P]) Match(r ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], error) {
+	beginPos, err := r.Position()
+	if ebnf.IsStreamError(err) {
+		return false, nil, err
+	}
+
+	offset, ok := ebnf.PosOffset(beginPos)
+	if !ok {
+		return false, nil, fmt.Errorf("%w: position %T", ErrNoOffset, beginPos)
+	}
+
+	n := p.Count(offset)
+
+	if ebnf.IsValidating(r) {
+		for i := 0; i < n; i++ {
+			matched, _, err := ebnf.MatchPattern(p.pad, r)
+			if err != nil || !matched {
+				return false, nil, err
+			}
+		}
+
+		return true, nil, nil
+	}
+
+	var matches []*ebnf.Match[T, P]
+
+	for i := 0; i < n; i++ {
+		matched, result, err := ebnf.MatchComponent(p.pad, r)
+		if err != nil {
+			return false, nil, err
+		}
+
+		if !matched {
+			endPos, err := r.Position()
+			if ebnf.IsStreamError(err) {
+				return false, nil, err
+			}
+
+			p.Logger().LogMismatch(ebnf.NewMismatch(p, beginPos, endPos, nil, matches))
+
+			return false, nil, nil
+		}
+
+		matches, err = ebnf.Lower(matches, result, r)
+		if err != nil {
+			return false, nil, err
+		}
+	}
+
+	endPos, err := r.Position()
+	if ebnf.IsStreamError(err) {
+		return false, nil, err
+	}
+
+	return true, ebnf.NewMatch(p, beginPos, endPos, nil, matches), nil
+}
+
+// Generate generates the number of padding objects for the offset of the writer
+func (p *Padding[T, P]) Generate(w ebnf.Writer[T]) error {
+	offset, ok := ebnf.WriterOffset(w)
+	if !ok {
+		return fmt.Errorf("%w: writer %T", ErrNoOffset, w)
+	}
+
+	for i := p.Count(offset); i > 0; i-- {
+		err := ebnf.GeneratePattern(p.pad, w)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Print prints the padding as EBNF repetition, a computed count is printed as special sequence (? align 4 ? * pad)
+func (p *Padding[T, P]) Print(w io.Writer) error {
+	_, err := io.WriteString(w, p.label)
+	if err != nil {
+		return err
+	}
+
+	return p.pad.PrintAsChild(w)
+}
+
+// Clone returns a shallow copy of the padding
+func (p *Padding[T, P]) Clone() ebnf.Pattern[T, P] {
+	c := *p
+	c.BasePattern = p.BasePattern.Copy()
+
+	return c.SetSelf(&c)
+}
+
+// Rebind replaces the pad pattern with the result of f
+func (p *Padding[T, P]) Rebind(f func(ebnf.Pattern[T, P]) ebnf.Pattern[T, P]) {
+	p.pad = f(p.pad)
+}
+
+// First reports if obj can start the pad pattern, padding can be empty
+func (p *Padding[T, P]) First(obj T) (bool, bool) {
+	first, _ := ebnf.First(p.pad, obj)
+	return first, true
+}
@@ -0,0 +1,332 @@
+package precedence
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"io"
+)
+
+// Associativity of a binary operator
+type Associativity int
+
+const (
+	// Left associative operators group from the left: a - b - c is (a - b) - c
+	Left Associativity = iota
+	// Right associative operators group from the right: a ^ b ^ c is a ^ (b ^ c)
+	Right
+)
+
+// Operator is a binary operator of the operator table, operators with a higher precedence bind tighter
+type Operator[T, P any] struct {
+	Pattern       ebnf.Pattern[T, P]
+	Precedence    int
+	Associativity Associativity
+}
+
+// Precedence matches expressions of operands and binary operators with precedence climbing, the operand pattern
+// matches the atoms of the expression (i.e. numbers, identifiers and parenthesized expressions). The match is a tree
+// of binary matches of the precedence pattern with the components left operand, operator and right operand, nested
+// as the precedence and associativity of the operators dictate. An expression without operators is a match of the
+// precedence pattern with the operand as only component. Operands and operators are not lowered, so the shape of the
+// tree is fixed
+type Precedence[T, P any] struct {
+	*ebnf.BasePattern[T, P]
+	operand   ebnf.Pattern[T, P]
+	operators []Operator[T, P]
+	maxGen    int
+}
+
+// New creates a new precedence pattern, if more than one operator matches at a position the first in the table wins,
+// so longer operators sharing a prefix with shorter ones (i.e. ** and *) must come first
+func New[T, P any](operand ebnf.Pattern[T, P], operators ...Operator[T, P]) *Precedence[T, P] {
+	p := &Precedence[T, P]{
+		BasePattern: ebnf.NewBasePattern[T, P](),
+		operand:     operand,
+		operators:   operators,
+		maxGen:      3,
+	}
+
+	p.SetSelf(p)
+
+	return p
+}
+
+// Operand returns the operand pattern
+func (p *Precedence[T, P]) Operand() ebnf.Pattern[T, P] {
+	return p.operand
+}
+
+// Operators returns the operator table
+func (p *Precedence[T, P]) Operators() []Operator[T, P] {
+	return p.operators
+}
+
+// SetMaxGen sets the maximum number of operators that are generated
+func (p *Precedence[T, P]) SetMaxGen(maxGen int) *Precedence[T, P] {
+	p.maxGen = maxGen
+	return p
+}
+
+// Split returns the left operand, operator and right operand of a binary match, false if m is a single operand
+func Split[T, P any](m *ebnf.Match[T, P]) (*ebnf.Match[T, P], *ebnf.Match[T, P], *ebnf.Match[T, P], bool) {
+	if len(m.Components) != 3 {
+		return nil, nil, nil, false
+	}
+
+	return m.Components[0], m.Components[1], m.Components[2], true
+}
+
+// OperatorOf returns the operator of a binary match, nil if m is a single operand
+func (p *Precedence[T, P]) OperatorOf(m *ebnf.Match[T, P]) *Operator[T, P] {
+	_, op, _, ok := Split(m)
+	if !ok || op == nil {
+		return nil
+	}
+
+	for i := range p.operators {
+		if p.operators[i].Pattern == op.Pattern {
+			return &p.operators[i]
+		}
+	}
+
+	return nil
+}
+
+// Match matches an expression
+func (p *Precedence[T, P]) Match(r ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], error) {
+	beginPos, err := r.Position()
+	if ebnf.IsStreamError(err) {
+		return false, nil, err
+	}
+
+	matched, result, binary, err := p.climb(r, beginPos, 0)
+	if err != nil || !matched || ebnf.IsValidating(r) {
+		return matched, nil, err
+	}
+
+	if binary {
+		return true, result, nil
+	}
+
+	endPos, err := r.Position()
+	if ebnf.IsStreamError(err) {
+		return false, nil, err
+	}
+
+	return true, ebnf.NewMatch(p, beginPos, endPos, nil, []*ebnf.Match[T, P]{result}), nil
+}
+
+// climb matches an operand followed by operators with at least precedence minPrec and their right operands, binary
+// is true if the result is a binary match
+func (p *Precedence[T, P]) climb(r ebnf.Reader[T, P], beginPos P, minPrec int) (bool, *ebnf.Match[T, P], bool, error) {
+	matched, lhs, err := ebnf.MatchPattern(p.operand, r)
+	if err != nil {
+		return false, nil, false, err
+	}
+
+	if !matched {
+		endPos, err := r.Position()
+		if ebnf.IsStreamError(err) {
+			return false, nil, false, err
+		}
+
+		p.Logger().LogMismatch(ebnf.NewMismatch(p, beginPos, endPos, ebnf.NewMatch(p.operand, beginPos, endPos, nil, nil), nil))
+
+		return false, nil, false, nil
+	}
+
+	binary := false
+
+	for {
+		mark, err := ebnf.NewMarker(r)
+		if ebnf.IsStreamError(err) {
+			return false, nil, false, err
+		}
+
+		op, opMatch, err := p.operator(r, mark, minPrec)
+		if err != nil {
+			mark.Discard()
+			return false, nil, false, err
+		}
+
+		if op == nil {
+			mark.Discard()
+			break
+		}
+
+		nextPrec := op.Precedence
+		if op.Associativity == Left {
+			nextPrec++
+		}
+
+		rhsBegin, err := r.Position()
+		if ebnf.IsStreamError(err) {
+			mark.Discard()
+			return false, nil, false, err
+		}
+
+		matched, rhs, _, err := p.climb(r, rhsBegin, nextPrec)
+		if err != nil {
+			mark.Discard()
+			return false, nil, false, err
+		}
+
+		// An operator without right operand is not part of the expression
+		if !matched {
+			err = mark.Reset()
+			mark.Discard()
+
+			if err != nil {
+				return false, nil, false, err
+			}
+
+			break
+		}
+
+		mark.Discard()
+
+		if !ebnf.IsValidating(r) {
+			endPos, err := r.Position()
+			if ebnf.IsStreamError(err) {
+				return false, nil, false, err
+			}
+
+			lhs = ebnf.NewMatch(p, beginPos, endPos, nil, []*ebnf.Match[T, P]{lhs, opMatch, rhs})
+			binary = true
+		}
+	}
+
+	return true, lhs, binary, nil
+}
+
+// operator matches the first operator of the table with at least precedence minPrec, the reader is reset to mark
+// if no operator matches
+func (p *Precedence[T, P]) operator(r ebnf.Reader[T, P], mark ebnf.Marker[T, P], minPrec int) (*Operator[T, P], *ebnf.Match[T, P], error) {
+	for i := range p.operators {
+		op := &p.operators[i]
+		if op.Precedence < minPrec {
+			continue
+		}
+
+		matched, result, err := ebnf.MatchPattern(op.Pattern, r)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if matched {
+			return op, result, nil
+		}
+
+		err = mark.Reset()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return nil, nil, nil
+}
+
+// Generate writes an operand followed by a random number of operators and operands
+func (p *Precedence[T, P]) Generate(w ebnf.Writer[T]) error {
+	err := ebnf.GeneratePattern(p.operand, w)
+	if err != nil || len(p.operators) == 0 {
+		return err
+	}
+
+	n := ebnf.Repeat[T, P](w, p, 0, p.maxGen)
+	if n < 0 {
+		n = ebnf.Rand(w).Intn(p.maxGen + 1)
+	}
+
+	patterns := make(ebnf.Patterns[T, P], len(p.operators))
+	for i, op := range p.operators {
+		patterns[i] = op.Pattern
+	}
+
+	for i := 0; i < n && !ebnf.Exhausted(w); i++ {
+		j := ebnf.Choose[T, P](w, p, patterns)
+		if j < 0 || j >= len(patterns) {
+			j = ebnf.Rand(w).Intn(len(patterns))
+		}
+
+		err = ebnf.GeneratePattern(patterns[j], w)
+		if err != nil {
+			return err
+		}
+
+		err = ebnf.GeneratePattern(p.operand, w)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Print prints the expression as EBNF, the operand followed by any number of operators and operands
+func (p *Precedence[T, P]) Print(w io.Writer) error {
+	_, err := io.WriteString(w, "(")
+	if err != nil {
+		return err
+	}
+
+	err = p.operand.PrintAsChild(w)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, ", ((")
+	if err != nil {
+		return err
+	}
+
+	for i, op := range p.operators {
+		if i > 0 {
+			_, err = io.WriteString(w, " | ")
+			if err != nil {
+				return err
+			}
+		}
+
+		err = op.Pattern.PrintAsChild(w)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(w, "), ")
+	if err != nil {
+		return err
+	}
+
+	err = p.operand.PrintAsChild(w)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, ")*)")
+
+	return err
+}
+
+// Clone returns a shallow copy of the precedence pattern
+func (p *Precedence[T, P]) Clone() ebnf.Pattern[T, P] {
+	c := *p
+	c.BasePattern = p.BasePattern.Copy()
+	c.operators = append([]Operator[T, P]{}, p.operators...)
+
+	return c.SetSelf(&c)
+}
+
+// Rebind replaces the operand and operator patterns with the result of f
+func (p *Precedence[T, P]) Rebind(f func(ebnf.Pattern[T, P]) ebnf.Pattern[T, P]) {
+	p.operand = f(p.operand)
+
+	for i := range p.operators {
+		p.operators[i].Pattern = f(p.operators[i].Pattern)
+	}
+}
+
+// First reports if obj can start the operand
+func (p *Precedence[T, P]) First(obj T) (bool, bool) {
+	return ebnf.First(p.operand, obj)
+}
@@ -0,0 +1,86 @@
+package reference
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"io"
+)
+
+// Reference refers to another pattern which can be set after creation, this makes recursive patterns possible
+type Reference[T, P any] struct {
+	*ebnf.BasePattern[T, P]
+	pattern ebnf.Pattern[T, P]
+}
+
+// New creates a new reference pattern, pattern can be nil and set later with Set
+func New[T, P any](pattern ebnf.Pattern[T, P]) *Reference[T, P] {
+	ref := &Reference[T, P]{
+		BasePattern: ebnf.NewBasePattern[T, P](),
+		pattern:     pattern,
+	}
+
+	ref.SetSelf(ref)
+
+	return ref
+}
+
+// Set sets the referred pattern
+func (ref *Reference[T, P]) Set(pattern ebnf.Pattern[T, P]) *Reference[T, P] {
+	ref.pattern = pattern
+	return ref
+}
+
+// Pattern returns the referred pattern
+func (ref *Reference[T, P]) Pattern() ebnf.Pattern[T, P] {
+	return ref.pattern
+}
+
+// Match matches the referred pattern, the match of the referred pattern is returned as is
+func (ref *Reference[T, P]) Match(r ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], error) {
+	if ref.pattern == nil {
+		return false, nil, ebnf.ErrUnresolvedReference
+	}
+
+	return ebnf.MatchPattern(ref.pattern, r)
+}
+
+// Generate lets the referred pattern generate to writer
+func (ref *Reference[T, P]) Generate(w ebnf.Writer[T]) error {
+	if ref.pattern == nil {
+		return ebnf.ErrUnresolvedReference
+	}
+
+	return ebnf.GeneratePattern(ref.pattern, w)
+}
+
+// Print prints the referred pattern as child, so a named pattern is printed by ID
+func (ref *Reference[T, P]) Print(w io.Writer) error {
+	if ref.pattern == nil {
+		return ebnf.ErrUnresolvedReference
+	}
+
+	return ref.pattern.PrintAsChild(w)
+}
+
+// Clone returns a shallow copy of the reference
+func (ref *Reference[T, P]) Clone() ebnf.Pattern[T, P] {
+	c := *ref
+	c.BasePattern = ref.BasePattern.Copy()
+
+	return c.SetSelf(&c)
+}
+
+// Rebind replaces the referred pattern with the result of f, an unresolved reference stays unresolved
+func (ref *Reference[T, P]) Rebind(f func(ebnf.Pattern[T, P]) ebnf.Pattern[T, P]) {
+	if ref.pattern != nil {
+		ref.pattern = f(ref.pattern)
+	}
+}
+
+// First reports if obj can start the referred pattern, an unresolved reference can start with anything
+func (ref *Reference[T, P]) First(obj T) (bool, bool) {
+	if ref.pattern == nil {
+		return true, true
+	}
+
+	return ebnf.First(ref.pattern, obj)
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	ebnf "github.com/almerlucke/exbana/v2"
 	"io"
-	"math/rand"
 )
 
 // Repetition matches a pattern repetition
@@ -42,9 +41,32 @@ func OneOrMore[T, P any](pattern ebnf.Pattern[T, P]) *Repetition[T, P] {
 	return New[T, P](pattern, 1, 0)
 }
 
+// Pattern returns the repeated pattern
+func (rep *Repetition[T, P]) Pattern() ebnf.Pattern[T, P] {
+	return rep.pattern
+}
+
+// Min returns the minimum number of repetitions
+func (rep *Repetition[T, P]) Min() int {
+	return rep.min
+}
+
+// Max returns the maximum number of repetitions, 0 means unbounded
+func (rep *Repetition[T, P]) Max() int {
+	return rep.max
+}
+
 // Match matches the repetition pattern aginst a stream
 func (rep *Repetition[T, P]) Match(r ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], error) {
-	var matches []*ebnf.Match[T, P]
+	if ebnf.IsValidating(r) {
+		ok, err := rep.validate(r)
+		return ok, nil, err
+	}
+
+	var (
+		matches []*ebnf.Match[T, P]
+		n       int
+	)
 
 	beginPos, err := r.Position()
 	if ebnf.IsStreamError(err) {
@@ -56,18 +78,20 @@ func (rep *Repetition[T, P]) Match(r ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P]
 			break
 		}
 
-		resetPos, err := r.Position()
+		reset, err := ebnf.NewMarker(r)
 		if ebnf.IsStreamError(err) {
 			return false, nil, err
 		}
 
-		matched, result, err := rep.pattern.Match(r)
+		matched, result, err := ebnf.MatchComponent(rep.pattern, r)
 		if err != nil {
+			reset.Discard()
 			return false, nil, err
 		}
 
 		if !matched {
-			err = r.SetPosition(resetPos)
+			err = reset.Reset()
+			reset.Discard()
 			if ebnf.IsStreamError(err) {
 				return false, nil, err
 			}
@@ -75,13 +99,20 @@ func (rep *Repetition[T, P]) Match(r ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P]
 			break
 		}
 
-		matches = append(matches, result)
-		if rep.max != 0 && len(matches) == rep.max {
+		reset.Discard()
+
+		matches, err = ebnf.Lower(matches, result, r)
+		if err != nil {
+			return false, nil, err
+		}
+
+		n++
+		if rep.max != 0 && n == rep.max {
 			break
 		}
 	}
 
-	if len(matches) < rep.min {
+	if n < rep.min {
 		endPos, err := r.Position()
 		if ebnf.IsStreamError(err) {
 			return false, nil, err
@@ -100,6 +131,39 @@ func (rep *Repetition[T, P]) Match(r ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P]
 	return true, ebnf.NewMatch(rep, beginPos, endPos, nil, matches), nil
 }
 
+// validate matches the repetition without creating a match
+func (rep *Repetition[T, P]) validate(r ebnf.Reader[T, P]) (bool, error) {
+	n := 0
+
+	for !r.Finished() && (rep.max == 0 || n < rep.max) {
+		reset, err := ebnf.NewMarker(r)
+		if ebnf.IsStreamError(err) {
+			return false, err
+		}
+
+		matched, _, err := ebnf.MatchPattern(rep.pattern, r)
+		if err != nil {
+			reset.Discard()
+			return false, err
+		}
+
+		if !matched {
+			err = reset.Reset()
+			reset.Discard()
+			if ebnf.IsStreamError(err) {
+				return false, err
+			}
+
+			break
+		}
+
+		reset.Discard()
+		n++
+	}
+
+	return n >= rep.min, nil
+}
+
 // SetMaxGen sets the maximum generated entities on top of min
 func (rep *Repetition[T, P]) SetMaxGen(maxGen int) {
 	rep.maxGen = maxGen
@@ -114,10 +178,18 @@ func (rep *Repetition[T, P]) Generate(w ebnf.Writer[T]) error {
 		repMax = repMin + rep.maxGen
 	}
 
-	n := rand.Intn(repMax-repMin+1) + repMin
+	n := ebnf.Repeat[T, P](w, rep, rep.min, rep.max)
+	if n < 0 {
+		n = ebnf.Rand(w).Intn(repMax-repMin+1) + repMin
+	}
 
 	for i := 0; i < n; i++ {
-		err := rep.pattern.Generate(w)
+		// Stop at the nearest point where the minimum is satisfied if the generation budget is exhausted
+		if i >= rep.min && ebnf.Exhausted(w) {
+			break
+		}
+
+		err := ebnf.GeneratePattern(rep.pattern, w)
 		if err != nil {
 			return err
 		}
@@ -211,3 +283,22 @@ func (rep *Repetition[T, P]) Print(w io.Writer) error {
 
 	return nil
 }
+
+// Clone returns a shallow copy of the repetition
+func (rep *Repetition[T, P]) Clone() ebnf.Pattern[T, P] {
+	c := *rep
+	c.BasePattern = rep.BasePattern.Copy()
+
+	return c.SetSelf(&c)
+}
+
+// Rebind replaces the repeated pattern with the result of f
+func (rep *Repetition[T, P]) Rebind(f func(ebnf.Pattern[T, P]) ebnf.Pattern[T, P]) {
+	rep.pattern = f(rep.pattern)
+}
+
+// First reports if obj can start the repeated pattern, the repetition can be empty if min is 0
+func (rep *Repetition[T, P]) First(obj T) (bool, bool) {
+	first, nullable := ebnf.First(rep.pattern, obj)
+	return first, nullable || rep.min == 0
+}
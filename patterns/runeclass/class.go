@@ -0,0 +1,287 @@
+package runeclass
+
+import (
+	"errors"
+	"fmt"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"sort"
+	"strconv"
+	"unicode"
+)
+
+// ErrClassSyntax is returned by Class for a malformed character class
+var ErrClassSyntax = errors.New("invalid character class")
+
+// Class creates an entity from a compact character class such as "[A-Za-z_]" or "[^\n\"]". A class contains single
+// runes and ranges (a-z), the escapes \t \n \r \f \v \\ \] \[ \- \^ \xHH \uHHHH and Unicode categories or scripts
+// (\p{L}, \p{Greek}). A leading ^ negates the class. The class is compiled to a range table, the class itself is the
+// print output
+func Class[P any](class string) (*entity.Entity[rune, P], error) {
+	ranges, negate, err := parseClass(class)
+	if err != nil {
+		return nil, err
+	}
+
+	table := rangeTable(ranges)
+	gen := table
+
+	var match func(rune) bool
+
+	if negate {
+		match = func(c rune) bool { return !unicode.Is(table, c) }
+		gen = complement(ranges)
+	} else {
+		match = func(c rune) bool { return unicode.Is(table, c) }
+	}
+
+	return FromFunc[P](match, gen, class, class), nil
+}
+
+// MustClass is like Class but panics if the class is malformed, it simplifies grammar definitions with constant
+// classes
+func MustClass[P any](class string) *entity.Entity[rune, P] {
+	e, err := Class[P](class)
+	if err != nil {
+		panic(err)
+	}
+
+	return e
+}
+
+// runeRange is an inclusive range of runes
+type runeRange struct {
+	lo, hi rune
+}
+
+// parseClass parses a class into merged sorted ranges
+func parseClass(class string) ([]runeRange, bool, error) {
+	rs := []rune(class)
+	if len(rs) < 2 || rs[0] != '[' || rs[len(rs)-1] != ']' {
+		return nil, false, fmt.Errorf("%w %q: must be enclosed in []", ErrClassSyntax, class)
+	}
+
+	rs = rs[1 : len(rs)-1]
+
+	negate := len(rs) > 0 && rs[0] == '^'
+	if negate {
+		rs = rs[1:]
+	}
+
+	var ranges []runeRange
+
+	for i := 0; i < len(rs); {
+		if rs[i] == '\\' && i+1 < len(rs) && rs[i+1] == 'p' {
+			table, n, err := parseProperty(rs[i:])
+			if err != nil {
+				return nil, false, fmt.Errorf("%w %q: %v", ErrClassSyntax, class, err)
+			}
+
+			ranges = append(ranges, tableRanges(table)...)
+			i += n
+
+			continue
+		}
+
+		lo, n, err := parseRune(rs[i:])
+		if err != nil {
+			return nil, false, fmt.Errorf("%w %q: %v", ErrClassSyntax, class, err)
+		}
+
+		i += n
+		hi := lo
+
+		if i+1 < len(rs) && rs[i] == '-' {
+			hi, n, err = parseRune(rs[i+1:])
+			if err != nil {
+				return nil, false, fmt.Errorf("%w %q: %v", ErrClassSyntax, class, err)
+			}
+
+			if hi < lo {
+				return nil, false, fmt.Errorf("%w %q: range %q-%q out of order", ErrClassSyntax, class, lo, hi)
+			}
+
+			i += n + 1
+		}
+
+		ranges = append(ranges, runeRange{lo: lo, hi: hi})
+	}
+
+	if len(ranges) == 0 {
+		return nil, false, fmt.Errorf("%w %q: empty class", ErrClassSyntax, class)
+	}
+
+	return merge(ranges), negate, nil
+}
+
+// parseRune parses a single or escaped rune, it returns the number of runes consumed
+func parseRune(rs []rune) (rune, int, error) {
+	if rs[0] != '\\' {
+		if rs[0] == '[' || rs[0] == ']' {
+			return 0, 0, fmt.Errorf("unescaped %q", rs[0])
+		}
+
+		return rs[0], 1, nil
+	}
+
+	if len(rs) < 2 {
+		return 0, 0, errors.New("trailing backslash")
+	}
+
+	switch rs[1] {
+	case 't':
+		return '\t', 2, nil
+	case 'n':
+		return '\n', 2, nil
+	case 'r':
+		return '\r', 2, nil
+	case 'f':
+		return '\f', 2, nil
+	case 'v':
+		return '\v', 2, nil
+	case '\\', ']', '[', '-', '^':
+		return rs[1], 2, nil
+	case 'x', 'u':
+		n := 2
+		if rs[1] == 'u' {
+			n = 4
+		}
+
+		if len(rs) < 2+n {
+			return 0, 0, fmt.Errorf("short \\%c escape", rs[1])
+		}
+
+		v, err := strconv.ParseUint(string(rs[2:2+n]), 16, 32)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid \\%c escape", rs[1])
+		}
+
+		return rune(v), 2 + n, nil
+	}
+
+	return 0, 0, fmt.Errorf("unknown escape \\%c", rs[1])
+}
+
+// parseProperty parses \p{Name} for a Unicode category or script
+func parseProperty(rs []rune) (*unicode.RangeTable, int, error) {
+	if len(rs) < 3 || rs[2] != '{' {
+		return nil, 0, errors.New("expected { after \\p")
+	}
+
+	for i := 3; i < len(rs); i++ {
+		if rs[i] == '}' {
+			name := string(rs[3:i])
+
+			if table, ok := unicode.Categories[name]; ok {
+				return table, i + 1, nil
+			}
+
+			if table, ok := unicode.Scripts[name]; ok {
+				return table, i + 1, nil
+			}
+
+			return nil, 0, fmt.Errorf("unknown category or script %q", name)
+		}
+	}
+
+	return nil, 0, errors.New("unterminated \\p{")
+}
+
+// tableRanges converts a range table to ranges
+func tableRanges(table *unicode.RangeTable) []runeRange {
+	var ranges []runeRange
+
+	add := func(lo, hi, stride rune) {
+		if stride == 1 {
+			ranges = append(ranges, runeRange{lo: lo, hi: hi})
+			return
+		}
+
+		for c := lo; c <= hi; c += stride {
+			ranges = append(ranges, runeRange{lo: c, hi: c})
+		}
+	}
+
+	for _, r := range table.R16 {
+		add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+
+	for _, r := range table.R32 {
+		add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+
+	return ranges
+}
+
+// merge sorts ranges and merges overlapping and adjacent ranges
+func merge(ranges []runeRange) []runeRange {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].lo < ranges[j].lo })
+
+	merged := ranges[:1]
+
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.lo <= last.hi+1 {
+			last.hi = max(last.hi, r.hi)
+		} else {
+			merged = append(merged, r)
+		}
+	}
+
+	return merged
+}
+
+// complement returns a table of runes not in ranges to generate from, printable ASCII is preferred
+func complement(ranges []runeRange) *unicode.RangeTable {
+	for _, bounds := range []runeRange{{lo: ' ', hi: '~'}, {lo: 0, hi: unicode.MaxRune}} {
+		var out []runeRange
+
+		next := bounds.lo
+
+		for _, r := range ranges {
+			if r.hi < next || r.lo > bounds.hi {
+				continue
+			}
+
+			if r.lo > next {
+				out = append(out, runeRange{lo: next, hi: r.lo - 1})
+			}
+
+			next = r.hi + 1
+		}
+
+		if next <= bounds.hi {
+			out = append(out, runeRange{lo: next, hi: bounds.hi})
+		}
+
+		if len(out) > 0 {
+			return rangeTable(out)
+		}
+	}
+
+	return &unicode.RangeTable{}
+}
+
+// rangeTable converts sorted ranges to a range table
+func rangeTable(ranges []runeRange) *unicode.RangeTable {
+	table := &unicode.RangeTable{}
+
+	for _, r := range ranges {
+		switch {
+		case r.hi <= 0xFFFF:
+			table.R16 = append(table.R16, unicode.Range16{Lo: uint16(r.lo), Hi: uint16(r.hi), Stride: 1})
+		case r.lo > 0xFFFF:
+			table.R32 = append(table.R32, unicode.Range32{Lo: uint32(r.lo), Hi: uint32(r.hi), Stride: 1})
+		default:
+			table.R16 = append(table.R16, unicode.Range16{Lo: uint16(r.lo), Hi: 0xFFFF, Stride: 1})
+			table.R32 = append(table.R32, unicode.Range32{Lo: 0x10000, Hi: uint32(r.hi), Stride: 1})
+		}
+	}
+
+	for _, r := range table.R16 {
+		if r.Hi <= unicode.MaxLatin1 {
+			table.LatinOffset++
+		}
+	}
+
+	return table
+}
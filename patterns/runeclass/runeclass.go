@@ -0,0 +1,208 @@
+package runeclass
+
+import (
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"math/rand"
+	"sort"
+	"unicode"
+)
+
+// FromTable creates an entity matching the runes of a Unicode range table, generated runes are drawn uniformly from
+// the table. print is the print output and expected the description used in error messages
+func FromTable[P any](table *unicode.RangeTable, print string, expected string) *entity.Entity[rune, P] {
+	return FromFunc[P](func(c rune) bool {
+		return unicode.Is(table, c)
+	}, table, print, expected)
+}
+
+// FromFunc creates an entity matching the runes for which match returns true, generated runes are drawn uniformly
+// from gen which must only contain matching runes
+func FromFunc[P any](match func(rune) bool, gen *unicode.RangeTable, print string, expected string) *entity.Entity[rune, P] {
+	e := entity.New[rune, P](match).SetRandGenerateFunc(generator(gen)).SetExpectation(expected)
+	e.SetPrintOutput(print)
+
+	return e
+}
+
+// Letter matches Unicode letters (category L)
+func Letter[P any]() *entity.Entity[rune, P] {
+	return FromFunc[P](unicode.IsLetter, unicode.Letter, `\p{L}`, "letter")
+}
+
+// Upper matches Unicode upper case letters (category Lu)
+func Upper[P any]() *entity.Entity[rune, P] {
+	return FromFunc[P](unicode.IsUpper, unicode.Upper, `\p{Lu}`, "upper case letter")
+}
+
+// Lower matches Unicode lower case letters (category Ll)
+func Lower[P any]() *entity.Entity[rune, P] {
+	return FromFunc[P](unicode.IsLower, unicode.Lower, `\p{Ll}`, "lower case letter")
+}
+
+// Digit matches Unicode decimal digits (category Nd)
+func Digit[P any]() *entity.Entity[rune, P] {
+	return FromFunc[P](unicode.IsDigit, unicode.Digit, `\p{Nd}`, "digit")
+}
+
+// Number matches Unicode numbers (category N)
+func Number[P any]() *entity.Entity[rune, P] {
+	return FromFunc[P](unicode.IsNumber, unicode.Number, `\p{N}`, "number")
+}
+
+// Space matches Unicode white space as defined by unicode.IsSpace
+func Space[P any]() *entity.Entity[rune, P] {
+	return FromFunc[P](unicode.IsSpace, unicode.White_Space, `\s`, "white space")
+}
+
+// Punct matches Unicode punctuation (category P)
+func Punct[P any]() *entity.Entity[rune, P] {
+	return FromFunc[P](unicode.IsPunct, unicode.Punct, `\p{P}`, "punctuation")
+}
+
+// Symbol matches Unicode symbols (category S)
+func Symbol[P any]() *entity.Entity[rune, P] {
+	return FromFunc[P](unicode.IsSymbol, unicode.Symbol, `\p{S}`, "symbol")
+}
+
+// Mark matches Unicode marks (category M)
+func Mark[P any]() *entity.Entity[rune, P] {
+	return FromFunc[P](unicode.IsMark, unicode.Mark, `\p{M}`, "mark")
+}
+
+// Control matches Unicode control characters (category Cc)
+func Control[P any]() *entity.Entity[rune, P] {
+	return FromFunc[P](unicode.IsControl, unicode.Cc, `\p{Cc}`, "control character")
+}
+
+// Script matches the runes of a Unicode script (i.e. Greek, Han), returns false for an unknown script
+func Script[P any](name string) (*entity.Entity[rune, P], bool) {
+	table, ok := unicode.Scripts[name]
+	if !ok {
+		return nil, false
+	}
+
+	return FromTable[P](table, `\p{`+name+`}`, name+" character"), true
+}
+
+// Category matches the runes of a Unicode category (i.e. Lu, Sm), returns false for an unknown category
+func Category[P any](name string) (*entity.Entity[rune, P], bool) {
+	table, ok := unicode.Categories[name]
+	if !ok {
+		return nil, false
+	}
+
+	return FromTable[P](table, `\p{`+name+`}`, name+" character"), true
+}
+
+var (
+	asciiLetter = &unicode.RangeTable{R16: []unicode.Range16{{Lo: 'A', Hi: 'Z', Stride: 1}, {Lo: 'a', Hi: 'z', Stride: 1}}}
+	asciiUpper  = &unicode.RangeTable{R16: []unicode.Range16{{Lo: 'A', Hi: 'Z', Stride: 1}}}
+	asciiLower  = &unicode.RangeTable{R16: []unicode.Range16{{Lo: 'a', Hi: 'z', Stride: 1}}}
+	asciiDigit  = &unicode.RangeTable{R16: []unicode.Range16{{Lo: '0', Hi: '9', Stride: 1}}}
+	octDigit    = &unicode.RangeTable{R16: []unicode.Range16{{Lo: '0', Hi: '7', Stride: 1}}}
+	hexDigit    = &unicode.RangeTable{R16: []unicode.Range16{{Lo: '0', Hi: '9', Stride: 1}, {Lo: 'A', Hi: 'F', Stride: 1}, {Lo: 'a', Hi: 'f', Stride: 1}}}
+	asciiAlnum  = &unicode.RangeTable{R16: []unicode.Range16{{Lo: '0', Hi: '9', Stride: 1}, {Lo: 'A', Hi: 'Z', Stride: 1}, {Lo: 'a', Hi: 'z', Stride: 1}}}
+	asciiSpace  = &unicode.RangeTable{R16: []unicode.Range16{{Lo: '\t', Hi: '\r', Stride: 1}, {Lo: ' ', Hi: ' ', Stride: 1}}}
+	asciiPunct  = &unicode.RangeTable{R16: []unicode.Range16{{Lo: '!', Hi: '/', Stride: 1}, {Lo: ':', Hi: '@', Stride: 1}, {Lo: '[', Hi: '`', Stride: 1}, {Lo: '{', Hi: '~', Stride: 1}}}
+	asciiPrint  = &unicode.RangeTable{R16: []unicode.Range16{{Lo: ' ', Hi: '~', Stride: 1}}}
+)
+
+// ASCIILetter matches A-Z and a-z
+func ASCIILetter[P any]() *entity.Entity[rune, P] {
+	return FromTable[P](asciiLetter, "[A-Za-z]", "letter")
+}
+
+// ASCIIUpper matches A-Z
+func ASCIIUpper[P any]() *entity.Entity[rune, P] {
+	return FromTable[P](asciiUpper, "[A-Z]", "upper case letter")
+}
+
+// ASCIILower matches a-z
+func ASCIILower[P any]() *entity.Entity[rune, P] {
+	return FromTable[P](asciiLower, "[a-z]", "lower case letter")
+}
+
+// ASCIIDigit matches 0-9
+func ASCIIDigit[P any]() *entity.Entity[rune, P] {
+	return FromTable[P](asciiDigit, "[0-9]", "decimal digit")
+}
+
+// OctDigit matches 0-7
+func OctDigit[P any]() *entity.Entity[rune, P] {
+	return FromTable[P](octDigit, "[0-7]", "octal digit")
+}
+
+// HexDigit matches 0-9, A-F and a-f
+func HexDigit[P any]() *entity.Entity[rune, P] {
+	return FromTable[P](hexDigit, "[0-9A-Fa-f]", "hexadecimal digit")
+}
+
+// ASCIIAlnum matches 0-9, A-Z and a-z
+func ASCIIAlnum[P any]() *entity.Entity[rune, P] {
+	return FromTable[P](asciiAlnum, "[0-9A-Za-z]", "letter or digit")
+}
+
+// ASCIISpace matches space, tab, newline, vertical tab, form feed and carriage return
+func ASCIISpace[P any]() *entity.Entity[rune, P] {
+	return FromTable[P](asciiSpace, `[ \t\n\v\f\r]`, "white space")
+}
+
+// ASCIIPunct matches the printable ASCII characters that are not letters, digits or space
+func ASCIIPunct[P any]() *entity.Entity[rune, P] {
+	return FromTable[P](asciiPunct, "[!-/:-@[-`{-~]", "punctuation")
+}
+
+// ASCIIPrint matches the printable ASCII characters including space
+func ASCIIPrint[P any]() *entity.Entity[rune, P] {
+	return FromTable[P](asciiPrint, "[ -~]", "printable character")
+}
+
+// Any matches any rune, generated runes are printable ASCII characters
+func Any[P any]() *entity.Entity[rune, P] {
+	return FromFunc[P](func(rune) bool { return true }, asciiPrint, ".", "any character")
+}
+
+// generator returns a function drawing runes uniformly from a range table
+func generator(table *unicode.RangeTable) func(*rand.Rand) rune {
+	type span struct {
+		lo, stride rune
+		n          int
+	}
+
+	var (
+		spans  []span
+		totals []int
+		total  int
+	)
+
+	add := func(lo, hi, stride rune) {
+		n := int((hi-lo)/stride) + 1
+		spans = append(spans, span{lo: lo, stride: stride, n: n})
+		total += n
+		totals = append(totals, total)
+	}
+
+	for _, r := range table.R16 {
+		add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+
+	for _, r := range table.R32 {
+		add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+
+	return func(rnd *rand.Rand) rune {
+		if total == 0 {
+			return 0
+		}
+
+		i := rnd.Intn(total)
+		s := sort.SearchInts(totals, i+1)
+
+		offset := i
+		if s > 0 {
+			offset -= totals[s-1]
+		}
+
+		return spans[s].lo + rune(offset)*spans[s].stride
+	}
+}
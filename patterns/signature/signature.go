@@ -0,0 +1,236 @@
+package signature
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	bytereader "github.com/almerlucke/exbana/v2/readers/bytes"
+	"io"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// File formats, the pattern of a format has the format as ID
+const (
+	PNG    = "png"
+	JPEG   = "jpeg"
+	GIF    = "gif"
+	WebP   = "webp"
+	TIFF   = "tiff"
+	BMP    = "bmp"
+	PDF    = "pdf"
+	WAV    = "wav"
+	AVI    = "avi"
+	AIFF   = "aiff"
+	FLAC   = "flac"
+	Ogg    = "ogg"
+	MP3    = "mp3"
+	MIDI   = "midi"
+	ZIP    = "zip"
+	Gzip   = "gzip"
+	Zstd   = "zstd"
+	Bzip2  = "bzip2"
+	XZ     = "xz"
+	SevenZ = "7z"
+	ELF    = "elf"
+	MachO  = "macho"
+	PE     = "pe"
+	Wasm   = "wasm"
+	SQLite = "sqlite"
+)
+
+// signatures are the magic bytes of the formats in hex, ?? matches any byte. The order is the order of Formats and
+// Patterns, detection does not depend on it: the detector dispatches on the first byte and takes the longest matching
+// signature, so a short signature (i.e. BMP or PE) only wins if no longer signature matches. The order only breaks
+// ties between matches of equal length
+var signatures = []struct {
+	format string
+	magic  []string
+}{
+	{PNG, []string{"89 50 4E 47 0D 0A 1A 0A"}},
+	{JPEG, []string{"FF D8 FF"}},
+	{GIF, []string{"47 49 46 38 37 61", "47 49 46 38 39 61"}},
+	{WebP, []string{"52 49 46 46 ?? ?? ?? ?? 57 45 42 50"}},
+	{WAV, []string{"52 49 46 46 ?? ?? ?? ?? 57 41 56 45"}},
+	{AVI, []string{"52 49 46 46 ?? ?? ?? ?? 41 56 49 20"}},
+	{AIFF, []string{"46 4F 52 4D ?? ?? ?? ?? 41 49 46 46", "46 4F 52 4D ?? ?? ?? ?? 41 49 46 43"}},
+	{TIFF, []string{"49 49 2A 00", "4D 4D 00 2A"}},
+	{PDF, []string{"25 50 44 46 2D"}},
+	{FLAC, []string{"66 4C 61 43"}},
+	{Ogg, []string{"4F 67 67 53"}},
+	{MP3, []string{"49 44 33"}},
+	{MIDI, []string{"4D 54 68 64"}},
+	{ZIP, []string{"50 4B 03 04", "50 4B 05 06", "50 4B 07 08"}},
+	{Gzip, []string{"1F 8B"}},
+	{Zstd, []string{"28 B5 2F FD"}},
+	{Bzip2, []string{"42 5A 68"}},
+	{XZ, []string{"FD 37 7A 58 5A 00"}},
+	{SevenZ, []string{"37 7A BC AF 27 1C"}},
+	{ELF, []string{"7F 45 4C 46"}},
+	{MachO, []string{"FE ED FA CE", "FE ED FA CF", "CE FA ED FE", "CF FA ED FE"}},
+	{Wasm, []string{"00 61 73 6D"}},
+	{SQLite, []string{"53 51 4C 69 74 65 20 66 6F 72 6D 61 74 20 33 00"}},
+	{BMP, []string{"42 4D"}},
+	{PE, []string{"4D 5A"}},
+}
+
+// ErrMagicSyntax is returned by Magic for malformed magic bytes
+var ErrMagicSyntax = errors.New("invalid magic")
+
+// MaxLength is the length of the longest signature
+const MaxLength = 16
+
+// Formats returns the known formats
+func Formats() []string {
+	formats := make([]string, len(signatures))
+
+	for i, s := range signatures {
+		formats[i] = s.format
+	}
+
+	return formats
+}
+
+// Patterns returns a pattern per format in the order of Formats, the pattern of a format has the format as ID and
+// matches any of its signatures
+func Patterns[P any]() ebnf.Patterns[byte, P] {
+	patterns := make(ebnf.Patterns[byte, P], len(signatures))
+
+	for i, s := range signatures {
+		var magics ebnf.Patterns[byte, P]
+
+		for _, magic := range s.magic {
+			magics = append(magics, MustMagic[P](magic))
+		}
+
+		p := magics[0]
+		if len(magics) > 1 {
+			p = alternation.New[byte, P](magics...)
+		}
+
+		patterns[i] = concatenation.New[byte, P](p).SetID(s.format)
+	}
+
+	return patterns
+}
+
+// Detector returns an alternation of the patterns of all formats, the alternatives are dispatched on the first byte
+// and the longest match wins
+func Detector[P any]() ebnf.Pattern[byte, P] {
+	return alternation.New[byte, P](Patterns[P]()...).SetDispatch(func(b byte) any { return b })
+}
+
+// Magic returns a pattern for magic bytes in hex notation, separated by white space. A ?? matches any byte
+func Magic[P any](magic string) (ebnf.Pattern[byte, P], error) {
+	var (
+		parts ebnf.Patterns[byte, P]
+		run   []byte
+	)
+
+	flush := func() {
+		if len(run) > 0 {
+			parts = append(parts, vector.New[byte, P](func(a, b byte) bool { return a == b }, run...))
+			run = nil
+		}
+	}
+
+	for _, field := range strings.Fields(magic) {
+		if field == "??" {
+			flush()
+			parts = append(parts, entity.New[byte, P](func(byte) bool { return true }).
+				SetRandGenerateFunc(func(r *rand.Rand) byte { return byte(r.Intn(256)) }).
+				SetExpectation("byte"))
+
+			continue
+		}
+
+		b, err := strconv.ParseUint(field, 16, 8)
+		if err != nil || len(field) != 2 {
+			return nil, fmt.Errorf("%w %q: invalid byte %q", ErrMagicSyntax, magic, field)
+		}
+
+		run = append(run, byte(b))
+	}
+
+	flush()
+
+	switch len(parts) {
+	case 0:
+		return nil, fmt.Errorf("%w %q: no bytes", ErrMagicSyntax, magic)
+	case 1:
+		return parts[0], nil
+	}
+
+	return concatenation.New[byte, P](parts...), nil
+}
+
+// MustMagic is like Magic but panics if the notation is invalid, it simplifies constant signatures
+func MustMagic[P any](magic string) ebnf.Pattern[byte, P] {
+	p, err := Magic[P](magic)
+	if err != nil {
+		panic(err)
+	}
+
+	return p
+}
+
+// DetectFormat detects the format from the magic bytes at the start of the input, the format is empty if it is not
+// known. The returned reader serves the complete input including the magic bytes
+func DetectFormat(r io.Reader) (string, io.Reader, error) {
+	in := bufio.NewReader(r)
+
+	header, err := in.Peek(MaxLength)
+	if err != nil && err != io.EOF {
+		return "", nil, err
+	}
+
+	matched, result, err := ebnf.MatchPattern[byte, int](Detector[int](), bytereader.NewFromBytes(header))
+	if err != nil || !matched {
+		return "", in, err
+	}
+
+	return result.Unpack().ID(), in, nil
+}
+
+// Location is the location of a signature found by Scan
+type Location struct {
+	Format string
+	Offset int
+}
+
+// Scan scans data for the signatures of formats, i.e. to find files embedded in archives, disk images or memory
+// dumps. Without formats all known formats are scanned for. Short signatures (i.e. BMP and PE) match often by
+// chance, so scanning for specific formats is recommended
+func Scan(data []byte, formats ...string) ([]Location, error) {
+	var patterns ebnf.Patterns[byte, int]
+
+	for _, p := range Patterns[int]() {
+		if len(formats) == 0 || slices.Contains(formats, p.ID()) {
+			patterns = append(patterns, p)
+		}
+	}
+
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	matches, err := ebnf.Scan[byte, int](bytereader.NewFromBytes(data), alternation.New[byte, int](patterns...))
+	if err != nil {
+		return nil, err
+	}
+
+	locations := make([]Location, len(matches))
+
+	for i, m := range matches {
+		locations[i] = Location{Format: m.Unpack().ID(), Offset: m.Begin}
+	}
+
+	return locations, nil
+}
@@ -0,0 +1,97 @@
+package tlv
+
+import (
+	"encoding/binary"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"math/rand"
+)
+
+// Uint matches an unsigned integer of size bytes (1 to 8) in byte order, it evaluates to an uint64 and can be used
+// as tag or length pattern
+func Uint[P any](size int, order binary.ByteOrder) ebnf.Pattern[byte, P] {
+	u := repetition.New[byte, P](anyByte[P](), size, size)
+	u.SetEvalFunc(func(m *ebnf.Match[byte, P], r ebnf.Reader[byte, P]) (any, error) {
+		b, err := m.Objects(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return decodeUint(b, order), nil
+	})
+
+	return u
+}
+
+// BERLength matches a BER (X.690) definite length: a single byte below 0x80 (short form) or 0x81 to 0x84 followed
+// by that number of big endian length bytes (long form). It evaluates to an int
+func BERLength[P any]() ebnf.Pattern[byte, P] {
+	short := entity.New[byte, P](func(b byte) bool { return b < 0x80 }).
+		SetRandGenerateFunc(func(r *rand.Rand) byte { return byte(r.Intn(0x80)) }).
+		SetExpectation("short length")
+
+	forms := ebnf.Patterns[byte, P]{short}
+
+	for n := 1; n <= 4; n++ {
+		prefix := byte(0x80 + n)
+		forms = append(forms, concatenation.New[byte, P](
+			entity.New[byte, P](func(b byte) bool { return b == prefix }).SetGenerateFunc(func() byte { return prefix }),
+			repetition.New[byte, P](anyByte[P](), n, n),
+		))
+	}
+
+	l := alternation.New[byte, P](forms...)
+	l.SetEvalFunc(func(m *ebnf.Match[byte, P], r ebnf.Reader[byte, P]) (any, error) {
+		b, err := m.Objects(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(b) == 1 {
+			return int(b[0]), nil
+		}
+
+		return int(decodeUint(b[1:], binary.BigEndian)), nil
+	})
+
+	return l
+}
+
+// EncodeBERLength encodes n as BER definite length, in short form if n is below 0x80
+func EncodeBERLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+
+	var b []byte
+
+	for v := uint32(n); v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+
+	return append([]byte{byte(0x80 + len(b))}, b...)
+}
+
+// decodeUint decodes up to 8 bytes in byte order
+func decodeUint(b []byte, order binary.ByteOrder) uint64 {
+	var buf [8]byte
+
+	if order == binary.LittleEndian {
+		copy(buf[:], b)
+		return binary.LittleEndian.Uint64(buf[:])
+	}
+
+	copy(buf[8-len(b):], b)
+
+	return binary.BigEndian.Uint64(buf[:])
+}
+
+// anyByte matches any byte
+func anyByte[P any]() ebnf.Pattern[byte, P] {
+	return entity.New[byte, P](func(byte) bool { return true }).
+		SetRandGenerateFunc(func(r *rand.Rand) byte { return byte(r.Intn(256)) }).
+		SetExpectation("byte")
+}
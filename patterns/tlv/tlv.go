@@ -0,0 +1,447 @@
+package tlv
+
+import (
+	"errors"
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// ErrTagKey is returned when the evaluated tag can not be used as key of the dispatch table
+var ErrTagKey = errors.New("tag does not evaluate to a comparable key")
+
+// ErrLength is returned when the length does not evaluate to an integer
+var ErrLength = errors.New("length does not evaluate to an integer")
+
+// ErrNoEncoder is returned by Generate if no encoder is set
+var ErrNoEncoder = errors.New("tlv generation needs an encoder")
+
+// Table maps evaluated tags to the patterns of their values
+type Table[T, P any] map[any]ebnf.Pattern[T, P]
+
+// LengthFunc decodes the length of the value from the match of the length pattern
+type LengthFunc[T, P any] func(*ebnf.Match[T, P], ebnf.Reader[T, P]) (int, error)
+
+// EncodeFunc encodes the tag and length of a record in stream order, it is used to generate records
+type EncodeFunc[T any] func(key any, length int) []T
+
+// Record is the default evaluation result of a TLV match, Value is the evaluated value. The value of a tag without
+// pattern in the table evaluates to the raw objects
+type Record struct {
+	Tag    any
+	Length int
+	Value  any
+}
+
+// TLV matches a type-length-value record: the tag pattern, the length pattern and exactly length objects matched by
+// the value pattern for the tag. The tag is evaluated to look up the value pattern in the table, so the tag pattern
+// needs an eval function that returns a comparable key (i.e. a byte or an uint16). The value pattern is confined to
+// the length of the record with ebnf.WithLimit, so a value can itself be a repetition of (nested) records. Values of
+// tags that are not in the table are matched by the default pattern, which skips the raw objects unless set with
+// SetDefault. The match has the components tag, length and value which can be accessed by name with Match.Component
+type TLV[T, P any] struct {
+	*ebnf.BasePattern[T, P]
+	tag      ebnf.Pattern[T, P]
+	length   ebnf.Pattern[T, P]
+	decode   LengthFunc[T, P]
+	table    Table[T, P]
+	fallback ebnf.Pattern[T, P]
+	encode   EncodeFunc[T]
+	swapped  bool
+}
+
+// New creates a new TLV pattern, if decode is nil the length pattern must evaluate to an integer
+func New[T, P any](tag ebnf.Pattern[T, P], length ebnf.Pattern[T, P], decode LengthFunc[T, P], table Table[T, P]) *TLV[T, P] {
+	raw := repetition.New[T, P](entity.New[T, P](func(T) bool { return true }).SetExpectation("value"), 0, 0)
+	raw.SetEvalFunc(func(m *ebnf.Match[T, P], r ebnf.Reader[T, P]) (any, error) {
+		return m.Objects(r)
+	})
+
+	t := &TLV[T, P]{
+		BasePattern: ebnf.NewBasePattern[T, P](),
+		tag:         tag,
+		length:      length,
+		decode:      decode,
+		table:       table,
+		fallback:    raw,
+	}
+
+	if t.table == nil {
+		t.table = Table[T, P]{}
+	}
+
+	t.SetSelf(t)
+	t.SetEvalFunc(t.evalRecord)
+
+	return t
+}
+
+// Register adds the value pattern for key to the table
+func (t *TLV[T, P]) Register(key any, value ebnf.Pattern[T, P]) *TLV[T, P] {
+	t.table[key] = value
+	return t
+}
+
+// SetDefault sets the value pattern for tags that are not in the table
+func (t *TLV[T, P]) SetDefault(value ebnf.Pattern[T, P]) *TLV[T, P] {
+	t.fallback = value
+	return t
+}
+
+// SetLengthFirst sets if the length comes before the tag (length-type-value, i.e. PNG chunks)
+func (t *TLV[T, P]) SetLengthFirst(lengthFirst bool) *TLV[T, P] {
+	t.swapped = lengthFirst
+	return t
+}
+
+// SetEncoder sets the function used to encode tag and length when generating records
+func (t *TLV[T, P]) SetEncoder(encode EncodeFunc[T]) *TLV[T, P] {
+	t.encode = encode
+	return t
+}
+
+// Tag returns the tag pattern
+func (t *TLV[T, P]) Tag() ebnf.Pattern[T, P] {
+	return t.tag
+}
+
+// Length returns the length pattern
+func (t *TLV[T, P]) Length() ebnf.Pattern[T, P] {
+	return t.length
+}
+
+// Table returns the dispatch table
+func (t *TLV[T, P]) Table() Table[T, P] {
+	return t.table
+}
+
+// Default returns the value pattern for tags that are not in the table
+func (t *TLV[T, P]) Default() ebnf.Pattern[T, P] {
+	return t.fallback
+}
+
+// Values returns the value patterns of the table ordered by printed key, followed by the default pattern
+func (t *TLV[T, P]) Values() ebnf.Patterns[T, P] {
+	keys := t.keys()
+	values := make(ebnf.Patterns[T, P], 0, len(keys)+1)
+
+	for _, key := range keys {
+		values = append(values, t.table[key])
+	}
+
+	return append(values, t.fallback)
+}
+
+// ComponentIndex returns the index of the tag, length or value component
+func (t *TLV[T, P]) ComponentIndex(name string) int {
+	switch name {
+	case "tag":
+		if t.swapped {
+			return 1
+		}
+
+		return 0
+	case "length":
+		if t.swapped {
+			return 0
+		}
+
+		return 1
+	case "value":
+		return 2
+	}
+
+	return -1
+}
+
+// Key evaluates the tag match to the key of the dispatch table
+func (t *TLV[T, P]) Key(tag *ebnf.Match[T, P], r ebnf.Reader[T, P]) (any, error) {
+	key, err := tag.Eval(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil || !reflect.TypeOf(key).Comparable() {
+		return nil, fmt.Errorf("%w: %T", ErrTagKey, key)
+	}
+
+	return key, nil
+}
+
+// Decode decodes the length match to the length of the value
+func (t *TLV[T, P]) Decode(length *ebnf.Match[T, P], r ebnf.Reader[T, P]) (int, error) {
+	if t.decode != nil {
+		return t.decode(length, r)
+	}
+
+	v, err := length.Eval(r)
+	if err != nil {
+		return 0, err
+	}
+
+	rv := reflect.ValueOf(v)
+
+	switch {
+	case rv.CanInt():
+		return int(rv.Int()), nil
+	case rv.CanUint():
+		return int(rv.Uint()), nil
+	}
+
+	return 0, fmt.Errorf("%w: %T", ErrLength, v)
+}
+
+// Match matches the tag and length, looks up the value pattern and matches it against exactly length objects
+func (t *TLV[T, P]) Match(r ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], error) {
+	beginPos, err := r.Position()
+	if ebnf.IsStreamError(err) {
+		return false, nil, err
+	}
+
+	// Tag and length are always matched with a match tree, they are needed to find the value pattern
+	rd := r
+	if ebnf.IsValidating(r) {
+		rd = &matching[T, P]{Reader: r}
+	}
+
+	components := make([]*ebnf.Match[T, P], 0, 3)
+
+	for _, pattern := range t.header() {
+		matched, result, err := ebnf.MatchPattern(pattern, rd)
+		if err != nil || !matched {
+			return false, nil, err
+		}
+
+		components = append(components, result)
+	}
+
+	tag, length := components[0], components[1]
+	if t.swapped {
+		tag, length = length, tag
+	}
+
+	key, err := t.Key(tag, rd)
+	if err != nil {
+		return false, nil, err
+	}
+
+	n, err := t.Decode(length, rd)
+	if err != nil {
+		return false, nil, err
+	}
+
+	value, ok := t.table[key]
+	if !ok {
+		value = t.fallback
+	}
+
+	valueBeginPos, err := r.Position()
+	if ebnf.IsStreamError(err) {
+		return false, nil, err
+	}
+
+	if n < 0 {
+		t.Logger().LogMismatch(ebnf.NewMismatch(t, beginPos, valueBeginPos, ebnf.NewMatch(value, valueBeginPos, valueBeginPos, nil, nil), components))
+		return false, nil, nil
+	}
+
+	limited, err := ebnf.WithLimit(r, n)
+	if err != nil {
+		return false, nil, err
+	}
+
+	matched, result, err := ebnf.MatchPattern(value, limited)
+	if err != nil {
+		return false, nil, err
+	}
+
+	endPos, err := r.Position()
+	if ebnf.IsStreamError(err) {
+		return false, nil, err
+	}
+
+	// The value must match exactly length objects
+	if !matched || r.Length(valueBeginPos, endPos) != n {
+		t.Logger().LogMismatch(ebnf.NewMismatch(t, beginPos, endPos, ebnf.NewMatch(value, valueBeginPos, endPos, nil, nil), components))
+		return false, nil, nil
+	}
+
+	if ebnf.IsValidating(r) {
+		return true, nil, nil
+	}
+
+	return true, ebnf.NewMatch(t, beginPos, endPos, nil, append(components, result)), nil
+}
+
+// Generate picks a random tag of the table, generates its value and writes the encoded tag and length followed by
+// the value. The value is generated to a buffer first to know its length
+func (t *TLV[T, P]) Generate(w ebnf.Writer[T]) error {
+	if t.encode == nil {
+		return ErrNoEncoder
+	}
+
+	keys := t.keys()
+	if len(keys) == 0 {
+		return nil
+	}
+
+	key := keys[ebnf.Rand(w).Intn(len(keys))]
+
+	value, err := ebnf.GenerateSlice[T, P](t.table[key])
+	if err != nil {
+		return err
+	}
+
+	err = w.Write(t.encode(key, len(value))...)
+	if err != nil {
+		return err
+	}
+
+	return w.Write(value...)
+}
+
+// Print prints the tag and length followed by the value patterns as alternatives
+func (t *TLV[T, P]) Print(w io.Writer) error {
+	_, err := w.Write([]byte("("))
+	if err != nil {
+		return err
+	}
+
+	for _, pattern := range t.header() {
+		err = pattern.PrintAsChild(w)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write([]byte(", "))
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = w.Write([]byte("("))
+	if err != nil {
+		return err
+	}
+
+	for i, value := range t.Values() {
+		if i > 0 {
+			_, err = w.Write([]byte(" | "))
+			if err != nil {
+				return err
+			}
+		}
+
+		err = value.PrintAsChild(w)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = w.Write([]byte("))"))
+
+	return err
+}
+
+// Clone returns a shallow copy of the TLV pattern
+func (t *TLV[T, P]) Clone() ebnf.Pattern[T, P] {
+	c := *t
+	c.BasePattern = t.BasePattern.Copy()
+	c.table = make(Table[T, P], len(t.table))
+
+	for key, value := range t.table {
+		c.table[key] = value
+	}
+
+	c.SetSelf(&c)
+	c.SetEvalFunc(c.evalRecord)
+
+	return &c
+}
+
+// Rebind replaces the tag, length, value and default patterns with the result of f
+func (t *TLV[T, P]) Rebind(f func(ebnf.Pattern[T, P]) ebnf.Pattern[T, P]) {
+	t.tag = f(t.tag)
+	t.length = f(t.length)
+	t.fallback = f(t.fallback)
+
+	for key, value := range t.table {
+		t.table[key] = f(value)
+	}
+}
+
+// First reports if obj can start the tag
+func (t *TLV[T, P]) First(obj T) (bool, bool) {
+	first, _ := ebnf.First(t.tag, obj)
+	return first, false
+}
+
+// evalRecord evaluates a TLV match to a Record
+func (t *TLV[T, P]) evalRecord(m *ebnf.Match[T, P], r ebnf.Reader[T, P]) (any, error) {
+	key, err := t.Key(m.Component("tag"), r)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := t.Decode(m.Component("length"), r)
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+
+	if v := m.Component("value"); v != nil {
+		value, err = v.Eval(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return Record{Tag: key, Length: n, Value: value}, nil
+}
+
+// header returns the tag and length patterns in stream order
+func (t *TLV[T, P]) header() ebnf.Patterns[T, P] {
+	if t.swapped {
+		return ebnf.Patterns[T, P]{t.length, t.tag}
+	}
+
+	return ebnf.Patterns[T, P]{t.tag, t.length}
+}
+
+// keys returns the keys of the table ordered by their printed form
+func (t *TLV[T, P]) keys() []any {
+	keys := make([]any, 0, len(t.table))
+
+	for key := range t.table {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+
+	return keys
+}
+
+// matching is a reader middleware that turns off validate only mode, so the tag and length create matches that can
+// be evaluated
+type matching[T, P any] struct {
+	ebnf.Reader[T, P]
+}
+
+func (m *matching[T, P]) Unwrap() ebnf.Reader[T, P] {
+	return m.Reader
+}
+
+func (m *matching[T, P]) Validating() bool {
+	return false
+}
+
+func (m *matching[T, P]) Capturing() bool {
+	return true
+}
@@ -0,0 +1,199 @@
+package uri
+
+import (
+	"errors"
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/runeclass"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	"net/url"
+	"strings"
+)
+
+// Pattern IDs of the URI, email and hostname patterns
+const (
+	URIID      = "uri"
+	EmailID    = "email"
+	HostnameID = "hostname"
+)
+
+// ErrHostnameLength is returned by the eval function of Hostname if a label is longer than 63 or the name longer
+// than 253 characters
+var ErrHostnameLength = errors.New("hostname too long")
+
+// Character classes of RFC 3986
+const (
+	alpha      = "[A-Za-z]"
+	unreserved = "[A-Za-z0-9._~-]"
+	subDelims  = "[!$&'()*+,;=]"
+)
+
+// URI matches an absolute URI as defined by RFC 3986 (scheme ":" hier-part ["?" query] ["#" fragment]), it evaluates
+// to a *url.URL. IP literals in the authority are matched loosely as hex digits, colons and dots between brackets
+func URI[P any]() ebnf.Pattern[rune, P] {
+	scheme := concatenation.New[rune, P](runeclass.MustClass[P](alpha), repetition.New[rune, P](
+		runeclass.MustClass[P]("[A-Za-z0-9+.-]"), 0, 0,
+	))
+
+	userinfo := repetition.New[rune, P](alternation.New[rune, P](
+		runeclass.MustClass[P](unreserved), pctEncoded[P](), runeclass.MustClass[P](subDelims), vector.Runes[P](":"),
+	), 0, 0)
+
+	ipLiteral := concatenation.New[rune, P](
+		vector.Runes[P]("["), repetition.New[rune, P](runeclass.MustClass[P]("[0-9A-Fa-f:.]"), 1, 0), vector.Runes[P]("]"),
+	)
+
+	regName := repetition.New[rune, P](alternation.New[rune, P](
+		runeclass.MustClass[P](unreserved), pctEncoded[P](), runeclass.MustClass[P](subDelims),
+	), 0, 0)
+
+	authority := concatenation.New[rune, P](
+		optional[P](concatenation.New[rune, P](userinfo, vector.Runes[P]("@"))),
+		alternation.New[rune, P](ipLiteral, regName),
+		optional[P](concatenation.New[rune, P](vector.Runes[P](":"), repetition.New[rune, P](runeclass.ASCIIDigit[P](), 0, 0))),
+	)
+
+	segment := repetition.New[rune, P](pchar[P](), 0, 0)
+	segmentNZ := repetition.New[rune, P](pchar[P](), 1, 0)
+	segments := repetition.New[rune, P](concatenation.New[rune, P](vector.Runes[P]("/"), segment), 0, 0)
+
+	hierPart := optional[P](alternation.New[rune, P](
+		concatenation.New[rune, P](vector.Runes[P]("//"), authority, segments),
+		concatenation.New[rune, P](vector.Runes[P]("/"), optional[P](concatenation.New[rune, P](segmentNZ, segments))),
+		concatenation.New[rune, P](segmentNZ, segments),
+	))
+
+	tail := func(prefix string) ebnf.Pattern[rune, P] {
+		return optional[P](concatenation.New[rune, P](vector.Runes[P](prefix), repetition.New[rune, P](
+			alternation.New[rune, P](pchar[P](), runeclass.MustClass[P]("[/?]")), 0, 0,
+		)))
+	}
+
+	u := concatenation.New[rune, P](scheme, vector.Runes[P](":"), hierPart, tail("?"), tail("#"))
+	u.SetID(URIID)
+	u.SetEvalFunc(func(m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+		s, err := ebnf.Text(m, r)
+		if err != nil {
+			return nil, err
+		}
+
+		return url.Parse(s)
+	})
+
+	return u
+}
+
+// Address is the result of evaluating an Email match
+type Address struct {
+	// Local is the local part as matched, quoted local parts keep their quotes
+	Local string
+	// Domain is the domain as matched, domain literals keep their brackets
+	Domain string
+}
+
+func (a Address) String() string {
+	return a.Local + "@" + a.Domain
+}
+
+// Email matches an RFC 5322 addr-spec without comments or folding white space (local-part "@" domain), the local
+// part is a dot-atom or quoted string and the domain a dot-atom or domain literal. It evaluates to an Address
+func Email[P any]() ebnf.Pattern[rune, P] {
+	atext := "[A-Za-z0-9!#$%&'*+/=?^_`{|}~-]"
+
+	dotAtom := func() ebnf.Pattern[rune, P] {
+		atom := repetition.New[rune, P](runeclass.MustClass[P](atext), 1, 0)
+		return concatenation.New[rune, P](atom, repetition.New[rune, P](
+			concatenation.New[rune, P](vector.Runes[P]("."), repetition.New[rune, P](runeclass.MustClass[P](atext), 1, 0)), 0, 0,
+		))
+	}
+
+	quoted := concatenation.New[rune, P](vector.Runes[P](`"`), repetition.New[rune, P](alternation.New[rune, P](
+		runeclass.MustClass[P](`[!#-\[\]-~ ]`),
+		concatenation.New[rune, P](vector.Runes[P](`\`), runeclass.MustClass[P](`[ -~\t]`)),
+	), 0, 0), vector.Runes[P](`"`))
+
+	domainLiteral := concatenation.New[rune, P](vector.Runes[P]("["), repetition.New[rune, P](
+		runeclass.MustClass[P](`[!-Z^-~]`), 0, 0,
+	), vector.Runes[P]("]"))
+
+	e := concatenation.New[rune, P]().
+		Add("local", alternation.New[rune, P](dotAtom(), quoted)).
+		Add("", vector.Runes[P]("@")).
+		Add("domain", alternation.New[rune, P](dotAtom(), domainLiteral))
+	e.SetID(EmailID)
+	e.SetEvalFunc(func(m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+		local, err := ebnf.Text(m.Component("local"), r)
+		if err != nil {
+			return nil, err
+		}
+
+		domain, err := ebnf.Text(m.Component("domain"), r)
+		if err != nil {
+			return nil, err
+		}
+
+		return Address{Local: local, Domain: domain}, nil
+	})
+
+	return e
+}
+
+// Hostname matches an RFC 1123 host name, dot separated labels of letters, digits and hyphens that do not start or
+// end with a hyphen. It evaluates to the labels, an error wrapping ErrHostnameLength is returned for names exceeding
+// the length limits
+func Hostname[P any]() ebnf.Pattern[rune, P] {
+	alnum := func() ebnf.Pattern[rune, P] {
+		return runeclass.ASCIIAlnum[P]()
+	}
+
+	// Hyphens must be followed by a letter or digit, so a label never ends with a hyphen
+	label := concatenation.New[rune, P](alnum(), repetition.New[rune, P](alternation.New[rune, P](
+		alnum(), concatenation.New[rune, P](repetition.New[rune, P](vector.Runes[P]("-"), 1, 0), alnum()),
+	), 0, 0))
+
+	h := concatenation.New[rune, P](label, repetition.New[rune, P](
+		concatenation.New[rune, P](vector.Runes[P]("."), label), 0, 0,
+	))
+	h.SetID(HostnameID)
+	h.SetEvalFunc(func(m *ebnf.Match[rune, P], r ebnf.Reader[rune, P]) (any, error) {
+		s, err := ebnf.Text(m, r)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(s) > 253 {
+			return nil, fmt.Errorf("%w: %d characters", ErrHostnameLength, len(s))
+		}
+
+		labels := strings.Split(s, ".")
+
+		for _, l := range labels {
+			if len(l) > 63 {
+				return nil, fmt.Errorf("%w: label %q has %d characters", ErrHostnameLength, l, len(l))
+			}
+		}
+
+		return labels, nil
+	})
+
+	return h
+}
+
+// pchar matches a path character
+func pchar[P any]() ebnf.Pattern[rune, P] {
+	return alternation.New[rune, P](
+		runeclass.MustClass[P](unreserved), pctEncoded[P](), runeclass.MustClass[P](subDelims), runeclass.MustClass[P]("[:@]"),
+	)
+}
+
+// pctEncoded matches a percent encoded octet
+func pctEncoded[P any]() ebnf.Pattern[rune, P] {
+	return concatenation.New[rune, P](vector.Runes[P]("%"), runeclass.HexDigit[P](), runeclass.HexDigit[P]())
+}
+
+func optional[P any](pattern ebnf.Pattern[rune, P]) ebnf.Pattern[rune, P] {
+	return repetition.New[rune, P](pattern, 0, 1)
+}
@@ -7,8 +7,9 @@ import (
 // Vector represents a series of entities to match
 type Vector[T, P any] struct {
 	*ebnf.BasePattern[T, P]
-	eq     func(T, T) bool
-	vector []T
+	eq          func(T, T) bool
+	vector      []T
+	expectation string
 }
 
 // New creates a new vector pattern
@@ -24,20 +25,45 @@ func New[T, P any](eq func(T, T) bool, vec ...T) *Vector[T, P] {
 	return v
 }
 
+// Runes creates a vector pattern matching the runes of s
+func Runes[P any](s string) *Vector[rune, P] {
+	return New[rune, P](func(a, b rune) bool { return a == b }, []rune(s)...)
+}
+
+// Vector returns the series of entities to match
+func (v *Vector[T, P]) Vector() []T {
+	return v.vector
+}
+
+// Equal returns the function comparing two objects
+func (v *Vector[T, P]) Equal() func(T, T) bool {
+	return v.eq
+}
+
 // Match matches the vector pattern against a stream
 func (v *Vector[T, P]) Match(rd ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], error) {
-	beginPos, err := rd.Position()
+	if ebnf.IsValidating(rd) {
+		ok, err := v.validate(rd)
+		return ok, nil, err
+	}
+
+	// Keep the begin position retained for the range of the matched value
+	begin, err := ebnf.NewMarker(rd)
 	if ebnf.IsStreamError(err) {
 		return false, nil, err
 	}
 
+	defer begin.Discard()
+
+	beginPos := begin.Pos()
+
 	for _, e1 := range v.vector {
 		e2, err := rd.Read1()
 		if ebnf.IsStreamError(err) {
 			return false, nil, err
 		}
 
-		if !v.eq(e1, e2) {
+		if err != nil || !v.eq(e1, e2) {
 			endPos, err := rd.Position()
 			if ebnf.IsStreamError(err) {
 				return false, nil, err
@@ -54,15 +80,81 @@ func (v *Vector[T, P]) Match(rd ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], err
 		return false, nil, err
 	}
 
-	val, err := rd.Range(beginPos, endPos)
-	if err != nil {
-		return false, nil, err
+	var val []T
+
+	if ebnf.IsCapturing(rd) {
+		val, err = rd.Range(beginPos, endPos)
+		if err != nil {
+			return false, nil, err
+		}
 	}
 
 	return true, ebnf.NewMatch(v, beginPos, endPos, val, nil), nil
 }
 
+// validate matches the vector without creating a match
+func (v *Vector[T, P]) validate(rd ebnf.Reader[T, P]) (bool, error) {
+	for _, e1 := range v.vector {
+		e2, err := rd.Read1()
+		if ebnf.IsStreamError(err) {
+			return false, err
+		}
+
+		if err != nil || !v.eq(e1, e2) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// SetExpectation sets a human description of the expected objects (i.e. "keyword") used in error messages
+func (v *Vector[T, P]) SetExpectation(expectation string) *Vector[T, P] {
+	v.expectation = expectation
+	return v
+}
+
+// Expected returns the expectation, the ID, the print output or the quoted vector
+func (v *Vector[T, P]) Expected() string {
+	if v.expectation != "" {
+		return v.expectation
+	}
+
+	if id := v.ID(); id != ebnf.NoID {
+		return id
+	}
+
+	if output := v.PrintOutput(); output != "" {
+		return output
+	}
+
+	return ebnf.Quote(v.vector...)
+}
+
 // Generate writes a series of entities to a writer
 func (v *Vector[T, P]) Generate(wr ebnf.Writer[T]) error {
 	return wr.Write(v.vector...)
 }
+
+// Clone returns a copy of the vector
+func (v *Vector[T, P]) Clone() ebnf.Pattern[T, P] {
+	c := *v
+	c.BasePattern = v.BasePattern.Copy()
+	c.vector = append([]T{}, v.vector...)
+
+	return c.SetSelf(&c)
+}
+
+// Rebind does nothing, a vector has no sub patterns
+func (v *Vector[T, P]) Rebind(_ func(ebnf.Pattern[T, P]) ebnf.Pattern[T, P]) {
+	/* no sub patterns */
+}
+
+// First reports if obj equals the first object of the vector, an empty vector matches without reading
+func (v *Vector[T, P]) First(obj T) (bool, bool) {
+	if len(v.vector) == 0 {
+		return false, true
+	}
+
+	return v.eq(v.vector[0], obj), false
+}
@@ -14,3 +14,140 @@ type Reader[T, P any] interface {
 	Range(P, P) ([]T, error)
 	Length(P, P) int
 }
+
+// Unwrapper is implemented by reader middleware, Unwrap returns the wrapped reader. The optional extensions of Reader
+// are looked up through the chain of middleware, so middleware only implements the extensions it changes
+type Unwrapper[T, P any] interface {
+	Unwrap() Reader[T, P]
+}
+
+// Lookup returns the outermost reader in the middleware chain starting at r that implements I
+func Lookup[I, T, P any](r Reader[T, P]) (I, bool) {
+	for r != nil {
+		if i, ok := r.(I); ok {
+			return i, true
+		}
+
+		u, ok := r.(Unwrapper[T, P])
+		if !ok {
+			break
+		}
+
+		r = u.Unwrap()
+	}
+
+	var zero I
+
+	return zero, false
+}
+
+// Checkpointer is an optional extension of Reader. A checkpoint is a position the reader guarantees to be able to
+// return to with SetPosition until the checkpoint is released, this allows streaming readers to discard everything
+// before the oldest live checkpoint
+type Checkpointer[P any] interface {
+	Checkpoint() (P, error)
+	Release(P)
+}
+
+// Checkpoint creates a checkpoint if the reader supports it, otherwise the current position is returned
+func Checkpoint[T, P any](r Reader[T, P]) (P, error) {
+	if c, ok := Lookup[Checkpointer[P]](r); ok {
+		return c.Checkpoint()
+	}
+
+	return r.Position()
+}
+
+// Release releases a checkpoint if the reader supports it
+func Release[T, P any](r Reader[T, P], p P) {
+	if c, ok := Lookup[Checkpointer[P]](r); ok {
+		c.Release(p)
+	}
+}
+
+// Matcher is an optional extension of Reader which intercepts the matching of patterns. Patterns match their sub
+// patterns with MatchPattern, so a reader implementing Matcher sees every (sub) pattern match and can instrument,
+// trace or memoize it. Reader middleware can be chained, r is the outermost reader of the chain, a Matcher passes
+// the match on to the next reader in the chain with MatchNext
+type Matcher[T, P any] interface {
+	MatchPattern(pattern Pattern[T, P], r Reader[T, P]) (bool, *Match[T, P], error)
+}
+
+// MatchPattern matches pattern against reader r, if r implements Matcher the match is delegated to the reader
+func MatchPattern[T, P any](pattern Pattern[T, P], r Reader[T, P]) (bool, *Match[T, P], error) {
+	return MatchNext(pattern, r, r)
+}
+
+// MatchNext delegates the match of pattern to next if next implements Matcher, otherwise pattern is matched against
+// the outermost reader r
+func MatchNext[T, P any](pattern Pattern[T, P], next Reader[T, P], r Reader[T, P]) (bool, *Match[T, P], error) {
+	if m, ok := next.(Matcher[T, P]); ok {
+		return m.MatchPattern(pattern, r)
+	}
+
+	return pattern.Match(r)
+}
+
+// Token is an opaque mark handed out by a MarkReader
+type Token uint64
+
+// MarkReader is an optional extension of Reader. Mark remembers the current position, Reset returns to a marked
+// position and Discard signals the mark is no longer referenced, so streaming readers can free all history before
+// the oldest live mark. Patterns prefer marks over raw SetPosition when the reader supports them
+type MarkReader interface {
+	Mark() Token
+	Reset(Token) error
+	Discard(Token)
+}
+
+// Marker is a backtrack point on a reader, it uses a mark if a reader in the middleware chain implements MarkReader,
+// a checkpoint if the reader supports them and the plain position otherwise
+type Marker[T, P any] struct {
+	r      Reader[T, P]
+	mr     MarkReader
+	pos    P
+	token  Token
+	direct bool
+}
+
+// NewMarker creates a backtrack point at the current position of r
+func NewMarker[T, P any](r Reader[T, P]) (Marker[T, P], error) {
+	if mr, ok := Lookup[MarkReader](r); ok {
+		pos, err := r.Position()
+		if err != nil {
+			return Marker[T, P]{}, err
+		}
+
+		_, direct := r.(MarkReader)
+
+		return Marker[T, P]{r: r, mr: mr, pos: pos, token: mr.Mark(), direct: direct}, nil
+	}
+
+	pos, err := Checkpoint(r)
+
+	return Marker[T, P]{r: r, pos: pos}, err
+}
+
+// Pos returns the marked position
+func (m Marker[T, P]) Pos() P {
+	return m.pos
+}
+
+// Reset returns the reader to the marked position. If the mark is held by a reader further down the middleware
+// chain the position is set on r, so the middleware in between sees the backtrack
+func (m Marker[T, P]) Reset() error {
+	if m.direct {
+		return m.mr.Reset(m.token)
+	}
+
+	return m.r.SetPosition(m.pos)
+}
+
+// Discard releases the mark, the marker can not be reset afterwards
+func (m Marker[T, P]) Discard() {
+	if m.mr != nil {
+		m.mr.Discard(m.token)
+	} else {
+		Release(m.r, m.pos)
+	}
+}
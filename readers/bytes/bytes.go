@@ -0,0 +1,139 @@
+package bytes
+
+import (
+	"fmt"
+	"github.com/almerlucke/exbana/v2/readers/compress"
+	"io"
+)
+
+// Options configures how input is read
+type Options struct {
+	// Decompress detects gzip, zstd and bzip2 compressed input by magic bytes and decompresses it on the fly
+	Decompress bool
+}
+
+func open(r io.Reader, opts Options) (io.ReadCloser, error) {
+	if opts.Decompress {
+		return compress.NewReader(r)
+	}
+
+	return io.NopCloser(r), nil
+}
+
+// Reader serves bytes from memory, positions are byte offsets
+type Reader struct {
+	data []byte
+	pos  int
+}
+
+// New reads all bytes from r
+func New(r io.Reader) (*Reader, error) {
+	return NewWithOptions(r, Options{})
+}
+
+// NewWithOptions reads all bytes from r with options
+func NewWithOptions(r io.Reader, opts Options) (*Reader, error) {
+	in, err := open(r, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	defer in.Close()
+
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reader{data: data}, nil
+}
+
+// NewFromBytes creates a reader on top of data, data is not copied
+func NewFromBytes(data []byte) *Reader {
+	return &Reader{data: data}
+}
+
+func (r *Reader) Data() []byte {
+	return r.data
+}
+
+func (r *Reader) Peek1() (byte, error) {
+	if r.pos < len(r.data) {
+		return r.data[r.pos], nil
+	}
+
+	return 0, io.EOF
+}
+
+func (r *Reader) Read1() (byte, error) {
+	if r.pos < len(r.data) {
+		b := r.data[r.pos]
+		r.pos++
+		return b, nil
+	}
+
+	return 0, io.EOF
+}
+
+func (r *Reader) Peek(n int, buf []byte) (int, error) {
+	i := copy(buf[:n], r.data[r.pos:])
+	if i != n {
+		return i, io.EOF
+	}
+
+	return i, nil
+}
+
+func (r *Reader) read(n int, buf []byte) (int, error) {
+	i := min(n, len(r.data)-r.pos)
+
+	if buf != nil {
+		copy(buf, r.data[r.pos:r.pos+i])
+	}
+
+	r.pos += i
+
+	if i != n {
+		return i, io.EOF
+	}
+
+	return i, nil
+}
+
+func (r *Reader) Read(n int, buf []byte) (int, error) {
+	return r.read(n, buf)
+}
+
+func (r *Reader) Skip(n int) (int, error) {
+	return r.read(n, nil)
+}
+
+func (r *Reader) Finished() bool {
+	return r.pos >= len(r.data)
+}
+
+func (r *Reader) Position() (int, error) {
+	return r.pos, nil
+}
+
+func (r *Reader) SetPosition(p int) error {
+	if p < 0 || p > len(r.data) {
+		return fmt.Errorf("position out of bounds: %v", p)
+	}
+
+	r.pos = p
+
+	return nil
+}
+
+func (r *Reader) Range(p1 int, p2 int) ([]byte, error) {
+	if p1 < 0 || p1 > p2 || p2 > len(r.data) {
+		return nil, fmt.Errorf("len(%d) -> position(s) out of bounds: %v - %v", len(r.data), p1, p2)
+	}
+
+	return r.data[p1:p2], nil
+}
+
+func (r *Reader) Length(p1 int, p2 int) int {
+	return p2 - p1
+}
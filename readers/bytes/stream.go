@@ -0,0 +1,271 @@
+package bytes
+
+import (
+	"bufio"
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"io"
+)
+
+// DefaultWindow is the default number of bytes a Stream keeps behind the current position for backtracking
+const DefaultWindow = 64 * 1024
+
+// minCompact is the minimum number of bytes a Stream discards at once
+const minCompact = 4096
+
+// WindowError is returned when a position is requested that has already been discarded from a streaming reader
+type WindowError struct {
+	Pos    int
+	Oldest int
+}
+
+func (e *WindowError) Error() string {
+	return fmt.Sprintf("position %d is outside the retained window, oldest position is %d", e.Pos, e.Oldest)
+}
+
+// Stream reads bytes from an io.Reader on demand and only keeps a sliding window of bytes behind the current
+// position and everything from the oldest live checkpoint or mark, so input of arbitrary size can be matched
+type Stream struct {
+	in          *bufio.Reader
+	buf         []byte
+	base        int
+	pos         int
+	window      int
+	checkpoints map[int]int
+	marks       map[ebnf.Token]int
+	nextMark    ebnf.Token
+	eof         bool
+	err         error
+}
+
+// NewStream creates a new streaming reader that keeps window bytes for backtracking, if window <= 0 DefaultWindow
+// is used
+func NewStream(r io.Reader, window int) (*Stream, error) {
+	return NewStreamWithOptions(r, window, Options{})
+}
+
+// NewStreamWithOptions creates a new streaming reader with options
+func NewStreamWithOptions(r io.Reader, window int, opts Options) (*Stream, error) {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+
+	return newStream(r, window, opts)
+}
+
+// NewCheckpointStream creates a new streaming reader without a backtracking window, only input from the oldest live
+// checkpoint is retained
+func NewCheckpointStream(r io.Reader, opts Options) (*Stream, error) {
+	return newStream(r, 0, opts)
+}
+
+func newStream(r io.Reader, window int, opts Options) (*Stream, error) {
+	in, err := open(r, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stream{
+		in:          bufio.NewReader(in),
+		window:      window,
+		checkpoints: map[int]int{},
+		marks:       map[ebnf.Token]int{},
+	}, nil
+}
+
+// Checkpoint returns the current position and retains all input from the position until it is released
+func (s *Stream) Checkpoint() (int, error) {
+	s.checkpoints[s.pos]++
+	return s.pos, nil
+}
+
+// Release releases a checkpoint
+func (s *Stream) Release(p int) {
+	if n, ok := s.checkpoints[p]; ok {
+		if n > 1 {
+			s.checkpoints[p] = n - 1
+		} else {
+			delete(s.checkpoints, p)
+		}
+	}
+}
+
+// Mark marks the current position and retains all input from the position until the mark is discarded
+func (s *Stream) Mark() ebnf.Token {
+	token := s.nextMark
+	s.nextMark++
+	s.marks[token], _ = s.Checkpoint()
+
+	return token
+}
+
+// Reset returns to a marked position
+func (s *Stream) Reset(token ebnf.Token) error {
+	p, ok := s.marks[token]
+	if !ok {
+		return fmt.Errorf("unknown mark %d", token)
+	}
+
+	s.pos = p
+
+	return nil
+}
+
+// Discard discards a mark
+func (s *Stream) Discard(token ebnf.Token) {
+	if p, ok := s.marks[token]; ok {
+		delete(s.marks, token)
+		s.Release(p)
+	}
+}
+
+// fill makes sure index is buffered if the input has enough bytes, returns false if index is beyond the end
+func (s *Stream) fill(index int) bool {
+	if s.base+len(s.buf) <= index {
+		s.discard()
+	}
+
+	for s.base+len(s.buf) <= index {
+		if s.eof || s.err != nil {
+			return false
+		}
+
+		b, err := s.in.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				s.eof = true
+			} else {
+				s.err = err
+			}
+
+			return false
+		}
+
+		s.buf = append(s.buf, b)
+	}
+
+	return true
+}
+
+// discard drops bytes that are more than window bytes behind the current position and before the oldest checkpoint
+func (s *Stream) discard() {
+	retain := s.pos - s.window
+
+	for index := range s.checkpoints {
+		retain = min(retain, index)
+	}
+
+	drop := retain - s.base
+
+	if drop < max(s.window, minCompact) {
+		return
+	}
+
+	n := copy(s.buf, s.buf[drop:])
+	s.buf = s.buf[:n]
+	s.base += drop
+}
+
+// endError returns the read error if set, otherwise io.EOF
+func (s *Stream) endError() error {
+	if s.err != nil {
+		return s.err
+	}
+
+	return io.EOF
+}
+
+func (s *Stream) Peek1() (byte, error) {
+	if !s.fill(s.pos) {
+		return 0, s.endError()
+	}
+
+	return s.buf[s.pos-s.base], nil
+}
+
+func (s *Stream) Read1() (byte, error) {
+	if !s.fill(s.pos) {
+		return 0, s.endError()
+	}
+
+	b := s.buf[s.pos-s.base]
+	s.pos++
+
+	return b, nil
+}
+
+func (s *Stream) Peek(n int, buf []byte) (int, error) {
+	s.fill(s.pos + n - 1)
+
+	i := copy(buf[:n], s.buf[s.pos-s.base:])
+	if i != n {
+		return i, s.endError()
+	}
+
+	return i, nil
+}
+
+func (s *Stream) read(n int, buf []byte) (int, error) {
+	s.fill(s.pos + n - 1)
+
+	i := min(n, s.base+len(s.buf)-s.pos)
+
+	if buf != nil {
+		copy(buf, s.buf[s.pos-s.base:s.pos-s.base+i])
+	}
+
+	s.pos += i
+
+	if i != n {
+		return i, s.endError()
+	}
+
+	return i, nil
+}
+
+func (s *Stream) Read(n int, buf []byte) (int, error) {
+	return s.read(n, buf)
+}
+
+func (s *Stream) Skip(n int) (int, error) {
+	return s.read(n, nil)
+}
+
+func (s *Stream) Finished() bool {
+	return !s.fill(s.pos)
+}
+
+func (s *Stream) Position() (int, error) {
+	return s.pos, nil
+}
+
+func (s *Stream) SetPosition(p int) error {
+	if p < s.base {
+		return &WindowError{Pos: p, Oldest: s.base}
+	}
+
+	if p > s.base+len(s.buf) {
+		return fmt.Errorf("position out of bounds: %v", p)
+	}
+
+	s.pos = p
+
+	return nil
+}
+
+// Range returns a copy of the bytes between p1 and p2, the buffer is reused when the window slides
+func (s *Stream) Range(p1 int, p2 int) ([]byte, error) {
+	if p1 < s.base {
+		return nil, &WindowError{Pos: p1, Oldest: s.base}
+	}
+
+	if p2 < p1 || p2 > s.base+len(s.buf) {
+		return nil, fmt.Errorf("position(s) out of bounds: %v - %v", p1, p2)
+	}
+
+	return append([]byte{}, s.buf[p1-s.base:p2-s.base]...), nil
+}
+
+func (s *Stream) Length(p1 int, p2 int) int {
+	return p2 - p1
+}
@@ -0,0 +1,121 @@
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"io"
+)
+
+// Format of compressed input
+type Format int
+
+const (
+	None Format = iota
+	Gzip
+	Zstd
+	Bzip2
+)
+
+func (f Format) String() string {
+	switch f {
+	case Gzip:
+		return "gzip"
+	case Zstd:
+		return "zstd"
+	case Bzip2:
+		return "bzip2"
+	}
+
+	return "none"
+}
+
+var magics = []struct {
+	format Format
+	magic  []byte
+}{
+	{Gzip, []byte{0x1F, 0x8B}},
+	{Zstd, []byte{0x28, 0xB5, 0x2F, 0xFD}},
+	{Bzip2, []byte("BZh")},
+}
+
+// Detect detects the compression format from the magic bytes at the start of the input, the returned reader
+// serves the complete input including the magic bytes
+func Detect(r io.Reader) (Format, io.Reader, error) {
+	in := bufio.NewReader(r)
+
+	header, err := in.Peek(4)
+	if err != nil && err != io.EOF {
+		return None, nil, err
+	}
+
+	for _, m := range magics {
+		if bytes.HasPrefix(header, m.magic) {
+			// The bzip2 magic is followed by the block size '1' to '9'
+			if m.format == Bzip2 && (len(header) < 4 || header[3] < '1' || header[3] > '9') {
+				continue
+			}
+
+			return m.format, in, nil
+		}
+	}
+
+	return None, in, nil
+}
+
+// NewReader detects compressed input by magic bytes and returns a reader that decompresses on the fly, input
+// that is not compressed is returned as is. The reader must be closed to release the resources of the decompressor,
+// the zstd decompressor releases them by itself when the end of the input or an error is reached
+func NewReader(r io.Reader) (io.ReadCloser, error) {
+	format, in, err := Detect(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case Gzip:
+		return gzip.NewReader(in)
+	case Zstd:
+		dec, err := zstd.NewReader(in, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+
+		return &zstdReader{dec: dec}, nil
+	case Bzip2:
+		return io.NopCloser(bzip2.NewReader(in)), nil
+	}
+
+	return io.NopCloser(in), nil
+}
+
+// zstdReader closes the zstd decoder, which runs its own goroutines, when it is closed or when a read fails
+type zstdReader struct {
+	dec *zstd.Decoder
+	err error
+}
+
+func (z *zstdReader) Read(p []byte) (int, error) {
+	if z.err != nil {
+		return 0, z.err
+	}
+
+	n, err := z.dec.Read(p)
+	if err != nil {
+		z.err = err
+		z.dec.Close()
+	}
+
+	return n, err
+}
+
+func (z *zstdReader) Close() error {
+	if z.err == nil {
+		z.err = io.EOF
+		z.dec.Close()
+	}
+
+	return nil
+}
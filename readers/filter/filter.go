@@ -0,0 +1,246 @@
+package filter
+
+import (
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"io"
+)
+
+// Pos is a position in the filtered stream together with the position of the object in the source stream
+type Pos[P any] struct {
+	Index  int
+	Source P
+}
+
+// Offset returns the index in the filtered stream
+func (p Pos[P]) Offset() int {
+	return p.Index
+}
+
+// Filter wraps a reader and drops objects matching a predicate or a skip pattern (i.e. whitespace and comments), so
+// a grammar does not need to match trivia between every element. Positions keep the original source position, Range
+// returns the filtered objects
+type Filter[T, P any] struct {
+	src      ebnf.Reader[T, P]
+	skipFunc func(T) bool
+	skip     ebnf.Pattern[T, P]
+	objects  []T
+	sources  []P
+	end      P
+	done     bool
+	err      error
+	pos      int
+}
+
+// New creates a filter that drops all objects for which skip returns true
+func New[T, P any](src ebnf.Reader[T, P], skip func(T) bool) *Filter[T, P] {
+	return &Filter[T, P]{
+		src:      src,
+		skipFunc: skip,
+	}
+}
+
+// NewWithPattern creates a filter that drops all input matched by the skip pattern
+func NewWithPattern[T, P any](src ebnf.Reader[T, P], skip ebnf.Pattern[T, P]) *Filter[T, P] {
+	return &Filter[T, P]{
+		src:  src,
+		skip: skip,
+	}
+}
+
+// skipTrivia skips objects from the source as long as they are filtered
+func (f *Filter[T, P]) skipTrivia() error {
+	for !f.src.Finished() {
+		if f.skipFunc != nil {
+			obj, err := f.src.Peek1()
+			if ebnf.IsStreamError(err) {
+				return err
+			}
+
+			if !f.skipFunc(obj) {
+				return nil
+			}
+
+			_, err = f.src.Read1()
+			if ebnf.IsStreamError(err) {
+				return err
+			}
+
+			continue
+		}
+
+		begin, err := ebnf.NewMarker(f.src)
+		if ebnf.IsStreamError(err) {
+			return err
+		}
+
+		matched, _, err := ebnf.MatchPattern(f.skip, f.src)
+		if err != nil {
+			begin.Discard()
+			return err
+		}
+
+		end, err := f.src.Position()
+		if ebnf.IsStreamError(err) {
+			begin.Discard()
+			return err
+		}
+
+		// Stop when the skip pattern does not match or does not consume anything
+		if !matched || f.src.Length(begin.Pos(), end) == 0 {
+			err = begin.Reset()
+			begin.Discard()
+			return err
+		}
+
+		begin.Discard()
+	}
+
+	return nil
+}
+
+// load makes sure the object at index is loaded if available, returns false if index is beyond the end
+func (f *Filter[T, P]) load(index int) bool {
+	for len(f.objects) <= index {
+		if f.done || f.err != nil {
+			return false
+		}
+
+		err := f.skipTrivia()
+		if ebnf.IsStreamError(err) {
+			f.err = err
+			return false
+		}
+
+		pos, err := f.src.Position()
+		if ebnf.IsStreamError(err) {
+			f.err = err
+			return false
+		}
+
+		if f.src.Finished() {
+			f.end = pos
+			f.done = true
+			return false
+		}
+
+		obj, err := f.src.Read1()
+		if ebnf.IsStreamError(err) {
+			f.err = err
+			return false
+		}
+
+		f.objects = append(f.objects, obj)
+		f.sources = append(f.sources, pos)
+	}
+
+	return true
+}
+
+// endError returns the source error if set, otherwise io.EOF
+func (f *Filter[T, P]) endError() error {
+	if f.err != nil {
+		return f.err
+	}
+
+	return io.EOF
+}
+
+func (f *Filter[T, P]) Peek1() (T, error) {
+	if !f.load(f.pos) {
+		var zero T
+		return zero, f.endError()
+	}
+
+	return f.objects[f.pos], nil
+}
+
+func (f *Filter[T, P]) Read1() (T, error) {
+	if !f.load(f.pos) {
+		var zero T
+		return zero, f.endError()
+	}
+
+	obj := f.objects[f.pos]
+	f.pos++
+
+	return obj, nil
+}
+
+func (f *Filter[T, P]) Peek(n int, buf []T) (int, error) {
+	f.load(f.pos + n - 1)
+
+	i := copy(buf[:n], f.objects[f.pos:])
+	if i != n {
+		return i, f.endError()
+	}
+
+	return i, nil
+}
+
+func (f *Filter[T, P]) read(n int, buf []T) (int, error) {
+	f.load(f.pos + n - 1)
+
+	i := min(n, len(f.objects)-f.pos)
+
+	if buf != nil {
+		copy(buf, f.objects[f.pos:f.pos+i])
+	}
+
+	f.pos += i
+
+	if i != n {
+		return i, f.endError()
+	}
+
+	return i, nil
+}
+
+func (f *Filter[T, P]) Read(n int, buf []T) (int, error) {
+	return f.read(n, buf)
+}
+
+func (f *Filter[T, P]) Skip(n int) (int, error) {
+	return f.read(n, nil)
+}
+
+func (f *Filter[T, P]) Finished() bool {
+	return !f.load(f.pos)
+}
+
+// Position returns the current position, the source position is the position of the next object that is not
+// filtered, or the end of the source
+func (f *Filter[T, P]) Position() (Pos[P], error) {
+	if f.load(f.pos) {
+		return Pos[P]{Index: f.pos, Source: f.sources[f.pos]}, nil
+	}
+
+	if f.err != nil {
+		return Pos[P]{}, f.err
+	}
+
+	return Pos[P]{Index: f.pos, Source: f.end}, nil
+}
+
+func (f *Filter[T, P]) SetPosition(p Pos[P]) error {
+	if p.Index < 0 || p.Index > len(f.objects) {
+		return fmt.Errorf("position out of bounds: %v", p.Index)
+	}
+
+	f.pos = p.Index
+
+	return nil
+}
+
+// Range returns the filtered objects between p1 and p2
+func (f *Filter[T, P]) Range(p1 Pos[P], p2 Pos[P]) ([]T, error) {
+	if p1.Index < 0 || p1.Index > p2.Index || p2.Index > len(f.objects) {
+		return nil, fmt.Errorf("position(s) out of bounds: %v - %v", p1.Index, p2.Index)
+	}
+
+	return f.objects[p1.Index:p2.Index], nil
+}
+
+func (f *Filter[T, P]) Length(p1 Pos[P], p2 Pos[P]) int {
+	return p2.Index - p1.Index
+}
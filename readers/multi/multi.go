@@ -0,0 +1,239 @@
+package multi
+
+import (
+	"fmt"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Source is a named input
+type Source struct {
+	Name   string
+	Reader io.Reader
+}
+
+// String creates a named source from a string
+func String(name string, s string) Source {
+	return Source{Name: name, Reader: strings.NewReader(s)}
+}
+
+// Pos is a position in a multi source stream, Line and Col are relative to the named source, Index is the index in
+// the concatenated stream
+type Pos struct {
+	Name string
+	runes.Pos
+}
+
+// String formats the position as name:line:col with one based line and column
+func (p Pos) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.Name, p.Line+1, p.Col+1)
+}
+
+// CrossSourceError is returned by Range if the range spans more than one source
+type CrossSourceError struct {
+	Begin Pos
+	End   Pos
+}
+
+func (e *CrossSourceError) Error() string {
+	return fmt.Sprintf("range %v - %v crosses source boundary", e.Begin, e.End)
+}
+
+type span struct {
+	name  string
+	begin int
+	end   int
+}
+
+// Reader concatenates multiple named sources into one rune stream, positions carry the name of the source so
+// errors can be reported as file:line:col
+type Reader struct {
+	data    []rune
+	spans   []span
+	pos     Pos
+	columns runes.Columns
+}
+
+// New reads all sources, sources are decoded like runes.New
+func New(sources ...Source) (*Reader, error) {
+	r := &Reader{}
+
+	for _, source := range sources {
+		rd, err := runes.New(source.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source.Name, err)
+		}
+
+		begin := len(r.data)
+		r.data = append(r.data, rd.Data()...)
+		r.spans = append(r.spans, span{name: source.Name, begin: begin, end: len(r.data)})
+	}
+
+	r.pos = r.positionAt(0)
+
+	return r, nil
+}
+
+// NewFromFiles opens and reads all files, the file path is used as source name
+func NewFromFiles(paths ...string) (*Reader, error) {
+	sources := make([]Source, 0, len(paths))
+
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		defer f.Close()
+
+		sources = append(sources, Source{Name: path, Reader: f})
+	}
+
+	return New(sources...)
+}
+
+// SetColumns sets the column configuration, must be set before reading
+func (r *Reader) SetColumns(columns runes.Columns) *Reader {
+	r.columns = columns
+	return r
+}
+
+// spanIndex returns the index of the span containing index, an index at the end of a span belongs to the next
+// non-empty span
+func (r *Reader) spanIndex(index int) int {
+	i := sort.Search(len(r.spans), func(i int) bool {
+		return index < r.spans[i].end
+	})
+
+	return min(i, len(r.spans)-1)
+}
+
+// positionAt computes the position of an index
+func (r *Reader) positionAt(index int) Pos {
+	if len(r.spans) == 0 {
+		return Pos{}
+	}
+
+	s := r.spans[r.spanIndex(index)]
+	pos := Pos{Name: s.name, Pos: runes.Pos{Index: s.begin}}
+
+	for pos.Index < index {
+		r.columns.Advance(&pos.Pos, r.data[pos.Index])
+	}
+
+	return pos
+}
+
+// advance moves the current position past c, switching to the next source at a source boundary
+func (r *Reader) advance(c rune) {
+	r.columns.Advance(&r.pos.Pos, c)
+
+	if r.pos.Index < len(r.data) {
+		if s := r.spans[r.spanIndex(r.pos.Index)]; s.begin == r.pos.Index {
+			r.pos = Pos{Name: s.name, Pos: runes.Pos{Index: r.pos.Index}}
+		}
+	}
+}
+
+// Sources returns the source names in order
+func (r *Reader) Sources() []string {
+	names := make([]string, len(r.spans))
+	for i, s := range r.spans {
+		names[i] = s.name
+	}
+
+	return names
+}
+
+func (r *Reader) Peek1() (rune, error) {
+	if r.pos.Index < len(r.data) {
+		return r.data[r.pos.Index], nil
+	}
+
+	return 0, io.EOF
+}
+
+func (r *Reader) Read1() (rune, error) {
+	if r.pos.Index < len(r.data) {
+		c := r.data[r.pos.Index]
+		r.advance(c)
+		return c, nil
+	}
+
+	return 0, io.EOF
+}
+
+func (r *Reader) Peek(n int, buf []rune) (int, error) {
+	i := copy(buf[:n], r.data[r.pos.Index:])
+	if i != n {
+		return i, io.EOF
+	}
+
+	return i, nil
+}
+
+func (r *Reader) read(n int, buf []rune) (int, error) {
+	i := 0
+
+	for i < n && r.pos.Index < len(r.data) {
+		c := r.data[r.pos.Index]
+		if buf != nil {
+			buf[i] = c
+		}
+		r.advance(c)
+		i++
+	}
+
+	if i != n {
+		return i, io.EOF
+	}
+
+	return i, nil
+}
+
+func (r *Reader) Read(n int, buf []rune) (int, error) {
+	return r.read(n, buf)
+}
+
+func (r *Reader) Skip(n int) (int, error) {
+	return r.read(n, nil)
+}
+
+func (r *Reader) Finished() bool {
+	return r.pos.Index >= len(r.data)
+}
+
+func (r *Reader) Position() (Pos, error) {
+	return r.pos, nil
+}
+
+func (r *Reader) SetPosition(p Pos) error {
+	if p.Index < 0 || p.Index > len(r.data) {
+		return fmt.Errorf("position out of bounds: %v", p)
+	}
+
+	r.pos = p
+
+	return nil
+}
+
+// Range returns the runes between p1 and p2, a range spanning more than one source is rejected with a
+// CrossSourceError
+func (r *Reader) Range(p1 Pos, p2 Pos) ([]rune, error) {
+	if p1.Index < 0 || p1.Index > p2.Index || p2.Index > len(r.data) {
+		return nil, fmt.Errorf("position(s) out of bounds: %v - %v", p1, p2)
+	}
+
+	if p2.Index > p1.Index && r.spanIndex(p1.Index) != r.spanIndex(p2.Index-1) {
+		return nil, &CrossSourceError{Begin: p1, End: p2}
+	}
+
+	return r.data[p1.Index:p2.Index], nil
+}
+
+func (r *Reader) Length(p1 Pos, p2 Pos) int {
+	return p2.Index - p1.Index
+}
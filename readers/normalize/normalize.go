@@ -0,0 +1,183 @@
+package normalize
+
+import (
+	"fmt"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"golang.org/x/text/unicode/norm"
+	"io"
+)
+
+// Pos is a position in the normalized stream together with the position in the original input
+type Pos struct {
+	Index  int
+	Source runes.Pos
+}
+
+// Reader serves Unicode normalized runes (NFC, NFD, NFKC or NFKD) of a rune reader, so patterns match both
+// precomposed and combining sequence input. Input is normalized per segment (a starter rune and the combining runes
+// following it), all normalized runes of a segment map back to the position of the segment in the original input
+type Reader struct {
+	src    *runes.Reader
+	data   []rune
+	source []runes.Pos
+	ends   []runes.Pos
+	pos    int
+}
+
+// New normalizes the runes of src from its current position to the end with form
+func New(src *runes.Reader, form norm.Form) (*Reader, error) {
+	r := &Reader{
+		src: src,
+	}
+
+	var (
+		segment      []rune
+		segmentBegin runes.Pos
+	)
+
+	flush := func(segmentEnd runes.Pos) {
+		if len(segment) == 0 {
+			return
+		}
+
+		for _, c := range form.String(string(segment)) {
+			r.data = append(r.data, c)
+			r.source = append(r.source, segmentBegin)
+			r.ends = append(r.ends, segmentEnd)
+		}
+
+		segment = segment[:0]
+	}
+
+	for !src.Finished() {
+		pos, err := src.Position()
+		if err != nil {
+			return nil, err
+		}
+
+		c, err := src.Read1()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(segment) == 0 || form.PropertiesString(string(c)).BoundaryBefore() {
+			flush(pos)
+			segmentBegin = pos
+		}
+
+		segment = append(segment, c)
+	}
+
+	end, err := src.Position()
+	if err != nil {
+		return nil, err
+	}
+
+	flush(end)
+
+	// End of stream position
+	r.source = append(r.source, end)
+
+	return r, nil
+}
+
+// Data returns the normalized runes
+func (r *Reader) Data() []rune {
+	return r.data
+}
+
+func (r *Reader) Peek1() (rune, error) {
+	if r.pos < len(r.data) {
+		return r.data[r.pos], nil
+	}
+
+	return 0, io.EOF
+}
+
+func (r *Reader) Read1() (rune, error) {
+	if r.pos < len(r.data) {
+		c := r.data[r.pos]
+		r.pos++
+		return c, nil
+	}
+
+	return 0, io.EOF
+}
+
+func (r *Reader) Peek(n int, buf []rune) (int, error) {
+	i := copy(buf[:n], r.data[r.pos:])
+	if i != n {
+		return i, io.EOF
+	}
+
+	return i, nil
+}
+
+func (r *Reader) read(n int, buf []rune) (int, error) {
+	i := min(n, len(r.data)-r.pos)
+
+	if buf != nil {
+		copy(buf, r.data[r.pos:r.pos+i])
+	}
+
+	r.pos += i
+
+	if i != n {
+		return i, io.EOF
+	}
+
+	return i, nil
+}
+
+func (r *Reader) Read(n int, buf []rune) (int, error) {
+	return r.read(n, buf)
+}
+
+func (r *Reader) Skip(n int) (int, error) {
+	return r.read(n, nil)
+}
+
+func (r *Reader) Finished() bool {
+	return r.pos >= len(r.data)
+}
+
+func (r *Reader) Position() (Pos, error) {
+	return Pos{Index: r.pos, Source: r.source[r.pos]}, nil
+}
+
+func (r *Reader) SetPosition(p Pos) error {
+	if p.Index < 0 || p.Index > len(r.data) {
+		return fmt.Errorf("position out of bounds: %v", p)
+	}
+
+	r.pos = p.Index
+
+	return nil
+}
+
+// Range returns the original input between p1 and p2, a begin position inside a segment maps to the begin of the
+// segment and an end position inside a segment maps to the end of the segment
+func (r *Reader) Range(p1 Pos, p2 Pos) ([]rune, error) {
+	if p1.Index < 0 || p1.Index > p2.Index || p2.Index > len(r.data) {
+		return nil, fmt.Errorf("position(s) out of bounds: %v - %v", p1, p2)
+	}
+
+	if p1.Index == p2.Index {
+		return []rune{}, nil
+	}
+
+	return r.src.Range(r.source[p1.Index], r.ends[p2.Index-1])
+}
+
+// Normalized returns the normalized runes between p1 and p2
+func (r *Reader) Normalized(p1 Pos, p2 Pos) ([]rune, error) {
+	if p1.Index < 0 || p1.Index > p2.Index || p2.Index > len(r.data) {
+		return nil, fmt.Errorf("position(s) out of bounds: %v - %v", p1, p2)
+	}
+
+	return r.data[p1.Index:p2.Index], nil
+}
+
+func (r *Reader) Length(p1 Pos, p2 Pos) int {
+	return p2.Index - p1.Index
+}
@@ -0,0 +1,327 @@
+package rope
+
+import (
+	"fmt"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"io"
+	"sort"
+)
+
+// Edit describes a single edit, Deleted runes at Index were replaced by Inserted runes
+type Edit struct {
+	Index    int
+	Deleted  int
+	Inserted int
+}
+
+// Map maps an index from before the edit to an index after the edit, indices inside the deleted range are mapped
+// to the end of the inserted runes
+func (e Edit) Map(index int) int {
+	switch {
+	case index < e.Index:
+		return index
+	case index < e.Index+e.Deleted:
+		return e.Index + e.Inserted
+	}
+
+	return index - e.Deleted + e.Inserted
+}
+
+// piece references a run of runes in the original or the add buffer
+type piece struct {
+	add    bool
+	start  int
+	length int
+}
+
+// Reader serves runes from a piece table so the text can be edited with Insert and Delete between matches without
+// copying the whole text. Every edit increments the version, positions from an older version can be translated
+// to the current version with Translate, this is the foundation for incremental re-parsing
+type Reader struct {
+	original []rune
+	add      []rune
+	pieces   []piece
+	starts   []int
+	length   int
+	edits    []Edit
+	pos      runes.Pos
+	columns  runes.Columns
+}
+
+// New reads all runes from r, input is decoded like runes.New
+func New(r io.Reader) (*Reader, error) {
+	rd, err := runes.New(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFromRunes(rd.Data()), nil
+}
+
+// NewFromRunes creates a reader on top of data, data is not copied and never modified
+func NewFromRunes(data []rune) *Reader {
+	r := &Reader{original: data}
+
+	if len(data) > 0 {
+		r.pieces = []piece{{start: 0, length: len(data)}}
+	}
+
+	r.index()
+
+	return r
+}
+
+// SetColumns sets the column configuration, must be set before reading
+func (r *Reader) SetColumns(columns runes.Columns) *Reader {
+	r.columns = columns
+	return r
+}
+
+// index recomputes the piece start indices and the total length
+func (r *Reader) index() {
+	r.starts = r.starts[:0]
+	r.length = 0
+
+	for _, p := range r.pieces {
+		r.starts = append(r.starts, r.length)
+		r.length += p.length
+	}
+}
+
+// locate returns the piece containing index and the offset in the piece
+func (r *Reader) locate(index int) (int, int) {
+	i := sort.Search(len(r.starts), func(i int) bool {
+		return r.starts[i] > index
+	}) - 1
+
+	return i, index - r.starts[i]
+}
+
+func (r *Reader) buffer(p piece) []rune {
+	if p.add {
+		return r.add[p.start : p.start+p.length]
+	}
+
+	return r.original[p.start : p.start+p.length]
+}
+
+func (r *Reader) at(index int) rune {
+	i, offset := r.locate(index)
+	return r.buffer(r.pieces[i])[offset]
+}
+
+// split makes sure a piece starts at index and returns the index of that piece
+func (r *Reader) split(index int) int {
+	if index >= r.length {
+		return len(r.pieces)
+	}
+
+	i, offset := r.locate(index)
+	if offset == 0 {
+		return i
+	}
+
+	p := r.pieces[i]
+	left := piece{add: p.add, start: p.start, length: offset}
+	right := piece{add: p.add, start: p.start + offset, length: p.length - offset}
+
+	r.pieces = append(r.pieces[:i], append([]piece{left, right}, r.pieces[i+1:]...)...)
+	r.index()
+
+	return i + 1
+}
+
+// Len returns the number of runes
+func (r *Reader) Len() int {
+	return r.length
+}
+
+// Version returns the number of edits applied
+func (r *Reader) Version() int {
+	return len(r.edits)
+}
+
+// Edits returns the edits applied since version
+func (r *Reader) Edits(since int) []Edit {
+	return r.edits[min(max(since, 0), len(r.edits)):]
+}
+
+// Data returns a copy of the current text
+func (r *Reader) Data() []rune {
+	data := make([]rune, 0, r.length)
+
+	for _, p := range r.pieces {
+		data = append(data, r.buffer(p)...)
+	}
+
+	return data
+}
+
+func (r *Reader) String() string {
+	return string(r.Data())
+}
+
+// Insert inserts data at index, the current position is translated
+func (r *Reader) Insert(index int, data []rune) error {
+	return r.Replace(index, 0, data)
+}
+
+// Delete deletes n runes at index, the current position is translated
+func (r *Reader) Delete(index int, n int) error {
+	return r.Replace(index, n, nil)
+}
+
+// Replace replaces n runes at index with data, the current position is translated
+func (r *Reader) Replace(index int, n int, data []rune) error {
+	if index < 0 || n < 0 || index+n > r.length {
+		return fmt.Errorf("len(%d) -> edit out of bounds: %d - %d", r.length, index, index+n)
+	}
+
+	if n == 0 && len(data) == 0 {
+		return nil
+	}
+
+	begin := r.split(index)
+	end := r.split(index + n)
+
+	var inserted []piece
+
+	if len(data) > 0 {
+		inserted = []piece{{add: true, start: len(r.add), length: len(data)}}
+		r.add = append(r.add, data...)
+	}
+
+	r.pieces = append(r.pieces[:begin], append(inserted, r.pieces[end:]...)...)
+	r.index()
+
+	edit := Edit{Index: index, Deleted: n, Inserted: len(data)}
+	r.edits = append(r.edits, edit)
+	r.pos = r.PositionAt(edit.Map(r.pos.Index))
+
+	return nil
+}
+
+// PositionAt returns the position of index in the current text
+func (r *Reader) PositionAt(index int) runes.Pos {
+	var pos runes.Pos
+
+	index = min(max(index, 0), r.length)
+
+	for _, p := range r.pieces {
+		for _, c := range r.buffer(p) {
+			if pos.Index == index {
+				return pos
+			}
+
+			r.columns.Advance(&pos, c)
+		}
+	}
+
+	return pos
+}
+
+// Translate translates a position from an older version to the current version
+func (r *Reader) Translate(p runes.Pos, version int) runes.Pos {
+	index := p.Index
+
+	for _, edit := range r.Edits(version) {
+		index = edit.Map(index)
+	}
+
+	return r.PositionAt(index)
+}
+
+func (r *Reader) Peek1() (rune, error) {
+	if r.pos.Index < r.length {
+		return r.at(r.pos.Index), nil
+	}
+
+	return 0, io.EOF
+}
+
+func (r *Reader) Read1() (rune, error) {
+	if r.pos.Index < r.length {
+		c := r.at(r.pos.Index)
+		r.columns.Advance(&r.pos, c)
+
+		return c, nil
+	}
+
+	return 0, io.EOF
+}
+
+func (r *Reader) Peek(n int, buf []rune) (int, error) {
+	i := 0
+	for p := r.pos.Index; i < n && p < r.length; p++ {
+		buf[i] = r.at(p)
+		i++
+	}
+
+	if i != n {
+		return i, io.EOF
+	}
+
+	return i, nil
+}
+
+func (r *Reader) read(n int, buf []rune) (int, error) {
+	i := 0
+	for i < n && r.pos.Index < r.length {
+		c := r.at(r.pos.Index)
+		if buf != nil {
+			buf[i] = c
+		}
+		r.columns.Advance(&r.pos, c)
+		i++
+	}
+
+	if i != n {
+		return i, io.EOF
+	}
+
+	return i, nil
+}
+
+func (r *Reader) Read(n int, buf []rune) (int, error) {
+	return r.read(n, buf)
+}
+
+func (r *Reader) Skip(n int) (int, error) {
+	return r.read(n, nil)
+}
+
+func (r *Reader) Finished() bool {
+	return r.pos.Index >= r.length
+}
+
+func (r *Reader) Position() (runes.Pos, error) {
+	return r.pos, nil
+}
+
+func (r *Reader) SetPosition(p runes.Pos) error {
+	if p.Index < 0 || p.Index > r.length {
+		return fmt.Errorf("position out of bounds: %v", p)
+	}
+
+	r.pos = p
+
+	return nil
+}
+
+// Range returns a copy of the runes between p1 and p2
+func (r *Reader) Range(p1 runes.Pos, p2 runes.Pos) ([]rune, error) {
+	if p1.Index < 0 || p2.Index < p1.Index || p2.Index > r.length {
+		return nil, fmt.Errorf("len(%d) -> position(s) out of bounds: %v - %v", r.length, p1, p2)
+	}
+
+	data := make([]rune, p2.Index-p1.Index)
+	for i := range data {
+		data[i] = r.at(p1.Index + i)
+	}
+
+	return data, nil
+}
+
+func (r *Reader) Length(p1 runes.Pos, p2 runes.Pos) int {
+	return p2.Index - p1.Index
+}
@@ -0,0 +1,47 @@
+package runes
+
+import (
+	"golang.org/x/text/width"
+	"unicode"
+)
+
+// Columns configures how Pos.Col is computed so reported columns match what editors display. The zero value counts
+// every rune as one column
+type Columns struct {
+	// TabWidth advances the column to the next multiple of TabWidth for a tab, 0 counts a tab as one column
+	TabWidth int
+	// EastAsianWidth counts East Asian wide and fullwidth runes as two columns and combining marks and format
+	// characters as zero columns
+	EastAsianWidth bool
+}
+
+// Advance moves pos past rune c
+func (cols Columns) Advance(pos *Pos, c rune) {
+	pos.Index++
+
+	switch {
+	case c == '\n':
+		pos.Line++
+		pos.Col = 0
+	case c == '\t' && cols.TabWidth > 0:
+		pos.Col = (pos.Col/cols.TabWidth + 1) * cols.TabWidth
+	case cols.EastAsianWidth:
+		pos.Col += runeWidth(c)
+	default:
+		pos.Col++
+	}
+}
+
+// runeWidth returns the number of columns a rune occupies in a monospaced East Asian aware display
+func runeWidth(c rune) int {
+	if unicode.In(c, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+
+	switch width.LookupRune(c).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+
+	return 1
+}
@@ -0,0 +1,225 @@
+package runes
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/almerlucke/exbana/v2/readers/compress"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding of the input
+type Encoding int
+
+const (
+	UTF8 Encoding = iota
+	UTF16LE
+	UTF16BE
+)
+
+func (e Encoding) String() string {
+	switch e {
+	case UTF16LE:
+		return "UTF-16LE"
+	case UTF16BE:
+		return "UTF-16BE"
+	}
+
+	return "UTF-8"
+}
+
+// Decoding configures how input bytes are decoded to runes. The encoding is detected from the byte order mark, a
+// UTF-8 BOM is stripped, FF FE selects UTF-16LE and FE FF selects UTF-16BE. Input without BOM is decoded as
+// Default
+type Decoding struct {
+	// Default is the encoding used when there is no byte order mark
+	Default Encoding
+	// Strict returns an EncodingError for invalid sequences instead of replacing them with U+FFFD
+	Strict bool
+	// Decompress detects gzip, zstd and bzip2 compressed input by magic bytes and decompresses it on the fly
+	Decompress bool
+}
+
+// EncodingError is returned in strict decoding mode for invalid encoded input
+type EncodingError struct {
+	Encoding Encoding
+	Offset   int64
+}
+
+func (e *EncodingError) Error() string {
+	return fmt.Sprintf("invalid %v sequence at byte offset %d", e.Encoding, e.Offset)
+}
+
+// decoder is an io.RuneReader that decodes UTF-8 or UTF-16 input
+type decoder struct {
+	src      io.Reader
+	closer   io.Closer
+	in       *bufio.Reader
+	decoding Decoding
+	encoding Encoding
+	offset   int64
+	started  bool
+	err      error
+}
+
+func newDecoder(r io.Reader, decoding Decoding) *decoder {
+	return &decoder{
+		src:      r,
+		decoding: decoding,
+		encoding: decoding.Default,
+	}
+}
+
+// detect decompresses the input if configured and reads the byte order mark if present
+func (d *decoder) detect() error {
+	d.started = true
+
+	src := d.src
+
+	if d.decoding.Decompress {
+		var err error
+
+		in, err := compress.NewReader(src)
+		if err != nil {
+			return err
+		}
+
+		src, d.closer = in, in
+	}
+
+	d.in = bufio.NewReader(src)
+
+	bom, _ := d.in.Peek(3)
+
+	switch {
+	case len(bom) >= 3 && bom[0] == 0xEF && bom[1] == 0xBB && bom[2] == 0xBF:
+		d.encoding = UTF8
+		d.skip(3)
+	case len(bom) >= 2 && bom[0] == 0xFF && bom[1] == 0xFE:
+		d.encoding = UTF16LE
+		d.skip(2)
+	case len(bom) >= 2 && bom[0] == 0xFE && bom[1] == 0xFF:
+		d.encoding = UTF16BE
+		d.skip(2)
+	}
+
+	return nil
+}
+
+// close releases the decompressor
+func (d *decoder) close() {
+	if d.closer != nil {
+		_ = d.closer.Close()
+	}
+}
+
+func (d *decoder) skip(n int) {
+	skipped, _ := d.in.Discard(n)
+	d.offset += int64(skipped)
+}
+
+// invalid returns U+FFFD or an error in strict mode
+func (d *decoder) invalid(offset int64, size int) (rune, int, error) {
+	if d.decoding.Strict {
+		return 0, 0, &EncodingError{Encoding: d.encoding, Offset: offset}
+	}
+
+	return utf8.RuneError, size, nil
+}
+
+func (d *decoder) ReadRune() (rune, int, error) {
+	if !d.started {
+		d.err = d.detect()
+	}
+
+	if d.err != nil {
+		return 0, 0, d.err
+	}
+
+	if d.encoding == UTF8 {
+		return d.readUTF8()
+	}
+
+	return d.readUTF16()
+}
+
+func (d *decoder) readUTF8() (rune, int, error) {
+	offset := d.offset
+
+	c, size, err := d.in.ReadRune()
+	if err != nil {
+		return c, size, err
+	}
+
+	d.offset += int64(size)
+
+	if c == utf8.RuneError && size == 1 {
+		return d.invalid(offset, size)
+	}
+
+	return c, size, nil
+}
+
+// readUnit reads a single UTF-16 code unit
+func (d *decoder) readUnit() (uint16, error) {
+	var b [2]byte
+
+	n, err := io.ReadFull(d.in, b[:])
+	d.offset += int64(n)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if d.encoding == UTF16LE {
+		return uint16(b[0]) | uint16(b[1])<<8, nil
+	}
+
+	return uint16(b[1]) | uint16(b[0])<<8, nil
+}
+
+func (d *decoder) readUTF16() (rune, int, error) {
+	offset := d.offset
+
+	u1, err := d.readUnit()
+	if err != nil {
+		if err == io.ErrUnexpectedEOF {
+			// Odd trailing byte
+			return d.invalid(offset, 1)
+		}
+
+		return 0, 0, err
+	}
+
+	if !utf16.IsSurrogate(rune(u1)) {
+		return rune(u1), 2, nil
+	}
+
+	// Low surrogate without high surrogate
+	if u1 >= 0xDC00 {
+		return d.invalid(offset, 2)
+	}
+
+	next, err := d.in.Peek(2)
+	if err != nil || len(next) < 2 {
+		return d.invalid(offset, 2)
+	}
+
+	var u2 uint16
+	if d.encoding == UTF16LE {
+		u2 = uint16(next[0]) | uint16(next[1])<<8
+	} else {
+		u2 = uint16(next[1]) | uint16(next[0])<<8
+	}
+
+	c := utf16.DecodeRune(rune(u1), rune(u2))
+	if c == utf8.RuneError {
+		// High surrogate not followed by low surrogate, leave the next unit for the next read
+		return d.invalid(offset, 2)
+	}
+
+	d.skip(2)
+
+	return c, 4, nil
+}
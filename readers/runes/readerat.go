@@ -0,0 +1,278 @@
+package runes
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"sort"
+	"unicode/utf8"
+)
+
+// DefaultBlockSize is the default number of bytes decoded at once by a ReaderAt
+const DefaultBlockSize = 4096
+
+// DefaultBlocks is the default number of decoded blocks a ReaderAt keeps in memory
+const DefaultBlocks = 64
+
+type block struct {
+	index int
+	data  []rune
+}
+
+// ReaderAt serves runes from an io.ReaderAt (i.e. os.File) and decodes UTF-8 on demand per block of bytes. Only
+// the most recently used decoded blocks are kept in memory, so random backtracking over huge files stays cheap
+// without a full copy of the input. Blocks are indexed the first time they are decoded, runes are served as read,
+// without line ending conversion, invalid sequences are replaced with U+FFFD
+type ReaderAt struct {
+	in        io.ReaderAt
+	size      int64
+	blockSize int
+	capacity  int
+	offsets   []int64
+	starts    []int
+	complete  bool
+	cache     map[int]*list.Element
+	lru       *list.List
+	pos       Pos
+	columns   Columns
+	err       error
+}
+
+// NewReaderAt creates a new reader on top of size bytes of in, blockSize is the number of bytes decoded at once and
+// blocks the number of decoded blocks to keep, if <= 0 DefaultBlockSize and DefaultBlocks are used
+func NewReaderAt(in io.ReaderAt, size int64, blockSize int, blocks int) *ReaderAt {
+	if blockSize <= 0 {
+		blockSize = DefaultBlockSize
+	}
+
+	if blocks <= 0 {
+		blocks = DefaultBlocks
+	}
+
+	return &ReaderAt{
+		in:        in,
+		size:      size,
+		blockSize: blockSize,
+		capacity:  blocks,
+		offsets:   []int64{0},
+		starts:    []int{0},
+		cache:     map[int]*list.Element{},
+		lru:       list.New(),
+	}
+}
+
+// SetColumns sets the column configuration, must be set before reading
+func (r *ReaderAt) SetColumns(columns Columns) *ReaderAt {
+	r.columns = columns
+	return r
+}
+
+// decode reads and decodes block k, the block is extended to the end of the last rune starting in the block
+func (r *ReaderAt) decode(k int) ([]rune, error) {
+	offset := r.offsets[k]
+	n := min(int64(r.blockSize+utf8.UTFMax-1), r.size-offset)
+	buf := make([]byte, n)
+
+	read, err := r.in.ReadAt(buf, offset)
+	if read < len(buf) && err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	buf = buf[:read]
+
+	var (
+		data     = make([]rune, 0, min(read, r.blockSize))
+		consumed int
+	)
+
+	for consumed < r.blockSize && consumed < len(buf) {
+		c, size := utf8.DecodeRune(buf[consumed:])
+		data = append(data, c)
+		consumed += size
+	}
+
+	if k == len(r.offsets)-1 && !r.complete {
+		end := offset + int64(consumed)
+
+		if end >= r.size || consumed == 0 {
+			r.complete = true
+		} else {
+			r.offsets = append(r.offsets, end)
+			r.starts = append(r.starts, r.starts[k]+len(data))
+		}
+	}
+
+	return data, nil
+}
+
+// block returns decoded block k from the cache or decodes it
+func (r *ReaderAt) block(k int) ([]rune, error) {
+	if e, ok := r.cache[k]; ok {
+		r.lru.MoveToFront(e)
+		return e.Value.(*block).data, nil
+	}
+
+	data, err := r.decode(k)
+	if err != nil {
+		return nil, err
+	}
+
+	r.cache[k] = r.lru.PushFront(&block{index: k, data: data})
+
+	if r.lru.Len() > r.capacity {
+		oldest := r.lru.Back()
+		r.lru.Remove(oldest)
+		delete(r.cache, oldest.Value.(*block).index)
+	}
+
+	return data, nil
+}
+
+// at returns the rune at index, returns false if index is beyond the end or decoding failed
+func (r *ReaderAt) at(index int) (rune, bool) {
+	if r.err != nil || index < 0 {
+		return 0, false
+	}
+
+	for {
+		k := sort.Search(len(r.starts), func(i int) bool {
+			return r.starts[i] > index
+		}) - 1
+
+		data, err := r.block(k)
+		if err != nil {
+			r.err = err
+			return 0, false
+		}
+
+		if index-r.starts[k] < len(data) {
+			return data[index-r.starts[k]], true
+		}
+
+		// Decoding the last known block indexes the next block, if there is none we are at the end
+		if k == len(r.starts)-1 {
+			return 0, false
+		}
+	}
+}
+
+// endError returns the read error if set, otherwise io.EOF
+func (r *ReaderAt) endError() error {
+	if r.err != nil {
+		return r.err
+	}
+
+	return io.EOF
+}
+
+func (r *ReaderAt) Peek1() (rune, error) {
+	c, ok := r.at(r.pos.Index)
+	if !ok {
+		return 0, r.endError()
+	}
+
+	return c, nil
+}
+
+func (r *ReaderAt) Read1() (rune, error) {
+	c, ok := r.at(r.pos.Index)
+	if !ok {
+		return 0, r.endError()
+	}
+
+	r.columns.Advance(&r.pos, c)
+
+	return c, nil
+}
+
+func (r *ReaderAt) Peek(n int, buf []rune) (int, error) {
+	i := 0
+	for ; i < n; i++ {
+		c, ok := r.at(r.pos.Index + i)
+		if !ok {
+			return i, r.endError()
+		}
+
+		buf[i] = c
+	}
+
+	return i, nil
+}
+
+func (r *ReaderAt) read(n int, buf []rune) (int, error) {
+	i := 0
+	for ; i < n; i++ {
+		c, ok := r.at(r.pos.Index)
+		if !ok {
+			return i, r.endError()
+		}
+
+		if buf != nil {
+			buf[i] = c
+		}
+
+		r.columns.Advance(&r.pos, c)
+	}
+
+	return i, nil
+}
+
+func (r *ReaderAt) Read(n int, buf []rune) (int, error) {
+	return r.read(n, buf)
+}
+
+func (r *ReaderAt) Skip(n int) (int, error) {
+	return r.read(n, nil)
+}
+
+func (r *ReaderAt) Finished() bool {
+	_, ok := r.at(r.pos.Index)
+	return !ok
+}
+
+func (r *ReaderAt) Position() (Pos, error) {
+	return r.pos, nil
+}
+
+// valid returns true if index is a valid position, the end of the input included
+func (r *ReaderAt) valid(index int) bool {
+	if index == 0 {
+		return true
+	}
+
+	_, ok := r.at(index - 1)
+
+	return ok
+}
+
+func (r *ReaderAt) SetPosition(p Pos) error {
+	if !r.valid(p.Index) {
+		return fmt.Errorf("position out of bounds: %v", p)
+	}
+
+	r.pos = p
+
+	return nil
+}
+
+// Range returns a copy of the runes between p1 and p2
+func (r *ReaderAt) Range(p1 Pos, p2 Pos) ([]rune, error) {
+	if p1.Index < 0 || p2.Index < p1.Index || !r.valid(p2.Index) {
+		return nil, fmt.Errorf("position(s) out of bounds: %v - %v", p1, p2)
+	}
+
+	data := make([]rune, p2.Index-p1.Index)
+	for i := range data {
+		data[i], _ = r.at(p1.Index + i)
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return data, nil
+}
+
+func (r *ReaderAt) Length(p1 Pos, p2 Pos) int {
+	return p2.Index - p1.Index
+}
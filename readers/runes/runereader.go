@@ -0,0 +1,266 @@
+package runes
+
+import (
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"io"
+)
+
+// RuneReader wraps an io.RuneReader (i.e. strings.Reader or bufio.Reader) directly, runes are served as read,
+// without line ending conversion. Read runes are kept in a ring buffer which serves both look ahead for Peek and
+// history for backtracking, when the ring is full the oldest rune behind the current position is overwritten. RuneReader
+// implements the Checkpointer and MarkReader interfaces, history from the oldest live checkpoint or mark is never
+// overwritten, if there is no history left to overwrite the ring grows
+type RuneReader struct {
+	in          io.RuneReader
+	ring        []rune
+	lo          int
+	hi          int
+	pos         Pos
+	checkpoints map[int]int
+	marks       map[ebnf.Token]Pos
+	nextMark    ebnf.Token
+	columns     Columns
+	eof         bool
+	err         error
+}
+
+// NewRuneReader creates a new reader on top of in with an initial ring buffer size, if size <= 0 DefaultWindow
+// is used
+func NewRuneReader(in io.RuneReader, size int) *RuneReader {
+	if size <= 0 {
+		size = DefaultWindow
+	}
+
+	return &RuneReader{
+		in:          in,
+		ring:        make([]rune, size),
+		checkpoints: map[int]int{},
+		marks:       map[ebnf.Token]Pos{},
+	}
+}
+
+// SetColumns sets the column configuration, must be set before reading
+func (r *RuneReader) SetColumns(columns Columns) *RuneReader {
+	r.columns = columns
+	return r
+}
+
+// Checkpoint returns the current position and retains all input from the position until it is released
+func (r *RuneReader) Checkpoint() (Pos, error) {
+	r.checkpoints[r.pos.Index]++
+	return r.pos, nil
+}
+
+// Release releases a checkpoint
+func (r *RuneReader) Release(p Pos) {
+	if n, ok := r.checkpoints[p.Index]; ok {
+		if n > 1 {
+			r.checkpoints[p.Index] = n - 1
+		} else {
+			delete(r.checkpoints, p.Index)
+		}
+	}
+}
+
+// Mark marks the current position and retains all input from the position until the mark is discarded
+func (r *RuneReader) Mark() ebnf.Token {
+	token := r.nextMark
+	r.nextMark++
+	r.marks[token], _ = r.Checkpoint()
+
+	return token
+}
+
+// Reset returns to a marked position
+func (r *RuneReader) Reset(token ebnf.Token) error {
+	p, ok := r.marks[token]
+	if !ok {
+		return fmt.Errorf("unknown mark %d", token)
+	}
+
+	r.pos = p
+
+	return nil
+}
+
+// Discard discards a mark
+func (r *RuneReader) Discard(token ebnf.Token) {
+	if p, ok := r.marks[token]; ok {
+		delete(r.marks, token)
+		r.Release(p)
+	}
+}
+
+func (r *RuneReader) at(index int) rune {
+	return r.ring[index%len(r.ring)]
+}
+
+// grow doubles the ring size
+func (r *RuneReader) grow() {
+	ring := make([]rune, len(r.ring)*2)
+
+	for i := r.lo; i < r.hi; i++ {
+		ring[i%len(ring)] = r.at(i)
+	}
+
+	r.ring = ring
+}
+
+// retain returns the oldest index that must be kept, the current position or the oldest live checkpoint
+func (r *RuneReader) retain() int {
+	retain := r.pos.Index
+
+	for index := range r.checkpoints {
+		retain = min(retain, index)
+	}
+
+	return retain
+}
+
+// fill makes sure index is in the ring if the input has enough runes, returns false if index is beyond the end
+func (r *RuneReader) fill(index int) bool {
+	for r.hi <= index {
+		if r.eof || r.err != nil {
+			return false
+		}
+
+		c, _, err := r.in.ReadRune()
+		if err != nil {
+			if err == io.EOF {
+				r.eof = true
+			} else {
+				r.err = err
+			}
+
+			return false
+		}
+
+		if r.hi-r.lo == len(r.ring) {
+			if r.lo < r.retain() {
+				r.lo++
+			} else {
+				r.grow()
+			}
+		}
+
+		r.ring[r.hi%len(r.ring)] = c
+		r.hi++
+	}
+
+	return true
+}
+
+// endError returns the read error if set, otherwise io.EOF
+func (r *RuneReader) endError() error {
+	if r.err != nil {
+		return r.err
+	}
+
+	return io.EOF
+}
+
+func (r *RuneReader) Peek1() (rune, error) {
+	if !r.fill(r.pos.Index) {
+		return 0, r.endError()
+	}
+
+	return r.at(r.pos.Index), nil
+}
+
+func (r *RuneReader) Read1() (rune, error) {
+	if !r.fill(r.pos.Index) {
+		return 0, r.endError()
+	}
+
+	c := r.at(r.pos.Index)
+	r.columns.Advance(&r.pos, c)
+
+	return c, nil
+}
+
+func (r *RuneReader) Peek(n int, buf []rune) (int, error) {
+	r.fill(r.pos.Index + n - 1)
+
+	i := 0
+	for p := r.pos.Index; i < n && p < r.hi; p++ {
+		buf[i] = r.at(p)
+		i++
+	}
+
+	if i != n {
+		return i, r.endError()
+	}
+
+	return i, nil
+}
+
+func (r *RuneReader) read(n int, buf []rune) (int, error) {
+	i := 0
+	for i < n && r.fill(r.pos.Index) {
+		c := r.at(r.pos.Index)
+		if buf != nil {
+			buf[i] = c
+		}
+		r.columns.Advance(&r.pos, c)
+		i++
+	}
+
+	if i != n {
+		return i, r.endError()
+	}
+
+	return i, nil
+}
+
+func (r *RuneReader) Read(n int, buf []rune) (int, error) {
+	return r.read(n, buf)
+}
+
+func (r *RuneReader) Skip(n int) (int, error) {
+	return r.read(n, nil)
+}
+
+func (r *RuneReader) Finished() bool {
+	return !r.fill(r.pos.Index)
+}
+
+func (r *RuneReader) Position() (Pos, error) {
+	return r.pos, nil
+}
+
+func (r *RuneReader) SetPosition(p Pos) error {
+	if p.Index < r.lo {
+		return &WindowError{Pos: p, Oldest: r.lo}
+	}
+
+	if p.Index > r.hi {
+		return fmt.Errorf("position out of bounds: %v", p)
+	}
+
+	r.pos = p
+
+	return nil
+}
+
+// Range returns a copy of the runes between p1 and p2
+func (r *RuneReader) Range(p1 Pos, p2 Pos) ([]rune, error) {
+	if p1.Index < r.lo {
+		return nil, &WindowError{Pos: p1, Oldest: r.lo}
+	}
+
+	if p2.Index < p1.Index || p2.Index > r.hi {
+		return nil, fmt.Errorf("position(s) out of bounds: %v - %v", p1, p2)
+	}
+
+	data := make([]rune, p2.Index-p1.Index)
+	for i := range data {
+		data[i] = r.at(p1.Index + i)
+	}
+
+	return data, nil
+}
+
+func (r *RuneReader) Length(p1 Pos, p2 Pos) int {
+	return p2.Index - p1.Index
+}
@@ -1,58 +1,121 @@
 package runes
 
 import (
-	"bufio"
 	"fmt"
 	"io"
+	"sort"
+	"unicode/utf8"
 )
 
+// Pos is a position in a rune stream, Line and Col are zero based
 type Pos struct {
 	Line  int
 	Col   int
 	Index int
 }
 
+// Offset returns the rune index
+func (p Pos) Offset() int {
+	return p.Index
+}
+
+// Reader serves runes from memory
 type Reader struct {
-	data []rune
-	pos  Pos
+	data       []rune
+	pos        Pos
+	columns    Columns
+	lineStarts []int
 }
 
+// New reads all runes from r, \r\n and \r line endings are converted to \n. A byte order mark is stripped and
+// UTF-16 input is decoded based on the byte order mark, invalid sequences are replaced with U+FFFD
 func New(r io.Reader) (*Reader, error) {
+	return NewWithDecoding(r, Decoding{})
+}
+
+// NewWithDecoding reads all runes from r with a decoding configuration
+func NewWithDecoding(r io.Reader, decoding Decoding) (*Reader, error) {
 	var (
-		data    = make([]rune, 0)
-		crFound bool
+		data = make([]rune, 0)
+		dec  = newDecoder(r, decoding)
+		in   = newNewlineReader(dec)
 	)
 
-	in := bufio.NewReader(r)
+	defer dec.close()
+
 	for {
-		if c, _, err := in.ReadRune(); err != nil {
+		c, _, err := in.ReadRune()
+		if err != nil {
 			if err == io.EOF {
-				if crFound {
-					data = append(data, '\n')
-				}
 				break
-			} else {
-				return nil, err
-			}
-		} else {
-			if crFound {
-				if c == '\n' {
-					data = append(data, c)
-				} else {
-					data = append(data, '\n', c)
-				}
-				crFound = false
-			} else if c == '\r' {
-				crFound = true
-			} else {
-				data = append(data, c)
 			}
+
+			return nil, err
 		}
+
+		data = append(data, c)
 	}
 
 	return &Reader{data: data}, nil
 }
 
+// newlineReader converts \r\n and \r line endings to \n
+type newlineReader struct {
+	in         io.RuneReader
+	pending    rune
+	hasPending bool
+}
+
+func newNewlineReader(in io.RuneReader) *newlineReader {
+	return &newlineReader{in: in}
+}
+
+func (nr *newlineReader) ReadRune() (rune, int, error) {
+	var (
+		c    rune
+		size int
+		err  error
+	)
+
+	if nr.hasPending {
+		c, size = nr.pending, utf8.RuneLen(nr.pending)
+		nr.hasPending = false
+	} else {
+		c, size, err = nr.in.ReadRune()
+		if err != nil {
+			return c, size, err
+		}
+	}
+
+	if c != '\r' {
+		return c, size, nil
+	}
+
+	next, nextSize, err := nr.in.ReadRune()
+	if err != nil {
+		if err == io.EOF {
+			return '\n', size, nil
+		}
+
+		return 0, 0, err
+	}
+
+	if next == '\n' {
+		return '\n', size + nextSize, nil
+	}
+
+	nr.pending = next
+	nr.hasPending = true
+
+	return '\n', size, nil
+}
+
+// SetColumns sets the column configuration, must be set before reading
+func (r *Reader) SetColumns(columns Columns) *Reader {
+	r.columns = columns
+	return r
+}
+
 func (r *Reader) Data() []rune {
 	return r.data
 }
@@ -68,12 +131,7 @@ func (r *Reader) Peek1() (rune, error) {
 func (r *Reader) Read1() (rune, error) {
 	if r.pos.Index < len(r.data) {
 		c := r.data[r.pos.Index]
-		r.pos.Index++
-		r.pos.Col++
-		if c == '\n' {
-			r.pos.Line++
-			r.pos.Col = 0
-		}
+		r.columns.Advance(&r.pos, c)
 
 		return c, nil
 	}
@@ -110,13 +168,8 @@ func (r *Reader) read(n int, buf []rune) (int, error) {
 		if buf != nil {
 			buf[i] = c
 		}
-		r.pos.Index++
-		r.pos.Col++
+		r.columns.Advance(&r.pos, c)
 		i++
-		if c == '\n' {
-			r.pos.Line++
-			r.pos.Col = 0
-		}
 	}
 
 	if i != n {
@@ -161,3 +214,74 @@ func (r *Reader) Range(p1 Pos, p2 Pos) ([]rune, error) {
 func (r *Reader) Length(p1 Pos, p2 Pos) int {
 	return p2.Index - p1.Index
 }
+
+// Chunks splits the runes from the current position into about n chunks for parallel scanning, chunks end after a
+// newline so matches that do not span lines never cross a chunk boundary. Chunks share the data of the reader and
+// serve absolute positions
+func (r *Reader) Chunks(n int) []*Reader {
+	var (
+		chunks []*Reader
+		size   = max((len(r.data)-r.pos.Index)/max(n, 1), 1)
+		pos    = r.pos
+		begin  = r.pos
+	)
+
+	for pos.Index < len(r.data) {
+		c := r.data[pos.Index]
+		r.columns.Advance(&pos, c)
+
+		if (c == '\n' && pos.Index-begin.Index >= size) || pos.Index == len(r.data) {
+			chunks = append(chunks, &Reader{data: r.data[:pos.Index], pos: begin, columns: r.columns})
+			begin = pos
+		}
+	}
+
+	return chunks
+}
+
+// lines computes the line start indices on first use
+func (r *Reader) lines() []int {
+	if r.lineStarts == nil {
+		r.lineStarts = []int{0}
+
+		for i, c := range r.data {
+			if c == '\n' {
+				r.lineStarts = append(r.lineStarts, i+1)
+			}
+		}
+	}
+
+	return r.lineStarts
+}
+
+// LineCount returns the number of lines, a trailing newline does not start a new line
+func (r *Reader) LineCount() int {
+	starts := r.lines()
+	n := len(starts)
+
+	if n > 1 && starts[n-1] == len(r.data) {
+		n--
+	}
+
+	return n
+}
+
+// LineAt returns the line containing position p without the line ending, together with the position of the begin
+// and end of the line
+func (r *Reader) LineAt(p Pos) ([]rune, Pos, Pos) {
+	starts := r.lines()
+	index := min(max(p.Index, 0), len(r.data))
+
+	line := sort.Search(len(starts), func(i int) bool {
+		return starts[i] > index
+	}) - 1
+
+	begin := Pos{Line: line, Index: starts[line]}
+	end := begin
+
+	for end.Index < len(r.data) && r.data[end.Index] != '\n' {
+		r.columns.Advance(&end, r.data[end.Index])
+	}
+
+	return r.data[begin.Index:end.Index], begin, end
+}
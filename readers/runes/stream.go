@@ -0,0 +1,282 @@
+package runes
+
+import (
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"io"
+)
+
+// DefaultWindow is the default number of runes a Stream keeps behind the current position for backtracking
+const DefaultWindow = 64 * 1024
+
+// WindowError is returned when a position is requested that has already been discarded from a streaming reader
+type WindowError struct {
+	Pos    Pos
+	Oldest int
+}
+
+func (e *WindowError) Error() string {
+	return fmt.Sprintf("position %v is outside the retained window, oldest index is %d", e.Pos, e.Oldest)
+}
+
+// minCompact is the minimum number of runes a Stream discards at once
+const minCompact = 1024
+
+// Stream reads runes from an io.Reader on demand and only keeps a sliding window of runes behind the current
+// position, so input of arbitrary size can be matched. Stream implements the Checkpointer and MarkReader
+// interfaces, everything from the oldest live checkpoint or mark is retained as well. Patterns can only backtrack within the window or to a live
+// checkpoint, earlier positions result in a WindowError
+type Stream struct {
+	in          io.RuneReader
+	buf         []rune
+	base        int
+	pos         Pos
+	window      int
+	checkpoints map[int]int
+	marks       map[ebnf.Token]Pos
+	nextMark    ebnf.Token
+	columns     Columns
+	eof         bool
+	err         error
+}
+
+// NewStream creates a new streaming reader that keeps window runes for backtracking, if window <= 0 DefaultWindow
+// is used. \r\n and \r line endings are converted to \n, input is decoded like New
+func NewStream(r io.Reader, window int) *Stream {
+	return NewStreamWithDecoding(r, window, Decoding{})
+}
+
+// NewStreamWithDecoding creates a new streaming reader with a decoding configuration
+func NewStreamWithDecoding(r io.Reader, window int, decoding Decoding) *Stream {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+
+	return newStream(newNewlineReader(newDecoder(r, decoding)), window)
+}
+
+// NewCheckpointStream creates a new streaming reader without a backtracking window, only input from the oldest live
+// checkpoint is retained. This makes memory use predictable, but patterns can only backtrack to checkpoints.
+// \r\n and \r line endings are converted to \n, input is decoded like New
+func NewCheckpointStream(r io.Reader) *Stream {
+	return newStream(newNewlineReader(newDecoder(r, Decoding{})), 0)
+}
+
+func newStream(in io.RuneReader, window int) *Stream {
+	return &Stream{
+		in:          in,
+		window:      window,
+		checkpoints: map[int]int{},
+		marks:       map[ebnf.Token]Pos{},
+	}
+}
+
+// SetColumns sets the column configuration, must be set before reading
+func (s *Stream) SetColumns(columns Columns) *Stream {
+	s.columns = columns
+	return s
+}
+
+// Checkpoint returns the current position and retains all input from the position until it is released
+func (s *Stream) Checkpoint() (Pos, error) {
+	s.checkpoints[s.pos.Index]++
+	return s.pos, nil
+}
+
+// Release releases a checkpoint
+func (s *Stream) Release(p Pos) {
+	if n, ok := s.checkpoints[p.Index]; ok {
+		if n > 1 {
+			s.checkpoints[p.Index] = n - 1
+		} else {
+			delete(s.checkpoints, p.Index)
+		}
+	}
+}
+
+// Mark marks the current position and retains all input from the position until the mark is discarded
+func (s *Stream) Mark() ebnf.Token {
+	token := s.nextMark
+	s.nextMark++
+	s.marks[token], _ = s.Checkpoint()
+
+	return token
+}
+
+// Reset returns to a marked position
+func (s *Stream) Reset(token ebnf.Token) error {
+	p, ok := s.marks[token]
+	if !ok {
+		return fmt.Errorf("unknown mark %d", token)
+	}
+
+	s.pos = p
+
+	return nil
+}
+
+// Discard discards a mark
+func (s *Stream) Discard(token ebnf.Token) {
+	if p, ok := s.marks[token]; ok {
+		delete(s.marks, token)
+		s.Release(p)
+	}
+}
+
+// fill makes sure index is buffered if the input has enough runes, returns false if index is beyond the end
+func (s *Stream) fill(index int) bool {
+	if s.base+len(s.buf) <= index {
+		s.discard()
+	}
+
+	for s.base+len(s.buf) <= index {
+		if s.eof || s.err != nil {
+			return false
+		}
+
+		c, _, err := s.in.ReadRune()
+		if err != nil {
+			if err == io.EOF {
+				s.eof = true
+			} else {
+				s.err = err
+			}
+
+			return false
+		}
+
+		s.buf = append(s.buf, c)
+	}
+
+	return true
+}
+
+// discard drops runes that are more than window runes behind the current position and before the oldest checkpoint
+func (s *Stream) discard() {
+	retain := s.pos.Index - s.window
+
+	for index := range s.checkpoints {
+		retain = min(retain, index)
+	}
+
+	drop := retain - s.base
+
+	if drop < max(s.window, minCompact) {
+		return
+	}
+
+	n := copy(s.buf, s.buf[drop:])
+	s.buf = s.buf[:n]
+	s.base += drop
+}
+
+// endError returns the read error if set, otherwise io.EOF
+func (s *Stream) endError() error {
+	if s.err != nil {
+		return s.err
+	}
+
+	return io.EOF
+}
+
+func (s *Stream) Peek1() (rune, error) {
+	if !s.fill(s.pos.Index) {
+		return 0, s.endError()
+	}
+
+	return s.buf[s.pos.Index-s.base], nil
+}
+
+func (s *Stream) Read1() (rune, error) {
+	if !s.fill(s.pos.Index) {
+		return 0, s.endError()
+	}
+
+	c := s.buf[s.pos.Index-s.base]
+	s.columns.Advance(&s.pos, c)
+
+	return c, nil
+}
+
+func (s *Stream) Peek(n int, buf []rune) (int, error) {
+	s.fill(s.pos.Index + n - 1)
+
+	i := 0
+	for p := s.pos.Index - s.base; i < n && p < len(s.buf); p++ {
+		buf[i] = s.buf[p]
+		i++
+	}
+
+	if i != n {
+		return i, s.endError()
+	}
+
+	return i, nil
+}
+
+func (s *Stream) read(n int, buf []rune) (int, error) {
+	s.fill(s.pos.Index + n - 1)
+
+	i := 0
+	for i < n && s.pos.Index-s.base < len(s.buf) {
+		c := s.buf[s.pos.Index-s.base]
+		if buf != nil {
+			buf[i] = c
+		}
+		s.columns.Advance(&s.pos, c)
+		i++
+	}
+
+	if i != n {
+		return i, s.endError()
+	}
+
+	return i, nil
+}
+
+func (s *Stream) Read(n int, buf []rune) (int, error) {
+	return s.read(n, buf)
+}
+
+func (s *Stream) Skip(n int) (int, error) {
+	return s.read(n, nil)
+}
+
+func (s *Stream) Finished() bool {
+	return !s.fill(s.pos.Index)
+}
+
+func (s *Stream) Position() (Pos, error) {
+	return s.pos, nil
+}
+
+func (s *Stream) SetPosition(p Pos) error {
+	if p.Index < s.base {
+		return &WindowError{Pos: p, Oldest: s.base}
+	}
+
+	if p.Index > s.base+len(s.buf) {
+		return fmt.Errorf("position out of bounds: %v", p)
+	}
+
+	s.pos = p
+
+	return nil
+}
+
+// Range returns a copy of the runes between p1 and p2, the buffer is reused when the window slides
+func (s *Stream) Range(p1 Pos, p2 Pos) ([]rune, error) {
+	if p1.Index < s.base {
+		return nil, &WindowError{Pos: p1, Oldest: s.base}
+	}
+
+	if p2.Index < p1.Index || p2.Index > s.base+len(s.buf) {
+		return nil, fmt.Errorf("position(s) out of bounds: %v - %v", p1, p2)
+	}
+
+	return append([]rune{}, s.buf[p1.Index-s.base:p2.Index-s.base]...), nil
+}
+
+func (s *Stream) Length(p1 Pos, p2 Pos) int {
+	return p2.Index - p1.Index
+}
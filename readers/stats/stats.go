@@ -0,0 +1,323 @@
+package stats
+
+import (
+	"bytes"
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"sort"
+)
+
+// Stats wraps a reader and records reads, peeks, position changes and backtracking. Stats implements the
+// ebnf.Matcher interface so objects read are also counted per pattern, the innermost pattern being matched is
+// charged for the read
+type Stats[T, P any] struct {
+	src ebnf.Reader[T, P]
+	// Reads is the number of objects read, including skipped objects
+	Reads int
+	// Peeks is the number of peek calls
+	Peeks int
+	// SetPositions is the number of SetPosition calls
+	SetPositions int
+	// Backtracks is the number of SetPosition calls that moved back
+	Backtracks int
+	// BacktrackDistance is the total number of objects moved back
+	BacktrackDistance int
+	// MaxBacktrack is the maximum number of objects moved back at once
+	MaxBacktrack int
+	// PatternReads is the number of objects read per pattern
+	PatternReads map[ebnf.Pattern[T, P]]int
+	// PatternMatches is the number of match attempts per pattern
+	PatternMatches map[ebnf.Pattern[T, P]]int
+	// PatternFailures is the number of failed match attempts per pattern
+	PatternFailures map[ebnf.Pattern[T, P]]int
+	// Rereads is the number of objects read again due to backtracking
+	Rereads int
+	// PatternRereads is the number of objects read again due to backtracking per pattern, re-reads are charged to
+	// the innermost pattern with an ID being matched
+	PatternRereads map[ebnf.Pattern[T, P]]int
+	stack          []ebnf.Pattern[T, P]
+	start          P
+	highWater      int
+}
+
+// New creates a new instrumenting reader on top of src
+func New[T, P any](src ebnf.Reader[T, P]) *Stats[T, P] {
+	start, _ := src.Position()
+
+	return &Stats[T, P]{
+		src:             src,
+		PatternReads:    map[ebnf.Pattern[T, P]]int{},
+		PatternMatches:  map[ebnf.Pattern[T, P]]int{},
+		PatternFailures: map[ebnf.Pattern[T, P]]int{},
+		PatternRereads:  map[ebnf.Pattern[T, P]]int{},
+		start:           start,
+	}
+}
+
+// MatchPattern matches a pattern and keeps track of the pattern stack
+func (s *Stats[T, P]) MatchPattern(pattern ebnf.Pattern[T, P], r ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], error) {
+	s.PatternMatches[pattern]++
+	s.stack = append(s.stack, pattern)
+	matched, result, err := ebnf.MatchNext(pattern, s.src, r)
+	s.stack = s.stack[:len(s.stack)-1]
+
+	if err == nil && !matched {
+		s.PatternFailures[pattern]++
+	}
+
+	return matched, result, err
+}
+
+// offset returns the offset of the current position from the start
+func (s *Stats[T, P]) offset() int {
+	pos, err := s.src.Position()
+	if err != nil {
+		return s.highWater
+	}
+
+	return s.src.Length(s.start, pos)
+}
+
+// countReads counts n objects read from offset
+func (s *Stats[T, P]) countReads(offset int, n int) {
+	s.Reads += n
+
+	rereads := max(min(n, s.highWater-offset), 0)
+	s.Rereads += rereads
+	s.highWater = max(s.highWater, offset+n)
+
+	if len(s.stack) == 0 {
+		return
+	}
+
+	s.PatternReads[s.stack[len(s.stack)-1]] += n
+
+	if rereads > 0 {
+		s.PatternRereads[s.rule()] += rereads
+	}
+}
+
+// rule returns the innermost pattern with an ID being matched, or the innermost pattern if none has an ID
+func (s *Stats[T, P]) rule() ebnf.Pattern[T, P] {
+	for i := len(s.stack) - 1; i >= 0; i-- {
+		if s.stack[i].ID() != ebnf.NoID {
+			return s.stack[i]
+		}
+	}
+
+	return s.stack[len(s.stack)-1]
+}
+
+func (s *Stats[T, P]) Peek1() (T, error) {
+	s.Peeks++
+	return s.src.Peek1()
+}
+
+func (s *Stats[T, P]) Read1() (T, error) {
+	offset := s.offset()
+
+	obj, err := s.src.Read1()
+	if err == nil {
+		s.countReads(offset, 1)
+	}
+
+	return obj, err
+}
+
+func (s *Stats[T, P]) Peek(n int, buf []T) (int, error) {
+	s.Peeks++
+	return s.src.Peek(n, buf)
+}
+
+func (s *Stats[T, P]) Read(n int, buf []T) (int, error) {
+	offset := s.offset()
+	n, err := s.src.Read(n, buf)
+	s.countReads(offset, n)
+
+	return n, err
+}
+
+func (s *Stats[T, P]) Skip(n int) (int, error) {
+	offset := s.offset()
+	n, err := s.src.Skip(n)
+	s.countReads(offset, n)
+
+	return n, err
+}
+
+func (s *Stats[T, P]) Finished() bool {
+	return s.src.Finished()
+}
+
+func (s *Stats[T, P]) Position() (P, error) {
+	return s.src.Position()
+}
+
+func (s *Stats[T, P]) SetPosition(p P) error {
+	s.SetPositions++
+
+	current, err := s.src.Position()
+	if err == nil {
+		if distance := s.src.Length(p, current); distance > 0 {
+			s.Backtracks++
+			s.BacktrackDistance += distance
+			s.MaxBacktrack = max(s.MaxBacktrack, distance)
+		}
+	}
+
+	return s.src.SetPosition(p)
+}
+
+func (s *Stats[T, P]) Range(p1 P, p2 P) ([]T, error) {
+	return s.src.Range(p1, p2)
+}
+
+func (s *Stats[T, P]) Length(p1 P, p2 P) int {
+	return s.src.Length(p1, p2)
+}
+
+// Unwrap returns the source reader
+func (s *Stats[T, P]) Unwrap() ebnf.Reader[T, P] {
+	return s.src
+}
+
+// Checkpoint forwards to the source reader
+func (s *Stats[T, P]) Checkpoint() (P, error) {
+	return ebnf.Checkpoint(s.src)
+}
+
+// Release forwards to the source reader
+func (s *Stats[T, P]) Release(p P) {
+	ebnf.Release(s.src, p)
+}
+
+// Reset clears all statistics
+func (s *Stats[T, P]) Reset() {
+	*s = *New(s.src)
+}
+
+// PatternStat holds the statistics of a single pattern
+type PatternStat[T, P any] struct {
+	Pattern ebnf.Pattern[T, P]
+	Matches int
+	Reads   int
+}
+
+// Patterns returns the per pattern statistics sorted by reads, most reads first
+func (s *Stats[T, P]) Patterns() []PatternStat[T, P] {
+	stats := make([]PatternStat[T, P], 0, len(s.PatternMatches))
+
+	for pattern, matches := range s.PatternMatches {
+		stats = append(stats, PatternStat[T, P]{Pattern: pattern, Matches: matches, Reads: s.PatternReads[pattern]})
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].Reads != stats[j].Reads {
+			return stats[i].Reads > stats[j].Reads
+		}
+
+		return stats[i].Matches > stats[j].Matches
+	})
+
+	return stats
+}
+
+// Label returns a printable label for a pattern, the ID, print output or EBNF print of the pattern
+func Label[T, P any](pattern ebnf.Pattern[T, P]) string {
+	if id := pattern.ID(); id != ebnf.NoID {
+		return id
+	}
+
+	var buf bytes.Buffer
+
+	if err := pattern.Print(&buf); err == nil && buf.Len() > 0 {
+		return buf.String()
+	}
+
+	return fmt.Sprintf("%T", pattern)
+}
+
+// Report returns a human readable report, at most top patterns are listed, top <= 0 lists all patterns
+func (s *Stats[T, P]) Report(top int) string {
+	var buf bytes.Buffer
+
+	buf.WriteString(fmt.Sprintf("reads: %d, peeks: %d, set positions: %d\n", s.Reads, s.Peeks, s.SetPositions))
+	buf.WriteString(fmt.Sprintf("backtracks: %d, distance: %d, max: %d\n", s.Backtracks, s.BacktrackDistance, s.MaxBacktrack))
+
+	for i, stat := range s.Patterns() {
+		if top > 0 && i == top {
+			break
+		}
+
+		buf.WriteString(fmt.Sprintf("%8d reads %8d matches  %s\n", stat.Reads, stat.Matches, Label(stat.Pattern)))
+	}
+
+	return buf.String()
+}
+
+// ProfileEntry holds the profile of the patterns with the same label
+type ProfileEntry struct {
+	Label    string
+	Attempts int
+	Failures int
+	Reads    int
+	Rereads  int
+}
+
+// Profile returns the statistics per pattern label (the ID for rules) sorted by cost, the number of objects re-read
+// due to backtracking first, then the number of attempts
+func (s *Stats[T, P]) Profile() []ProfileEntry {
+	index := map[string]*ProfileEntry{}
+
+	for pattern, attempts := range s.PatternMatches {
+		label := Label(pattern)
+
+		entry, ok := index[label]
+		if !ok {
+			entry = &ProfileEntry{Label: label}
+			index[label] = entry
+		}
+
+		entry.Attempts += attempts
+		entry.Failures += s.PatternFailures[pattern]
+		entry.Reads += s.PatternReads[pattern]
+		entry.Rereads += s.PatternRereads[pattern]
+	}
+
+	profile := make([]ProfileEntry, 0, len(index))
+	for _, entry := range index {
+		profile = append(profile, *entry)
+	}
+
+	sort.Slice(profile, func(i, j int) bool {
+		if profile[i].Rereads != profile[j].Rereads {
+			return profile[i].Rereads > profile[j].Rereads
+		}
+
+		if profile[i].Attempts != profile[j].Attempts {
+			return profile[i].Attempts > profile[j].Attempts
+		}
+
+		return profile[i].Label < profile[j].Label
+	})
+
+	return profile
+}
+
+// ProfileReport returns a human readable profile, at most top entries are listed, top <= 0 lists all entries
+func (s *Stats[T, P]) ProfileReport(top int) string {
+	var buf bytes.Buffer
+
+	buf.WriteString(fmt.Sprintf("reads: %d, re-reads: %d\n", s.Reads, s.Rereads))
+	buf.WriteString(fmt.Sprintf("%10s %10s %10s %10s  %s\n", "re-reads", "attempts", "failures", "reads", "pattern"))
+
+	for i, entry := range s.Profile() {
+		if top > 0 && i == top {
+			break
+		}
+
+		buf.WriteString(fmt.Sprintf("%10d %10d %10d %10d  %s\n", entry.Rereads, entry.Attempts, entry.Failures, entry.Reads, entry.Label))
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,375 @@
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"io"
+)
+
+// Token is a token of kind with the source objects of the token as value, Begin and End are the source positions.
+// Trivia are the trivia tokens (i.e. whitespace and comments) before the token if the source keeps them
+type Token[T, P any] struct {
+	Kind   string
+	Value  []T
+	Begin  P
+	End    P
+	Trivia []Token[T, P]
+}
+
+// TriviaOf returns the trivia of the token of kind
+func (t Token[T, P]) TriviaOf(kind string) []Token[T, P] {
+	var trivia []Token[T, P]
+
+	for _, tok := range t.Trivia {
+		if tok.Kind == kind {
+			trivia = append(trivia, tok)
+		}
+	}
+
+	return trivia
+}
+
+// Source produces tokens, Next returns io.EOF after the last token. Position returns the current source position,
+// it is used as source position at the start and the end of the token stream
+type Source[T, P any] interface {
+	Next() (Token[T, P], error)
+	Position() (P, error)
+}
+
+// Trailer is an optional extension of Source which returns the trivia after the last token
+type Trailer[T, P any] interface {
+	Trailing() []Token[T, P]
+}
+
+// Pos is a position in a token stream together with positions in the source stream. Source is the begin of the token
+// at Index or the end of the source, End is the end of the token before Index or the start of the source. So the
+// begin of a match resolves to the begin of its first token and the end of a match to the end of its last token,
+// trivia between tokens is not part of the match
+type Pos[P any] struct {
+	Index  int
+	Source P
+	End    P
+}
+
+// Offset returns the token index
+func (p Pos[P]) Offset() int {
+	return p.Index
+}
+
+// String formats the source position, so diagnostics point at the source
+func (p Pos[P]) String() string {
+	return fmt.Sprint(p.Source)
+}
+
+// SourceSpan returns the source positions of the tokens between p1 and p2
+func SourceSpan[P any](p1 Pos[P], p2 Pos[P]) (P, P) {
+	if p2.Index <= p1.Index {
+		return p1.Source, p1.Source
+	}
+
+	return p1.Source, p2.End
+}
+
+// SourceRange returns the source objects of the tokens between p1 and p2 from the source reader, including trivia
+// between the tokens
+func SourceRange[T, P any](src ebnf.Reader[T, P], p1 Pos[P], p2 Pos[P]) ([]T, error) {
+	begin, end := SourceSpan(p1, p2)
+	return src.Range(begin, end)
+}
+
+// Kind returns a pattern matching a single token of kind
+func Kind[T, P any](kind string) ebnf.Pattern[Token[T, P], Pos[P]] {
+	return entity.New[Token[T, P], Pos[P]](func(t Token[T, P]) bool {
+		return t.Kind == kind
+	}).SetExpectation(kind)
+}
+
+// Reader is a reader of tokens from a token source, tokens are read from the source as far as the reader is read
+// or peeked. Positions resolve to source positions, see Pos
+type Reader[T, P any] struct {
+	src     Source[T, P]
+	tokens  []Token[T, P]
+	start   P
+	end     P
+	started bool
+	done    bool
+	err     error
+	pos     int
+}
+
+// New creates a token reader over src
+func New[T, P any](src Source[T, P]) *Reader[T, P] {
+	return &Reader[T, P]{src: src}
+}
+
+// NewFromTokens creates a token reader over tokens, end is the source position after the last token
+func NewFromTokens[T, P any](tokens []Token[T, P], end P) *Reader[T, P] {
+	return New[T, P](&sliceSource[T, P]{tokens: tokens, end: end})
+}
+
+// Tokens returns all tokens, the position of the reader is not changed
+func (r *Reader[T, P]) Tokens() ([]Token[T, P], error) {
+	for r.load(len(r.tokens)) {
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return r.tokens, nil
+}
+
+// Trailing returns the trivia after the last token if the source implements Trailer, the trivia is only known after
+// the last token is read
+func (r *Reader[T, P]) Trailing() []Token[T, P] {
+	if t, ok := r.src.(Trailer[T, P]); ok && r.done {
+		return t.Trailing()
+	}
+
+	return nil
+}
+
+// load makes sure the token at index is loaded if available, returns false if index is beyond the end
+func (r *Reader[T, P]) load(index int) bool {
+	if !r.started && r.err == nil {
+		r.start, r.err = r.src.Position()
+		r.started = true
+	}
+
+	for len(r.tokens) <= index {
+		if r.done || r.err != nil {
+			return false
+		}
+
+		tok, err := r.src.Next()
+		if errors.Is(err, io.EOF) {
+			r.end, r.err = r.src.Position()
+			r.done = true
+			return false
+		}
+
+		if err != nil {
+			r.err = err
+			return false
+		}
+
+		r.tokens = append(r.tokens, tok)
+	}
+
+	return true
+}
+
+// endError returns the source error if set, otherwise io.EOF
+func (r *Reader[T, P]) endError() error {
+	if r.err != nil {
+		return r.err
+	}
+
+	return io.EOF
+}
+
+func (r *Reader[T, P]) Peek1() (Token[T, P], error) {
+	if !r.load(r.pos) {
+		return Token[T, P]{}, r.endError()
+	}
+
+	return r.tokens[r.pos], nil
+}
+
+func (r *Reader[T, P]) Read1() (Token[T, P], error) {
+	if !r.load(r.pos) {
+		return Token[T, P]{}, r.endError()
+	}
+
+	tok := r.tokens[r.pos]
+	r.pos++
+
+	return tok, nil
+}
+
+func (r *Reader[T, P]) Peek(n int, buf []Token[T, P]) (int, error) {
+	r.load(r.pos + n - 1)
+
+	i := copy(buf[:n], r.tokens[r.pos:])
+	if i != n {
+		return i, r.endError()
+	}
+
+	return i, nil
+}
+
+func (r *Reader[T, P]) read(n int, buf []Token[T, P]) (int, error) {
+	r.load(r.pos + n - 1)
+
+	i := min(n, len(r.tokens)-r.pos)
+
+	if buf != nil {
+		copy(buf, r.tokens[r.pos:r.pos+i])
+	}
+
+	r.pos += i
+
+	if i != n {
+		return i, r.endError()
+	}
+
+	return i, nil
+}
+
+func (r *Reader[T, P]) Read(n int, buf []Token[T, P]) (int, error) {
+	return r.read(n, buf)
+}
+
+func (r *Reader[T, P]) Skip(n int) (int, error) {
+	return r.read(n, nil)
+}
+
+func (r *Reader[T, P]) Finished() bool {
+	return !r.load(r.pos)
+}
+
+// Position returns the current position, see Pos for the source positions
+func (r *Reader[T, P]) Position() (Pos[P], error) {
+	return r.position(r.pos)
+}
+
+// position returns the position of index, the tokens up to index must be loaded
+func (r *Reader[T, P]) position(index int) (Pos[P], error) {
+	p := Pos[P]{Index: index, End: r.start}
+
+	if index > 0 {
+		p.End = r.tokens[index-1].End
+	}
+
+	if r.load(index) {
+		p.Source = r.tokens[index].Begin
+		return p, nil
+	}
+
+	if r.err != nil {
+		return p, r.err
+	}
+
+	p.Source = r.end
+
+	return p, nil
+}
+
+func (r *Reader[T, P]) SetPosition(p Pos[P]) error {
+	if p.Index < 0 || p.Index > len(r.tokens) {
+		return fmt.Errorf("position out of bounds: %v", p.Index)
+	}
+
+	r.pos = p.Index
+
+	return nil
+}
+
+// Range returns the tokens between p1 and p2, use SourceRange for the source objects
+func (r *Reader[T, P]) Range(p1 Pos[P], p2 Pos[P]) ([]Token[T, P], error) {
+	if p1.Index < 0 || p1.Index > p2.Index || p2.Index > len(r.tokens) {
+		return nil, fmt.Errorf("position(s) out of bounds: %v - %v", p1.Index, p2.Index)
+	}
+
+	return r.tokens[p1.Index:p2.Index], nil
+}
+
+func (r *Reader[T, P]) Length(p1 Pos[P], p2 Pos[P]) int {
+	return p2.Index - p1.Index
+}
+
+// State is the state of a transformation, Prev is the last emitted token and Emitted the number of emitted tokens
+type State[T, P any] struct {
+	Prev    Token[T, P]
+	Emitted int
+}
+
+// TransformFunc rewrites a token stream, it is called for every token of the source with the state and returns the
+// tokens to emit in place of the token: the token itself, nothing to drop it, a rewritten token or extra tokens to
+// inject. At the end of the stream it is called once more with eof true and a zero token, to inject tokens at the end
+type TransformFunc[T, P any] func(state *State[T, P], tok Token[T, P], eof bool) []Token[T, P]
+
+// Transform returns a source which rewrites the tokens of src with f, i.e. to insert semicolons at line ends as Go
+// does. Trailing trivia of src is passed on
+func Transform[T, P any](src Source[T, P], f TransformFunc[T, P]) Source[T, P] {
+	return &transform[T, P]{src: src, f: f}
+}
+
+// transform is a token source that rewrites the tokens of another source
+type transform[T, P any] struct {
+	src     Source[T, P]
+	f       TransformFunc[T, P]
+	state   State[T, P]
+	pending []Token[T, P]
+	eof     bool
+}
+
+func (t *transform[T, P]) Next() (Token[T, P], error) {
+	for len(t.pending) == 0 {
+		if t.eof {
+			return Token[T, P]{}, io.EOF
+		}
+
+		tok, err := t.src.Next()
+		if errors.Is(err, io.EOF) {
+			t.eof = true
+			t.pending = t.f(&t.state, Token[T, P]{}, true)
+
+			continue
+		}
+
+		if err != nil {
+			return tok, err
+		}
+
+		t.pending = t.f(&t.state, tok, false)
+	}
+
+	tok := t.pending[0]
+	t.pending = t.pending[1:]
+
+	t.state.Prev = tok
+	t.state.Emitted++
+
+	return tok, nil
+}
+
+func (t *transform[T, P]) Position() (P, error) {
+	return t.src.Position()
+}
+
+func (t *transform[T, P]) Trailing() []Token[T, P] {
+	if tr, ok := t.src.(Trailer[T, P]); ok {
+		return tr.Trailing()
+	}
+
+	return nil
+}
+
+// sliceSource is a token source over a slice of tokens
+type sliceSource[T, P any] struct {
+	tokens []Token[T, P]
+	end    P
+	index  int
+}
+
+func (s *sliceSource[T, P]) Next() (Token[T, P], error) {
+	if s.index == len(s.tokens) {
+		return Token[T, P]{}, io.EOF
+	}
+
+	tok := s.tokens[s.index]
+	s.index++
+
+	return tok, nil
+}
+
+func (s *sliceSource[T, P]) Position() (P, error) {
+	if s.index < len(s.tokens) {
+		return s.tokens[s.index].Begin, nil
+	}
+
+	return s.end, nil
+}
@@ -0,0 +1,68 @@
+package trace
+
+import (
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"io"
+	"strings"
+)
+
+// Console is a tracer that writes an indented line per match event to a writer
+type Console[T, P any] struct {
+	w      io.Writer
+	indent string
+	ids    bool
+}
+
+// NewConsole creates a new console tracer writing to w
+func NewConsole[T, P any](w io.Writer) *Console[T, P] {
+	return &Console[T, P]{
+		w:      w,
+		indent: "  ",
+	}
+}
+
+// SetIndent sets the indentation per depth level, defaults to two spaces
+func (c *Console[T, P]) SetIndent(indent string) *Console[T, P] {
+	c.indent = indent
+	return c
+}
+
+// SetOnlyIDs only traces patterns with an ID, the depth of anonymous patterns still counts for indentation
+func (c *Console[T, P]) SetOnlyIDs(ids bool) *Console[T, P] {
+	c.ids = ids
+	return c
+}
+
+func (c *Console[T, P]) skip(pattern ebnf.Pattern[T, P]) bool {
+	return c.ids && pattern.ID() == ebnf.NoID
+}
+
+func (c *Console[T, P]) OnEnter(pattern ebnf.Pattern[T, P], depth int, pos P) {
+	if c.skip(pattern) {
+		return
+	}
+
+	_, _ = fmt.Fprintf(c.w, "%s> %s at %v\n", strings.Repeat(c.indent, depth), ebnf.Expectation(pattern), pos)
+}
+
+func (c *Console[T, P]) OnSuccess(pattern ebnf.Pattern[T, P], depth int, pos P, end P) {
+	if c.skip(pattern) {
+		return
+	}
+
+	_, _ = fmt.Fprintf(c.w, "%s+ %s at %v to %v\n", strings.Repeat(c.indent, depth), ebnf.Expectation(pattern), pos, end)
+}
+
+func (c *Console[T, P]) OnFail(pattern ebnf.Pattern[T, P], depth int, pos P, err error) {
+	if c.skip(pattern) {
+		return
+	}
+
+	if err != nil {
+		_, _ = fmt.Fprintf(c.w, "%s- %s at %v: %v\n", strings.Repeat(c.indent, depth), ebnf.Expectation(pattern), pos, err)
+		return
+	}
+
+	_, _ = fmt.Fprintf(c.w, "%s- %s at %v\n", strings.Repeat(c.indent, depth), ebnf.Expectation(pattern), pos)
+}
@@ -0,0 +1,105 @@
+package trace
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+)
+
+// Trace wraps a reader and reports the enter, success and failure of every (sub) pattern match to a tracer. Trace
+// implements the ebnf.Matcher interface and can be chained with other reader middleware
+type Trace[T, P any] struct {
+	src    ebnf.Reader[T, P]
+	tracer ebnf.Tracer[T, P]
+	depth  int
+}
+
+// New creates a new tracing reader on top of src
+func New[T, P any](src ebnf.Reader[T, P], tracer ebnf.Tracer[T, P]) *Trace[T, P] {
+	return &Trace[T, P]{
+		src:    src,
+		tracer: tracer,
+	}
+}
+
+// MatchPattern matches a pattern and reports the match events to the tracer
+func (t *Trace[T, P]) MatchPattern(pattern ebnf.Pattern[T, P], r ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], error) {
+	pos, err := t.src.Position()
+	if err != nil {
+		return false, nil, err
+	}
+
+	depth := t.depth
+	t.tracer.OnEnter(pattern, depth, pos)
+
+	t.depth++
+	matched, result, err := ebnf.MatchNext(pattern, t.src, r)
+	t.depth--
+
+	if !matched || err != nil {
+		t.tracer.OnFail(pattern, depth, pos, err)
+		return matched, result, err
+	}
+
+	end, err := t.src.Position()
+	if err != nil {
+		return false, nil, err
+	}
+
+	t.tracer.OnSuccess(pattern, depth, pos, end)
+
+	return matched, result, nil
+}
+
+func (t *Trace[T, P]) Peek1() (T, error) {
+	return t.src.Peek1()
+}
+
+func (t *Trace[T, P]) Read1() (T, error) {
+	return t.src.Read1()
+}
+
+func (t *Trace[T, P]) Peek(n int, buf []T) (int, error) {
+	return t.src.Peek(n, buf)
+}
+
+func (t *Trace[T, P]) Read(n int, buf []T) (int, error) {
+	return t.src.Read(n, buf)
+}
+
+func (t *Trace[T, P]) Skip(n int) (int, error) {
+	return t.src.Skip(n)
+}
+
+func (t *Trace[T, P]) Finished() bool {
+	return t.src.Finished()
+}
+
+func (t *Trace[T, P]) Position() (P, error) {
+	return t.src.Position()
+}
+
+func (t *Trace[T, P]) SetPosition(p P) error {
+	return t.src.SetPosition(p)
+}
+
+func (t *Trace[T, P]) Range(p1 P, p2 P) ([]T, error) {
+	return t.src.Range(p1, p2)
+}
+
+func (t *Trace[T, P]) Length(p1 P, p2 P) int {
+	return t.src.Length(p1, p2)
+}
+
+// Unwrap returns the source reader
+func (t *Trace[T, P]) Unwrap() ebnf.Reader[T, P] {
+	return t.src
+}
+
+// Checkpoint forwards to the source reader
+func (t *Trace[T, P]) Checkpoint() (P, error) {
+	return ebnf.Checkpoint(t.src)
+}
+
+// Release forwards to the source reader
+func (t *Trace[T, P]) Release(p P) {
+	ebnf.Release(t.src, p)
+}
@@ -0,0 +1,117 @@
+package exbana
+
+// SkippedSpan is a span of objects a scan did not recognize
+type SkippedSpan[T, P any] struct {
+	Begin P
+	End   P
+	// NearMiss is the farthest failure of the match attempt in the span that got farthest from its start
+	NearMiss *Failure[T, P]
+}
+
+// ScanReport describes what a scan skipped and why
+type ScanReport[T, P any] struct {
+	Skipped []*SkippedSpan[T, P]
+	// SkippedObjects is the number of skipped objects
+	SkippedObjects int
+	// MatchedObjects is the number of objects in matches
+	MatchedObjects int
+}
+
+// Coverage returns the fraction of the scanned objects that were matched, 1 if nothing was scanned
+func (r *ScanReport[T, P]) Coverage() float64 {
+	total := r.SkippedObjects + r.MatchedObjects
+	if total == 0 {
+		return 1
+	}
+
+	return float64(r.MatchedObjects) / float64(total)
+}
+
+// ScanWithReport scans stream for pattern like Scan and reports the skipped spans. Every match attempt is run in a
+// session with failure tracking, for each skipped span the farthest failure of the attempt that got farthest from its start
+// is kept as near miss. Like in ScanN an empty match is followed by a skip of the next object
+func ScanWithReport[T, P any](stream Reader[T, P], pattern Pattern[T, P]) ([]*Match[T, P], *ScanReport[T, P], error) {
+	var (
+		results []*Match[T, P]
+		report  = &ScanReport[T, P]{}
+		span    *SkippedSpan[T, P]
+		reach   int
+	)
+
+	for !stream.Finished() {
+		mark, err := NewMarker(stream)
+		if IsStreamError(err) {
+			return nil, nil, err
+		}
+
+		session, err := NewSession(stream)
+		if err != nil {
+			mark.Discard()
+			return nil, nil, err
+		}
+
+		session.SetTrackFailures(true)
+
+		matched, result, err := MatchPattern(pattern, Reader[T, P](session))
+		if err != nil {
+			mark.Discard()
+			return nil, nil, err
+		}
+
+		var (
+			failure *Failure[T, P]
+			failed  bool
+		)
+
+		if matched {
+			mark.Discard()
+
+			results = append(results, result)
+
+			if n := stream.Length(result.Begin, result.End); n > 0 {
+				span = nil
+				report.MatchedObjects += n
+
+				continue
+			}
+		} else {
+			failure, failed = session.Farthest()
+
+			err = mark.Reset()
+			mark.Discard()
+			if IsStreamError(err) {
+				return nil, nil, err
+			}
+		}
+
+		pos, err := stream.Position()
+		if IsStreamError(err) {
+			return nil, nil, err
+		}
+
+		if span == nil {
+			span = &SkippedSpan[T, P]{Begin: pos}
+			report.Skipped = append(report.Skipped, span)
+			reach = -1
+		}
+
+		if failed && failure.Offset > reach {
+			span.NearMiss = failure
+			reach = failure.Offset
+		}
+
+		_, err = stream.Skip(1)
+		if IsStreamError(err) {
+			return nil, nil, err
+		}
+
+		span.End, err = stream.Position()
+		if IsStreamError(err) {
+			return nil, nil, err
+		}
+
+		report.SkippedObjects++
+	}
+
+	return results, report, nil
+}
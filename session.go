@@ -0,0 +1,352 @@
+package exbana
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrBudgetExceeded is returned when a matching session exceeds its budget
+var ErrBudgetExceeded = errors.New("match budget exceeded")
+
+// Budget limits the work of a matching session, zero values are unlimited
+type Budget struct {
+	// MaxSteps is the maximum number of pattern invocations
+	MaxSteps int
+	// Deadline is the wall clock deadline
+	Deadline time.Time
+}
+
+// BudgetError is returned when a matching session exceeds its budget, it wraps ErrBudgetExceeded
+type BudgetError[P any] struct {
+	// Pos is the position reached
+	Pos P
+	// Steps is the number of pattern invocations
+	Steps int
+}
+
+func (e *BudgetError[P]) Error() string {
+	return fmt.Sprintf("%v at position %v after %d steps", ErrBudgetExceeded, e.Pos, e.Steps)
+}
+
+func (e *BudgetError[P]) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
+// deadlineInterval is the number of steps between deadline checks
+const deadlineInterval = 64
+
+// memoKey identifies a match attempt by pattern, offset from the start of the session and validation mode
+type memoKey[T, P any] struct {
+	pattern    Pattern[T, P]
+	offset     int
+	validating bool
+}
+
+// memoEntry is a memoized match result
+type memoEntry[T, P any] struct {
+	matched bool
+	match   *Match[T, P]
+	end     P
+}
+
+// Failure is the farthest position at which patterns failed to match
+type Failure[T, P any] struct {
+	// Pos is the farthest position
+	Pos P
+	// Offset is the offset of Pos from the start of the session
+	Offset int
+	// Patterns are the innermost patterns that failed at Pos
+	Patterns []Pattern[T, P]
+	// Found is the object found at Pos
+	Found T
+	// AtEnd is true if Pos is the end of the input
+	AtEnd bool
+}
+
+// Session is a reader middleware for a single matching session, it implements Matcher and adds opt-in engine
+// features. With memoization enabled match results are memoized by pattern and position (packrat parsing), repeated
+// attempts of a sub pattern at the same position during backtracking are served from the memo, this assumes
+// patterns are pure functions of the input from their position, as in PEG grammars. With failure tracking enabled
+// the farthest position at which patterns failed is recorded, independent of pattern loggers. With a budget set the
+// session returns a BudgetError when the budget is exceeded, this protects against pathological input
+type Session[T, P any] struct {
+	src      Reader[T, P]
+	start    P
+	memoize  bool
+	memo     map[memoKey[T, P]]memoEntry[T, P]
+	hits     int
+	misses   int
+	track    bool
+	farthest *Failure[T, P]
+	seq      int
+	budget   Budget
+	steps    int
+}
+
+// NewSession creates a new matching session on top of reader r, offsets are relative to the current position of r
+func NewSession[T, P any](r Reader[T, P]) (*Session[T, P], error) {
+	start, err := r.Position()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session[T, P]{
+		src:   r,
+		start: start,
+		memo:  map[memoKey[T, P]]memoEntry[T, P]{},
+	}, nil
+}
+
+// SetMemoize enables or disables memoization of match results
+func (s *Session[T, P]) SetMemoize(memoize bool) *Session[T, P] {
+	s.memoize = memoize
+	return s
+}
+
+// SetBudget sets the budget of the session
+func (s *Session[T, P]) SetBudget(budget Budget) *Session[T, P] {
+	s.budget = budget
+	return s
+}
+
+// Steps returns the number of pattern invocations
+func (s *Session[T, P]) Steps() int {
+	return s.steps
+}
+
+// exceeded checks the budget, returns a BudgetError if the budget is exceeded
+func (s *Session[T, P]) exceeded() error {
+	s.steps++
+
+	if (s.budget.MaxSteps > 0 && s.steps > s.budget.MaxSteps) ||
+		(!s.budget.Deadline.IsZero() && s.steps%deadlineInterval == 0 && time.Now().After(s.budget.Deadline)) {
+		pos, _ := s.src.Position()
+		return &BudgetError[P]{Pos: pos, Steps: s.steps}
+	}
+
+	return nil
+}
+
+// SetTrackFailures enables or disables tracking of the farthest failure
+func (s *Session[T, P]) SetTrackFailures(track bool) *Session[T, P] {
+	s.track = track
+	return s
+}
+
+// Farthest returns the farthest failure, returns false if no pattern failed. The object found at the failure
+// position is looked up, the position of the reader is restored afterwards
+func (s *Session[T, P]) Farthest() (*Failure[T, P], bool) {
+	if s.farthest == nil {
+		return nil, false
+	}
+
+	if current, err := s.src.Position(); err == nil && s.src.SetPosition(s.farthest.Pos) == nil {
+		found, err := s.src.Peek1()
+
+		s.farthest.Found = found
+		s.farthest.AtEnd = err != nil
+
+		_ = s.src.SetPosition(current)
+	}
+
+	return s.farthest, true
+}
+
+// fail records a failure of pattern at pos, seq is the sequence number of recorded failures before the pattern was
+// matched, if a sub pattern failed at the same position only the sub pattern is kept
+func (s *Session[T, P]) fail(pattern Pattern[T, P], pos P, offset int, seq int) {
+	switch {
+	case s.farthest == nil || offset > s.farthest.Offset:
+		s.farthest = &Failure[T, P]{Pos: pos, Offset: offset, Patterns: []Pattern[T, P]{pattern}}
+	case offset == s.farthest.Offset && seq == s.seq:
+		for _, p := range s.farthest.Patterns {
+			if p == pattern {
+				return
+			}
+		}
+
+		s.farthest.Patterns = append(s.farthest.Patterns, pattern)
+	default:
+		return
+	}
+
+	s.seq++
+}
+
+// MemoStats returns the number of memo hits and misses
+func (s *Session[T, P]) MemoStats() (int, int) {
+	return s.hits, s.misses
+}
+
+// ClearMemo clears all memoized results, needed when the underlying input changes
+func (s *Session[T, P]) ClearMemo() {
+	s.memo = map[memoKey[T, P]]memoEntry[T, P]{}
+}
+
+// Match matches pattern from the current position
+func (s *Session[T, P]) Match(pattern Pattern[T, P]) (bool, *Match[T, P], error) {
+	return MatchPattern[T, P](pattern, s)
+}
+
+// MatchPattern serves the match from the memo or matches the pattern
+func (s *Session[T, P]) MatchPattern(pattern Pattern[T, P], r Reader[T, P]) (bool, *Match[T, P], error) {
+	if err := s.exceeded(); err != nil {
+		return false, nil, err
+	}
+
+	if !s.memoize && !s.track {
+		return MatchNext(pattern, s.src, r)
+	}
+
+	pos, err := s.src.Position()
+	if IsStreamError(err) {
+		return false, nil, err
+	}
+
+	offset := s.src.Length(s.start, pos)
+
+	if !s.memoize {
+		seq := s.seq
+
+		matched, match, err := MatchNext(pattern, s.src, r)
+		if err == nil && !matched {
+			s.fail(pattern, pos, offset, seq)
+		}
+
+		return matched, match, err
+	}
+
+	// Validated matches have no match tree, so they are memoized separately
+	key := memoKey[T, P]{pattern: pattern, offset: offset, validating: IsValidating(r)}
+
+	if entry, ok := s.memo[key]; ok {
+		s.hits++
+
+		err = r.SetPosition(entry.end)
+		if IsStreamError(err) {
+			return false, nil, err
+		}
+
+		return entry.matched, entry.match, nil
+	}
+
+	s.misses++
+	seq := s.seq
+
+	matched, match, err := MatchNext(pattern, s.src, r)
+	if err != nil {
+		return matched, match, err
+	}
+
+	if s.track && !matched {
+		s.fail(pattern, pos, offset, seq)
+	}
+
+	end, err := s.src.Position()
+	if IsStreamError(err) {
+		return false, nil, err
+	}
+
+	s.memo[key] = memoEntry[T, P]{matched: matched, match: match, end: end}
+
+	return matched, match, nil
+}
+
+func (s *Session[T, P]) Peek1() (T, error) {
+	return s.src.Peek1()
+}
+
+func (s *Session[T, P]) Read1() (T, error) {
+	return s.src.Read1()
+}
+
+func (s *Session[T, P]) Peek(n int, buf []T) (int, error) {
+	return s.src.Peek(n, buf)
+}
+
+func (s *Session[T, P]) Read(n int, buf []T) (int, error) {
+	return s.src.Read(n, buf)
+}
+
+func (s *Session[T, P]) Skip(n int) (int, error) {
+	return s.src.Skip(n)
+}
+
+func (s *Session[T, P]) Finished() bool {
+	return s.src.Finished()
+}
+
+func (s *Session[T, P]) Position() (P, error) {
+	return s.src.Position()
+}
+
+func (s *Session[T, P]) SetPosition(p P) error {
+	return s.src.SetPosition(p)
+}
+
+func (s *Session[T, P]) Range(p1 P, p2 P) ([]T, error) {
+	return s.src.Range(p1, p2)
+}
+
+func (s *Session[T, P]) Length(p1 P, p2 P) int {
+	return s.src.Length(p1, p2)
+}
+
+// Unwrap returns the source reader
+func (s *Session[T, P]) Unwrap() Reader[T, P] {
+	return s.src
+}
+
+// Checkpoint forwards to the source reader
+func (s *Session[T, P]) Checkpoint() (P, error) {
+	return Checkpoint(s.src)
+}
+
+// Release forwards to the source reader
+func (s *Session[T, P]) Release(p P) {
+	Release(s.src, p)
+}
+
+// SkipPattern forwards the default skip pattern of the source reader
+func (s *Session[T, P]) SkipPattern() Pattern[T, P] {
+	return DefaultSkip(s.src)
+}
+
+// MatchFull matches pattern from the current position of r and requires it to consume the entire stream. If the
+// pattern does not match or does not reach the end, the farthest failure is returned as error
+func MatchFull[T, P any](r Reader[T, P], pattern Pattern[T, P]) (*Match[T, P], error) {
+	s, err := NewSession(r)
+	if err != nil {
+		return nil, err
+	}
+
+	s.SetTrackFailures(true)
+
+	matched, match, err := s.Match(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if matched && r.Finished() {
+		return match, nil
+	}
+
+	if matched {
+		// The pattern matched but did not reach the end, report the end of the match unless a failure was farther
+		end, err := r.Position()
+		if IsStreamError(err) {
+			return nil, err
+		}
+
+		offset := r.Length(s.start, end)
+
+		if s.farthest == nil || s.farthest.Offset < offset {
+			s.farthest = &Failure[T, P]{Pos: end, Offset: offset}
+		}
+	}
+
+	failure, _ := s.Farthest()
+
+	return nil, failure
+}
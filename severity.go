@@ -0,0 +1,37 @@
+package exbana
+
+// Severity is the severity of a diagnostic, the zero value is SeverityError
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	}
+
+	return "error"
+}
+
+// DiagnosticOf returns the diagnostic code and severity of the first pattern with a code or a severity other than
+// error
+func DiagnosticOf[T, P any](patterns ...Pattern[T, P]) (string, Severity) {
+	for _, pattern := range patterns {
+		if pattern == nil {
+			continue
+		}
+
+		if code, severity := pattern.Diagnostic(); code != "" || severity != SeverityError {
+			return code, severity
+		}
+	}
+
+	return "", SeverityError
+}
@@ -0,0 +1,77 @@
+package exbana
+
+// Skipper is an optional extension of Reader which provides a default skip pattern (i.e. whitespace and comments)
+// for lexemes that do not have their own skip pattern
+type Skipper[T, P any] interface {
+	SkipPattern() Pattern[T, P]
+}
+
+// WithSkip returns a reader middleware on top of r which provides skip as default skip pattern
+func WithSkip[T, P any](r Reader[T, P], skip Pattern[T, P]) Reader[T, P] {
+	return &withSkip[T, P]{Reader: r, skip: skip}
+}
+
+// DefaultSkip returns the default skip pattern of r, nil if no reader in the middleware chain of r implements Skipper
+func DefaultSkip[T, P any](r Reader[T, P]) Pattern[T, P] {
+	if s, ok := Lookup[Skipper[T, P]](r); ok {
+		return s.SkipPattern()
+	}
+
+	return nil
+}
+
+// SkipTrivia matches skip repeatedly from the current position of r until it does not match or does not consume
+// anything. The skipped input is validated only, no matches are created
+func SkipTrivia[T, P any](skip Pattern[T, P], r Reader[T, P]) error {
+	if skip == nil {
+		return nil
+	}
+
+	for !r.Finished() {
+		begin, err := NewMarker(r)
+		if IsStreamError(err) {
+			return err
+		}
+
+		matched, err := Matches(skip, r)
+		if err != nil {
+			begin.Discard()
+			return err
+		}
+
+		end, err := r.Position()
+		if IsStreamError(err) {
+			begin.Discard()
+			return err
+		}
+
+		if !matched || r.Length(begin.Pos(), end) == 0 {
+			err = begin.Reset()
+			begin.Discard()
+
+			return err
+		}
+
+		begin.Discard()
+	}
+
+	return nil
+}
+
+// withSkip is a reader middleware that provides a default skip pattern
+type withSkip[T, P any] struct {
+	Reader[T, P]
+	skip Pattern[T, P]
+}
+
+func (s *withSkip[T, P]) SkipPattern() Pattern[T, P] {
+	return s.skip
+}
+
+func (s *withSkip[T, P]) Unwrap() Reader[T, P] {
+	return s.Reader
+}
+
+func (s *withSkip[T, P]) MatchPattern(pattern Pattern[T, P], r Reader[T, P]) (bool, *Match[T, P], error) {
+	return MatchNext(pattern, s.Reader, r)
+}
@@ -0,0 +1,115 @@
+package exbana
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// SlogLog emits mismatches as structured slog records with pattern, position and rule path attributes. It is also a
+// Tracer, installed with a tracing reader (see readers/trace) it emits match events and knows the rule path of
+// mismatches, without tracer the path only contains the failing pattern
+type SlogLog[T, P any] struct {
+	logger     *slog.Logger
+	level      slog.Level
+	traceLevel slog.Level
+	stack      []Pattern[T, P]
+}
+
+// NewSlogLog creates a new slog logger, mismatches are logged at info level and match events at debug level
+func NewSlogLog[T, P any](logger *slog.Logger) *SlogLog[T, P] {
+	return &SlogLog[T, P]{
+		logger:     logger,
+		level:      slog.LevelInfo,
+		traceLevel: slog.LevelDebug,
+	}
+}
+
+// SetLevel sets the level of mismatch records
+func (l *SlogLog[T, P]) SetLevel(level slog.Level) *SlogLog[T, P] {
+	l.level = level
+	return l
+}
+
+// SetTraceLevel sets the level of match event records
+func (l *SlogLog[T, P]) SetTraceLevel(level slog.Level) *SlogLog[T, P] {
+	l.traceLevel = level
+	return l
+}
+
+func (l *SlogLog[T, P]) LogMismatch(m *Mismatch[T, P]) {
+	if !l.logger.Enabled(context.Background(), l.level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("pattern", Expectation(m.Pattern)),
+		slog.Any("begin", m.Begin),
+		slog.Any("end", m.End),
+		slog.String("path", l.path(m.Pattern)),
+	}
+
+	if m.Unmatched != nil {
+		attrs = append(attrs, slog.String("unmatched", Expectation(m.Unmatched.Pattern)))
+	}
+
+	l.logger.LogAttrs(context.Background(), l.level, "mismatch", attrs...)
+}
+
+func (l *SlogLog[T, P]) OnEnter(pattern Pattern[T, P], depth int, pos P) {
+	l.stack = append(l.stack, pattern)
+	l.event("enter", pattern, depth, slog.Any("pos", pos))
+}
+
+func (l *SlogLog[T, P]) OnSuccess(pattern Pattern[T, P], depth int, pos P, end P) {
+	l.event("match", pattern, depth, slog.Any("pos", pos), slog.Any("end", end))
+	l.pop()
+}
+
+func (l *SlogLog[T, P]) OnFail(pattern Pattern[T, P], depth int, pos P, err error) {
+	if err != nil {
+		l.event("fail", pattern, depth, slog.Any("pos", pos), slog.Any("error", err))
+	} else {
+		l.event("fail", pattern, depth, slog.Any("pos", pos))
+	}
+
+	l.pop()
+}
+
+func (l *SlogLog[T, P]) event(msg string, pattern Pattern[T, P], depth int, attrs ...slog.Attr) {
+	if !l.logger.Enabled(context.Background(), l.traceLevel) {
+		return
+	}
+
+	attrs = append([]slog.Attr{
+		slog.String("pattern", Expectation(pattern)),
+		slog.Int("depth", depth),
+		slog.String("path", l.path(nil)),
+	}, attrs...)
+
+	l.logger.LogAttrs(context.Background(), l.traceLevel, msg, attrs...)
+}
+
+func (l *SlogLog[T, P]) pop() {
+	if n := len(l.stack); n > 0 {
+		l.stack = l.stack[:n-1]
+	}
+}
+
+// path returns the IDs of the named patterns being matched separated by /, pattern is added if it is not the
+// innermost pattern being matched
+func (l *SlogLog[T, P]) path(pattern Pattern[T, P]) string {
+	var ids []string
+
+	for _, p := range l.stack {
+		if id := p.ID(); id != NoID {
+			ids = append(ids, id)
+		}
+	}
+
+	if pattern != nil && (len(l.stack) == 0 || l.stack[len(l.stack)-1] != pattern) && pattern.ID() != NoID {
+		ids = append(ids, pattern.ID())
+	}
+
+	return strings.Join(ids, "/")
+}
@@ -0,0 +1,68 @@
+package exbana
+
+import "slices"
+
+// CompareFunc compares two positions, it returns a negative number if a is before b, zero if a equals b and a
+// positive number if a is after b. For ordered position types cmp.Compare can be used
+type CompareFunc[P any] func(a P, b P) int
+
+// ReaderCompare returns a CompareFunc which compares positions by the distance between them, this requires Length to
+// return a negative length when the second position is before the first, which is the case for all readers of this
+// module
+func ReaderCompare[T, P any](r Reader[T, P]) CompareFunc[P] {
+	return func(a P, b P) int {
+		return -r.Length(a, b)
+	}
+}
+
+// Length returns the number of matched objects
+func (m *Match[T, P]) Length(r Reader[T, P]) int {
+	return r.Length(m.Begin, m.End)
+}
+
+// Contains reports if pos is inside the match, the end position is not part of the match
+func (m *Match[T, P]) Contains(pos P, cmp CompareFunc[P]) bool {
+	return cmp(m.Begin, pos) <= 0 && cmp(pos, m.End) < 0
+}
+
+// Covers reports if the span of other is inside the span of the match
+func (m *Match[T, P]) Covers(other *Match[T, P], cmp CompareFunc[P]) bool {
+	return cmp(m.Begin, other.Begin) <= 0 && cmp(other.End, m.End) <= 0
+}
+
+// Overlaps reports if the match and other have at least one object in common
+func (m *Match[T, P]) Overlaps(other *Match[T, P], cmp CompareFunc[P]) bool {
+	return cmp(m.Begin, other.End) < 0 && cmp(other.Begin, m.End) < 0
+}
+
+// CompareSpans orders matches by begin position, matches with the same begin position are ordered from longest to
+// shortest so an enclosing match comes before the matches it contains
+func CompareSpans[T, P any](a *Match[T, P], b *Match[T, P], cmp CompareFunc[P]) int {
+	if c := cmp(a.Begin, b.Begin); c != 0 {
+		return c
+	}
+
+	return cmp(b.End, a.End)
+}
+
+// SortMatches sorts matches in place with CompareSpans, the sort is stable
+func SortMatches[T, P any](matches []*Match[T, P], cmp CompareFunc[P]) {
+	slices.SortStableFunc(matches, func(a *Match[T, P], b *Match[T, P]) int {
+		return CompareSpans(a, b, cmp)
+	})
+}
+
+// MatchAt returns the innermost match of the tree that contains pos, nil if pos is outside the match
+func (m *Match[T, P]) MatchAt(pos P, cmp CompareFunc[P]) *Match[T, P] {
+	if !m.Contains(pos, cmp) {
+		return nil
+	}
+
+	for _, component := range m.Components {
+		if inner := component.MatchAt(pos, cmp); inner != nil {
+			return inner
+		}
+	}
+
+	return m
+}
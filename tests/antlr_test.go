@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"bytes"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/exporters/antlr"
+	"github.com/almerlucke/exbana/v2/patterns/end"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestANTLRExport(t *testing.T) {
+	digit := runeFuncMatch(unicode.IsDigit).SetPrintOutput("[0-9]").SetID("digit")
+	letter := runeFuncMatch(unicode.IsLetter).SetPrintOutput("[a-zA-Z]").SetID("letter")
+	identifier := conc(letter, rep(alt(letter, digit))).SetID("identifier")
+	number := conc(digit, rep(digit)).SetID("number")
+	value := alt(identifier, number, conc(runeVector([]rune("'")), rep(letter), runeVector([]rune("'")))).SetID("value")
+	assignment := conc(identifier, runeVector([]rune(":=")), value).SetID("assignment")
+	whiteSpace := runeFuncMatch(unicode.IsSpace).SetPrintOutput(`[ \t\n]`)
+	program := conc(rep(alt(assignment, whiteSpace)), end.New[rune, runes.Pos]()).SetID("program")
+
+	g := ebnf.NewGrammar[rune, runes.Pos](program, assignment, value, identifier, number, digit, letter)
+
+	var buf bytes.Buffer
+
+	err := antlr.Export(&buf, "Assign", g)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	expected := `grammar Assign;
+
+program : (Assignment | EXBANA_LIT_0)* EOF ;
+
+Assignment : Identifier ':=' Value ;
+fragment Value : Identifier | Number | '\'' Letter* '\'' ;
+fragment Identifier : Letter (Letter | Digit)* ;
+fragment Number : Digit Digit* ;
+fragment Digit : [0-9] ;
+fragment Letter : [a-zA-Z] ;
+EXBANA_LIT_0 : [ \t\n] ;
+`
+
+	if buf.String() != expected {
+		t.Errorf("unexpected output:\n%v", buf.String())
+	}
+
+	err = antlr.Export(&strings.Builder{}, "Err", ebnf.NewGrammar[rune, runes.Pos](conc(runeFuncMatch(unicode.IsSpace)).SetID("ws")))
+	if err == nil {
+		t.Errorf("expected error for entity without print output")
+	}
+
+	// Anonymous patterns are not rules
+	g = ebnf.NewGrammar[rune, runes.Pos](program, runeMatch('x'), runeMatch('y'))
+	if len(g.Rules()) != 1 {
+		t.Errorf("expected anonymous patterns to be skipped, got %d rules", len(g.Rules()))
+	}
+
+	if _, ok := g.Rule(ebnf.NoID); ok {
+		t.Errorf("expected no rule without ID")
+	}
+}
@@ -0,0 +1,54 @@
+package tests
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/ast"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type sumNode struct {
+	terms []int
+	span  ast.Span[runes.Pos]
+}
+
+func TestASTBuilder(t *testing.T) {
+	digit := runeBetween('0', '9')
+	number := conc(digit, rep(digit)).SetID("number")
+	sum := conc(number, rep(conc(runeMatch('+'), number))).SetID("sum")
+
+	builder := ast.New[rune, runes.Pos]().
+		RegisterText("number", func(text []rune, _ ast.Span[runes.Pos]) (any, error) {
+			return strconv.Atoi(string(text))
+		}).
+		Register("sum", func(children []any, span ast.Span[runes.Pos]) (any, error) {
+			node := &sumNode{span: span}
+
+			for _, child := range children {
+				if n, ok := child.(int); ok {
+					node.terms = append(node.terms, n)
+				}
+			}
+
+			return node, nil
+		})
+
+	rd, _ := runes.New(strings.NewReader("1+22+3"))
+
+	_, result, err := ebnf.MatchPattern(sum, rd)
+	if err != nil || result == nil {
+		t.Fatalf("expected match, got %v", err)
+	}
+
+	root, err := builder.Build(result, rd)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	node, ok := root.(*sumNode)
+	if !ok || len(node.terms) != 3 || node.terms[1] != 22 || node.span.End.Index != 6 {
+		t.Errorf("unexpected ast %v", root)
+	}
+}
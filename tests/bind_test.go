@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"errors"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/bind"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	bytereader "github.com/almerlucke/exbana/v2/readers/bytes"
+	"reflect"
+	"testing"
+)
+
+type bindEntry struct {
+	Name  string `exbana:"name,cstr"`
+	Value int16  `exbana:"value,le"`
+}
+
+type bindExtension struct {
+	Scale float32 `exbana:"scale"`
+}
+
+type bindHeader struct {
+	Magic     [4]byte `exbana:"magic"`
+	Version   uint16  `exbana:"version,le,required"`
+	Flags     byte
+	Entries   []bindEntry    `exbana:"entry"`
+	Extension *bindExtension `exbana:"extension"`
+	Count     int            `exbana:"entries,eval"`
+	Ignored   string         `exbana:"-"`
+}
+
+func TestBind(t *testing.T) {
+	eq := func(a, b byte) bool { return a == b }
+	anyByte := func(n int) ebnf.Pattern[byte, int] {
+		return repetition.New[byte, int](entity.New[byte, int](func(byte) bool { return true }), n, n)
+	}
+	id := func(id string, p ebnf.Pattern[byte, int]) ebnf.Pattern[byte, int] {
+		return concatenation.New[byte, int](p).SetID(id)
+	}
+
+	entry := concatenation.New[byte, int](id("name", anyByte(4)), id("value", anyByte(2)))
+	entry.SetID("entry")
+
+	entries := repetition.New[byte, int](entry, 0, 0)
+	entries.SetID("entries")
+	entries.SetEvalFunc(func(m *ebnf.Match[byte, int], _ ebnf.Reader[byte, int]) (any, error) {
+		return len(m.Components), nil
+	})
+
+	// The extension is present if flags is 1
+	layout := alternation.New[byte, int](
+		concatenation.New[byte, int](id("flags", vector.New[byte, int](eq, 1)), id("extension", id("scale", anyByte(4))), entries),
+		concatenation.New[byte, int](id("flags", vector.New[byte, int](eq, 0)), entries),
+	)
+
+	header := concatenation.New[byte, int](id("magic", vector.New[byte, int](eq, 'B', 'I', 'N', '1')), id("version", anyByte(2)), layout)
+
+	input := []byte{'B', 'I', 'N', '1', 0x02, 0x01, 1, 0x3F, 0xC0, 0, 0, 'a', 'b', 0, 0, 0xFE, 0xFF, 'c', 'd', 'e', 'f', 0x10, 0}
+
+	rd := bytereader.NewFromBytes(input)
+
+	m, err := ebnf.MatchFull[byte, int](rd, header)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	h := bindHeader{Ignored: "kept"}
+
+	if err := bind.Decode(m, rd, &h); err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	expected := bindHeader{
+		Magic:     [4]byte{'B', 'I', 'N', '1'},
+		Version:   0x0102,
+		Flags:     1,
+		Entries:   []bindEntry{{Name: "ab", Value: -2}, {Name: "cdef", Value: 16}},
+		Extension: &bindExtension{Scale: 1.5},
+		Count:     2,
+		Ignored:   "kept",
+	}
+
+	if !reflect.DeepEqual(h, expected) {
+		t.Errorf("expected %+v, got %+v", expected, h)
+	}
+
+	// Without extension the pointer stays nil
+	input = []byte{'B', 'I', 'N', '1', 0x02, 0x01, 0}
+	rd = bytereader.NewFromBytes(input)
+
+	m, err = ebnf.MatchFull[byte, int](rd, header)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	h = bindHeader{}
+	if err := bind.Decode(m, rd, &h); err != nil || h.Extension != nil || h.Entries != nil || h.Version != 0x0102 {
+		t.Errorf("unexpected result %+v %v", h, err)
+	}
+
+	// Errors
+	var wrongSize struct {
+		Version uint16 `exbana:"version,size=4"`
+	}
+
+	var fieldErr *bind.FieldError
+	if err := bind.Decode(m, rd, &wrongSize); !errors.As(err, &fieldErr) || !errors.Is(err, bind.ErrSize) || fieldErr.Field != "Version" {
+		t.Errorf("expected size error, got %v", err)
+	}
+
+	var missing struct {
+		Checksum uint32 `exbana:"checksum,required"`
+	}
+
+	if err := bind.Decode(m, rd, &missing); !errors.Is(err, bind.ErrMissing) {
+		t.Errorf("expected missing error, got %v", err)
+	}
+
+	if err := bind.Decode(m, rd, h); !errors.Is(err, bind.ErrTarget) {
+		t.Errorf("expected target error, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/almerlucke/exbana/v2/grammars/png"
+	"github.com/almerlucke/exbana/v2/grammars/riff"
+	"image"
+	"image/color"
+	imagepng "image/png"
+	"reflect"
+	"testing"
+)
+
+func TestPNG(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	img.Set(1, 1, color.NRGBA{R: 255, A: 255})
+
+	var buf bytes.Buffer
+	if err := imagepng.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := png.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	expected := png.Header{Width: 3, Height: 2, BitDepth: 8, ColorType: 6}
+	if decoded.Header != expected {
+		t.Errorf("expected %+v, got %+v", expected, decoded.Header)
+	}
+
+	var types []string
+	for _, c := range decoded.Chunks {
+		types = append(types, c.Type)
+	}
+
+	if types[0] != "IHDR" || types[len(types)-1] != "IEND" || !reflect.DeepEqual(decoded.Chunks[0].Data, buf.Bytes()[16:29]) {
+		t.Errorf("unexpected chunks %v", types)
+	}
+
+	// Corrupt the IHDR data
+	corrupt := bytes.Clone(buf.Bytes())
+	corrupt[20] ^= 0xFF
+
+	var crcErr *png.CRCError
+	if _, err := png.Decode(bytes.NewReader(corrupt)); !errors.As(err, &crcErr) || crcErr.Type != "IHDR" || !errors.Is(err, png.ErrCRC) {
+		t.Errorf("expected CRC error, got %v", err)
+	}
+
+	// Truncated file
+	if _, err := png.Decode(bytes.NewReader(buf.Bytes()[:buf.Len()-6])); err == nil {
+		t.Errorf("expected error for truncated file")
+	}
+}
+
+func TestRIFF(t *testing.T) {
+	chunk := func(id string, data []byte) []byte {
+		b := append([]byte(id), binary.LittleEndian.AppendUint32(nil, uint32(len(data)))...)
+		b = append(b, data...)
+
+		if len(data)%2 == 1 {
+			b = append(b, 0)
+		}
+
+		return b
+	}
+
+	format := []byte{1, 0, 2, 0}
+	format = binary.LittleEndian.AppendUint32(format, 44100)
+	format = binary.LittleEndian.AppendUint32(format, 44100*4)
+	format = append(format, 4, 0, 16, 0)
+
+	info := append([]byte("INFO"), chunk("INAM", []byte("tune"))...)
+	info = append(info, chunk("ISFT", []byte("exb"))...)
+
+	body := append([]byte("WAVE"), chunk("fmt ", format)...)
+	body = append(body, chunk("LIST", info)...)
+	body = append(body, chunk("data", []byte{1, 2, 3})...)
+
+	file := chunk("RIFF", body)
+
+	wav, err := riff.DecodeWAV(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	expected := riff.Format{AudioFormat: 1, Channels: 2, SampleRate: 44100, ByteRate: 176400, BlockAlign: 4, BitsPerSample: 16}
+	if wav.Format != expected {
+		t.Errorf("expected %+v, got %+v", expected, wav.Format)
+	}
+
+	if !bytes.Equal(wav.Data, []byte{1, 2, 3}) {
+		t.Errorf("unexpected data %v", wav.Data)
+	}
+
+	list := wav.Chunks[1]
+	if list.ID != "LIST" || list.List != "INFO" || len(list.Chunks) != 2 || string(list.Chunks[1].Data) != "exb" {
+		t.Errorf("unexpected list %+v", list)
+	}
+
+	// A chunk size crossing the list size is an error
+	bad := bytes.Clone(file)
+	binary.LittleEndian.PutUint32(bad[len(file)-8:], 9)
+
+	if _, err := riff.Decode(bytes.NewReader(bad)); err == nil {
+		t.Errorf("expected error")
+	}
+
+	if _, err := riff.DecodeWAV(bytes.NewReader(chunk("RIFF", []byte("AVI ")))); !errors.Is(err, riff.ErrNotWAV) {
+		t.Errorf("expected not WAV error, got %v", err)
+	}
+}
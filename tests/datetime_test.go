@@ -0,0 +1,78 @@
+package tests
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/datetime"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	runewriter "github.com/almerlucke/exbana/v2/writers/runes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDateTime(t *testing.T) {
+	stamp := alternation.New[rune, runes.Pos](
+		datetime.RFC3339[runes.Pos](),
+		datetime.CommonLog[runes.Pos](),
+		datetime.Syslog[runes.Pos](),
+		datetime.Date[runes.Pos](),
+		datetime.Duration[runes.Pos](),
+	)
+
+	input := `2024-03-01t10:20:30.25+01:00 GET [10/Oct/2000:13:55:36 -0700] Feb  2 01:02:03 took 1h2m3.5s on 2024-02-29 (-300ms)`
+
+	rd, _ := runes.New(strings.NewReader(input))
+
+	results, err := ebnf.Scan[rune, runes.Pos](rd, stamp)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	expected := []any{
+		time.Date(2024, 3, 1, 10, 20, 30, 250000000, time.FixedZone("", 3600)),
+		time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600)),
+		time.Date(0, 2, 2, 1, 2, 3, 0, time.UTC),
+		time.Hour + 2*time.Minute + 3500*time.Millisecond,
+		time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		-300 * time.Millisecond,
+	}
+
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(results))
+	}
+
+	for i, result := range results {
+		v, err := result.Unpack().Eval(rd)
+		if err != nil {
+			t.Fatalf("eval %s: %v", result.Unpack().ID(), err)
+		}
+
+		switch e := expected[i].(type) {
+		case time.Time:
+			if tm, ok := v.(time.Time); !ok || !tm.Equal(e) {
+				t.Errorf("%s: expected %v, got %v", result.Unpack().ID(), e, v)
+			}
+		default:
+			if v != e {
+				t.Errorf("%s: expected %v, got %v", result.Unpack().ID(), e, v)
+			}
+		}
+	}
+
+	for _, pattern := range []ebnf.Pattern[rune, runes.Pos]{datetime.RFC3339[runes.Pos](), datetime.CommonLog[runes.Pos](),
+		datetime.Syslog[runes.Pos](), datetime.Time[runes.Pos](), datetime.Duration[runes.Pos]()} {
+		for i := 0; i < 50; i++ {
+			sw := runewriter.NewStringWriter()
+			_ = pattern.Generate(sw)
+			_ = sw.Finish()
+
+			rd, _ := runes.New(strings.NewReader(sw.String()))
+
+			if matched, _, _ := pattern.Match(rd); !matched || !rd.Finished() {
+				t.Errorf("%s: generated %q does not match", pattern.ID(), sw.String())
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package tests
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/dfa"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func tokenGrammar() ebnf.Pattern[rune, runes.Pos] {
+	letter := runeBetween('a', 'z')
+	digit := runeBetween('0', '9')
+	identifier := conc(letter, rep(alt(letter, digit)))
+	number := conc(opt(runeVector([]rune("-"))), digit, rep(digit))
+	hex := conc(runeVector([]rune("0x")), repetition.New[rune, runes.Pos](alt(digit, runeBetween('a', 'f')), 1, 4))
+	keyword := alternation.New[rune, runes.Pos](runeVector([]rune("if")), runeVector([]rune("for")), runeVector([]rune("func"))).SetOrthogonal(true)
+	operator := alt(runeMatch('+'), runeVector([]rune("++")), runeVector([]rune("+=")), runeMatch('-'), runeVector([]rune("->")))
+
+	return alt(keyword, identifier, number, hex, operator, runeMatch(' '))
+}
+
+func TestRegular(t *testing.T) {
+	a := runeMatch('a')
+
+	cases := []struct {
+		pattern ebnf.Pattern[rune, runes.Pos]
+		regular bool
+	}{
+		{tokenGrammar(), true},
+		{conc(runeVector([]rune("ab")), rep(a)), true},
+		{conc(rep(a), a), false},
+		{conc(opt(a), a), false},
+		{conc(opt(runeVector([]rune("a"))), runeMatch('b')), true},
+		{conc(opt(runeVector([]rune("a"))), runeMatch('a')), false},
+		{rep(opt(a)), false},
+		{repetition.New[rune, runes.Pos](conc(a, runeMatch('b')), 2, 2), true},
+		{arithmetic(), false},
+	}
+
+	for i, c := range cases {
+		if dfa.Regular(c.pattern) != c.regular {
+			t.Errorf("case %d: expected regular %v", i, c.regular)
+		}
+	}
+}
+
+func TestDFA(t *testing.T) {
+	pattern := tokenGrammar()
+
+	d, err := dfa.New(pattern, func(r rune) any { return r })
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	alphabet := "iforuncx0123-+>= af"
+	rnd := rand.New(rand.NewSource(1))
+
+	for n := 0; n < 2000; n++ {
+		var sb strings.Builder
+
+		for l := rnd.Intn(8); l > 0; l-- {
+			sb.WriteByte(alphabet[rnd.Intn(len(alphabet))])
+		}
+
+		input := sb.String()
+
+		rd1, _ := runes.New(strings.NewReader(input))
+		matched1, result1, err1 := ebnf.MatchPattern(pattern, rd1)
+
+		rd2, _ := runes.New(strings.NewReader(input))
+		matched2, result2, err2 := ebnf.MatchPattern[rune, runes.Pos](d, rd2)
+
+		if err1 != nil || err2 != nil || matched1 != matched2 || (matched1 && result1.End != result2.End) {
+			t.Fatalf("input %q: tree %v %v %v, dfa %v %v %v", input, matched1, result1, err1, matched2, result2, err2)
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	input := "((1+2)*(3-4)+5*(6+(7*8)))-9"
+	expr := arithmetic()
+	rewritten := dfa.Rewrite(expr, func(r rune) any { return r })
+
+	rd, _ := runes.New(strings.NewReader(input))
+
+	matched, result, err := ebnf.MatchPattern(rewritten, rd)
+	if err != nil || !matched || result.End.Index != len(input) {
+		t.Fatalf("expected match, got %v %v %v", matched, result, err)
+	}
+
+	tokens := rep(tokenGrammar())
+
+	// A repetition of tokens is not regular, the tokens are
+	rewritten = dfa.Rewrite(tokens, nil)
+	if _, ok := rewritten.(*repetition.Repetition[rune, runes.Pos]).Pattern().(*dfa.DFA[rune, runes.Pos]); !ok {
+		t.Errorf("expected the token grammar to be replaced by a DFA")
+	}
+
+	// Sub trees with named patterns or eval functions are not collapsed
+	digits := conc(runeBetween('0', '9'), rep(runeBetween('0', '9')))
+	fraction := conc(runeMatch('.'), digits.SetID("frac"))
+	sign := alt(runeMatch('+'), runeMatch('-')).SetEvalFunc(func(m *ebnf.Match[rune, runes.Pos], r ebnf.Reader[rune, runes.Pos]) (any, error) {
+		return ebnf.Text(m, r)
+	})
+
+	rewritten = dfa.Rewrite(conc(sign, fraction), func(r rune) any { return r })
+
+	rd, _ = runes.New(strings.NewReader("-.25"))
+
+	matched, result, err = ebnf.MatchPattern(rewritten, rd)
+	if err != nil || !matched {
+		t.Fatalf("expected match, got %v %v", matched, err)
+	}
+
+	if frac := result.Find("frac"); frac == nil || frac.Begin.Index != 2 {
+		t.Errorf("expected named fraction digits, got %v", frac)
+	}
+
+	if value, err := result.Components[0].Eval(rd); err != nil || value != "-" {
+		t.Errorf("expected eval function of sign, got %v %v", value, err)
+	}
+}
+
+func BenchmarkTokensTree(b *testing.B) {
+	benchmarkTokens(b, tokenGrammar())
+}
+
+func BenchmarkTokensDFA(b *testing.B) {
+	benchmarkTokens(b, dfa.Rewrite(tokenGrammar(), func(r rune) any { return r }))
+}
+
+func benchmarkTokens(b *testing.B, pattern ebnf.Pattern[rune, runes.Pos]) {
+	input := strings.Repeat("func foo42 for x += 0x1f3 -> -12 ", 50)
+
+	for i := 0; i < b.N; i++ {
+		rd, _ := runes.New(strings.NewReader(input))
+
+		_, err := ebnf.Scan(rd, pattern)
+		if err != nil {
+			b.Fatalf("err %v", err)
+		}
+	}
+}
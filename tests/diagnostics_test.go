@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"errors"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/diagnostics"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"strings"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	item := runeBetween('a', 'z').SetID("letter")
+	list := conc(runeVector([]rune("(")), item, rep(conc(runeVector([]rune(",")), item)), runeVector([]rune(")")))
+
+	rd, _ := runes.New(strings.NewReader("# list\n\t(a,b;"))
+	_, _ = rd.Skip(8)
+
+	session, _ := ebnf.NewSession[rune, runes.Pos](rd)
+	session.SetTrackFailures(true)
+
+	_, _, _ = session.Match(list)
+
+	failure, ok := session.Farthest()
+	if !ok {
+		t.Fatalf("expected failure")
+	}
+
+	err := diagnostics.FromFailure("list.txt", rd, failure)
+
+	expected := "list.txt:2:6: expected \")\" or \",\", got \";\"\n\t(a,b;\n\t    ^"
+	if err.Report() != expected {
+		t.Errorf("unexpected report:\n%s", err.Report())
+	}
+
+	var f *ebnf.Failure[rune, runes.Pos]
+	if !errors.As(err, &f) {
+		t.Errorf("expected wrapped failure")
+	}
+
+	// The mismatch of the concatenation is located where the closing parenthesis was expected
+	log := ebnf.NewStackLog[rune, runes.Pos]()
+	list.SetLogger(log)
+
+	rd, _ = runes.New(strings.NewReader("(a,b"))
+	_, _, _ = ebnf.MatchPattern(list, rd)
+
+	mismatch := log.Stack[0]
+
+	err = diagnostics.FromMismatch("", rd, mismatch)
+	if err.Error() != "1:5: expected \")\", got end of input" || err.Excerpt() != "(a,b\n    ^" {
+		t.Errorf("unexpected report:\n%s", err.Report())
+	}
+}
+
+func TestDiagnosticCodes(t *testing.T) {
+	semicolon := runeMatch(';').SetExpectation("semicolon")
+	semicolon.SetDiagnostic("E001", ebnf.SeverityError)
+
+	stmt := conc(runeBetween('a', 'z'), semicolon)
+
+	log := ebnf.NewStackLog[rune, runes.Pos]()
+	stmt.SetLogger(log)
+
+	rd, _ := runes.New(strings.NewReader("x"))
+	_, _, _ = ebnf.MatchPattern(stmt, rd)
+
+	err := diagnostics.FromMismatch("main.x", rd, log.Stack[0])
+	if err.Error() != "main.x:1:2: error[E001]: expected semicolon, got end of input" {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	semicolon.SetDiagnostic("", ebnf.SeverityWarning)
+
+	rd, _ = runes.New(strings.NewReader("x,"))
+	session, _ := ebnf.NewSession[rune, runes.Pos](rd)
+	session.SetTrackFailures(true)
+
+	_, _, _ = session.Match(stmt)
+	failure, _ := session.Farthest()
+
+	err = diagnostics.FromFailure("", rd, failure)
+	if err.Error() != "1:2: warning: expected semicolon, got \",\"" || err.Severity != ebnf.SeverityWarning {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	keyword := alt(runeVector([]rune("while")), runeVector([]rune("if")), runeVector([]rune("return")))
+	stmt := conc(keyword, runeMatch(' '), runeBetween('a', 'z'))
+
+	if d := diagnostics.Distance("wihle", "while"); d != 1 {
+		t.Errorf("expected distance 1, got %d", d)
+	}
+
+	literals := diagnostics.Literals(stmt)
+	if strings.Join(literals, ",") != "if,return,while" {
+		t.Errorf("unexpected literals %v", literals)
+	}
+
+	for input, expected := range map[string]string{
+		"wihle x":  `1:1: unknown "wihle", did you mean "while"?`,
+		"retrun x": `1:1: unknown "retrun", did you mean "return"?`,
+		"loop x":   `1:1: expected "if", "return" or "while", got "l"`,
+	} {
+		rd, _ := runes.New(strings.NewReader(input))
+		session, _ := ebnf.NewSession[rune, runes.Pos](rd)
+		session.SetTrackFailures(true)
+
+		_, _, _ = session.Match(stmt)
+		failure, _ := session.Farthest()
+
+		err := diagnostics.FromFailure("", rd, failure)
+		err.Suggest(rd, literals)
+
+		if err.Error() != expected {
+			t.Errorf("%s: expected %q, got %q", input, expected, err.Error())
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"encoding/xml"
+	"errors"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/exporters/diagram"
+	"github.com/almerlucke/exbana/v2/importers/goebnf"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"io"
+	"strings"
+	"testing"
+)
+
+const diagramEBNF = `
+Expr   = Term { ( "+" | "-" ) Term } .
+Term   = Number | "(" Expr ")" .
+Number = digit { digit } .
+digit  = "0" … "9" .
+`
+
+func TestDiagram(t *testing.T) {
+	g, err := goebnf.Parse[runes.Pos]("expr.ebnf", strings.NewReader(diagramEBNF), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expr, _ := g.Rule("Expr")
+
+	// Groups get diagrams of their own
+	diagrams := diagram.Build(g, ebnf.Patterns[rune, runes.Pos]{expr}, diagram.Options{})
+
+	var names []string
+	for _, d := range diagrams {
+		names = append(names, d.Name)
+	}
+
+	if strings.Join(names, " ") != "Expr Expr.1 Expr.2" || !diagrams[1].IsGroup() || diagrams[0].IsGroup() {
+		t.Fatalf("unexpected diagrams %v", names)
+	}
+
+	root := diagrams[0].Root
+	if root.Kind != diagram.KindSequence || root.Children[0].Kind != diagram.KindNonTerminal ||
+		root.Children[1].Kind != diagram.KindOptional || root.Children[1].Children[0].Kind != diagram.KindLoop {
+		t.Errorf("unexpected root %+v", root)
+	}
+
+	if choice := diagrams[2].Root; choice.Kind != diagram.KindChoice || choice.Children[1].Label != `"-"` {
+		t.Errorf("unexpected group %+v", choice)
+	}
+
+	// Inline draws the groups in the diagram of the rule
+	diagrams = diagram.Build(g, nil, diagram.Options{Inline: true})
+	if len(diagrams) != 4 {
+		t.Fatalf("expected a diagram per rule, got %d", len(diagrams))
+	}
+
+	loop := diagrams[0].Root.Children[1].Children[0]
+	if loop.Children[0].Kind != diagram.KindSequence || loop.Children[0].Children[0].Kind != diagram.KindChoice {
+		t.Errorf("unexpected inlined loop %+v", loop.Children[0])
+	}
+
+	// SVG is well formed XML
+	for _, d := range diagrams {
+		var sb strings.Builder
+
+		err = d.WriteSVG(&sb)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dec := xml.NewDecoder(strings.NewReader(sb.String()))
+
+		for err == nil {
+			_, err = dec.Token()
+		}
+
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("invalid SVG for %s: %v", d.Name, err)
+		}
+
+		if !strings.Contains(sb.String(), ">"+d.Name+"</text>") {
+			t.Errorf("expected title %s", d.Name)
+		}
+	}
+
+	var sb strings.Builder
+
+	err = diagram.WriteDOT(&sb, "expr", diagrams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, edge := range []string{`"Expr" -> "Term";`, `"Term" -> "Expr";`, `"Number" -> "digit";`} {
+		if !strings.Contains(sb.String(), edge) {
+			t.Errorf("expected edge %s in:\n%s", edge, sb.String())
+		}
+	}
+}
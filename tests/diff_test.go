@@ -0,0 +1,94 @@
+package tests
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/diff"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestGrammarDiff(t *testing.T) {
+	makeGrammar := func(withHex bool, withOctal bool) *ebnf.Grammar[rune, runes.Pos] {
+		digit := runeFuncMatch(unicode.IsDigit).SetPrintOutput("[0-9]").SetID("digit")
+		decimal := conc(digit, rep(digit)).SetID("decimal")
+		lits := []ebnf.Pattern[rune, runes.Pos]{decimal}
+		rules := []ebnf.Pattern[rune, runes.Pos]{digit, decimal}
+
+		if withHex {
+			hex := conc(runeVector([]rune("0x")), rep(digit)).SetID("hex")
+			lits = append(lits, hex)
+			rules = append(rules, hex)
+		}
+
+		if withOctal {
+			octal := conc(runeVector([]rune("0o")), rep(digit)).SetID("octal")
+			lits = append(lits, octal)
+			rules = append(rules, octal)
+		}
+
+		return ebnf.NewGrammar[rune, runes.Pos](append(rules, alt(lits...).SetID("literal"))...)
+	}
+
+	report := diff.Grammars(makeGrammar(true, false), makeGrammar(true, false))
+	if !report.Empty() {
+		t.Fatalf("expected empty report, got:\n%v", report)
+	}
+
+	report = diff.Grammars(makeGrammar(true, false), makeGrammar(false, true))
+	if len(report.Added) != 1 || report.Added[0] != "octal" || len(report.Removed) != 1 || report.Removed[0] != "hex" {
+		t.Fatalf("unexpected added/removed:\n%v", report)
+	}
+
+	if len(report.Changed) != 1 || report.Changed[0].Rule != "literal" {
+		t.Fatalf("expected literal rule change:\n%v", report)
+	}
+
+	change := report.Changed[0].Changes[0]
+	if change.Type != diff.Changed || len(change.Path) != 1 || change.Path[0] != 1 || change.Old.Label != "hex" || change.New.Label != "octal" {
+		t.Errorf("unexpected change:\n%v", report)
+	}
+}
+
+func TestMatchDiff(t *testing.T) {
+	parse := func(withDigits bool) *ebnf.Match[rune, runes.Pos] {
+		letter := runeBetween('a', 'z')
+		digit := runeBetween('0', '9')
+		var wordRest ebnf.Pattern[rune, runes.Pos] = letter
+
+		if withDigits {
+			wordRest = alt(letter, digit)
+		}
+
+		word := conc(letter, rep(wordRest)).SetID("word")
+		number := conc(digit, rep(digit)).SetID("number")
+		list := rep(alt(word, number, runeMatch(' '))).SetID("list")
+
+		rd, _ := runes.New(strings.NewReader("ab12 cd 34"))
+
+		_, m, err := list.Match(rd)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		return m
+	}
+
+	if changes := diff.Matches(parse(false), parse(false)); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %v", changes)
+	}
+
+	changes := diff.Matches(parse(false), parse(true))
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %v", changes)
+	}
+
+	if c := changes[0]; c.Type != diff.Changed || len(c.Path) != 1 || c.Path[0] != 0 || c.Old.ID != "word" || c.New.End.Index != 4 {
+		t.Errorf("unexpected change %v", c)
+	}
+
+	if c := changes[1]; c.Type != diff.Removed || len(c.Path) != 1 || c.Path[0] != 1 || c.Old.ID != "number" {
+		t.Errorf("unexpected change %v", c)
+	}
+}
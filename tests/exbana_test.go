@@ -1,6 +1,9 @@
 package tests
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	ebnf "github.com/almerlucke/exbana/v2"
 	"github.com/almerlucke/exbana/v2/patterns/alternation"
 	"github.com/almerlucke/exbana/v2/patterns/concatenation"
@@ -8,6 +11,7 @@ import (
 	"github.com/almerlucke/exbana/v2/patterns/repetition"
 	vec "github.com/almerlucke/exbana/v2/patterns/vector"
 	"github.com/almerlucke/exbana/v2/readers/runes"
+	"github.com/almerlucke/exbana/v2/readers/stats"
 	"math/rand"
 	"strings"
 	"testing"
@@ -502,3 +506,784 @@ func TestExbana(t *testing.T) {
 // 	}
 
 // }
+
+func TestDispatch(t *testing.T) {
+	keywords := []string{"break", "case", "chan", "const", "continue", "default", "defer", "else", "for", "func", "go",
+		"goto", "if", "import", "interface", "map", "package", "range", "return", "select", "struct", "switch", "type"}
+
+	var tokens []ebnf.Pattern[rune, runes.Pos]
+
+	for _, keyword := range keywords {
+		tokens = append(tokens, runeVector([]rune(keyword)))
+	}
+
+	letter := runeBetween('a', 'z')
+	tokens = append(tokens, conc(letter, rep(letter)), runeMatch(' '), conc(opt(runeMatch('-')), runeBetween('0', '9')))
+
+	plain := alternation.New[rune, runes.Pos](tokens...)
+	dispatched := alternation.New[rune, runes.Pos](tokens...).SetDispatch(func(r rune) any { return r })
+
+	input := "package main func range 1 -2 x if gox"
+
+	rd1, _ := runes.New(strings.NewReader(input))
+	st1 := stats.New[rune, runes.Pos](rd1)
+
+	results1, err := ebnf.Scan[rune, runes.Pos](st1, plain)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	rd2, _ := runes.New(strings.NewReader(input))
+	st2 := stats.New[rune, runes.Pos](rd2)
+
+	results2, err := ebnf.Scan[rune, runes.Pos](st2, dispatched)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	if len(results1) != len(results2) {
+		t.Fatalf("expected %d results, got %d", len(results1), len(results2))
+	}
+
+	for i := range results1 {
+		if results1[i].End != results2[i].End || results1[i].Components[0].Pattern != results2[i].Components[0].Pattern {
+			t.Errorf("result %d differs", i)
+		}
+	}
+
+	attempts1, attempts2 := 0, 0
+	for _, pm := range tokens {
+		attempts1 += st1.PatternMatches[pm]
+		attempts2 += st2.PatternMatches[pm]
+	}
+
+	if attempts2*4 > attempts1 {
+		t.Errorf("expected far less attempts with dispatch, got %d and %d", attempts1, attempts2)
+	}
+}
+
+func TestWithoutValues(t *testing.T) {
+	word := conc(runeVector([]rune("ab")), rep(runeBetween('a', 'z')))
+
+	rd, _ := runes.New(strings.NewReader("abcd"))
+
+	matched, result, err := ebnf.MatchPattern(word, ebnf.WithoutValues[rune, runes.Pos](rd))
+	if err != nil || !matched {
+		t.Fatalf("expected match, got %v %v", matched, err)
+	}
+
+	if len(result.Components[0].Value.([]rune)) != 0 || len(result.Components[1].Components[0].Value.([]rune)) != 0 {
+		t.Errorf("expected no values")
+	}
+
+	objs, err := rd.Range(result.Begin, result.End)
+	if err != nil || string(objs) != "abcd" {
+		t.Errorf("expected range abcd, got %q %v", string(objs), err)
+	}
+
+	rd, _ = runes.New(strings.NewReader("abcd"))
+
+	_, result, _ = ebnf.Compile(word).Match(rd, ebnf.Options[rune, runes.Pos]{SkipValues: true})
+	if result == nil || len(result.Components[0].Value.([]rune)) != 0 {
+		t.Errorf("expected no values with options")
+	}
+
+	// Values are not captured behind other middleware
+	rd, _ = runes.New(strings.NewReader("abcd"))
+
+	session, _ := ebnf.NewSession(ebnf.WithoutValues[rune, runes.Pos](rd))
+
+	_, result, _ = session.Match(word)
+	if result == nil || len(result.Components[0].Value.([]rune)) != 0 {
+		t.Errorf("expected no values through a session")
+	}
+}
+
+func TestWalk(t *testing.T) {
+	digits := rep(runeBetween('0', '9')).SetID("digits")
+	number := conc(digits, opt(conc(runeMatch('.'), digits))).SetID("number")
+
+	rd, _ := runes.New(strings.NewReader("12.5"))
+
+	matched, result, err := ebnf.MatchPattern(number, rd)
+	if err != nil || !matched {
+		t.Fatalf("expected match, got %v %v", matched, err)
+	}
+
+	var pre []string
+
+	result.Walk(func(m *ebnf.Match[rune, runes.Pos], depth int) bool {
+		if m.ID() != ebnf.NoID {
+			pre = append(pre, fmt.Sprintf("%s:%d", m.ID(), depth))
+		}
+
+		// Do not descend into digits
+		return m.ID() != "digits"
+	})
+
+	if strings.Join(pre, " ") != "number:0 digits:1 digits:3" {
+		t.Errorf("unexpected pre-order walk %v", pre)
+	}
+
+	var post []string
+
+	result.WalkPost(func(m *ebnf.Match[rune, runes.Pos], depth int) {
+		if m.ID() != ebnf.NoID {
+			post = append(post, m.ID())
+		}
+	})
+
+	if strings.Join(post, " ") != "digits digits number" {
+		t.Errorf("unexpected post-order walk %v", post)
+	}
+}
+
+func TestFind(t *testing.T) {
+	digits := conc(runeBetween('0', '9'), rep(runeBetween('0', '9'))).SetID("digits")
+	number := conc(digits, opt(conc(runeMatch('.'), digits))).SetID("number")
+	list := conc(number, rep(conc(runeMatch(','), number))).SetID("list")
+
+	rd, _ := runes.New(strings.NewReader("12.5,3,4.25"))
+
+	matched, result, err := ebnf.MatchPattern(list, rd)
+	if err != nil || !matched {
+		t.Fatalf("expected match, got %v %v", matched, err)
+	}
+
+	if first := result.Find("number"); first == nil || first.End.Index != 4 {
+		t.Errorf("expected first number 12.5, got %v", first)
+	}
+
+	if result.Find("list") != nil || result.Find("none") != nil {
+		t.Errorf("expected no match for list or none")
+	}
+
+	if numbers := result.FindAll("number"); len(numbers) != 3 {
+		t.Errorf("expected 3 numbers, got %d", len(numbers))
+	}
+
+	if digits := result.FindAll("digits"); len(digits) != 5 {
+		t.Errorf("expected 5 digits, got %d", len(digits))
+	}
+}
+
+func TestMatchJSON(t *testing.T) {
+	digits := conc(runeBetween('0', '9'), rep(runeBetween('0', '9'))).SetID("digits")
+	pair := conc(digits, runeMatch(','), digits).SetID("pair")
+
+	rd, _ := runes.New(strings.NewReader("12,3"))
+
+	matched, result, err := ebnf.MatchPattern(pair, rd)
+	if err != nil || !matched {
+		t.Fatalf("expected match, got %v %v", matched, err)
+	}
+
+	data, err := ebnf.ToJSON(result, func(m *ebnf.Match[rune, runes.Pos]) string {
+		if m.ID() == "digits" {
+			objs, _ := rd.Range(m.Begin, m.End)
+			return string(objs)
+		}
+
+		return ""
+	})
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	var tree ebnf.JSONMatch[runes.Pos]
+
+	err = json.Unmarshal(data, &tree)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	if tree.ID != "pair" || len(tree.Components) != 3 || tree.Components[2].Value != "3" || tree.End.Index != 4 {
+		t.Errorf("unexpected json %s", data)
+	}
+
+	data, err = json.Marshal(result)
+	if err != nil || !strings.Contains(string(data), `"value":","`) {
+		t.Errorf("unexpected default json %s %v", data, err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	digits := conc(runeBetween('0', '9'), rep(runeBetween('0', '9'))).SetID("digits")
+	number := conc(digits, opt(conc(runeMatch('.'), digits))).SetID("number")
+	list := conc(number, rep(conc(runeMatch(','), number))).SetID("list")
+
+	rd, _ := runes.New(strings.NewReader("12.5,3"))
+
+	_, result, err := ebnf.MatchPattern(list, rd)
+	if err != nil || result == nil {
+		t.Fatalf("expected match, got %v", err)
+	}
+
+	var ids []string
+
+	result.Prune().Walk(func(m *ebnf.Match[rune, runes.Pos], depth int) bool {
+		ids = append(ids, fmt.Sprintf("%s:%d", m.ID(), depth))
+		return true
+	})
+
+	if strings.Join(ids, " ") != "list:0 number:1 digits:2 digits:2 number:1 digits:2" {
+		t.Errorf("unexpected pruned tree %v", ids)
+	}
+
+	// The original tree is not changed
+	if len(result.Components) != 2 || result.Components[1].ID() != ebnf.NoID {
+		t.Errorf("expected original tree to be unchanged")
+	}
+}
+
+func TestNamedComponents(t *testing.T) {
+	letter := runeBetween('a', 'z')
+	identifier := conc(letter, rep(letter))
+	number := conc(runeBetween('0', '9'), rep(runeBetween('0', '9')))
+
+	assignment := concatenation.New[rune, runes.Pos]().
+		Add("lhs", identifier).
+		Add("op", runeVector([]rune(":="))).
+		Add("rhs", alt(identifier, number))
+
+	rd, _ := runes.New(strings.NewReader("x:=42"))
+
+	matched, result, err := ebnf.MatchPattern[rune, runes.Pos](assignment, rd)
+	if err != nil || !matched {
+		t.Fatalf("expected match, got %v %v", matched, err)
+	}
+
+	rhs := result.Component("rhs")
+	if rhs == nil || rhs.Begin.Index != 3 || result.Component("lhs").End.Index != 1 {
+		t.Errorf("unexpected components")
+	}
+
+	if result.Component("none") != nil || rhs.Component("lhs") != nil {
+		t.Errorf("expected no component")
+	}
+}
+
+func TestTypedEval(t *testing.T) {
+	digit := ebnf.Typed(runeBetween('0', '9'), func(m *ebnf.Match[rune, runes.Pos], _ ebnf.Reader[rune, runes.Pos]) (int, error) {
+		return int(m.Value.([]rune)[0] - '0'), nil
+	})
+
+	sum := ebnf.Typed(conc(digit, runeMatch('+'), digit), func(m *ebnf.Match[rune, runes.Pos], r ebnf.Reader[rune, runes.Pos]) (int, error) {
+		lhs, err := digit.Value(m.Components[0], r)
+		if err != nil {
+			return 0, err
+		}
+
+		rhs, err := digit.Value(m.Components[2], r)
+
+		return lhs + rhs, err
+	})
+
+	rd, _ := runes.New(strings.NewReader("3+4"))
+
+	_, result, err := ebnf.MatchPattern[rune, runes.Pos](sum, rd)
+	if err != nil || result == nil {
+		t.Fatalf("expected match, got %v", err)
+	}
+
+	value, err := sum.Value(result, rd)
+	if err != nil || value != 7 {
+		t.Errorf("expected 7, got %v %v", value, err)
+	}
+
+	_, err = ebnf.EvalAs[string](result, rd)
+	if !errors.Is(err, ebnf.ErrEvalType) {
+		t.Errorf("expected type error, got %v", err)
+	}
+}
+
+func TestTransformTable(t *testing.T) {
+	isA := runeMatch('a').SetID("is_a")
+	isB := runeMatch('b').SetID("is_b")
+	altAB := alt(isA, isB)
+	repAB := repetition.New[rune, runes.Pos](altAB, 3, 4).SetID("ab_repeat")
+
+	table := ebnf.TransformTable[rune, runes.Pos]{
+		"is_b": func(m *ebnf.Match[rune, runes.Pos], _ ebnf.TransformTable[rune, runes.Pos], _ ebnf.Reader[rune, runes.Pos]) (any, error) {
+			return []rune("B"), nil
+		},
+		"ab_repeat": func(m *ebnf.Match[rune, runes.Pos], t ebnf.TransformTable[rune, runes.Pos], r ebnf.Reader[rune, runes.Pos]) (any, error) {
+			results, err := t.TransformAll(m.Components, r)
+			if err != nil {
+				return nil, err
+			}
+
+			str := ""
+
+			for _, result := range results {
+				str += string(result.([]rune))
+			}
+
+			return str, nil
+		},
+	}
+
+	rd, _ := runes.New(strings.NewReader("abba"))
+
+	_, result, err := ebnf.MatchPattern(repAB, rd)
+	if err != nil || result == nil {
+		t.Fatalf("expected match, got %v", err)
+	}
+
+	value, err := result.Transform(table, rd)
+	if err != nil || value != "aBBa" {
+		t.Errorf("expected aBBa, got %v %v", value, err)
+	}
+}
+
+func TestMatchText(t *testing.T) {
+	word := conc(runeBetween('a', 'z'), rep(runeBetween('a', 'z'))).SetID("word")
+	words := conc(word, rep(conc(runeMatch(' '), word)), opt(runeMatch('!')))
+
+	rd, _ := runes.New(strings.NewReader("hello big world"))
+
+	_, result, err := ebnf.MatchPattern(words, rd)
+	if err != nil || result == nil {
+		t.Fatalf("expected match, got %v", err)
+	}
+
+	text, err := ebnf.Text(result, rd)
+	if err != nil || text != "hello big world" {
+		t.Errorf("unexpected text %q %v", text, err)
+	}
+
+	text, _ = ebnf.Text(result.FindAll("word")[1], rd)
+	if text != "big" {
+		t.Errorf("unexpected word %q", text)
+	}
+
+	// The optional exclamation mark did not match
+	objs, err := result.Components[2].Objects(rd)
+	if err != nil || len(objs) != 0 {
+		t.Errorf("expected no objects, got %q %v", string(objs), err)
+	}
+}
+
+func TestSpans(t *testing.T) {
+	word := conc(runeBetween('a', 'z'), rep(runeBetween('a', 'z'))).SetID("word")
+	words := conc(word, rep(conc(runeMatch(' '), word)))
+
+	rd, _ := runes.New(strings.NewReader("hello big world"))
+
+	_, result, err := ebnf.MatchPattern(words, rd)
+	if err != nil || result == nil {
+		t.Fatalf("expected match, got %v", err)
+	}
+
+	cmp := ebnf.ReaderCompare[rune, runes.Pos](rd)
+	found := result.FindAll("word")
+
+	if result.Length(rd) != 15 || found[1].Length(rd) != 3 {
+		t.Errorf("unexpected lengths %d %d", result.Length(rd), found[1].Length(rd))
+	}
+
+	if !found[1].Contains(runes.Pos{Index: 6}, cmp) || found[1].Contains(found[1].End, cmp) {
+		t.Errorf("unexpected contains")
+	}
+
+	if found[0].Overlaps(found[1], cmp) || !result.Overlaps(found[2], cmp) || !result.Covers(found[2], cmp) {
+		t.Errorf("unexpected overlap")
+	}
+
+	if m := result.MatchAt(runes.Pos{Index: 7}, cmp); m == nil || m.Begin.Index != 7 || m.End.Index != 8 {
+		t.Errorf("unexpected match at %v", m)
+	}
+
+	matches := []*ebnf.Match[rune, runes.Pos]{found[2], found[0], result, found[1]}
+	ebnf.SortMatches(matches, cmp)
+
+	if matches[0] != result || matches[1] != found[0] || matches[2] != found[1] || matches[3] != found[2] {
+		t.Errorf("unexpected order")
+	}
+}
+
+func TestLowering(t *testing.T) {
+	letter := runeBetween('a', 'z')
+	ws := rep(runeMatch(' ')).MarkDiscard()
+	identifier := conc(letter, rep(letter)).SetID("identifier").MarkToken()
+	list := conc(identifier, rep(conc(runeMatch(','), ws, identifier).MarkList()).MarkList())
+
+	call := concatenation.New[rune, runes.Pos]().
+		Add("name", identifier).
+		Add("open", runeMatch('(').MarkDiscard()).
+		Add("args", list).
+		Add("close", runeMatch(')'))
+
+	rd, _ := runes.New(strings.NewReader("f(a, bc,d)"))
+
+	_, result, err := ebnf.MatchPattern[rune, runes.Pos](call, rd)
+	if err != nil || result == nil {
+		t.Fatalf("expected match, got %v", err)
+	}
+
+	if len(result.Components) != 3 {
+		t.Fatalf("expected 3 components, got %d", len(result.Components))
+	}
+
+	args := result.Component("args")
+	if args == nil || result.Component("open") != nil || result.Component("close").Begin.Index != 9 {
+		t.Fatalf("unexpected named components")
+	}
+
+	// The identifiers are flattened into the list with the separators, the white space is dropped
+	var texts []string
+
+	for _, component := range args.Components {
+		if len(component.Components) != 0 {
+			t.Errorf("expected token without components")
+		}
+
+		text, _ := ebnf.Text(component, rd)
+		texts = append(texts, text)
+	}
+
+	if strings.Join(texts, "") != "a,bc,d" || len(texts) != 5 {
+		t.Errorf("unexpected list %q", texts)
+	}
+
+	if name, _ := result.Component("name").Value.([]rune); string(name) != "f" {
+		t.Errorf("unexpected name %q", string(name))
+	}
+}
+
+func TestEvalContext(t *testing.T) {
+	type symbols map[string]int
+
+	letter := runeBetween('a', 'z').SetEvalFunc(func(m *ebnf.Match[rune, runes.Pos], r ebnf.Reader[rune, runes.Pos]) (any, error) {
+		table, ok := ebnf.ContextAs[symbols](r)
+		if !ok {
+			return nil, fmt.Errorf("no symbol table")
+		}
+
+		return table[string(m.Value.([]rune))], nil
+	})
+
+	sum := conc(letter, runeMatch('+'), letter).SetEvalFunc(func(m *ebnf.Match[rune, runes.Pos], r ebnf.Reader[rune, runes.Pos]) (any, error) {
+		lhs, err := ebnf.EvalAs[int](m.Components[0], r)
+		if err != nil {
+			return nil, err
+		}
+
+		rhs, err := ebnf.EvalAs[int](m.Components[2], r)
+
+		return lhs + rhs, err
+	})
+
+	rd, _ := runes.New(strings.NewReader("x+y"))
+
+	_, result, err := ebnf.MatchPattern[rune, runes.Pos](sum, rd)
+	if err != nil || result == nil {
+		t.Fatalf("expected match, got %v", err)
+	}
+
+	value, err := result.EvalContext(rd, symbols{"x": 2, "y": 5})
+	if err != nil || value != 7 {
+		t.Errorf("expected 7, got %v %v", value, err)
+	}
+
+	if _, err = result.Eval(rd); err == nil {
+		t.Errorf("expected error without context")
+	}
+
+	// The context is carried through other middleware
+	session, _ := ebnf.NewSession(ebnf.WithContext[rune, runes.Pos](rd, symbols{"x": 3, "y": 6}))
+
+	value, err = result.Eval(session)
+	if err != nil || value != 9 {
+		t.Errorf("expected 9 through a session, got %v %v", value, err)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	errDivZero := errors.New("division by zero")
+
+	digit := runeBetween('0', '9').SetEvalFunc(func(m *ebnf.Match[rune, runes.Pos], _ ebnf.Reader[rune, runes.Pos]) (any, error) {
+		return int(m.Value.([]rune)[0] - '0'), nil
+	})
+
+	division := conc(digit, runeMatch('/'), digit).SetID("division").SetEvalFunc(func(m *ebnf.Match[rune, runes.Pos], r ebnf.Reader[rune, runes.Pos]) (any, error) {
+		lhs, _ := ebnf.EvalAs[int](m.Components[0], r)
+		rhs, _ := ebnf.EvalAs[int](m.Components[2], r)
+
+		if rhs == 0 {
+			return nil, errDivZero
+		}
+
+		return lhs / rhs, nil
+	})
+
+	list := conc(division, rep(conc(runeMatch(','), division))).SetEvalFunc(func(m *ebnf.Match[rune, runes.Pos], r ebnf.Reader[rune, runes.Pos]) (any, error) {
+		var results []any
+
+		for _, d := range m.FindAll("division") {
+			v, err := d.Eval(r)
+			if err != nil {
+				return nil, err
+			}
+
+			results = append(results, v)
+		}
+
+		return results, nil
+	})
+
+	parse := func(input string) (*ebnf.Match[rune, runes.Pos], ebnf.Reader[rune, runes.Pos]) {
+		rd, _ := runes.New(strings.NewReader(input))
+
+		_, result, err := ebnf.MatchPattern[rune, runes.Pos](list, rd)
+		if err != nil || result == nil {
+			t.Fatalf("expected match, got %v", err)
+		}
+
+		return result, rd
+	}
+
+	result, rd := parse("8/2,9/3")
+
+	value, err := ebnf.Evaluate(result, rd)
+	if err != nil || fmt.Sprint(value) != "[4 3]" {
+		t.Errorf("unexpected value %v %v", value, err)
+	}
+
+	result, rd = parse("8/0,9/3,1/0")
+
+	_, err = ebnf.Evaluate(result, rd)
+	if !errors.Is(err, errDivZero) {
+		t.Fatalf("expected division by zero, got %v", err)
+	}
+
+	var evalErrs []*ebnf.EvalError[runes.Pos]
+
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var evalErr *ebnf.EvalError[runes.Pos]
+		if errors.As(e, &evalErr) {
+			evalErrs = append(evalErrs, evalErr)
+		}
+	}
+
+	if len(evalErrs) != 2 || evalErrs[0].ID != "division" || evalErrs[0].Begin.Index != 0 || evalErrs[1].Begin.Index != 8 {
+		t.Errorf("unexpected errors %v", err)
+	}
+}
+
+func TestEvalSession(t *testing.T) {
+	evals := 0
+
+	digit := runeBetween('0', '9').SetEvalFunc(func(m *ebnf.Match[rune, runes.Pos], _ ebnf.Reader[rune, runes.Pos]) (any, error) {
+		evals++
+		return int(m.Value.([]rune)[0] - '0'), nil
+	})
+
+	plus := runeMatch('+')
+
+	sum := conc(digit, plus, digit).SetEvalFunc(func(m *ebnf.Match[rune, runes.Pos], r ebnf.Reader[rune, runes.Pos]) (any, error) {
+		total := 0
+
+		for _, c := range m.Components {
+			if c.Pattern != plus {
+				v, err := ebnf.EvalAs[int](c, r)
+				if err != nil {
+					return nil, err
+				}
+
+				total += v
+			}
+		}
+
+		return total, nil
+	})
+
+	rd, _ := runes.New(strings.NewReader("4+5"))
+
+	_, result, err := ebnf.MatchPattern[rune, runes.Pos](sum, rd)
+	if err != nil || result == nil {
+		t.Fatalf("expected match, got %v", err)
+	}
+
+	// Share the first digit match, it is evaluated once in the session
+	result.Components[2] = result.Components[0]
+
+	session := ebnf.NewEvalSession[rune, runes.Pos](rd)
+
+	value, err := session.Eval(result)
+	if err != nil || value != 8 || evals != 1 || session.Hits() != 1 {
+		t.Errorf("unexpected eval %v %v, evals %d, hits %d", value, err, evals, session.Hits())
+	}
+
+	// The session is found behind other middleware
+	session.Reset()
+	evals = 0
+
+	value, err = result.Eval(ebnf.WithSkip[rune, runes.Pos](session, runeMatch(' ')))
+	if err != nil || value != 8 || evals != 1 || session.Hits() != 1 {
+		t.Errorf("unexpected eval through middleware %v %v, evals %d, hits %d", value, err, evals, session.Hits())
+	}
+
+	// A match that contains itself is a cycle
+	result.Components[2] = result
+
+	session.Reset()
+
+	_, err = result.EvalContext(session, nil)
+	if !errors.Is(err, ebnf.ErrEvalCycle) {
+		t.Errorf("expected cycle error, got %v", err)
+	}
+}
+
+func TestCapture(t *testing.T) {
+	letter := runeBetween('a', 'z')
+	word := conc(letter, rep(letter)).SetID("word")
+	space := runeMatch(' ')
+	ws := rep(space).SetCapture(false)
+
+	words := concatenation.New[rune, runes.Pos]().
+		Add("first", word).
+		Add("ws", ws).
+		Add("rest", rep(conc(word, ws))).
+		Add("end", runeMatch('.'))
+
+	rd, _ := runes.New(strings.NewReader("one  two three ."))
+
+	_, result, err := ebnf.MatchPattern[rune, runes.Pos](words, rd)
+	if err != nil || result == nil {
+		t.Fatalf("expected match, got %v", err)
+	}
+
+	if len(result.Components) != 3 || result.Component("ws") != nil || result.Component("end").Begin.Index != 15 {
+		t.Fatalf("unexpected components %d", len(result.Components))
+	}
+
+	rest := result.Component("rest")
+	if rest == nil || len(rest.Components) != 2 || len(rest.Components[0].Components) != 1 {
+		t.Fatalf("unexpected rest")
+	}
+
+	// The first alternative validates the spaces, the second alternative captures them at the same positions
+	pair := alt(conc(word, ws, runeMatch('!')), conc(word, space, space, word))
+
+	rd, _ = runes.New(strings.NewReader("one  two"))
+	session, _ := ebnf.NewSession[rune, runes.Pos](rd)
+	session.SetMemoize(true)
+
+	_, result, err = ebnf.MatchPattern[rune, runes.Pos](pair, session)
+	if err != nil || result == nil {
+		t.Fatalf("expected match, got %v", err)
+	}
+
+	second, _ := result.Optional()
+	if len(second.Components) != 4 || second.Components[1] == nil || second.Components[2] == nil {
+		t.Errorf("expected captured spaces")
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	digits := conc(runeBetween('0', '9'), rep(runeBetween('0', '9'))).SetID("digits")
+	pair := conc(digits, runeMatch(','), digits).SetID("pair")
+
+	rd, _ := runes.New(strings.NewReader("12,3"))
+
+	_, result, err := ebnf.MatchPattern(pair, rd)
+	if err != nil || result == nil {
+		t.Fatalf("expected match, got %v", err)
+	}
+
+	for _, d := range result.FindAll("digits") {
+		text, _ := ebnf.Text(d, rd)
+		d.SetAnno("type", "int").SetAnno("len", len(text))
+	}
+
+	if n, ok := ebnf.AnnoAs[int](result.Components[0], "len"); !ok || n != 2 {
+		t.Errorf("expected len annotation, got %v", n)
+	}
+
+	if _, ok := result.Anno("type"); ok {
+		t.Errorf("expected no annotation")
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil || !strings.Contains(string(data), `"annotations":{"len":1,"type":"int"}`) {
+		t.Errorf("unexpected json %s %v", data, err)
+	}
+
+	var sb strings.Builder
+
+	err = result.Prune().PrettyPrint(&sb, func(m *ebnf.Match[rune, runes.Pos]) string { return "" })
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	expected := "pair {0 0 0} - {0 4 4}\n  digits {0 0 0} - {0 2 2} len=2 type=int\n  digits {0 3 3} - {0 4 4} len=1 type=int\n"
+	if sb.String() != expected {
+		t.Errorf("unexpected pretty print:\n%s", sb.String())
+	}
+}
+
+func TestStackLogOptions(t *testing.T) {
+	a, b := runeMatch('a'), runeMatch('b')
+
+	mismatch := func(p ebnf.Pattern[rune, runes.Pos], begin int, end int) *ebnf.Mismatch[rune, runes.Pos] {
+		return ebnf.NewMismatch[rune, runes.Pos](p, runes.Pos{Index: begin}, runes.Pos{Index: end}, nil, nil)
+	}
+
+	log := ebnf.NewStackLog[rune, runes.Pos]().SetDedup(true).SetMax(3)
+
+	for _, m := range []*ebnf.Mismatch[rune, runes.Pos]{mismatch(a, 0, 1), mismatch(a, 0, 1), mismatch(b, 0, 1), mismatch(a, 2, 3), mismatch(b, 2, 3)} {
+		log.LogMismatch(m)
+	}
+
+	if len(log.Stack) != 3 || log.Dropped != 2 || log.Stack[2].Begin.Index != 2 {
+		t.Errorf("unexpected stack %d, dropped %d", len(log.Stack), log.Dropped)
+	}
+
+	cmp := func(p1 runes.Pos, p2 runes.Pos) int { return p1.Index - p2.Index }
+	log = ebnf.NewStackLog[rune, runes.Pos]().SetDeepest(2, cmp)
+
+	for _, end := range []int{3, 1, 5, 2, 4} {
+		log.LogMismatch(mismatch(a, 0, end))
+	}
+
+	if len(log.Stack) != 2 || log.Stack[0].End.Index != 5 || log.Stack[1].End.Index != 4 {
+		t.Errorf("expected deepest entries, got %v", log.Stack)
+	}
+}
+
+func TestScanReport(t *testing.T) {
+	pair := conc(runeVector([]rune("key=")), runeBetween('0', '9').SetExpectation("digit"))
+
+	rd, _ := runes.New(strings.NewReader("key=1;key=x;key=2"))
+
+	results, report, err := ebnf.ScanWithReport[rune, runes.Pos](rd, pair)
+	if err != nil || len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d %v", len(results), err)
+	}
+
+	if len(report.Skipped) != 1 || report.SkippedObjects != 7 || report.MatchedObjects != 10 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	span := report.Skipped[0]
+	if span.Begin.Index != 5 || span.End.Index != 12 {
+		t.Errorf("unexpected span %v - %v", span.Begin, span.End)
+	}
+
+	if span.NearMiss == nil || span.NearMiss.Pos.Index != 10 || span.NearMiss.Error() != `expected digit, got "x"` {
+		t.Errorf("unexpected near miss %v", span.NearMiss)
+	}
+
+	if coverage := report.Coverage(); coverage < 0.58 || coverage > 0.59 {
+		t.Errorf("unexpected coverage %v", coverage)
+	}
+
+	// Empty matches do not stall the scan
+	rd, _ = runes.New(strings.NewReader("x12y"))
+
+	results, report, err = ebnf.ScanWithReport[rune, runes.Pos](rd, rep(runeBetween('0', '9')))
+	if err != nil || len(results) != 3 || report.SkippedObjects != 2 || report.MatchedObjects != 2 {
+		t.Errorf("expected 3 matches and 2 skipped objects, got %v %v %v", results, report, err)
+	}
+}
@@ -0,0 +1,221 @@
+package tests
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/generate"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/runeclass"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	runewriter "github.com/almerlucke/exbana/v2/writers/runes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestGenerateConfig(t *testing.T) {
+	letter := runeBetween('a', 'z').SetID("letter")
+	digit := runeBetween('0', '9').SetID("digit")
+	char := alternation.New[rune, runes.Pos](letter, digit)
+	word := repetition.New[rune, runes.Pos](char, 1, 0)
+
+	config := generate.NewConfig[rune, runes.Pos]().
+		SetWeights(char, 0, 1).
+		SetRepeat(word, 5, 5).
+		SetGenerator("letter", func(*rand.Rand) rune { return 'x' }).
+		SetGenerator("digit", func(*rand.Rand) rune { return '4' })
+
+	sw := runewriter.NewStringWriter()
+	w := generate.New[rune, runes.Pos](sw, config)
+
+	if err := ebnf.GeneratePattern[rune, runes.Pos](word, w); err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	_ = w.Finish()
+
+	if sw.String() != "44444" {
+		t.Errorf("expected 44444, got %q", sw.String())
+	}
+
+	config.SetWeights(char, 1, 0)
+
+	sw = runewriter.NewStringWriter()
+	_ = word.Generate(generate.New[rune, runes.Pos](sw, config))
+	_ = sw.Finish()
+
+	if strings.Trim(sw.String(), "x") != "" || len(sw.String()) != 5 {
+		t.Errorf("expected xxxxx, got %q", sw.String())
+	}
+}
+
+func TestGenerateSeed(t *testing.T) {
+	word := repetition.New[rune, runes.Pos](alternation.New[rune, runes.Pos](runeclass.Letter[runes.Pos](), runeclass.Digit[runes.Pos]()), 1, 0)
+	word.SetMaxGen(20)
+
+	sample := func(seed int64) string {
+		sw := runewriter.NewStringWriter()
+		config := generate.NewConfig[rune, runes.Pos]().SetRand(rand.New(rand.NewSource(seed)))
+
+		if err := ebnf.GeneratePattern[rune, runes.Pos](word, generate.New[rune, runes.Pos](sw, config)); err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		_ = sw.Finish()
+
+		return sw.String()
+	}
+
+	var samples []string
+
+	for i := 0; i < 5; i++ {
+		samples = append(samples, sample(int64(i+1)))
+
+		if again := sample(int64(i + 1)); again != samples[i] {
+			t.Errorf("expected the same sample for the same seed, got %q and %q", samples[i], again)
+		}
+	}
+
+	if samples[0] == samples[1] && samples[1] == samples[2] {
+		t.Errorf("expected different samples for different seeds, got %q", samples)
+	}
+
+	// Generators draw from the seeded source of the profile
+	digits := repetition.New[rune, runes.Pos](runeBetween('0', '9').SetID("digit"), 10, 10)
+
+	generated := func(seed int64) string {
+		sw := runewriter.NewStringWriter()
+		config := generate.NewConfig[rune, runes.Pos]().
+			SetRand(rand.New(rand.NewSource(seed))).
+			SetGenerator("digit", func(rnd *rand.Rand) rune { return rune('0' + rnd.Intn(10)) })
+
+		if err := ebnf.GeneratePattern[rune, runes.Pos](digits, generate.New[rune, runes.Pos](sw, config)); err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		_ = sw.Finish()
+
+		return sw.String()
+	}
+
+	if first, second := generated(1), generated(1); first != second || first == generated(2) {
+		t.Errorf("expected generator output to depend on the seed only, got %q and %q", first, second)
+	}
+}
+
+func TestGenerateContext(t *testing.T) {
+	keyword := func(s string) ebnf.Pattern[rune, runes.Pos] {
+		return runeVector([]rune(s))
+	}
+
+	letter := runeBetween('a', 'z').SetGenerateFunc(randomRuneFunc("abcdefghij"))
+	ident := repetition.New[rune, runes.Pos](letter, 1, 3).SetID("ident")
+	decl := conc(keyword("let "), ident, keyword(";")).SetID("decl")
+	use := conc(keyword("use "), ident, keyword(";")).SetID("use")
+	program := conc(decl, repetition.New[rune, runes.Pos](alternation.New[rune, runes.Pos](decl, use), 10, 10))
+
+	sw := runewriter.NewStringWriter()
+	ctx := generate.NewContext[rune, runes.Pos](sw, nil).
+		OnRuleExit("ident", func(ctx *generate.Context[rune, runes.Pos], output []rune) error {
+			if ctx.InRule("decl") {
+				ctx.Intern("ident", output)
+			}
+			return nil
+		}).
+		SetGenerator("ident", func(ctx *generate.Context[rune, runes.Pos]) ([]rune, bool) {
+			if ctx.InRule("use") {
+				return ctx.Pick("ident")
+			}
+			return nil, false
+		})
+
+	if err := ebnf.GeneratePattern[rune, runes.Pos](program, ctx); err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	_ = ctx.Finish()
+
+	declared := map[string]bool{}
+
+	for _, stmt := range strings.Split(strings.TrimSuffix(sw.String(), ";"), ";") {
+		fields := strings.Fields(stmt)
+		if fields[0] == "let" {
+			declared[fields[1]] = true
+		} else if !declared[fields[1]] {
+			t.Errorf("use of undeclared identifier %q in %q", fields[1], sw.String())
+		}
+	}
+}
+
+func TestNearMiss(t *testing.T) {
+	digit := runeBetween('0', '9').SetGenerateFunc(randomRuneFunc("0123456789"))
+	digits := repetition.New[rune, runes.Pos](digit, 1, 3)
+	group := conc(runeVector([]rune("(")), digits, runeVector([]rune(")")))
+
+	nm := &generate.NearMiss[rune, runes.Pos]{
+		Mutate: func(c rune) rune { return 'x' },
+		Reader: func(output []rune) ebnf.Reader[rune, runes.Pos] {
+			rd, _ := runes.New(strings.NewReader(string(output)))
+			return rd
+		},
+	}
+
+	for _, mutation := range []generate.Mutation{generate.MutateObject, generate.DropElement, generate.ExceedRepetition} {
+		sample, err := nm.Generate(group, mutation)
+		if err != nil {
+			t.Fatalf("%v: err %v", mutation, err)
+		}
+
+		if sample.Mutation != mutation || sample.Pattern == nil {
+			t.Errorf("%v: unexpected sample %v", mutation, sample)
+		}
+
+		if mutation == generate.ExceedRepetition && (sample.Pattern != digits || len(sample.Output) != 6) {
+			t.Errorf("expected repetition to be exceeded, got %q", string(sample.Output))
+		}
+	}
+}
+
+func TestShrink(t *testing.T) {
+	digit := runeBetween('0', '9')
+	number := repetition.New[rune, runes.Pos](digit, 1, 0)
+	list := conc(number, repetition.New[rune, runes.Pos](conc(runeMatch(','), number), 0, 0))
+
+	reader := func(sample []rune) ebnf.Reader[rune, runes.Pos] {
+		rd, _ := runes.New(strings.NewReader(string(sample)))
+		return rd
+	}
+
+	// The failure is triggered by a 7 anywhere in the list
+	fails := func(sample []rune) bool {
+		return strings.ContainsRune(string(sample), '7')
+	}
+
+	shrunk, err := generate.Shrink[rune, runes.Pos](list, []rune("123,4567,89,70"), reader, fails)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	// The first number is required so it can only be reduced to a single digit
+	if string(shrunk) != "1,7" {
+		t.Errorf("expected 1,7, got %q", string(shrunk))
+	}
+
+	if _, err = generate.Shrink[rune, runes.Pos](list, []rune("1,,2"), reader, fails); err != generate.ErrSampleMismatch {
+		t.Errorf("expected sample mismatch, got %v", err)
+	}
+}
+
+func TestGenerateString(t *testing.T) {
+	greeting := conc(runeVector([]rune("hello ")), runeMatch('w').SetGenerateFunc(func() rune { return 'w' }))
+
+	s, err := ebnf.GenerateString(greeting)
+	if err != nil || s != "hello w" {
+		t.Errorf("expected hello w, got %q %v", s, err)
+	}
+
+	objects, err := ebnf.GenerateSlice(greeting)
+	if err != nil || len(objects) != 7 {
+		t.Errorf("expected 7 objects, got %d %v", len(objects), err)
+	}
+}
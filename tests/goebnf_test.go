@@ -0,0 +1,182 @@
+package tests
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/importers/goebnf"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+// Go spec integer and floating-point literals, https://go.dev/ref/spec#Integer_literals
+const goLiteralsEBNF = `
+token          = identifier | int_lit | float_lit .
+identifier     = letter { letter | unicode_digit } .
+letter         = unicode_letter | "_" .
+decimal_digit  = "0" … "9" .
+binary_digit   = "0" | "1" .
+octal_digit    = "0" … "7" .
+hex_digit      = "0" … "9" | "A" … "F" | "a" … "f" .
+
+int_lit        = decimal_lit | binary_lit | octal_lit | hex_lit .
+decimal_lit    = "0" | ( "1" … "9" ) [ [ "_" ] decimal_digits ] .
+binary_lit     = "0" ( "b" | "B" ) [ "_" ] binary_digits .
+octal_lit      = "0" [ "o" | "O" ] [ "_" ] octal_digits .
+hex_lit        = "0" ( "x" | "X" ) [ "_" ] hex_digits .
+
+decimal_digits = decimal_digit { [ "_" ] decimal_digit } .
+binary_digits  = binary_digit { [ "_" ] binary_digit } .
+octal_digits   = octal_digit { [ "_" ] octal_digit } .
+hex_digits     = hex_digit { [ "_" ] hex_digit } .
+
+float_lit         = decimal_float_lit | hex_float_lit .
+decimal_float_lit = decimal_digits "." [ decimal_digits ] [ decimal_exponent ] |
+                    decimal_digits decimal_exponent |
+                    "." decimal_digits [ decimal_exponent ] .
+decimal_exponent  = ( "e" | "E" ) [ "+" | "-" ] decimal_digits .
+hex_float_lit     = "0" ( "x" | "X" ) hex_mantissa hex_exponent .
+hex_mantissa      = [ "_" ] hex_digits "." [ hex_digits ] |
+                    [ "_" ] hex_digits |
+                    "." hex_digits .
+hex_exponent      = ( "p" | "P" ) [ "+" | "-" ] decimal_digits .
+`
+
+func TestGoEBNFImport(t *testing.T) {
+	g, err := goebnf.Parse[runes.Pos]("literals.ebnf", strings.NewReader(goLiteralsEBNF), goebnf.Predefined[runes.Pos]{
+		"unicode_letter": runeFuncMatch(unicode.IsLetter),
+		"unicode_digit":  runeFuncMatch(unicode.IsDigit),
+	})
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	token, ok := g.Rule("token")
+	if !ok {
+		t.Fatalf("expected token rule")
+	}
+
+	rd, _ := runes.New(strings.NewReader("_identifier 123 0.23 2e3 tipie 0x7ff 0x1p-2"))
+
+	results, err := ebnf.Scan[rune, runes.Pos](rd, token)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	expected := []string{
+		"identifier:_identifier", "int_lit:123", "float_lit:0.23", "float_lit:2e3", "identifier:tipie",
+		"int_lit:0x7ff", "float_lit:0x1p-2",
+	}
+
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(results))
+	}
+
+	for i, result := range results {
+		s, _ := rd.Range(result.Begin, result.End)
+		kind := result.Components[0].ID()
+		if kind+":"+string(s) != expected[i] {
+			t.Errorf("expected %v, got %v:%v", expected[i], kind, string(s))
+		}
+	}
+}
+
+func TestGoEBNFFormat(t *testing.T) {
+	src := `// Expressions
+
+// start rule
+Expr = Term{("+"|"-")Term}. // sums
+Term = Factor { ( "*" | "/" ) Factor } .
+Factor=Number|"("Expr")"|` + "`-`" + `Factor /* negation */ | identifier .
+
+Number = digit{digit}.
+digit = "0"…"9" .
+identifier = "x" .
+`
+
+	expected := `// Expressions
+
+// start rule
+Expr = Term { ( "+" | "-" ) Term } . // sums
+Term = Factor { ( "*" | "/" ) Factor } .
+
+/* negation */
+Factor = Number |
+         "(" Expr ")" |
+         "-" Factor |
+         identifier .
+
+Number     = digit { digit } .
+digit      = "0" … "9" .
+identifier = "x" .
+`
+
+	out, err := goebnf.Format("expr.ebnf", []byte(src), goebnf.FormatOptions{Width: 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(out) != expected {
+		t.Errorf("unexpected format:\n%s", out)
+	}
+
+	// Sorted orders, formatting is idempotent
+	for _, order := range []goebnf.Order{goebnf.SourceOrder, goebnf.AlphaOrder, goebnf.UseOrder} {
+		out, err = goebnf.Format("expr.ebnf", []byte(src), goebnf.FormatOptions{Order: order})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		again, err := goebnf.Format("expr.ebnf", out, goebnf.FormatOptions{Order: order})
+		if err != nil || string(again) != string(out) {
+			t.Errorf("format of order %d is not idempotent: %v\n%s\n%s", order, err, out, again)
+		}
+
+		var rules []string
+
+		for _, line := range strings.Split(string(out), "\n") {
+			if name, _, ok := strings.Cut(line, " "); ok && !strings.HasPrefix(line, "/") {
+				rules = append(rules, name)
+			}
+		}
+
+		expectedRules := map[goebnf.Order]string{
+			goebnf.SourceOrder: "Expr Term Factor Number digit identifier",
+			goebnf.AlphaOrder:  "Expr Factor Number Term digit identifier",
+			goebnf.UseOrder:    "Expr Term Factor Number digit identifier",
+		}
+
+		if strings.Join(rules, " ") != expectedRules[order] {
+			t.Errorf("unexpected order %d: %v", order, rules)
+		}
+	}
+
+	// The formatted grammar is the same grammar
+	out, _ = goebnf.Format("expr.ebnf", []byte(src), goebnf.FormatOptions{Order: goebnf.AlphaOrder, Width: 20})
+
+	g1, err1 := goebnf.Parse[runes.Pos]("expr.ebnf", strings.NewReader(src), nil)
+	g2, err2 := goebnf.Parse[runes.Pos]("expr.ebnf", strings.NewReader(string(out)), nil)
+
+	if err1 != nil || err2 != nil || len(g1.Rules()) != len(g2.Rules()) {
+		t.Fatalf("formatted grammar does not import: %v %v", err1, err2)
+	}
+
+	for _, rule := range g1.Rules() {
+		other, ok := g2.Rule(rule.ID())
+		if !ok {
+			t.Fatalf("rule %s is missing", rule.ID())
+		}
+
+		s1, _ := ebnf.PrintRules([]ebnf.Pattern[rune, runes.Pos]{rule})
+		s2, _ := ebnf.PrintRules([]ebnf.Pattern[rune, runes.Pos]{other})
+
+		if s1 != s2 {
+			t.Errorf("rule %s changed", rule.ID())
+		}
+	}
+
+	_, err = goebnf.Format("bad.ebnf", []byte("A = \"a\"\n"), goebnf.FormatOptions{})
+	if err == nil {
+		t.Error("expected syntax error")
+	}
+}
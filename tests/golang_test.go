@@ -0,0 +1,209 @@
+package tests
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/grammars/golang"
+	"github.com/almerlucke/exbana/v2/patterns/lexeme"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"go/scanner"
+	"go/token"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const goSource = `// Package demo
+package demo
+
+import "fmt"
+
+/* general
+   comment */
+func format(a, b int, s ...string) (x float64, ok bool) {
+	const big = 0x_7fff_ffff + 0b1010 + 0o17 + 017 + 1_000_000
+	f := 1.5e-3 + .25 + 6. + 0x1p-2 + 0X_1FFFFp-16 + 1E6
+	c := 3i + 1.5i + 0x10i + 089i
+	r := 'a' + '\n' + '\x7f' + 'ዤ' + '\U00101234' + '\000' + 'é' + '\''
+	str := "hi\t\"there\"é" + ` + "`raw\\n\nline`" + `
+	x <<= 2; x &^= 3; ch <- x
+	for i := range s { go goto_(i...) }
+	if a != b && !ok || a >= b { return }
+	var m map[string]struct{}
+	return f / 2, a%b == 0
+}
+`
+
+func TestGoLexical(t *testing.T) {
+	// Reference tokens of the Go scanner
+	var expected []string
+
+	fset := token.NewFileSet()
+
+	var s scanner.Scanner
+
+	s.Init(fset.AddFile("demo.go", -1, len(goSource)), []byte(goSource), nil, scanner.ScanComments)
+
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+
+		if tok == token.SEMICOLON && lit == "\n" {
+			// Inserted semicolon
+			continue
+		}
+
+		if lit == "" {
+			lit = tok.String()
+		}
+
+		expected = append(expected, strings.TrimRight(lit, "\n"))
+	}
+
+	lex := golang.New[runes.Pos]()
+
+	rd, _ := runes.New(strings.NewReader(goSource))
+
+	var found []string
+
+	var kinds []string
+
+	token := lexeme.New[rune, runes.Pos](lex.Token, lex.WhiteSpace)
+
+	for !rd.Finished() {
+		matched, result, err := token.Match(rd)
+		if err != nil || !matched {
+			pos, _ := rd.Position()
+			t.Fatalf("no token at %v: %v", pos, err)
+		}
+
+		text, _ := ebnf.Text(result, rd)
+		found = append(found, text)
+		kinds = append(kinds, golang.Kind(result))
+
+		if _, err := result.Unpack().Eval(rd); err != nil {
+			t.Errorf("eval %s %q: %v", golang.Kind(result), text, err)
+		}
+	}
+
+	if !reflect.DeepEqual(found, expected) {
+		for i := range min(len(found), len(expected)) {
+			if found[i] != expected[i] {
+				t.Fatalf("token %d: expected %q, got %q", i, expected[i], found[i])
+			}
+		}
+
+		t.Fatalf("expected %d tokens, got %d", len(expected), len(found))
+	}
+
+	count := map[string]int{}
+	for _, kind := range kinds {
+		count[kind]++
+	}
+
+	for kind, n := range map[string]int{golang.HexLit: 1, golang.BinaryLit: 1, golang.OctalLit: 2, golang.HexFloatLit: 2,
+		golang.ImaginaryLit: 4, golang.RuneLit: 8, golang.RawStringLit: 1, golang.LineComment: 1, golang.GeneralComment: 1} {
+		if count[kind] != n {
+			t.Errorf("expected %d %s, got %d", n, kind, count[kind])
+		}
+	}
+
+	eval := func(p ebnf.Pattern[rune, runes.Pos], input string) any {
+		rd, _ := runes.New(strings.NewReader(input))
+		_, m, _ := p.Match(rd)
+		v, _ := m.Unpack().Eval(rd)
+
+		return v
+	}
+
+	if v := eval(lex.IntLit, "0x_7fff_ffff_ffff_ffff_ffff"); v.(*big.Int).Text(16) != "7fffffffffffffffffff" {
+		t.Errorf("unexpected int %v", v)
+	}
+
+	if v := eval(lex.StringLit, `"aé\x41"`); v != "aéA" {
+		t.Errorf("unexpected string %v", v)
+	}
+
+	if v := eval(lex.ImaginaryLit, "089i"); v != complex(0, 89) {
+		t.Errorf("unexpected imaginary %v", v)
+	}
+
+	if output, err := ebnf.PrintRules(lex.Rules); err != nil || !strings.Contains(output, "hex_lit = ") {
+		t.Errorf("unexpected rules %v: %s", err, output)
+	}
+
+	if golang.IsKeyword("goto_") || !golang.IsKeyword("fallthrough") {
+		t.Error("unexpected keyword classification")
+	}
+}
+
+func TestGoSemicolons(t *testing.T) {
+	// Reference tokens of the Go scanner, including inserted semicolons
+	var expected []string
+
+	fset := token.NewFileSet()
+
+	var s scanner.Scanner
+
+	s.Init(fset.AddFile("demo.go", -1, len(goSource)), []byte(goSource), nil, 0)
+
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+
+		if lit == "" || tok == token.SEMICOLON {
+			lit = tok.String()
+		}
+
+		expected = append(expected, lit)
+	}
+
+	r, err := runes.New(strings.NewReader(goSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	toks, err := golang.New[runes.Pos]().Tokens(r).Tokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []string
+
+	for _, tok := range toks {
+		actual = append(actual, string(tok.Value))
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("token mismatch\nexpected %q\ngot      %q", expected, actual)
+	}
+
+	// The inserted semicolon after the package clause is at the end of the package name
+	if toks[2].Kind != golang.Operator || toks[2].Begin.Line != 1 || toks[2].Begin.Col != 12 {
+		t.Errorf("unexpected inserted semicolon %+v", toks[2])
+	}
+}
+
+func TestGoKinds(t *testing.T) {
+	rd, _ := runes.New(strings.NewReader("_identifier 123 0.23 2e3 tipie 0x7ff"))
+
+	results, err := ebnf.Scan[rune, runes.Pos](rd, golang.New[runes.Pos]().Token)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	var kinds []string
+
+	for _, result := range results {
+		kinds = append(kinds, golang.Kind(result))
+	}
+
+	expected := []string{golang.Identifier, golang.DecimalLit, golang.DecimalFloatLit, golang.DecimalFloatLit, golang.Identifier, golang.HexLit}
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("expected %v, got %v", expected, kinds)
+	}
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/highlight"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestHighlight(t *testing.T) {
+	ident := conc(runeFuncMatch(unicode.IsLetter), rep(runeFuncMatch(unicode.IsLetter)))
+	ident.SetID("ident")
+
+	number := conc(runeFuncMatch(unicode.IsDigit), rep(runeFuncMatch(unicode.IsDigit)))
+	number.SetID("number")
+
+	space := rep(runeMatch(' '))
+
+	// A call: the call is highlighted, except for the arguments which have their own class
+	call := conc(ident, runeMatch('('), space, alt(number, ident), space, runeMatch(')'))
+	call.SetID("call")
+
+	src := "f( 12 ) <x>"
+
+	r, err := runes.New(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := ebnf.Scan[rune, runes.Pos](r, alt(call, ident))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans, err := highlight.Spans(results, highlight.Classes(map[string]string{
+		"call":   "fn",
+		"number": "num",
+		"ident":  "id",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []highlight.Span{
+		{Class: "id", Begin: 0, End: 1},
+		{Class: "fn", Begin: 1, End: 3},
+		{Class: "num", Begin: 3, End: 5},
+		{Class: "fn", Begin: 5, End: 7},
+		{Class: "id", Begin: 9, End: 10},
+	}
+
+	if !reflect.DeepEqual(spans, expected) {
+		t.Errorf("unexpected spans %+v", spans)
+	}
+
+	var sb strings.Builder
+
+	err = highlight.WriteHTML(&sb, []rune(src), spans, "hl-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	html := `<span class="hl-id">f</span><span class="hl-fn">( </span><span class="hl-num">12</span>` +
+		`<span class="hl-fn"> )</span> &lt;<span class="hl-id">x</span>&gt;`
+
+	if sb.String() != html {
+		t.Errorf("unexpected html %s", sb.String())
+	}
+
+	// Without class func every ID is a class
+	spans, err = highlight.Spans(results[1:], nil)
+	if err != nil || len(spans) != 1 || spans[0].Class != "ident" {
+		t.Errorf("unexpected spans %+v: %v", spans, err)
+	}
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"errors"
+	"github.com/almerlucke/exbana/v2/diagnostics"
+	"github.com/almerlucke/exbana/v2/grammars/ini"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestINI(t *testing.T) {
+	input := `; global settings
+name = demo
+[server]
+host: example.com ; inline comment
+  port=8080
+
+# paths
+[paths]
+url = http://x/#anchor
+list = a, \
+       b, \
+       c
+empty =
+`
+
+	doc, err := ini.Parse("config.ini", strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	expected := ini.Document{
+		"":       {"name": "demo"},
+		"server": {"host": "example.com", "port": "8080"},
+		"paths":  {"url": "http://x/#anchor", "list": "a, b, c", "empty": ""},
+	}
+
+	if !reflect.DeepEqual(doc, expected) {
+		t.Errorf("expected %v, got %v", expected, doc)
+	}
+
+	_, err = ini.Parse("config.ini", strings.NewReader("[server]\nhost = x\ngarbage line\n"))
+
+	var parseErr *diagnostics.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected parse error, got %v", err)
+	}
+
+	if parseErr.Location() != "config.ini:3:13" || parseErr.Code != ini.CodeMalformedLine {
+		t.Errorf("unexpected parse error %v (%v)", parseErr, parseErr.Code)
+	}
+
+	t.Log(parseErr.Report())
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/ipaddr"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	runewriter "github.com/almerlucke/exbana/v2/writers/runes"
+	"math/rand"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestIPAddr(t *testing.T) {
+	full := func(pattern ebnf.Pattern[rune, runes.Pos], input string) (any, bool) {
+		rd, _ := runes.New(strings.NewReader(input))
+
+		matched, result, _ := pattern.Match(rd)
+		if !matched || !rd.Finished() {
+			return nil, false
+		}
+
+		v, err := result.Unpack().Eval(rd)
+
+		return v, err == nil
+	}
+
+	ip := ipaddr.IP[runes.Pos]()
+
+	for _, input := range []string{"0.0.0.0", "255.255.255.255", "10.1.20.199", "::", "::1", "1::", "2001:db8::8a2e:370:7334",
+		"1:2:3:4:5:6:7:8", "1::8", "1:2:3:4:5:6::8", "1:2:3:4:5:6:7::", "::ffff:192.168.1.1", "fe80::1:2:3:4:5",
+		"1:2:3:4:5:6:1.2.3.4", "1::2:3:4:5:6:7"} {
+		v, ok := full(ip, input)
+		if !ok || v != netip.MustParseAddr(input) {
+			t.Errorf("%s: expected address, got %v", input, v)
+		}
+	}
+
+	for _, input := range []string{"256.1.1.1", "01.2.3.4", "1.2.3", "1:2:3:4:5:6:7:8:9", "1::2::3", "12345::", ":1"} {
+		if _, ok := full(ip, input); ok {
+			t.Errorf("%s: expected no full match", input)
+		}
+	}
+
+	// Random addresses in compressed form round trip
+	for i := 0; i < 500; i++ {
+		var b [16]byte
+
+		for j := range b {
+			if rand.Intn(3) > 0 {
+				b[j] = byte(rand.Intn(256))
+			}
+		}
+
+		addr := netip.AddrFrom16(b)
+		if v, ok := full(ip, addr.String()); !ok || v != addr {
+			t.Fatalf("%s: expected address, got %v", addr, v)
+		}
+	}
+
+	cidr := ipaddr.CIDR[runes.Pos]()
+
+	for _, input := range []string{"10.0.0.0/8", "192.168.0.0/16", "0.0.0.0/0", "2001:db8::/32", "::/0", "::1/128"} {
+		if v, ok := full(cidr, input); !ok || v != netip.MustParsePrefix(input) {
+			t.Errorf("%s: expected prefix, got %v", input, v)
+		}
+	}
+
+	if _, ok := full(cidr, "10.0.0.0/33"); ok {
+		t.Error("expected no full match for /33")
+	}
+
+	rd, _ := runes.New(strings.NewReader("deny from 10.0.0.1 and 2001:db8::1, allow 192.168.0.0/24"))
+
+	results, err := ebnf.Scan[rune, runes.Pos](rd, alternation.New[rune, runes.Pos](cidr, ip))
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	var found []string
+
+	for _, result := range results {
+		text, _ := ebnf.Text(result, rd)
+		found = append(found, result.Unpack().ID()+" "+text)
+	}
+
+	if strings.Join(found, ", ") != "ipv4 10.0.0.1, ipv6 2001:db8::1, ipv4_cidr 192.168.0.0/24" {
+		t.Errorf("unexpected scan results %v", found)
+	}
+
+	for _, pattern := range []ebnf.Pattern[rune, runes.Pos]{ipaddr.IPv4[runes.Pos](), ipaddr.IPv6[runes.Pos]()} {
+		for i := 0; i < 100; i++ {
+			sw := runewriter.NewStringWriter()
+			_ = pattern.Generate(sw)
+			_ = sw.Finish()
+
+			if _, ok := full(pattern, sw.String()); !ok {
+				t.Errorf("%s: generated %q does not match", pattern.ID(), sw.String())
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package tests
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/lexeme"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"github.com/almerlucke/exbana/v2/readers/stats"
+	"github.com/almerlucke/exbana/v2/readers/trace"
+	"io"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestLexeme(t *testing.T) {
+	ws := repetition.New[rune, runes.Pos](runeFuncMatch(unicode.IsSpace), 1, 0)
+	comment := conc(runeVector([]rune("//")), rep(runeFuncMatch(func(r rune) bool { return r != '\n' })))
+	trivia := alt(ws, comment)
+
+	ident := repetition.New[rune, runes.Pos](runeFuncMatch(unicode.IsLetter), 1, 0)
+	number := repetition.New[rune, runes.Pos](runeFuncMatch(unicode.IsDigit), 1, 0)
+
+	statement := func(skip ebnf.Pattern[rune, runes.Pos]) ebnf.Pattern[rune, runes.Pos] {
+		lex := func(p ebnf.Pattern[rune, runes.Pos]) ebnf.Pattern[rune, runes.Pos] {
+			return lexeme.New[rune, runes.Pos](p, skip)
+		}
+
+		return conc(lex(runeVector([]rune("let"))), lex(ident), lex(runeMatch('=')), lex(number), lex(runeMatch(';')))
+	}
+
+	input := "  let  x // the answer\n = 42 ;\n"
+
+	check := func(r ebnf.Reader[rune, runes.Pos], rd *runes.Reader, pattern ebnf.Pattern[rune, runes.Pos]) {
+		t.Helper()
+
+		matched, result, err := pattern.Match(r)
+		if err != nil || !matched || !rd.Finished() {
+			t.Fatalf("expected full match, got %v %v", matched, err)
+		}
+
+		var tokens []string
+
+		for _, c := range result.Components {
+			text, _ := ebnf.Text(c, rd)
+			tokens = append(tokens, text)
+		}
+
+		if strings.Join(tokens, " ") != "let x = 42 ;" {
+			t.Errorf("expected tokens without trivia, got %q", tokens)
+		}
+	}
+
+	rd, _ := runes.New(strings.NewReader(input))
+	check(rd, rd, statement(trivia))
+
+	// Default skip pattern of the grammar
+	g := ebnf.NewGrammar[rune, runes.Pos](statement(nil).SetID("statement")).SetSkip(trivia)
+
+	rd, _ = runes.New(strings.NewReader(input))
+
+	session, _ := ebnf.NewSession(g.Reader(rd))
+	rule, _ := g.Rule("statement")
+	check(session, rd, rule)
+
+	// Without default skip pattern the trivia is not skipped
+	rd, _ = runes.New(strings.NewReader(input))
+
+	if matched, _, _ := statement(nil).Match(rd); matched {
+		t.Error("expected no match without skip pattern")
+	}
+}
+
+func TestSkipThroughMiddleware(t *testing.T) {
+	ws := repetition.New[rune, runes.Pos](runeFuncMatch(unicode.IsSpace), 1, 0)
+	letters := repetition.New[rune, runes.Pos](runeFuncMatch(unicode.IsLetter), 1, 0)
+	words := conc(lexeme.New[rune, runes.Pos](letters, nil), lexeme.New[rune, runes.Pos](letters, nil))
+
+	input := "  ab  cd  "
+
+	wrap := map[string]func(r ebnf.Reader[rune, runes.Pos]) ebnf.Reader[rune, runes.Pos]{
+		"skip": func(r ebnf.Reader[rune, runes.Pos]) ebnf.Reader[rune, runes.Pos] {
+			return r
+		},
+		"values": func(r ebnf.Reader[rune, runes.Pos]) ebnf.Reader[rune, runes.Pos] {
+			return ebnf.WithoutValues(r)
+		},
+		"stats": func(r ebnf.Reader[rune, runes.Pos]) ebnf.Reader[rune, runes.Pos] {
+			return stats.New(r)
+		},
+		"trace": func(r ebnf.Reader[rune, runes.Pos]) ebnf.Reader[rune, runes.Pos] {
+			return trace.New(r, trace.NewConsole[rune, runes.Pos](io.Discard))
+		},
+		"session": func(r ebnf.Reader[rune, runes.Pos]) ebnf.Reader[rune, runes.Pos] {
+			s, _ := ebnf.NewSession(r)
+			return s
+		},
+	}
+
+	for name, f := range wrap {
+		rd, _ := runes.New(strings.NewReader(input))
+
+		matched, err := ebnf.Matches(words, f(ebnf.WithSkip[rune, runes.Pos](rd, ws)))
+		if err != nil || !matched {
+			t.Errorf("%s: expected validate only match, got %v %v", name, matched, err)
+		}
+
+		rd, _ = runes.New(strings.NewReader(input))
+
+		matched, _, err = ebnf.MatchPattern(words, f(ebnf.WithSkip[rune, runes.Pos](rd, ws)))
+		if err != nil || !matched {
+			t.Errorf("%s: expected match, got %v %v", name, matched, err)
+		}
+
+		rd, _ = runes.New(strings.NewReader(input))
+
+		count, err := ebnf.Count(f(ebnf.WithSkip[rune, runes.Pos](rd, ws)), words)
+		if err != nil || count != 1 {
+			t.Errorf("%s: expected 1 match, got %d %v", name, count, err)
+		}
+	}
+}
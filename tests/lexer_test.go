@@ -0,0 +1,259 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/lexer"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"github.com/almerlucke/exbana/v2/readers/tokens"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+type testToken = tokens.Token[rune, runes.Pos]
+
+type testTokenPos = tokens.Pos[runes.Pos]
+
+func testLexer(t *testing.T, src string) *tokens.Reader[rune, runes.Pos] {
+	return testLexerOver(t, testRunes(t, src))
+}
+
+func testRunes(t *testing.T, src string) *runes.Reader {
+	r, err := runes.New(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
+
+func testLexerOver(t *testing.T, r *runes.Reader) *tokens.Reader[rune, runes.Pos] {
+
+	ident := conc(runeFuncMatch(unicode.IsLetter), rep(runeFuncMatch(unicode.IsLetter)))
+	ident.SetID("ident")
+
+	number := conc(runeFuncMatch(unicode.IsDigit), rep(runeFuncMatch(unicode.IsDigit)))
+	number.SetID("number")
+
+	white := repetition.New[rune, runes.Pos](runeFuncMatch(unicode.IsSpace), 1, 0)
+
+	// The keyword comes first so it wins the tie with ident
+	return lexer.NewReader[rune, runes.Pos](r, white,
+		runeVector([]rune("if")).SetID("if"),
+		ident,
+		number,
+		runeVector([]rune("=")).SetID("="),
+		runeVector([]rune("==")).SetID("=="),
+	)
+}
+
+func TestLexer(t *testing.T) {
+	l := testLexer(t, "if iffy == 12\n x = 3")
+
+	toks, err := l.Tokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var kinds, values []string
+
+	for _, tok := range toks {
+		kinds = append(kinds, tok.Kind)
+		values = append(values, string(tok.Value))
+	}
+
+	if strings.Join(kinds, " ") != "if ident == number ident = number" {
+		t.Errorf("unexpected kinds %v", kinds)
+	}
+
+	if strings.Join(values, " ") != "if iffy == 12 x = 3" {
+		t.Errorf("unexpected values %v", values)
+	}
+
+	if toks[4].Begin.Line != 1 || toks[4].Begin.Col != 1 || toks[4].End.Index != 16 {
+		t.Errorf("unexpected token positions %+v", toks[4])
+	}
+
+	// Two phase parsing: a grammar over the tokens
+	assign := concatenation.New[testToken, testTokenPos](
+		tokens.Kind[rune, runes.Pos]("ident"),
+		tokens.Kind[rune, runes.Pos]("="),
+		tokens.Kind[rune, runes.Pos]("number"),
+	)
+
+	l = testLexer(t, "x = 3 y")
+
+	matched, m, err := assign.Match(l)
+	if err != nil || !matched {
+		t.Fatalf("expected assignment to match: %v", err)
+	}
+
+	if m.Begin.Index != 0 || m.End.Index != 3 || l.Finished() {
+		t.Errorf("unexpected match range %v - %v", m.Begin.Index, m.End.Index)
+	}
+
+	_, err = ebnf.MatchFull[testToken, testTokenPos](testLexer(t, "x = ?"), assign)
+	if !errors.Is(err, lexer.ErrNoToken) {
+		t.Errorf("expected no token error, got %v", err)
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	src := testRunes(t, "  x =\n  42  ")
+	l := testLexerOver(t, src)
+
+	assign := concatenation.New[testToken, testTokenPos](
+		tokens.Kind[rune, runes.Pos]("ident"),
+		tokens.Kind[rune, runes.Pos]("="),
+		tokens.Kind[rune, runes.Pos]("number"),
+	)
+
+	m, err := ebnf.MatchFull[testToken, testTokenPos](l, assign)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The match resolves to the source from the first to the last token, leading and trailing trivia excluded
+	begin, end := tokens.SourceSpan(m.Begin, m.End)
+	if begin.Index != 2 || end.Index != 10 || end.Line != 1 {
+		t.Errorf("unexpected source span %+v - %+v", begin, end)
+	}
+
+	text, err := tokens.SourceRange[rune, runes.Pos](src, m.Begin, m.End)
+	if err != nil || string(text) != "x =\n  42" {
+		t.Errorf("unexpected source range %q: %v", string(text), err)
+	}
+
+	// Mismatch positions print as source positions
+	_, err = ebnf.MatchFull[testToken, testTokenPos](testLexer(t, "x\n = y"), assign)
+
+	var failure *ebnf.Failure[testToken, testTokenPos]
+	if !errors.As(err, &failure) || failure.Pos.Index != 2 || failure.Pos.Source.Line != 1 {
+		t.Fatalf("expected failure at the y token, got %v", err)
+	}
+
+	if failure.Pos.String() != fmt.Sprint(failure.Pos.Source) {
+		t.Errorf("expected position to print as source position, got %v", failure.Pos)
+	}
+
+	// A reader over a slice of tokens
+	toks, err := testLexer(t, "a = 1").Tokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := tokens.NewFromTokens(toks, toks[2].End)
+
+	matched, m, err := assign.Match(r)
+	if err != nil || !matched || !r.Finished() || m.End.Source.Index != 5 {
+		t.Errorf("expected slice tokens to match")
+	}
+}
+
+func TestLexerTrivia(t *testing.T) {
+	ident := conc(runeFuncMatch(unicode.IsLetter), rep(runeFuncMatch(unicode.IsLetter)))
+	ident.SetID("ident")
+
+	white := repetition.New[rune, runes.Pos](runeFuncMatch(unicode.IsSpace), 1, 0)
+	white.SetID("white")
+
+	comment := conc(runeVector([]rune("//")), rep(runeFuncMatch(func(c rune) bool { return c != '\n' })))
+	comment.SetID("comment")
+
+	// Spaces are discarded, newlines and comments are kept
+	spaces := repetition.New[rune, runes.Pos](runeMatch(' '), 1, 0)
+
+	lex := lexer.New[rune, runes.Pos](testRunes(t, "// doc\nfoo bar // trailing\n"), spaces, ident).
+		SetTrivia(white, comment)
+	r := tokens.New[rune, runes.Pos](lex)
+
+	toks, err := r.Tokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(toks) != 2 || len(toks[0].Trivia) != 2 || len(toks[1].Trivia) != 0 {
+		t.Fatalf("unexpected tokens %+v", toks)
+	}
+
+	doc := toks[0].TriviaOf("comment")
+	if len(doc) != 1 || string(doc[0].Value) != "// doc" || toks[0].Trivia[1].Kind != "white" {
+		t.Errorf("unexpected trivia %+v", toks[0].Trivia)
+	}
+
+	trailing := r.Trailing()
+	if len(trailing) != 2 || string(trailing[0].Value) != "// trailing" || string(trailing[1].Value) != "\n" {
+		t.Errorf("unexpected trailing trivia %+v", trailing)
+	}
+}
+
+func TestLexerKeywords(t *testing.T) {
+	ident := conc(runeFuncMatch(unicode.IsLetter), rep(runeFuncMatch(unicode.IsLetter)))
+	ident.SetID("ident")
+
+	white := repetition.New[rune, runes.Pos](runeFuncMatch(unicode.IsSpace), 1, 0)
+
+	table := lexer.NewKeywordTable("", "if", "else")
+	table["nil"] = "literal"
+
+	lex := lexer.New[rune, runes.Pos](testRunes(t, "if iffy else nil elsewhere"), white, ident).
+		SetKeywords("ident", table)
+
+	toks, err := tokens.New[rune, runes.Pos](lex).Tokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var kinds []string
+
+	for _, tok := range toks {
+		kinds = append(kinds, tok.Kind)
+	}
+
+	if strings.Join(kinds, " ") != "if ident else literal ident" {
+		t.Errorf("unexpected kinds %v", kinds)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	ident := conc(runeFuncMatch(unicode.IsLetter), rep(runeFuncMatch(unicode.IsLetter)))
+	ident.SetID("ident")
+
+	white := repetition.New[rune, runes.Pos](runeFuncMatch(unicode.IsSpace), 1, 0)
+
+	patterns := ebnf.Patterns[rune, runes.Pos]{
+		runeVector([]rune("=")).SetID("="),
+		runeVector([]rune("==")).SetID("=="),
+		ident,
+	}
+
+	results, err := ebnf.Tokenize[rune, runes.Pos](testRunes(t, "a == b ?! c = #"), patterns, white)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tags []string
+
+	for _, m := range results {
+		tags = append(tags, fmt.Sprintf("%s:%d", m.ID(), m.Begin.Index))
+	}
+
+	if strings.Join(tags, " ") != "ident:0 ==:2 ident:5 unknown:7 ident:10 =:12 unknown:14" {
+		t.Errorf("unexpected tokens %v", tags)
+	}
+
+	unknown := results[3]
+	if unknown.End.Index != 9 || string(unknown.Value.([]rune)) != "?!" {
+		t.Errorf("unexpected unknown token %v - %v: %v", unknown.Begin, unknown.End, unknown.Value)
+	}
+
+	// Skipped trivia ends a run of unknown objects
+	results, err = ebnf.Tokenize[rune, runes.Pos](testRunes(t, "@ #"), patterns, white)
+	if err != nil || len(results) != 2 || string(results[0].Value.([]rune)) != "@" || string(results[1].Value.([]rune)) != "#" {
+		t.Errorf("expected two unknown tokens, got %v %v", results, err)
+	}
+}
@@ -0,0 +1,225 @@
+package tests
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/lexical"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	runewriter "github.com/almerlucke/exbana/v2/writers/runes"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLexical(t *testing.T) {
+	token := alternation.New[rune, runes.Pos](
+		lexical.LineComment[runes.Pos]("//"),
+		lexical.BlockComment[runes.Pos]("/*", "*/"),
+		lexical.Identifier[runes.Pos](),
+		lexical.Number[runes.Pos](),
+		lexical.String[runes.Pos]('"'),
+		lexical.String[runes.Pos]('\''),
+	)
+
+	input := `_ident x1 42 1_000 0x7f 0o17 017 0b101 1.5 .25 1e3 2.5E-2 "a\tb\"cé" 'it\'s' /* block * / */ // line
+next`
+
+	rd, _ := runes.New(strings.NewReader(input))
+
+	results, err := ebnf.Scan[rune, runes.Pos](rd, token)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	var values []any
+
+	for _, result := range results {
+		v, err := result.Unpack().Eval(rd)
+		if err != nil {
+			t.Fatalf("eval %v: %v", result.Unpack().ID(), err)
+		}
+
+		values = append(values, v)
+	}
+
+	expected := []any{"_ident", "x1", int64(42), int64(1000), int64(0x7f), int64(017), int64(017), int64(5), 1.5, 0.25,
+		1000.0, 0.025, "a\tb\"cé", "it's", "/* block * / */", "// line", "next"}
+
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+
+	// Generated literals parse back to a full match
+	for _, pattern := range []ebnf.Pattern[rune, runes.Pos]{lexical.Identifier[runes.Pos](), lexical.Int[runes.Pos](),
+		lexical.Float[runes.Pos](), lexical.String[runes.Pos]('"'), lexical.BlockComment[runes.Pos]("/*", "*/")} {
+		for i := 0; i < 50; i++ {
+			sw := runewriter.NewStringWriter()
+			_ = pattern.Generate(sw)
+			_ = sw.Finish()
+
+			rd, _ := runes.New(strings.NewReader(sw.String()))
+
+			matched, result, _ := pattern.Match(rd)
+			if !matched || !rd.Finished() {
+				t.Errorf("%s: generated %q does not match", pattern.ID(), sw.String())
+				break
+			}
+
+			if _, err := result.Eval(rd); err != nil {
+				t.Errorf("%s: generated %q does not eval: %v", pattern.ID(), sw.String(), err)
+				break
+			}
+		}
+	}
+}
+
+func TestQuotedString(t *testing.T) {
+	configs := []struct {
+		config  *lexical.StringConfig
+		valid   map[string]string
+		invalid []string
+	}{
+		{
+			config: lexical.NewStringConfig('\'').SetEscape(0).SetDoubling(true),
+			valid:  map[string]string{`'it''s'`: "it's", `'a\n'`: `a\n`, `''`: ""},
+		},
+		{
+			config:  lexical.NewStringConfig('«').SetQuotes('«', '»').SetMultiline(true),
+			valid:   map[string]string{"«a\n\\»b»": "a\n»b", `«é\101»`: "éA"},
+			invalid: []string{`«a\q»`},
+		},
+		{
+			config: lexical.NewStringConfig('"').SetEscapes(map[rune]rune{'"': '"', '\\': '\\', '/': '/', 'b': '\b', 'f': '\f',
+				'n': '\n', 'r': '\r', 't': '\t'}).SetHex(false).SetOctal(false),
+			valid:   map[string]string{`"é\/\n"`: "é/\n"},
+			invalid: []string{`"\x41"`, "\"a\nb\"", `"\U0001F600"`[:5]},
+		},
+		{
+			config: lexical.NewStringConfig('"').SetEscape('`'),
+			valid:  map[string]string{"\"a`n``\\`\"\"": "a\n`\\\""},
+		},
+	}
+
+	for _, c := range configs {
+		pattern := lexical.QuotedString[runes.Pos](c.config)
+
+		for input, expected := range c.valid {
+			rd, _ := runes.New(strings.NewReader(input))
+
+			matched, result, _ := pattern.Match(rd)
+			if !matched || !rd.Finished() {
+				t.Errorf("%s: expected full match", input)
+				continue
+			}
+
+			if v, err := result.Eval(rd); err != nil || v != expected {
+				t.Errorf("%s: expected %q, got %q (%v)", input, expected, v, err)
+			}
+		}
+
+		for _, input := range c.invalid {
+			rd, _ := runes.New(strings.NewReader(input))
+
+			if matched, _, _ := pattern.Match(rd); matched && rd.Finished() {
+				t.Errorf("%s: expected no match", input)
+			}
+		}
+
+		for i := 0; i < 50; i++ {
+			sw := runewriter.NewStringWriter()
+			_ = pattern.Generate(sw)
+			_ = sw.Finish()
+
+			rd, _ := runes.New(strings.NewReader(sw.String()))
+
+			matched, result, _ := pattern.Match(rd)
+			if !matched || !rd.Finished() {
+				t.Errorf("generated %q does not match", sw.String())
+				break
+			}
+
+			if _, err := result.Eval(rd); err != nil {
+				t.Errorf("generated %q does not eval: %v", sw.String(), err)
+				break
+			}
+		}
+	}
+}
+
+func TestLocaleNumber(t *testing.T) {
+	configs := []struct {
+		config  *lexical.NumberConfig
+		valid   map[string]any
+		partial []string
+	}{
+		{
+			config:  lexical.NewNumberConfig().SetGroupSeparators(',').SetStrictGroups(true),
+			valid:   map[string]any{"1,234,567.5": 1234567.5, "-1234": -1234.0, "+.5e-2": 0.005, "12": 12.0},
+			partial: []string{"1,23", "1,2345"},
+		},
+		{
+			config: lexical.NewNumberConfig().SetGroupSeparators('.', ' ', ' ').SetDecimalMark(',').SetSigns("+", "-−"),
+			valid:  map[string]any{"1.234,56": 1234.56, "−1 000,5": -1000.5, "1 000": 1000.0, "1,5E3": 1500.0},
+		},
+		{
+			config: lexical.NewNumberConfig().SetTrailingSign(true).SetExponent(false).SetRational(true),
+			valid:  map[string]any{"10.1-": big.NewRat(-101, 10), "0.3": big.NewRat(3, 10)},
+		},
+	}
+
+	for _, c := range configs {
+		pattern := lexical.LocaleNumber[runes.Pos](c.config)
+
+		for input, expected := range c.valid {
+			rd, _ := runes.New(strings.NewReader(input))
+
+			matched, result, _ := pattern.Match(rd)
+			if !matched || !rd.Finished() {
+				t.Errorf("%s: expected full match", input)
+				continue
+			}
+
+			v, err := result.Eval(rd)
+			if err != nil {
+				t.Errorf("%s: eval %v", input, err)
+				continue
+			}
+
+			if rat, ok := expected.(*big.Rat); ok {
+				if v.(*big.Rat).Cmp(rat) != 0 {
+					t.Errorf("%s: expected %v, got %v", input, rat, v)
+				}
+			} else if v != expected {
+				t.Errorf("%s: expected %v, got %v", input, expected, v)
+			}
+		}
+
+		for _, input := range c.partial {
+			rd, _ := runes.New(strings.NewReader(input))
+
+			if matched, _, _ := pattern.Match(rd); matched && rd.Finished() {
+				t.Errorf("%s: expected partial match", input)
+			}
+		}
+
+		for i := 0; i < 50; i++ {
+			sw := runewriter.NewStringWriter()
+			_ = pattern.Generate(sw)
+			_ = sw.Finish()
+
+			rd, _ := runes.New(strings.NewReader(sw.String()))
+
+			matched, result, _ := pattern.Match(rd)
+			if !matched || !rd.Finished() {
+				t.Errorf("generated %q does not match", sw.String())
+				break
+			}
+
+			if _, err := result.Eval(rd); err != nil {
+				t.Errorf("generated %q does not eval: %v", sw.String(), err)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package tests
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/lint"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/reference"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	exprRef := reference.New[rune, runes.Pos](nil)
+	termRef := reference.New[rune, runes.Pos](nil)
+
+	// expr = [ "-" ], term, "+", expr | term ; term = expr, "*" | "x"
+	expr := alt(conc(opt(runeMatch('-')), termRef, runeMatch('+'), exprRef), termRef)
+	expr.SetID("expr")
+	exprRef.Set(expr)
+
+	term := alt(conc(exprRef, runeMatch('*')), runeMatch('x'))
+	term.SetID("term")
+	termRef.Set(term)
+
+	loop := rep(opt(runeMatch('a')))
+	loop.SetID("loop")
+
+	undefined := conc(runeMatch('u'), reference.New[rune, runes.Pos](nil))
+	undefined.SetID("undefined")
+
+	keywords := alternation.New[rune, runes.Pos](runeVector([]rune("if")), runeVector([]rune("ifelse")), runeVector([]rune("else"))).SetOrthogonal(true)
+	keywords.SetID("keywords")
+
+	longest := alt(runeVector([]rune("if")), runeVector([]rune("ifelse")), runeFuncMatch(func(r rune) bool { return r == 'e' }), runeVector([]rune("e")))
+	longest.SetID("longest")
+
+	empty := alternation.New[rune, runes.Pos](opt(runeMatch('a')), runeMatch('b')).SetOrthogonal(true)
+	empty.SetID("empty")
+
+	clean := conc(runeMatch('a'), rep(runeMatch('b')))
+	clean.SetID("clean")
+
+	g := ebnf.NewGrammar[rune, runes.Pos](expr, term, loop, undefined, keywords, longest, empty, clean)
+
+	found := map[string][]lint.Kind{}
+
+	for _, issue := range lint.Check(g) {
+		found[issue.Rule] = append(found[issue.Rule], issue.Kind)
+	}
+
+	expected := map[string][]lint.Kind{
+		"expr":      {lint.LeftRecursion},
+		"term":      {lint.LeftRecursion},
+		"loop":      {lint.EmptyLoop},
+		"undefined": {lint.UndefinedRule},
+		"keywords":  {lint.ShadowedBranch},
+		"longest":   {lint.ShadowedBranch},
+		"empty":     {lint.ShadowedBranch},
+	}
+
+	for rule, kinds := range expected {
+		if len(found[rule]) != len(kinds) || found[rule][0] != kinds[0] {
+			t.Errorf("expected %v for %s, got %v", kinds, rule, found[rule])
+		}
+	}
+
+	if len(found["clean"]) != 0 {
+		t.Errorf("unexpected issues for clean: %v", found["clean"])
+	}
+}
@@ -0,0 +1,74 @@
+package tests
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/grammars/markdown"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarkdownInline(t *testing.T) {
+	text := func(s string) markdown.Node { return markdown.Node{Kind: markdown.TextID, Text: s} }
+
+	tests := map[string][]markdown.Node{
+		"plain snake_case text": {text("plain snake_case text")},
+		"a *b **c** d* e": {text("a "), {Kind: markdown.EmphasisID, Children: []markdown.Node{
+			text("b "), {Kind: markdown.StrongID, Children: []markdown.Node{text("c")}}, text(" d"),
+		}}, text(" e")},
+		"__x__ and _y_": {{Kind: markdown.StrongID, Children: []markdown.Node{text("x")}}, text(" and "),
+			{Kind: markdown.EmphasisID, Children: []markdown.Node{text("y")}}},
+		"2 * 3 * 4 \\*not\\*": {text("2 * 3 * 4 *not*")},
+		"`a` `` b`c `` ``` ```": {{Kind: markdown.CodeSpanID, Text: "a"}, text(" "), {Kind: markdown.CodeSpanID, Text: "b`c"},
+			text(" "), {Kind: markdown.CodeSpanID, Text: " "}},
+		`[see *the* [docs]](https://en.wikipedia.org/wiki/Go_(language) "Go")`: {{Kind: markdown.LinkID,
+			Dest: "https://en.wikipedia.org/wiki/Go_(language)", Title: "Go", Children: []markdown.Node{
+				text("see "), {Kind: markdown.EmphasisID, Children: []markdown.Node{text("the")}}, text(" [docs]"),
+			}}},
+		"![logo](<a b.png>) <https://x.org/?q=1>": {{Kind: markdown.ImageID, Dest: "a b.png",
+			Children: []markdown.Node{text("logo")}}, text(" "), {Kind: markdown.AutolinkID, Text: "https://x.org/?q=1",
+			Dest: "https://x.org/?q=1"}},
+		"[unclosed *em and <x>": {text("[unclosed *em and <x>")},
+	}
+
+	for input, expected := range tests {
+		nodes, err := markdown.Parse(input)
+		if err != nil {
+			t.Errorf("%s: %v", input, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(nodes, expected) {
+			t.Errorf("%s: expected %+v, got %+v", input, expected, nodes)
+		}
+	}
+
+	// The match tree can be used for highlighting: collect the top level spans of the named elements
+	input := "**bold** `code`"
+	rd, _ := runes.New(strings.NewReader(input))
+
+	m, err := ebnf.MatchFull[rune, runes.Pos](rd, markdown.Grammar[runes.Pos]())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var spans []string
+
+	m.Walk(func(sub *ebnf.Match[rune, runes.Pos], depth int) bool {
+		if depth == 0 || sub.ID() == ebnf.NoID {
+			return true
+		}
+
+		s, _ := ebnf.Text(sub, rd)
+		spans = append(spans, sub.ID()+":"+s)
+
+		return true
+	})
+
+	expected := []string{"strong:**bold**", "markup:**", "text:bold", "markup:**", "text: ", "code_span:`code`",
+		"markup:`", "markup:`"}
+	if !reflect.DeepEqual(spans, expected) {
+		t.Errorf("expected %v, got %v", expected, spans)
+	}
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"bytes"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/padding"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	bytereader "github.com/almerlucke/exbana/v2/readers/bytes"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"strings"
+	"testing"
+)
+
+func TestPadding(t *testing.T) {
+	eq := func(a, b byte) bool { return a == b }
+	letter := entity.New[byte, int](func(b byte) bool { return b >= 'a' && b <= 'z' }).SetGenerateFunc(func() byte { return 'a' })
+	zero := entity.New[byte, int](func(b byte) bool { return b == 0 }).SetGenerateFunc(func() byte { return 0 })
+
+	// Name records padded with zeros to a 4 byte boundary
+	record := concatenation.New[byte, int](vector.New[byte, int](eq, 'N'), repetition.New[byte, int](letter, 1, 6),
+		padding.Align[byte, int](4, zero))
+	records := repetition.New[byte, int](record, 1, 0)
+
+	valid := [][]byte{
+		[]byte("Nabc"),
+		[]byte("Nab\x00Nabcdef\x00Na\x00\x00"),
+	}
+
+	for _, in := range valid {
+		rd := bytereader.NewFromBytes(in)
+
+		if _, err := ebnf.MatchFull[byte, int](rd, records); err != nil {
+			t.Errorf("%q: %v", in, err)
+		}
+
+		if matched, err := ebnf.Matches[byte, int](records, bytereader.NewFromBytes(in)); !matched || err != nil {
+			t.Errorf("%q: expected validation to succeed, got %v %v", in, matched, err)
+		}
+	}
+
+	invalid := [][]byte{
+		[]byte("Nab\x00\x00"),
+		[]byte("Nab\x01"),
+		[]byte("Nabcde\x00"),
+	}
+
+	for _, in := range invalid {
+		if _, err := ebnf.MatchFull[byte, int](bytereader.NewFromBytes(in), records); err == nil {
+			t.Errorf("%q: expected error", in)
+		}
+	}
+
+	// Generated records are aligned
+	for i := 0; i < 20; i++ {
+		b, err := ebnf.GenerateSlice[byte, int](records)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		if len(b)%4 != 0 || bytes.Count(b, []byte{'N'}) == 0 {
+			t.Fatalf("unexpected generated output %q", b)
+		}
+
+		if _, err := ebnf.MatchFull[byte, int](bytereader.NewFromBytes(b), records); err != nil {
+			t.Errorf("generated %q: %v", b, err)
+		}
+	}
+
+	// Fixed padding with any object, and rune positions report their offset
+	fixed := concatenation.New[rune, runes.Pos](runeVector([]rune("ab")), padding.Fixed[rune, runes.Pos](3, nil), padding.Align[rune, runes.Pos](8, runeMatch('.')))
+
+	rd, _ := runes.New(strings.NewReader("ab\n\n\n..."))
+	if _, err := ebnf.MatchFull[rune, runes.Pos](rd, fixed); err != nil {
+		t.Errorf("err %v", err)
+	}
+
+	var sb strings.Builder
+	_ = fixed.Print(&sb)
+
+	if !strings.Contains(sb.String(), "3 * ") || !strings.Contains(sb.String(), "? align 8 ? * ") {
+		t.Errorf("unexpected print output %s", sb.String())
+	}
+}
@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/precedence"
+	"github.com/almerlucke/exbana/v2/patterns/reference"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func testExpression() *precedence.Precedence[rune, runes.Pos] {
+	expr := reference.New[rune, runes.Pos](nil)
+
+	number := conc(runeFuncMatch(unicode.IsDigit), rep(runeFuncMatch(unicode.IsDigit)))
+	number.SetID("number")
+
+	operand := alt(number, conc(runeMatch('('), expr, runeMatch(')')))
+
+	op := func(s string, prec int, assoc precedence.Associativity) precedence.Operator[rune, runes.Pos] {
+		return precedence.Operator[rune, runes.Pos]{
+			Pattern:       runeVector([]rune(s)).SetID(s),
+			Precedence:    prec,
+			Associativity: assoc,
+		}
+	}
+
+	p := precedence.New[rune, runes.Pos](operand,
+		op("+", 1, precedence.Left),
+		op("-", 1, precedence.Left),
+		op("*", 2, precedence.Left),
+		op("/", 2, precedence.Left),
+		op("^", 3, precedence.Right),
+	)
+	p.SetID("expr")
+
+	expr.Set(p)
+
+	return p
+}
+
+// testGroup prints the nesting of an expression match without parentheses
+func testGroup(m *ebnf.Match[rune, runes.Pos], r ebnf.Reader[rune, runes.Pos]) string {
+	left, op, right, ok := precedence.Split(m)
+	if !ok {
+		s, _ := ebnf.Text(m, r)
+		return s
+	}
+
+	return fmt.Sprintf("(%s %s %s)", testGroup(left, r), op.ID(), testGroup(right, r))
+}
+
+func TestPrecedence(t *testing.T) {
+	expr := testExpression()
+
+	var value func(m *ebnf.Match[rune, runes.Pos], r ebnf.Reader[rune, runes.Pos]) (int, error)
+
+	// value evaluates a number, a binary match or a parenthesized expression
+	value = func(m *ebnf.Match[rune, runes.Pos], r ebnf.Reader[rune, runes.Pos]) (int, error) {
+		m = m.Unpack()
+
+		switch m.ID() {
+		case "number":
+			s, err := ebnf.Text(m, r)
+			if err != nil {
+				return 0, err
+			}
+
+			return strconv.Atoi(s)
+		case "expr":
+			return ebnf.EvalAs[int](m, r)
+		}
+
+		return value(m.Components[1], r)
+	}
+
+	expr.SetEvalFunc(func(m *ebnf.Match[rune, runes.Pos], r ebnf.Reader[rune, runes.Pos]) (any, error) {
+		left, _, right, ok := precedence.Split(m)
+		if !ok {
+			return value(m.Components[0], r)
+		}
+
+		a, err := value(left, r)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := value(right, r)
+		if err != nil {
+			return nil, err
+		}
+
+		switch expr.OperatorOf(m).Pattern.ID() {
+		case "+":
+			return a + b, nil
+		case "-":
+			return a - b, nil
+		case "*":
+			return a * b, nil
+		case "/":
+			return a / b, nil
+		}
+
+		result := 1
+		for i := 0; i < b; i++ {
+			result *= a
+		}
+
+		return result, nil
+	})
+
+	tests := []struct {
+		input string
+		group string
+		value int
+	}{
+		{"7", "7", 7},
+		{"1-2-3", "((1 - 2) - 3)", -4},
+		{"2^3^2", "(2 ^ (3 ^ 2))", 512},
+		{"1+2*3-4/2", "((1 + (2 * 3)) - (4 / 2))", 5},
+		{"(1+2)*3", "", 9},
+		{"2*(3+4)^2", "", 98},
+	}
+
+	for _, test := range tests {
+		r, err := runes.New(strings.NewReader(test.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := ebnf.MatchFull[rune, runes.Pos](r, expr)
+		if err != nil {
+			t.Fatalf("%s: %v", test.input, err)
+		}
+
+		v, err := ebnf.EvalAs[int](m, r)
+		if err != nil || v != test.value {
+			t.Errorf("%s: expected %d, got %d (%v)", test.input, test.value, v, err)
+		}
+
+		if test.group == "" {
+			continue
+		}
+
+		if group := testGroup(m, r); group != test.group {
+			t.Errorf("%s: expected %s, got %s", test.input, test.group, group)
+		}
+	}
+
+	// A trailing operator without operand is not part of the expression
+	r, err := runes.New(strings.NewReader("1+2*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matched, m, err := expr.Match(r)
+	if err != nil || !matched || m.End.Index != 3 {
+		t.Fatalf("expected 1+2 to match: %v", err)
+	}
+
+	var sb strings.Builder
+
+	err = testExpression().Print(&sb)
+	if err != nil || sb.String() != "((number | (, expr, )), ((+ | - | * | / | ^), (number | (, expr, )))*)" {
+		t.Errorf("unexpected print %s", sb.String())
+	}
+}
@@ -0,0 +1,726 @@
+package tests
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	"github.com/almerlucke/exbana/v2/readers/compress"
+	"github.com/almerlucke/exbana/v2/readers/filter"
+	"github.com/almerlucke/exbana/v2/readers/multi"
+	"github.com/almerlucke/exbana/v2/readers/normalize"
+	"github.com/almerlucke/exbana/v2/readers/rope"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"github.com/almerlucke/exbana/v2/readers/stats"
+	"github.com/almerlucke/exbana/v2/readers/trace"
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/text/unicode/norm"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestStream(t *testing.T) {
+	input := strings.Repeat("abc 123\r\ndef 4567\n", 1000)
+	number := conc(runeFuncMatch(unicode.IsDigit), rep(runeFuncMatch(unicode.IsDigit)))
+
+	rd, _ := runes.New(strings.NewReader(input))
+	expected, err := ebnf.Scan[rune, runes.Pos](rd, number)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	st := runes.NewStream(strings.NewReader(input), 16)
+	results, err := ebnf.Scan[rune, runes.Pos](st, number)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(results))
+	}
+
+	for i, result := range results {
+		if result.Begin != expected[i].Begin || result.End != expected[i].End {
+			t.Fatalf("expected %v - %v, got %v - %v", expected[i].Begin, expected[i].End, result.Begin, result.End)
+		}
+	}
+
+	var windowErr *runes.WindowError
+
+	err = st.SetPosition(runes.Pos{})
+	if !errors.As(err, &windowErr) {
+		t.Errorf("expected window error, got %v", err)
+	}
+}
+
+func TestRuneReader(t *testing.T) {
+	input := strings.Repeat("abc 123\ndef 4567\n", 1000)
+	number := conc(runeFuncMatch(unicode.IsDigit), rep(runeFuncMatch(unicode.IsDigit)))
+
+	rd := runes.NewRuneReader(strings.NewReader(input), 8)
+	results, err := ebnf.Scan[rune, runes.Pos](rd, number)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	if len(results) != 2000 {
+		t.Fatalf("expected 2000 results, got %d", len(results))
+	}
+
+	last := results[len(results)-1]
+	v, err := rd.Range(last.Begin, last.End)
+	if err != nil || string(v) != "4567" || last.Begin.Line != 1999 || last.Begin.Col != 4 {
+		t.Errorf("unexpected last result %v - %v: %q (%v)", last.Begin, last.End, string(v), err)
+	}
+
+	// Backtracking further than the ring size keeps the history of live marks
+	as := rep(runeMatch('a'))
+	pattern := alt(conc(as, runeMatch('b')), as)
+
+	rd = runes.NewRuneReader(strings.NewReader(strings.Repeat("a", 40)+"c"), 8)
+	matched, result, err := ebnf.MatchPattern[rune, runes.Pos](pattern, rd)
+	if err != nil || !matched || result.End.Index != 40 {
+		t.Errorf("expected match of 40 runes, got %v %v %v", matched, result, err)
+	}
+}
+
+func TestCheckpointStream(t *testing.T) {
+	input := strings.Repeat("abc 123 def 4567\n", 1000)
+	number := conc(runeFuncMatch(unicode.IsDigit), rep(runeFuncMatch(unicode.IsDigit)))
+
+	st := runes.NewCheckpointStream(strings.NewReader(input))
+	results, err := ebnf.Scan[rune, runes.Pos](st, number)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	if len(results) != 2000 {
+		t.Fatalf("expected 2000 results, got %d", len(results))
+	}
+
+	var windowErr *runes.WindowError
+
+	_, err = st.Range(results[0].Begin, results[0].End)
+	if !errors.As(err, &windowErr) {
+		t.Errorf("expected window error, got %v", err)
+	}
+}
+
+func TestMarks(t *testing.T) {
+	st := runes.NewCheckpointStream(strings.NewReader(strings.Repeat("abcdefgh", 1000)))
+
+	mark := st.Mark()
+	_, _ = st.Skip(5000)
+
+	if err := st.Reset(mark); err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	c, _ := st.Read1()
+	if c != 'a' {
+		t.Errorf("expected to reset to the mark, got %q", c)
+	}
+
+	st.Discard(mark)
+	_, _ = st.Skip(3000)
+	_, _ = st.Skip(3000)
+
+	if err := st.Reset(mark); err == nil {
+		t.Errorf("expected error resetting a discarded mark")
+	}
+
+	var windowErr *runes.WindowError
+
+	if err := st.SetPosition(runes.Pos{}); !errors.As(err, &windowErr) {
+		t.Errorf("expected history to be freed after discard, got %v", err)
+	}
+}
+
+// countingMarks is a stream that counts the marks handed out
+type countingMarks struct {
+	*runes.Stream
+	marks int
+}
+
+func (c *countingMarks) Mark() ebnf.Token {
+	c.marks++
+	return c.Stream.Mark()
+}
+
+func TestMarksThroughMiddleware(t *testing.T) {
+	st := &countingMarks{Stream: runes.NewCheckpointStream(strings.NewReader(strings.Repeat("abcdefgh", 1000)))}
+	sts := stats.New[rune, runes.Pos](st)
+	rd := ebnf.WithSkip[rune, runes.Pos](ebnf.WithoutValues[rune, runes.Pos](sts), nil)
+
+	marker, err := ebnf.NewMarker(rd)
+	if err != nil || st.marks != 1 {
+		t.Fatalf("expected a mark through the middleware, got %d marks %v", st.marks, err)
+	}
+
+	_, _ = rd.Skip(5000)
+
+	if err = marker.Reset(); err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	if c, _ := rd.Read1(); c != 'a' || sts.Backtracks != 1 {
+		t.Errorf("expected to reset to the mark seen by the middleware, got %q and %d backtracks", c, sts.Backtracks)
+	}
+
+	marker.Discard()
+	_, _ = rd.Skip(3000)
+	_, _ = rd.Skip(3000)
+
+	var windowErr *runes.WindowError
+
+	if err = rd.SetPosition(runes.Pos{}); !errors.As(err, &windowErr) {
+		t.Errorf("expected history to be freed after discard, got %v", err)
+	}
+}
+
+func TestReaderAt(t *testing.T) {
+	input := strings.Repeat("αβγ 123 déf 4567\n", 500)
+	number := conc(runeFuncMatch(unicode.IsDigit), rep(runeFuncMatch(unicode.IsDigit)))
+
+	rd := runes.NewReaderAt(strings.NewReader(input), int64(len(input)), 64, 4)
+	results, err := ebnf.Scan[rune, runes.Pos](rd, number)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	if len(results) != 1000 {
+		t.Fatalf("expected 1000 results, got %d", len(results))
+	}
+
+	value, _ := rd.Range(results[0].Begin, results[0].End)
+	if string(value) != "123" {
+		t.Errorf("expected 123, got %q", string(value))
+	}
+
+	last := results[len(results)-1]
+	if last.Begin.Line != 499 || last.Begin.Col != 12 {
+		t.Errorf("unexpected position %v", last.Begin)
+	}
+}
+
+func TestColumns(t *testing.T) {
+	rd, _ := runes.New(strings.NewReader("\tab\t世界x"))
+	rd.SetColumns(runes.Columns{TabWidth: 4, EastAsianWidth: true})
+
+	_, _ = rd.Skip(6)
+
+	pos, _ := rd.Position()
+	if pos.Col != 12 || pos.Index != 6 {
+		t.Errorf("expected col 12 index 6, got %v", pos)
+	}
+}
+
+func TestDecoding(t *testing.T) {
+	utf16le := []byte{0xFF, 0xFE, 'h', 0, 'i', 0, 0x3D, 0xD8, 0x00, 0xDE, '\r', 0, '\n', 0}
+
+	rd, err := runes.New(strings.NewReader(string(utf16le)))
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	if string(rd.Data()) != "hi😀\n" {
+		t.Errorf("unexpected UTF-16 decoding %q", string(rd.Data()))
+	}
+
+	rd, _ = runes.New(strings.NewReader("\xEF\xBB\xBFabc"))
+	if string(rd.Data()) != "abc" {
+		t.Errorf("expected BOM to be stripped, got %q", string(rd.Data()))
+	}
+
+	var encodingErr *runes.EncodingError
+
+	_, err = runes.NewWithDecoding(strings.NewReader("ab\xFFc"), runes.Decoding{Strict: true})
+	if !errors.As(err, &encodingErr) || encodingErr.Offset != 2 {
+		t.Errorf("expected encoding error at offset 2, got %v", err)
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write([]byte(strings.Repeat("abc 123\n", 100)))
+	_ = zw.Close()
+
+	number := conc(runeFuncMatch(unicode.IsDigit), rep(runeFuncMatch(unicode.IsDigit)))
+
+	results, err := ebnf.Scan[rune, runes.Pos](runes.NewStreamWithDecoding(bytes.NewReader(buf.Bytes()), 0, runes.Decoding{Decompress: true}), number)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	if len(results) != 100 {
+		t.Errorf("expected 100 matches, got %d", len(results))
+	}
+
+	rd, err := runes.NewWithDecoding(strings.NewReader("plain"), runes.Decoding{Decompress: true})
+	if err != nil || string(rd.Data()) != "plain" {
+		t.Errorf("expected uncompressed input to pass through, got %q %v", string(rd.Data()), err)
+	}
+
+	// Text starting with BZh is not bzip2 compressed
+	for input, expected := range map[string]compress.Format{"BZh9\x31AY": compress.Bzip2, "BZhello": compress.None} {
+		if format, _, _ := compress.Detect(strings.NewReader(input)); format != expected {
+			t.Errorf("%q: expected %v, got %v", input, expected, format)
+		}
+	}
+
+	enc, _ := zstd.NewWriter(nil)
+	compressed := enc.EncodeAll([]byte("zstd compressed"), nil)
+	_ = enc.Close()
+
+	zr, err := compress.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	data, err := io.ReadAll(zr)
+	if err != nil || string(data) != "zstd compressed" || zr.Close() != nil {
+		t.Errorf("expected zstd input to be decompressed, got %q %v", string(data), err)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	src, _ := runes.New(strings.NewReader("caf\u00e9 cafe\u0301!"))
+
+	rd, err := normalize.New(src, norm.NFC)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	cafe := vector.New[rune, normalize.Pos](runeEq, []rune("caf\u00e9")...)
+
+	results, err := ebnf.Scan[rune, normalize.Pos](rd, cafe)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+
+	original, _ := rd.Range(results[1].Begin, results[1].End)
+	if string(original) != "cafe\u0301" || results[1].End.Source.Index != 10 {
+		t.Errorf("unexpected original range %q %v", string(original), results[1].End)
+	}
+
+	// A match that ends inside a multi-rune segment covers the whole segment in the original input
+	src, _ = runes.New(strings.NewReader("caf\u00e9!"))
+
+	rd, err = normalize.New(src, norm.NFD)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	matched, result, err := ebnf.MatchPattern[rune, normalize.Pos](vector.New[rune, normalize.Pos](runeEq, []rune("cafe")...), rd)
+	if err != nil || !matched {
+		t.Fatalf("expected match, got %v %v", matched, err)
+	}
+
+	original, err = rd.Range(result.Begin, result.End)
+	if err != nil || string(original) != "caf\u00e9" {
+		t.Errorf("unexpected original range %q %v", string(original), err)
+	}
+
+	// An empty range at the end of input
+	_, _ = rd.Skip(2)
+	end, _ := rd.Position()
+
+	original, err = rd.Range(end, end)
+	if err != nil || len(original) != 0 {
+		t.Errorf("expected empty range, got %q %v", string(original), err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	type fpos = filter.Pos[runes.Pos]
+
+	src, _ := runes.New(strings.NewReader("x = 12 # comment\n  y=3"))
+
+	isSpace := entity.New[rune, runes.Pos](unicode.IsSpace)
+	comment := concatenation.New[rune, runes.Pos](runeMatch('#'), rep(runeFuncMatch(func(r rune) bool { return r != '\n' })))
+	rd := filter.NewWithPattern[rune, runes.Pos](src, alternation.New[rune, runes.Pos](isSpace, comment))
+
+	letter := entity.New[rune, fpos](unicode.IsLetter)
+	digit := entity.New[rune, fpos](unicode.IsDigit)
+	assignment := concatenation.New[rune, fpos](letter, entity.New[rune, fpos](func(r rune) bool { return r == '=' }), repetition.OneOrMore[rune, fpos](digit))
+
+	results, err := ebnf.Scan[rune, fpos](rd, assignment)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+
+	value, _ := rd.Range(results[0].Begin, results[0].End)
+	if string(value) != "x=12" {
+		t.Errorf("expected x=12, got %q", string(value))
+	}
+
+	if results[1].Begin.Source.Line != 1 || results[1].Begin.Source.Col != 2 {
+		t.Errorf("expected source position 1:2, got %v", results[1].Begin.Source)
+	}
+}
+
+func TestMulti(t *testing.T) {
+	rd, err := multi.New(multi.String("a.txt", "one\ntwo\n"), multi.String("empty.txt", ""), multi.String("b.txt", "three\nfour\n"))
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	word := repetition.OneOrMore[rune, multi.Pos](entity.New[rune, multi.Pos](unicode.IsLetter))
+
+	results, err := ebnf.Scan[rune, multi.Pos](rd, word)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	expected := []string{"a.txt:1:1", "a.txt:2:1", "b.txt:1:1", "b.txt:2:1"}
+
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d: %v", len(expected), len(results), results)
+	}
+
+	for i, result := range results {
+		if result.Begin.String() != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], result.Begin)
+		}
+	}
+
+	var crossErr *multi.CrossSourceError
+
+	_, err = rd.Range(results[1].Begin, results[2].End)
+	if !errors.As(err, &crossErr) {
+		t.Errorf("expected cross source error, got %v", err)
+	}
+}
+
+func TestLineAt(t *testing.T) {
+	rd, _ := runes.New(strings.NewReader("first\nsecond line\n\nlast\n"))
+
+	if rd.LineCount() != 4 {
+		t.Errorf("expected 4 lines, got %d", rd.LineCount())
+	}
+
+	line, begin, end := rd.LineAt(runes.Pos{Line: 1, Col: 3, Index: 9})
+	if string(line) != "second line" || begin.Index != 6 || begin.Line != 1 || end.Col != 11 {
+		t.Errorf("unexpected line %q %v %v", string(line), begin, end)
+	}
+
+	line, begin, _ = rd.LineAt(runes.Pos{Index: 18})
+	if string(line) != "" || begin.Line != 2 {
+		t.Errorf("unexpected empty line %q %v", string(line), begin)
+	}
+}
+
+func TestStats(t *testing.T) {
+	src, _ := runes.New(strings.NewReader("aaab aab"))
+
+	a := runeMatch('a').SetID("a")
+	ab := conc(rep(a), runeMatch('b')).SetID("ab")
+	aac := conc(rep(a), runeMatch('c')).SetID("aac")
+
+	rd := stats.New[rune, runes.Pos](src)
+
+	results, err := ebnf.Scan[rune, runes.Pos](rd, alt(aac, ab))
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+
+	if rd.MaxBacktrack != 4 || rd.PatternMatches[aac] != 3 {
+		t.Errorf("unexpected stats:\n%v", rd.Report(0))
+	}
+}
+
+func TestProfile(t *testing.T) {
+	src, _ := runes.New(strings.NewReader("aaab aab"))
+
+	a := runeMatch('a')
+	ab := conc(rep(a), runeMatch('b')).SetID("ab")
+	aac := conc(rep(a), runeMatch('c')).SetID("aac")
+
+	rd := stats.New[rune, runes.Pos](src)
+
+	_, err := ebnf.Scan[rune, runes.Pos](rd, alt(aac, ab))
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	profile := rd.Profile()
+	if len(profile) == 0 || profile[0].Label != "ab" || profile[0].Rereads != rd.PatternRereads[ab] {
+		t.Fatalf("unexpected profile:\n%v", rd.ProfileReport(0))
+	}
+
+	if rd.PatternRereads[ab] < 7 || rd.PatternFailures[aac] != 3 {
+		t.Errorf("unexpected profile:\n%v", rd.ProfileReport(0))
+	}
+}
+
+func TestTrace(t *testing.T) {
+	src, _ := runes.New(strings.NewReader("aab"))
+
+	ab := conc(rep(runeMatch('a')), runeMatch('b')).SetID("ab")
+	ac := conc(rep(runeMatch('a')), runeMatch('c')).SetID("ac")
+
+	var buf bytes.Buffer
+
+	rd := trace.New[rune, runes.Pos](src, trace.NewConsole[rune, runes.Pos](&buf).SetOnlyIDs(true))
+
+	matched, _, err := ebnf.MatchPattern[rune, runes.Pos](alt(ac, ab), rd)
+	if err != nil || !matched {
+		t.Fatalf("expected match, got %v %v", matched, err)
+	}
+
+	expected := "  > ac at {0 0 0}\n  - ac at {0 0 0}\n  > ab at {0 0 0}\n  + ab at {0 0 0} to {0 3 3}\n"
+	if buf.String() != expected {
+		t.Errorf("unexpected trace:\n%s", buf.String())
+	}
+}
+
+func TestTreeLog(t *testing.T) {
+	src, _ := runes.New(strings.NewReader("aab?"))
+
+	ab := conc(rep(runeMatch('a')), runeMatch('b')).SetID("ab")
+	ac := conc(rep(runeMatch('a')), runeMatch('c')).SetID("ac")
+	bang := runeMatch('!').SetID("bang")
+	stmt := conc(alt(ac, ab), bang).SetID("stmt")
+
+	log := ebnf.NewTreeLog[rune, runes.Pos]()
+	ac.SetLogger(log)
+
+	rd := trace.New[rune, runes.Pos](src, log)
+
+	matched, _, err := ebnf.MatchPattern[rune, runes.Pos](stmt, rd)
+	if err != nil || matched {
+		t.Fatalf("expected no match, got %v %v", matched, err)
+	}
+
+	if len(log.Roots) != 1 || log.Roots[0].Pattern != stmt {
+		t.Fatalf("expected stmt root")
+	}
+
+	fatal := log.Fatal()
+	if len(fatal) != 2 || fatal[1].Pattern != bang || strings.Join(fatal[1].Path(), "/") != "stmt/bang" {
+		t.Errorf("unexpected fatal attempts %v", fatal)
+	}
+
+	var acNode *ebnf.MismatchNode[rune, runes.Pos]
+
+	log.Walk(func(n *ebnf.MismatchNode[rune, runes.Pos]) bool {
+		if n.Pattern == ac {
+			acNode = n
+		}
+
+		return true
+	})
+
+	if acNode == nil || !acNode.Recovered || acNode.Mismatch == nil || strings.Join(acNode.Path(), "/") != "stmt/ac" {
+		t.Errorf("expected recovered ac attempt with mismatch")
+	}
+
+	var buf bytes.Buffer
+
+	_ = log.Print(&buf)
+
+	if !strings.HasPrefix(buf.String(), "! stmt at {0 0 0}\n  + ") || !strings.Contains(buf.String(), "    - ac at {0 0 0}\n") {
+		t.Errorf("unexpected tree:\n%s", buf.String())
+	}
+}
+
+func TestSlogLog(t *testing.T) {
+	src, _ := runes.New(strings.NewReader("ab?"))
+
+	bang := runeMatch('!').SetID("bang")
+	stmt := conc(runeVector([]rune("ab")), bang).SetID("stmt")
+
+	var buf bytes.Buffer
+
+	handler := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+
+			return a
+		},
+	})
+
+	log := ebnf.NewSlogLog[rune, runes.Pos](slog.New(handler))
+	stmt.SetLogger(log)
+
+	rd := trace.New[rune, runes.Pos](src, log)
+
+	_, _, _ = ebnf.MatchPattern[rune, runes.Pos](stmt, rd)
+
+	if !strings.Contains(buf.String(), `level=INFO msg=mismatch pattern=stmt begin="{Line:0 Col:0 Index:0}" end="{Line:0 Col:3 Index:3}" path=stmt unmatched=bang`) {
+		t.Errorf("expected mismatch record, got:\n%s", buf.String())
+	}
+
+	if !strings.Contains(buf.String(), `level=DEBUG msg=fail pattern=bang depth=1 path=stmt/bang pos="{Line:0 Col:2 Index:2}"`) {
+		t.Errorf("expected fail record, got:\n%s", buf.String())
+	}
+}
+
+func TestFilterLog(t *testing.T) {
+	parse := func(configure func(*ebnf.FilterLog[rune, runes.Pos])) []*ebnf.Mismatch[rune, runes.Pos] {
+		c := runeMatch('c').SetID("c")
+		ab := conc(rep(runeMatch('a')), runeMatch('b')).SetID("ab")
+		ac := conc(rep(runeMatch('a')), c).SetID("ac")
+		bang := runeMatch('!').SetID("bang")
+		stmt := conc(alt(ac, ab), bang).SetID("stmt")
+
+		stack := ebnf.NewStackLog[rune, runes.Pos]()
+		log := ebnf.NewFilterLog[rune, runes.Pos](stack)
+		configure(log)
+
+		for _, p := range []ebnf.Pattern[rune, runes.Pos]{c, ab, ac, bang, stmt} {
+			p.SetLogger(log)
+		}
+
+		src, _ := runes.New(strings.NewReader("aab?"))
+		_, _, _ = ebnf.MatchPattern[rune, runes.Pos](stmt, trace.New[rune, runes.Pos](src, log))
+
+		return stack.Stack
+	}
+
+	ids := func(stack []*ebnf.Mismatch[rune, runes.Pos]) string {
+		var s []string
+		for _, m := range stack {
+			s = append(s, m.Pattern.ID())
+		}
+
+		return strings.Join(s, ",")
+	}
+
+	if all := ids(parse(func(*ebnf.FilterLog[rune, runes.Pos]) {})); all != "c,ac,bang,stmt" {
+		t.Errorf("unexpected mismatches %s", all)
+	}
+
+	if included := ids(parse(func(l *ebnf.FilterLog[rune, runes.Pos]) { l.Include("ac") })); included != "c,ac" {
+		t.Errorf("unexpected included mismatches %s", included)
+	}
+
+	if excluded := ids(parse(func(l *ebnf.FilterLog[rune, runes.Pos]) { l.Exclude("ac").SetMaxDepth(1) })); excluded != "bang,stmt" {
+		t.Errorf("unexpected excluded mismatches %s", excluded)
+	}
+
+	if deep := ids(parse(func(l *ebnf.FilterLog[rune, runes.Pos]) { l.SetMinDepth(2) })); deep != "c,ac" {
+		t.Errorf("unexpected deep mismatches %s", deep)
+	}
+
+	cmp := func(p1 runes.Pos, p2 runes.Pos) int { return p1.Index - p2.Index }
+	if ranged := ids(parse(func(l *ebnf.FilterLog[rune, runes.Pos]) { l.SetRange(runes.Pos{Index: 1}, runes.Pos{Index: 4}, cmp) })); ranged != "c,bang" {
+		t.Errorf("unexpected ranged mismatches %s", ranged)
+	}
+}
+
+func TestRope(t *testing.T) {
+	rd, _ := rope.New(strings.NewReader("abc 123\ndef 456"))
+	number := conc(runeFuncMatch(unicode.IsDigit), rep(runeFuncMatch(unicode.IsDigit)))
+
+	results, _ := ebnf.Scan[rune, runes.Pos](rd, number)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 matches, got %d", len(results))
+	}
+
+	second := results[1].Begin
+	version := rd.Version()
+
+	_ = rd.Insert(4, []rune("99 "))
+	_ = rd.Delete(0, 2)
+
+	if rd.String() != "c 99 123\ndef 456" {
+		t.Errorf("unexpected text %q", rd.String())
+	}
+
+	translated := rd.Translate(second, version)
+	if translated.Index != 13 || translated.Line != 1 || translated.Col != 4 {
+		t.Errorf("unexpected translated position %v", translated)
+	}
+
+	_ = rd.SetPosition(runes.Pos{})
+
+	results, _ = ebnf.Scan[rune, runes.Pos](rd, number)
+	if len(results) != 3 {
+		t.Errorf("expected 3 matches after edit, got %d", len(results))
+	}
+}
+
+func TestScanParallel(t *testing.T) {
+	input := strings.Repeat("abc 123 def 4567\n", 1000)
+	number := conc(runeFuncMatch(unicode.IsDigit), rep(runeFuncMatch(unicode.IsDigit)))
+
+	rd, _ := runes.New(strings.NewReader(input))
+	expected, _ := ebnf.Scan[rune, runes.Pos](rd, number)
+
+	rd, _ = runes.New(strings.NewReader(input))
+
+	var chunks []ebnf.Reader[rune, runes.Pos]
+	for _, chunk := range rd.Chunks(16) {
+		chunks = append(chunks, chunk)
+	}
+
+	results, err := ebnf.ScanParallel(chunks, number, 4)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(results))
+	}
+
+	for i, result := range results {
+		if result.Begin != expected[i].Begin || result.End != expected[i].End {
+			t.Fatalf("result %d: expected %v - %v, got %v - %v", i, expected[i].Begin, expected[i].End, result.Begin, result.End)
+		}
+	}
+}
+
+func TestScanN(t *testing.T) {
+	number := conc(runeFuncMatch(unicode.IsDigit), rep(runeFuncMatch(unicode.IsDigit)))
+
+	rd, _ := runes.New(strings.NewReader("abc 123 def 4567 ghi 89"))
+
+	first, err := ebnf.FindFirst[rune, runes.Pos](rd, number)
+	if err != nil || first == nil || first.Begin.Index != 4 {
+		t.Fatalf("expected first match at 4, got %v %v", first, err)
+	}
+
+	results, _ := ebnf.ScanN[rune, runes.Pos](rd, number, 1)
+	if len(results) != 1 || results[0].Begin.Index != 12 {
+		t.Errorf("expected next match at 12, got %v", results)
+	}
+
+	rd, _ = runes.New(strings.NewReader("abc"))
+	if first, _ = ebnf.FindFirst[rune, runes.Pos](rd, number); first != nil {
+		t.Errorf("expected no match, got %v", first)
+	}
+
+	// Empty matches do not stall the scan
+	rd, _ = runes.New(strings.NewReader("x12y"))
+
+	results, err = ebnf.Scan[rune, runes.Pos](rd, rep(runeFuncMatch(unicode.IsDigit)))
+	if err != nil || len(results) != 3 || results[1].End.Index != 3 {
+		t.Errorf("expected 3 matches, got %v %v", results, err)
+	}
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/runeclass"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	runewriter "github.com/almerlucke/exbana/v2/writers/runes"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestRuneClass(t *testing.T) {
+	greek, ok := runeclass.Script[runes.Pos]("Greek")
+	if !ok {
+		t.Fatal("expected Greek script")
+	}
+
+	if _, ok := runeclass.Script[runes.Pos]("Klingon"); ok {
+		t.Error("expected unknown script")
+	}
+
+	classes := []struct {
+		pattern ebnf.Pattern[rune, runes.Pos]
+		is      func(rune) bool
+		print   string
+		match   string
+		nomatch string
+	}{
+		{runeclass.Letter[runes.Pos](), unicode.IsLetter, `\p{L}`, "é", "1"},
+		{runeclass.Digit[runes.Pos](), unicode.IsDigit, `\p{Nd}`, "٣", "a"},
+		{runeclass.Space[runes.Pos](), unicode.IsSpace, `\s`, " ", "x"},
+		{runeclass.Punct[runes.Pos](), unicode.IsPunct, `\p{P}`, "¿", "+"},
+		{greek, func(c rune) bool { return unicode.Is(unicode.Greek, c) }, `\p{Greek}`, "λ", "l"},
+		{runeclass.ASCIILetter[runes.Pos](), func(c rune) bool { return c < 128 && unicode.IsLetter(c) }, "[A-Za-z]", "Q", "é"},
+		{runeclass.HexDigit[runes.Pos](), func(c rune) bool { return strings.ContainsRune("0123456789abcdefABCDEF", c) }, "[0-9A-Fa-f]", "f", "g"},
+		{runeclass.ASCIISpace[runes.Pos](), func(c rune) bool { return strings.ContainsRune(" \t\n\v\f\r", c) }, `[ \t\n\v\f\r]`, "\v", " "},
+	}
+
+	for _, class := range classes {
+		var buf bytes.Buffer
+
+		_ = class.pattern.Print(&buf)
+
+		if buf.String() != class.print {
+			t.Errorf("expected print %s, got %s", class.print, buf.String())
+		}
+
+		rd, _ := runes.New(strings.NewReader(class.match))
+		if matched, _, _ := class.pattern.Match(rd); !matched {
+			t.Errorf("%s: expected %q to match", class.print, class.match)
+		}
+
+		rd, _ = runes.New(strings.NewReader(class.nomatch))
+		if matched, _, _ := class.pattern.Match(rd); matched {
+			t.Errorf("%s: expected %q not to match", class.print, class.nomatch)
+		}
+
+		for i := 0; i < 100; i++ {
+			w := runewriter.NewStringWriter()
+			_ = class.pattern.Generate(w)
+			_ = w.Finish()
+
+			generated := []rune(w.String())
+			if len(generated) != 1 || !class.is(generated[0]) {
+				t.Errorf("%s: generated %q outside of class", class.print, w.String())
+				break
+			}
+		}
+	}
+}
+
+func TestClass(t *testing.T) {
+	classes := []struct {
+		class   string
+		match   string
+		nomatch string
+	}{
+		{"[A-Za-z_]", "aZ_", "0-é"},
+		{"[0-9A-Fa-f]", "09aF", "gG_"},
+		{`[^\n"\\]`, "a é'", "\n\"\\"},
+		{`[\p{Greek}\-]`, "λΩ-", "l_"},
+		{`[\x41-\x43é]`, "ABCé", "De"},
+		{"[a-]", "a-", "b"},
+	}
+
+	for _, class := range classes {
+		e, err := runeclass.Class[runes.Pos](class.class)
+		if err != nil {
+			t.Fatalf("%s: err %v", class.class, err)
+		}
+
+		var buf bytes.Buffer
+
+		_ = e.Print(&buf)
+
+		if buf.String() != class.class {
+			t.Errorf("expected print %s, got %s", class.class, buf.String())
+		}
+
+		for _, c := range class.match {
+			if !e.MatchFunc()(c) {
+				t.Errorf("%s: expected %q to match", class.class, c)
+			}
+		}
+
+		for _, c := range class.nomatch {
+			if e.MatchFunc()(c) {
+				t.Errorf("%s: expected %q not to match", class.class, c)
+			}
+		}
+
+		for i := 0; i < 100; i++ {
+			w := runewriter.NewStringWriter()
+			_ = e.Generate(w)
+			_ = w.Finish()
+
+			if generated := []rune(w.String()); len(generated) != 1 || !e.MatchFunc()(generated[0]) {
+				t.Errorf("%s: generated %q outside of class", class.class, w.String())
+				break
+			}
+		}
+	}
+
+	for _, class := range []string{"A-Z", "[]", "[z-a]", `[\p{Klingon}]`, `[\q]`, "[a[]"} {
+		if _, err := runeclass.Class[runes.Pos](class); !errors.Is(err, runeclass.ErrClassSyntax) {
+			t.Errorf("%s: expected syntax error, got %v", class, err)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package tests
+
+import (
+	"errors"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/end"
+	"github.com/almerlucke/exbana/v2/patterns/reference"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"github.com/almerlucke/exbana/v2/readers/stats"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func arithmetic() ebnf.Pattern[rune, runes.Pos] {
+	expr := reference.New[rune, runes.Pos](nil)
+	term := reference.New[rune, runes.Pos](nil)
+	factor := alt(runeBetween('0', '9'), conc(runeMatch('('), expr, runeMatch(')')))
+
+	term.Set(alt(conc(factor, runeMatch('*'), term), factor))
+	expr.Set(alt(conc(term, runeMatch('+'), expr), conc(term, runeMatch('-'), expr), term))
+
+	return expr
+}
+
+func TestMemoize(t *testing.T) {
+	input := "((1+2)*(3-4)+5*(6+(7*8)))-9"
+	expr := arithmetic()
+
+	plain, _ := runes.New(strings.NewReader(input))
+	plainStats := stats.New[rune, runes.Pos](plain)
+
+	matched, expected, err := ebnf.MatchPattern[rune, runes.Pos](expr, plainStats)
+	if err != nil || !matched {
+		t.Fatalf("expected match, got %v %v", matched, err)
+	}
+
+	rd, _ := runes.New(strings.NewReader(input))
+	memoStats := stats.New[rune, runes.Pos](rd)
+	session, _ := ebnf.NewSession[rune, runes.Pos](memoStats)
+	session.SetMemoize(true)
+
+	matched, result, err := session.Match(expr)
+	if err != nil || !matched {
+		t.Fatalf("expected memoized match, got %v %v", matched, err)
+	}
+
+	if result.End != expected.End {
+		t.Errorf("expected end %v, got %v", expected.End, result.End)
+	}
+
+	if hits, _ := session.MemoStats(); hits == 0 {
+		t.Errorf("expected memo hits")
+	}
+
+	if memoStats.Reads >= plainStats.Reads {
+		t.Errorf("expected memoization to reduce reads, got %d >= %d", memoStats.Reads, plainStats.Reads)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	expr := conc(arithmetic(), end.New[rune, runes.Pos]())
+
+	for input, expected := range map[string]bool{
+		"((1+2)*(3-4)+5*(6+(7*8)))-9": true,
+		"1+2*(3":                      false,
+		"1+2*3)":                      false,
+	} {
+		rd, _ := runes.New(strings.NewReader(input))
+
+		matched, err := ebnf.Matches[rune, runes.Pos](expr, rd)
+		if err != nil || matched != expected {
+			t.Errorf("%s: expected %v, got %v %v", input, expected, matched, err)
+		}
+
+		rd, _ = runes.New(strings.NewReader(input))
+
+		full, _, _ := ebnf.MatchPattern[rune, runes.Pos](expr, rd)
+		if full != matched {
+			t.Errorf("%s: validate only differs from full match", input)
+		}
+	}
+}
+
+func TestFarthestFailure(t *testing.T) {
+	expr := conc(arithmetic(), end.New[rune, runes.Pos]())
+
+	rd, _ := runes.New(strings.NewReader("1+2*(3"))
+	session, _ := ebnf.NewSession[rune, runes.Pos](rd)
+	session.SetTrackFailures(true)
+
+	matched, _, _ := session.Match(expr)
+	if matched {
+		t.Fatalf("expected no match")
+	}
+
+	failure, ok := session.Farthest()
+	if !ok || failure.Offset != 6 || failure.Pos.Col != 6 {
+		t.Fatalf("expected farthest failure at offset 6, got %v", failure)
+	}
+
+	if len(failure.Patterns) == 0 {
+		t.Errorf("expected failed patterns at farthest position")
+	}
+}
+
+func TestExpectedSet(t *testing.T) {
+	item := runeBetween('a', 'z').SetID("letter")
+	list := conc(runeVector([]rune("(")), item, rep(conc(runeVector([]rune(",")), item)), runeVector([]rune(")")))
+
+	for input, expected := range map[string]string{
+		"(a,b;": `expected ")" or ",", got ";"`,
+		"(a,":   `expected letter, got end of input`,
+	} {
+		rd, _ := runes.New(strings.NewReader(input))
+		session, _ := ebnf.NewSession[rune, runes.Pos](rd)
+		session.SetTrackFailures(true)
+
+		_, _, _ = session.Match(list)
+
+		failure, ok := session.Farthest()
+		if !ok || failure.Error() != expected {
+			t.Errorf("%s: expected %q, got %v", input, expected, failure)
+		}
+	}
+}
+
+func TestSetExpectation(t *testing.T) {
+	digit := runeBetween('0', '9').SetExpectation("decimal digit")
+	keyword := runeVector([]rune("let")).SetExpectation("keyword")
+	stmt := conc(keyword, runeMatch(' '), digit)
+
+	for input, expected := range map[string]string{
+		"let x": `expected decimal digit, got "x"`,
+		"var 1": `expected keyword, got "v"`,
+	} {
+		rd, _ := runes.New(strings.NewReader(input))
+		session, _ := ebnf.NewSession[rune, runes.Pos](rd)
+		session.SetTrackFailures(true)
+
+		_, _, _ = session.Match(stmt)
+
+		failure, ok := session.Farthest()
+		if !ok || failure.Error() != expected {
+			t.Errorf("%s: expected %q, got %v", input, expected, failure)
+		}
+	}
+}
+
+func TestCount(t *testing.T) {
+	number := conc(runeBetween('0', '9'), rep(runeBetween('0', '9')))
+
+	rd, _ := runes.New(strings.NewReader(strings.Repeat("ERROR 12 ok 345\n", 100)))
+
+	count, err := ebnf.Count[rune, runes.Pos](rd, number)
+	if err != nil || count != 200 {
+		t.Errorf("expected 200 matches, got %d %v", count, err)
+	}
+
+	// Empty matches do not stall the count
+	rd, _ = runes.New(strings.NewReader("abab"))
+
+	count, err = ebnf.Count[rune, runes.Pos](rd, opt(runeVector([]rune("x"))))
+	if err != nil || count != 4 {
+		t.Errorf("expected 4 empty matches, got %d %v", count, err)
+	}
+}
+
+func TestMatchFull(t *testing.T) {
+	item := runeBetween('a', 'z').SetID("letter")
+	list := conc(item, rep(conc(runeVector([]rune(",")), item)))
+
+	rd, _ := runes.New(strings.NewReader("a,b,c"))
+	if match, err := ebnf.MatchFull[rune, runes.Pos](rd, list); err != nil || match.End.Index != 5 {
+		t.Errorf("expected full match, got %v %v", match, err)
+	}
+
+	var failure *ebnf.Failure[rune, runes.Pos]
+
+	rd, _ = runes.New(strings.NewReader("a,b;c"))
+	if _, err := ebnf.MatchFull[rune, runes.Pos](rd, list); !errors.As(err, &failure) || failure.Offset != 3 || err.Error() != `expected ",", got ";"` {
+		t.Errorf("expected failure at offset 3, got %v", err)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	rd, _ := runes.New(strings.NewReader(strings.Repeat("(", 20) + "1" + strings.Repeat(")", 20)))
+	session, _ := ebnf.NewSession[rune, runes.Pos](rd)
+	session.SetBudget(ebnf.Budget{MaxSteps: 1000})
+
+	var budgetErr *ebnf.BudgetError[runes.Pos]
+
+	_, _, err := session.Match(arithmetic())
+	if !errors.Is(err, ebnf.ErrBudgetExceeded) || !errors.As(err, &budgetErr) || budgetErr.Steps != 1001 {
+		t.Errorf("expected budget exceeded error, got %v", err)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	input := "((1+2)*(3-4)+5*(6+(7*8)))-9"
+	expr := arithmetic()
+	compiled := ebnf.Compile(expr)
+
+	// Changes to the original tree do not affect the compiled pattern
+	expr.(*reference.Reference[rune, runes.Pos]).Set(runeMatch('x'))
+
+	var wg sync.WaitGroup
+
+	results := make([]bool, 8)
+	logs := make([]*ebnf.StackLog[rune, runes.Pos], 8)
+
+	for i := range results {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			rd, _ := runes.New(strings.NewReader(input))
+			logs[i] = ebnf.NewStackLog[rune, runes.Pos]()
+
+			matched, result, err := compiled.Match(rd, ebnf.Options[rune, runes.Pos]{Logger: logs[i], Memoize: i%2 == 0})
+			results[i] = err == nil && matched && result.End.Index == len(input)
+		}(i)
+	}
+
+	wg.Wait()
+
+	for i, ok := range results {
+		if !ok || len(logs[i].Stack) == 0 {
+			t.Errorf("expected call %d to match and log mismatches", i)
+		}
+	}
+
+	rd, _ := runes.New(strings.NewReader(input))
+
+	_, _, err := compiled.Match(rd, ebnf.Options[rune, runes.Pos]{Budget: ebnf.Budget{MaxSteps: 5}})
+	if !errors.Is(err, ebnf.ErrBudgetExceeded) {
+		t.Errorf("expected budget exceeded, got %v", err)
+	}
+}
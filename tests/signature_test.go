@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/signature"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestSignature(t *testing.T) {
+	inputs := map[string][]byte{
+		signature.PNG:  {0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n', 0, 0, 0, 13},
+		signature.GIF:  []byte("GIF89a..."),
+		signature.WAV:  []byte("RIFF\x24\x08\x00\x00WAVEfmt "),
+		signature.WebP: []byte("RIFF\x24\x08\x00\x00WEBPVP8 "),
+		signature.PDF:  []byte("%PDF-1.7\n"),
+		signature.ELF:  {0x7F, 'E', 'L', 'F', 2, 1, 1},
+		signature.Gzip: {0x1F, 0x8B, 8},
+		signature.BMP:  []byte("BM6\x00"),
+		"":             []byte("plain text"),
+	}
+
+	for format, input := range inputs {
+		detected, r, err := signature.DetectFormat(bytes.NewReader(input))
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		if detected != format {
+			t.Errorf("expected %q, got %q", format, detected)
+		}
+
+		if all, _ := io.ReadAll(r); !bytes.Equal(all, input) {
+			t.Errorf("%s: reader does not serve the complete input", format)
+		}
+	}
+
+	// Short input
+	if detected, _, err := signature.DetectFormat(bytes.NewReader([]byte{0x89, 'P'})); detected != "" || err != nil {
+		t.Errorf("expected no format, got %q %v", detected, err)
+	}
+
+	// Malformed magic bytes
+	for _, magic := range []string{"", "4G", "ABC", "00 ?"} {
+		if _, err := signature.Magic[int](magic); !errors.Is(err, signature.ErrMagicSyntax) {
+			t.Errorf("%q: expected magic syntax error, got %v", magic, err)
+		}
+	}
+
+	// Generated signatures are detected as their format
+	for _, p := range signature.Patterns[int]() {
+		b, err := ebnf.GenerateSlice[byte, int](p)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		if detected, _, _ := signature.DetectFormat(bytes.NewReader(b)); detected != p.ID() {
+			t.Errorf("generated %x: expected %q, got %q", b, p.ID(), detected)
+		}
+	}
+
+	// Scan for embedded files
+	var data []byte
+
+	data = append(data, "header "...)
+	data = append(data, inputs[signature.PNG]...)
+	data = append(data, " and "...)
+	data = append(data, inputs[signature.PDF]...)
+
+	locations, err := signature.Scan(data, signature.PNG, signature.PDF)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	expected := []signature.Location{{Format: signature.PNG, Offset: 7}, {Format: signature.PDF, Offset: 24}}
+	if !reflect.DeepEqual(locations, expected) {
+		t.Errorf("expected %v, got %v", expected, locations)
+	}
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"encoding/binary"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/reference"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/tlv"
+	bytereader "github.com/almerlucke/exbana/v2/readers/bytes"
+	"reflect"
+	"testing"
+)
+
+func TestTLV(t *testing.T) {
+	tag := entity.New[byte, int](func(byte) bool { return true })
+	tag.SetEvalFunc(func(m *ebnf.Match[byte, int], _ ebnf.Reader[byte, int]) (any, error) {
+		return m.Value.([]byte)[0], nil
+	})
+
+	record := reference.New[byte, int](nil)
+
+	sequence := repetition.New[byte, int](record, 0, 0)
+	sequence.SetEvalFunc(func(m *ebnf.Match[byte, int], r ebnf.Reader[byte, int]) (any, error) {
+		var values []any
+
+		for _, c := range m.Components {
+			v, err := c.Eval(r)
+			if err != nil {
+				return nil, err
+			}
+
+			values = append(values, v)
+		}
+
+		return values, nil
+	})
+
+	integer := tlv.Uint[int](1, binary.BigEndian)
+
+	ber := tlv.New[byte, int](tag, tlv.BERLength[int](), nil, tlv.Table[byte, int]{byte(0x30): sequence}).
+		Register(byte(0x02), integer)
+	record.Set(ber)
+
+	// SEQUENCE { INTEGER 5, OCTET STRING "abc", NULL }, octet string and null are not in the table
+	input := []byte{0x30, 0x0A, 0x02, 0x01, 0x05, 0x04, 0x03, 'a', 'b', 'c', 0x05, 0x00}
+
+	m, err := ebnf.MatchFull[byte, int](bytereader.NewFromBytes(input), ber)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	v, err := m.Eval(bytereader.NewFromBytes(input))
+	if err != nil {
+		t.Fatalf("eval err %v", err)
+	}
+
+	expected := tlv.Record{Tag: byte(0x30), Length: 10, Value: []any{
+		tlv.Record{Tag: byte(0x02), Length: 1, Value: uint64(5)},
+		tlv.Record{Tag: byte(0x04), Length: 3, Value: []byte("abc")},
+		tlv.Record{Tag: byte(0x05), Length: 0, Value: []byte(nil)},
+	}}
+
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("expected %v, got %v", expected, v)
+	}
+
+	if value := m.Component("value"); value == nil || value.Begin != 2 || value.End != 12 {
+		t.Errorf("unexpected value component %v", value)
+	}
+
+	invalid := [][]byte{
+		{0x02, 0x02, 0x05, 0x06},                   // integer pattern matches one byte only
+		{0x04, 0x05, 'a', 'b'},                     // truncated value
+		{0x30, 0x04, 0x02, 0x01, 0x05, 0x02, 0x01}, // nested record crosses the sequence length
+	}
+
+	for _, in := range invalid {
+		if matched, err := ebnf.Matches[byte, int](ber, bytereader.NewFromBytes(in)); matched || err != nil {
+			t.Errorf("%x: expected mismatch, got %v %v", in, matched, err)
+		}
+	}
+
+	// Long form lengths, validation only
+	long := append([]byte{0x04, 0x81, 0x80}, make([]byte, 0x80)...)
+
+	rd := bytereader.NewFromBytes(long)
+	if matched, err := ebnf.Matches[byte, int](ber, rd); !matched || err != nil || !rd.Finished() {
+		t.Errorf("expected long form to match, got %v %v", matched, err)
+	}
+
+	if !reflect.DeepEqual(tlv.EncodeBERLength(0x80), []byte{0x81, 0x80}) ||
+		!reflect.DeepEqual(tlv.EncodeBERLength(0x1234), []byte{0x82, 0x12, 0x34}) {
+		t.Errorf("unexpected BER length encoding")
+	}
+}
+
+func TestTLVGenerate(t *testing.T) {
+	text := repetition.New[byte, int](entity.New[byte, int](func(b byte) bool { return b >= 'a' && b <= 'z' }).
+		SetGenerateFunc(func() byte { return 'x' }), 1, 8)
+
+	record := tlv.New[byte, int](tlv.Uint[int](2, binary.LittleEndian), tlv.Uint[int](2, binary.LittleEndian), nil, nil).
+		Register(uint64(1), text).
+		Register(uint64(2), tlv.Uint[int](4, binary.LittleEndian)).
+		SetEncoder(func(key any, length int) []byte {
+			return binary.LittleEndian.AppendUint16(binary.LittleEndian.AppendUint16(nil, uint16(key.(uint64))), uint16(length))
+		})
+
+	for i := 0; i < 20; i++ {
+		b, err := ebnf.GenerateSlice[byte, int](record)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		rd := bytereader.NewFromBytes(b)
+
+		m, err := ebnf.MatchFull[byte, int](rd, record)
+		if err != nil {
+			t.Fatalf("%x: err %v", b, err)
+		}
+
+		r, err := ebnf.EvalAs[tlv.Record](m, rd)
+		if err != nil || r.Length != len(b)-4 {
+			t.Errorf("%x: unexpected record %v %v", b, r, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"errors"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/uri"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	runewriter "github.com/almerlucke/exbana/v2/writers/runes"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestURI(t *testing.T) {
+	match := func(pattern ebnf.Pattern[rune, runes.Pos], input string) (any, string, error) {
+		rd, _ := runes.New(strings.NewReader(input))
+
+		matched, result, err := pattern.Match(rd)
+		if err != nil || !matched {
+			return nil, "", err
+		}
+
+		text, _ := ebnf.Text(result, rd)
+		v, err := result.Eval(rd)
+
+		return v, text, err
+	}
+
+	u := uri.URI[runes.Pos]()
+
+	for input, expected := range map[string]string{
+		"https://user:pw@example.com:8080/a/b%20c?q=1&r=/x#frag": "https://user:pw@example.com:8080/a/b%20c?q=1&r=/x#frag",
+		"mailto:bob@example.com":                                 "mailto:bob@example.com",
+		"http://[::1]:80/ rest":                                  "http://[::1]:80/",
+		"urn:isbn:0451450523":                                    "urn:isbn:0451450523",
+		"file:///etc/hosts":                                      "file:///etc/hosts",
+	} {
+		v, text, err := match(u, input)
+		if err != nil || text != expected {
+			t.Errorf("%s: expected %q, got %q (%v)", input, expected, text, err)
+			continue
+		}
+
+		if parsed, ok := v.(*url.URL); !ok || parsed.String() != expected {
+			t.Errorf("%s: expected url %q, got %v", input, expected, v)
+		}
+	}
+
+	if v, _, _ := match(u, "https://example.com:8080/x"); v.(*url.URL).Port() != "8080" {
+		t.Errorf("expected port 8080, got %v", v)
+	}
+
+	if _, text, _ := match(u, "1http://x"); text != "" {
+		t.Errorf("expected no match for invalid scheme, got %q", text)
+	}
+
+	email := uri.Email[runes.Pos]()
+
+	for input, expected := range map[string]uri.Address{
+		"bob.smith+tag@mail.example.com.": {Local: "bob.smith+tag", Domain: "mail.example.com"},
+		`"john doe"@[192.168.0.1]`:        {Local: `"john doe"`, Domain: "[192.168.0.1]"},
+	} {
+		if v, _, err := match(email, input); err != nil || v != expected {
+			t.Errorf("%s: expected %v, got %v (%v)", input, expected, v, err)
+		}
+	}
+
+	if _, text, _ := match(email, "no-at-sign"); text != "" {
+		t.Errorf("expected no match, got %q", text)
+	}
+
+	host := uri.Hostname[runes.Pos]()
+
+	if v, text, err := match(host, "my-host.example.com-"); err != nil || text != "my-host.example.com" ||
+		!reflect.DeepEqual(v, []string{"my-host", "example", "com"}) {
+		t.Errorf("expected hostname labels, got %v %q (%v)", v, text, err)
+	}
+
+	if _, _, err := match(host, strings.Repeat("a", 64)+".com"); !errors.Is(err, uri.ErrHostnameLength) {
+		t.Errorf("expected hostname length error, got %v", err)
+	}
+
+	for _, pattern := range []ebnf.Pattern[rune, runes.Pos]{u, email, host} {
+		for i := 0; i < 50; i++ {
+			sw := runewriter.NewStringWriter()
+			_ = pattern.Generate(sw)
+			_ = sw.Finish()
+
+			rd, _ := runes.New(strings.NewReader(sw.String()))
+
+			if matched, _, _ := pattern.Match(rd); !matched || !rd.Finished() {
+				t.Errorf("%s: generated %q does not match", pattern.ID(), sw.String())
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/end"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/exception"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	"github.com/almerlucke/exbana/v2/vm"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestVM(t *testing.T) {
+	keyword := alternation.New[rune, runes.Pos](runeVector([]rune("if")), runeVector([]rune("else"))).SetOrthogonal(true)
+	letter := runeBetween('a', 'z')
+	identifier := exception.New[rune, runes.Pos](conc(letter, rep(letter)), keyword)
+	digits := repetition.New[rune, runes.Pos](runeBetween('0', '9'), 2, 3)
+
+	patterns := []ebnf.Pattern[rune, runes.Pos]{
+		arithmetic(),
+		conc(arithmetic(), end.New[rune, runes.Pos]()),
+		rep(alt(identifier, keyword, digits, runeMatch(' '))),
+		conc(runeMatch('a'), entity.New[rune, runes.Pos](func(r rune) bool { return r != '"' })),
+		conc(runeMatch('a'), runeVector([]rune("bc"))),
+	}
+
+	alphabets := []string{"0123()+-*", "0123()+-*", "ifelsx 0129", "a\"b", "abc"}
+
+	// Inputs that end before an entity or vector is complete
+	edges := [][]string{nil, nil, nil, {"a", "a\""}, {"a", "ab"}}
+
+	rnd := rand.New(rand.NewSource(1))
+
+	for i, pattern := range patterns {
+		program := vm.Compile(pattern)
+
+		inputs := edges[i]
+
+		for n := 0; n < 500; n++ {
+			var sb strings.Builder
+
+			for l := rnd.Intn(12); l > 0; l-- {
+				sb.WriteByte(alphabets[i][rnd.Intn(len(alphabets[i]))])
+			}
+
+			inputs = append(inputs, sb.String())
+		}
+
+		for _, input := range inputs {
+			rd1, _ := runes.New(strings.NewReader(input))
+			matched1, result1, err1 := ebnf.MatchPattern(pattern, rd1)
+
+			rd2, _ := runes.New(strings.NewReader(input))
+			matched2, result2, err2 := ebnf.MatchPattern[rune, runes.Pos](program, rd2)
+
+			if err1 != nil || err2 != nil || matched1 != matched2 || (matched1 && result1.End != result2.End) {
+				t.Fatalf("pattern %d input %q: tree %v %v %v, vm %v %v %v\n%v", i, input, matched1, result1, err1, matched2, result2, err2, program)
+			}
+		}
+	}
+}
+
+func BenchmarkTree(b *testing.B) {
+	benchmarkMatch(b, arithmetic())
+}
+
+func BenchmarkVM(b *testing.B) {
+	benchmarkMatch(b, vm.Compile(arithmetic()))
+}
+
+func benchmarkMatch(b *testing.B, pattern ebnf.Pattern[rune, runes.Pos]) {
+	input := strings.Repeat("((1+2)*(3-4)+5*(6+(7*8)))-", 20) + "9"
+
+	for i := 0; i < b.N; i++ {
+		rd, _ := runes.New(strings.NewReader(input))
+
+		matched, _, err := ebnf.MatchPattern(pattern, rd)
+		if err != nil || !matched {
+			b.Fatalf("expected match, got %v %v", matched, err)
+		}
+	}
+}
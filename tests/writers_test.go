@@ -0,0 +1,271 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/generate"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/hint"
+	"github.com/almerlucke/exbana/v2/patterns/reference"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/runeclass"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	bytereader "github.com/almerlucke/exbana/v2/readers/bytes"
+	"github.com/almerlucke/exbana/v2/readers/runes"
+	bytewriter "github.com/almerlucke/exbana/v2/writers/bytes"
+	"github.com/almerlucke/exbana/v2/writers/format"
+	"github.com/almerlucke/exbana/v2/writers/limit"
+	runewriter "github.com/almerlucke/exbana/v2/writers/runes"
+	"github.com/almerlucke/exbana/v2/writers/tee"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestRuneWriter(t *testing.T) {
+	hello := conc(runeMatch('h').SetGenerateFunc(func() rune { return 'h' }), runeMatch('é').SetGenerateFunc(func() rune { return 'é' }))
+
+	var buf bytes.Buffer
+
+	w := runewriter.New(&buf)
+
+	if err := hello.Generate(w); err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	_ = w.Finish()
+
+	if buf.String() != "hé" {
+		t.Errorf("expected hé, got %q", buf.String())
+	}
+
+	sw := runewriter.NewStringWriter()
+	_ = hello.Generate(sw)
+	_ = sw.Finish()
+
+	if sw.String() != "hé" {
+		t.Errorf("expected hé, got %q", sw.String())
+	}
+}
+
+func TestByteWriter(t *testing.T) {
+	eq := func(a, b byte) bool { return a == b }
+	length := entity.New[byte, int](func(b byte) bool { return b < 16 }).SetGenerateFunc(func() byte { return 3 })
+	header := concatenation.New[byte, int](vector.New[byte, int](eq, 0xCA, 0xFE), length)
+
+	w := bytewriter.NewBufferWriter()
+
+	if err := header.Generate(w); err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	_ = w.Finish()
+
+	if !bytes.Equal(w.Bytes(), []byte{0xCA, 0xFE, 3}) {
+		t.Fatalf("unexpected output %x", w.Bytes())
+	}
+
+	matched, _, err := ebnf.MatchPattern[byte, int](header, bytereader.NewFromBytes(w.Bytes()))
+	if err != nil || !matched {
+		t.Errorf("expected generated output to match, got %v %v", matched, err)
+	}
+}
+
+func TestFormatWriter(t *testing.T) {
+	statement := func(s string) ebnf.Pattern[rune, runes.Pos] {
+		return hint.New[rune, runes.Pos]("statement", runeVector([]rune(s)))
+	}
+
+	block := hint.New[rune, runes.Pos]("block", conc(statement("a;"), statement("b;")))
+	program := conc(runeVector([]rune("{")), block, runeVector([]rune("}")))
+
+	sw := runewriter.NewStringWriter()
+	w := format.New[rune](sw, format.Config[rune]{
+		Rules: map[string]format.Rule[rune]{
+			"block":     {Before: []rune("\n"), After: []rune("\n"), Indent: true},
+			"statement": {Separator: []rune("\n")},
+		},
+		Newline: '\n',
+		Indent:  []rune("  "),
+	})
+
+	if err := program.Generate(w); err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	_ = w.Finish()
+
+	if sw.String() != "{\n  a;\n  b;\n}" {
+		t.Errorf("unexpected formatted output %q", sw.String())
+	}
+}
+
+// passWriter is writer middleware that only implements Writer and Unwrap
+type passWriter struct {
+	w ebnf.Writer[rune]
+}
+
+func (p passWriter) Write(objects ...rune) error {
+	return p.w.Write(objects...)
+}
+
+func (p passWriter) Finish() error {
+	return p.w.Finish()
+}
+
+func (p passWriter) Unwrap() ebnf.Writer[rune] {
+	return p.w
+}
+
+func TestWriterMiddleware(t *testing.T) {
+	// Hints reach the format writer behind the middleware
+	sw := runewriter.NewStringWriter()
+	w := passWriter{w: format.New[rune](sw, format.Config[rune]{
+		Rules: map[string]format.Rule[rune]{"item": {Before: []rune("<"), After: []rune(">")}},
+	})}
+
+	if err := hint.New[rune, runes.Pos]("item", runeVector([]rune("a"))).Generate(w); err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	_ = w.Finish()
+
+	if sw.String() != "<a>" {
+		t.Errorf("expected hinted output, got %q", sw.String())
+	}
+
+	// The seeded source of a generation profile is found behind the middleware
+	word := repetition.New[rune, runes.Pos](runeclass.Letter[runes.Pos](), 1, 0)
+	word.SetMaxGen(20)
+
+	sample := func() string {
+		sw := runewriter.NewStringWriter()
+		config := generate.NewConfig[rune, runes.Pos]().SetRand(rand.New(rand.NewSource(1)))
+
+		if err := ebnf.GeneratePattern[rune, runes.Pos](word, passWriter{w: generate.New[rune, runes.Pos](sw, config)}); err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		_ = sw.Finish()
+
+		return sw.String()
+	}
+
+	if first, second := sample(), sample(); first != second {
+		t.Errorf("expected the same sample for the same seed, got %q and %q", first, second)
+	}
+
+	// Limits are found behind the middleware
+	lw := passWriter{w: limit.New[rune, runes.Pos](runewriter.NewStringWriter(), limit.Limits{MaxObjects: 1})}
+	_ = lw.Write('a')
+
+	if !ebnf.Exhausted[rune](lw) {
+		t.Errorf("expected exhausted limit")
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(...rune) error {
+	return errors.New("failed")
+}
+
+func (failingWriter) Finish() error {
+	return nil
+}
+
+func TestTeeWriter(t *testing.T) {
+	word := runeVector([]rune("abc"))
+
+	sw := runewriter.NewStringWriter()
+	slice := tee.NewSlice[rune]()
+
+	w := tee.New[rune](sw, failingWriter{}, slice)
+
+	var sinkErr *tee.SinkError
+
+	if err := word.Generate(w); !errors.As(err, &sinkErr) || sinkErr.Index != 1 {
+		t.Errorf("expected sink error for sink 1, got %v", err)
+	}
+
+	if err := word.Generate(w); err != nil {
+		t.Errorf("expected failed sink to be skipped, got %v", err)
+	}
+
+	_ = w.Finish()
+
+	if sw.String() != "abcabc" || string(slice.Objects) != "abcabc" {
+		t.Errorf("unexpected output %q %q", sw.String(), string(slice.Objects))
+	}
+
+	w = tee.New[rune](failingWriter{}, tee.NewSlice[rune]()).SetBestEffort(true)
+	if err := word.Generate(w); err != nil {
+		t.Errorf("expected no error in best effort mode, got %v", err)
+	}
+}
+
+func TestLimitWriter(t *testing.T) {
+	digit := runeBetween('0', '9').SetGenerateFunc(func() rune { return '7' })
+	expr := reference.New[rune, runes.Pos](nil)
+	expr.Set(alt(conc(runeMatch('(').SetGenerateFunc(func() rune { return '(' }), expr, runeMatch(')').SetGenerateFunc(func() rune { return ')' })), digit))
+
+	list := repetition.New[rune, runes.Pos](expr, 1, 0)
+	list.SetMaxGen(1000)
+
+	for i := 0; i < 20; i++ {
+		sw := runewriter.NewStringWriter()
+		w := limit.New[rune, runes.Pos](sw, limit.Limits{MaxObjects: 50, MaxDepth: 40})
+
+		if err := list.Generate(w); err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		_ = w.Finish()
+
+		if len(sw.String()) > 100 {
+			t.Fatalf("expected output to be limited, got %d objects", len(sw.String()))
+		}
+
+		rd, _ := runes.New(strings.NewReader(sw.String()))
+		matched, _, err := ebnf.MatchPattern[rune, runes.Pos](list, rd)
+		if err != nil || !matched || !rd.Finished() {
+			t.Fatalf("expected generated output %q to match", sw.String())
+		}
+	}
+}
+
+func TestReplace(t *testing.T) {
+	number := conc(runeBetween('0', '9'), rep(runeBetween('0', '9')))
+
+	rd, _ := runes.New(strings.NewReader("a 12 b 345 c"))
+	sw := runewriter.NewStringWriter()
+
+	err := ebnf.Replace[rune, runes.Pos](rd, number, sw, func(m *ebnf.Match[rune, runes.Pos], r ebnf.Reader[rune, runes.Pos]) ([]rune, error) {
+		value, err := r.Range(m.Begin, m.End)
+		return []rune("<" + string(value) + ">"), err
+	})
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	if sw.String() != "a <12> b <345> c" {
+		t.Errorf("unexpected output %q", sw.String())
+	}
+
+	// Empty matches do not stall the scan
+	rd, _ = runes.New(strings.NewReader("xaay"))
+	sw = runewriter.NewStringWriter()
+
+	err = ebnf.Replace[rune, runes.Pos](rd, rep(runeMatch('a')), sw, func(*ebnf.Match[rune, runes.Pos], ebnf.Reader[rune, runes.Pos]) ([]rune, error) {
+		return []rune("-"), nil
+	})
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	if sw.String() != "-x--y" {
+		t.Errorf("unexpected output %q", sw.String())
+	}
+}
@@ -0,0 +1,148 @@
+package exbana
+
+// UnknownID is the pattern ID of the matches Tokenize returns for input no token pattern matches
+const UnknownID = "unknown"
+
+// Tokenize splits stream into tokens. At each position the skip pattern is skipped (skip may be nil), after that the
+// token pattern with the longest match wins, a tie goes to the pattern that comes first. The result is the match of
+// the winning pattern, so the pattern ID tags the token. Input no token pattern matches is not skipped silently as
+// with Scan but returned as a match with ID UnknownID, a run of unknown objects up to the next token or skipped trivia
+// is a single match with the objects as value
+func Tokenize[T, P any](stream Reader[T, P], patterns Patterns[T, P], skip Pattern[T, P]) ([]*Match[T, P], error) {
+	var (
+		results []*Match[T, P]
+		unknown *Match[T, P]
+		mark    Marker[T, P]
+	)
+
+	unknownPattern := NewBasePattern[T, P]()
+	unknownPattern.SetSelf(unknownPattern)
+	unknownPattern.SetID(UnknownID)
+	unknownPattern.SetPrintOutput("? unknown ?")
+
+	// endUnknown ends the current run of unknown objects, the value is read once when the run ends. The mark at the
+	// start of the run keeps the run available on streaming readers
+	endUnknown := func() error {
+		if unknown == nil {
+			return nil
+		}
+
+		defer mark.Discard()
+
+		var err error
+
+		unknown.Value, err = stream.Range(unknown.Begin, unknown.End)
+		unknown = nil
+
+		return err
+	}
+
+	for {
+		before, err := stream.Position()
+		if IsStreamError(err) {
+			return nil, err
+		}
+
+		err = SkipTrivia(skip, stream)
+		if IsStreamError(err) {
+			return nil, err
+		}
+
+		// Skipped trivia ends a run of unknown objects
+		if unknown != nil {
+			after, err := stream.Position()
+			if IsStreamError(err) {
+				return nil, err
+			}
+
+			if stream.Length(before, after) > 0 {
+				err = endUnknown()
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if stream.Finished() {
+			break
+		}
+
+		best, err := longestMatch(stream, patterns)
+		if err != nil {
+			return nil, err
+		}
+
+		if best != nil {
+			err = endUnknown()
+			if err != nil {
+				return nil, err
+			}
+
+			results = append(results, best)
+
+			continue
+		}
+
+		if unknown == nil {
+			mark, err = NewMarker(stream)
+			if IsStreamError(err) {
+				return nil, err
+			}
+
+			unknown = NewMatch[T, P](unknownPattern, mark.Pos(), mark.Pos(), nil, nil)
+			results = append(results, unknown)
+		}
+
+		_, err = stream.Skip(1)
+		if IsStreamError(err) {
+			return nil, err
+		}
+
+		unknown.End, err = stream.Position()
+		if IsStreamError(err) {
+			return nil, err
+		}
+	}
+
+	return results, endUnknown()
+}
+
+// longestMatch matches all patterns at the current position and returns the longest match, a tie goes to the
+// pattern that comes first. The stream is positioned after the match, nil is returned if no pattern matches input
+func longestMatch[T, P any](stream Reader[T, P], patterns Patterns[T, P]) (*Match[T, P], error) {
+	begin, err := NewMarker(stream)
+	if IsStreamError(err) {
+		return nil, err
+	}
+
+	defer begin.Discard()
+
+	var (
+		best   *Match[T, P]
+		length int
+	)
+
+	for _, pattern := range patterns {
+		matched, result, err := MatchPattern(pattern, stream)
+		if err != nil {
+			return nil, err
+		}
+
+		if matched && result != nil {
+			if n := stream.Length(begin.Pos(), result.End); n > length {
+				best, length = result, n
+			}
+		}
+
+		err = begin.Reset()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if best == nil {
+		return nil, nil
+	}
+
+	return best, stream.SetPosition(best.End)
+}
@@ -0,0 +1,230 @@
+package exbana
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MismatchNode is a pattern attempt in a TreeLog, either a failed attempt or a successful attempt with failed sub
+// pattern attempts
+type MismatchNode[T, P any] struct {
+	Pattern Pattern[T, P]
+	Depth   int
+	// Pos is the position the attempt started at
+	Pos P
+	// Matched is true for a successful attempt, its failed sub pattern attempts were ordinary backtracking
+	Matched bool
+	// Recovered is true if an ancestor attempt matched
+	Recovered bool
+	// Mismatch is the mismatch logged by the pattern, if the pattern logs to the tree
+	Mismatch *Mismatch[T, P]
+	// Err is the stream error of the attempt, if any
+	Err      error
+	Parent   *MismatchNode[T, P]
+	Children []*MismatchNode[T, P]
+}
+
+// Fatal returns true if the attempt failed and no ancestor attempt matched, so the failure contributed to the failure
+// of the parse
+func (n *MismatchNode[T, P]) Fatal() bool {
+	return !n.Matched && !n.Recovered
+}
+
+// Path returns the IDs of the named patterns from the root to the node
+func (n *MismatchNode[T, P]) Path() []string {
+	var path []string
+
+	for a := n; a != nil; a = a.Parent {
+		if id := a.Pattern.ID(); id != NoID {
+			path = append([]string{id}, path...)
+		}
+	}
+
+	return path
+}
+
+func (n *MismatchNode[T, P]) recover() {
+	n.Recovered = true
+
+	for _, child := range n.Children {
+		child.recover()
+	}
+}
+
+// treeFrame is an attempt in progress
+type treeFrame[T, P any] struct {
+	pattern  Pattern[T, P]
+	pos      P
+	mismatch *Mismatch[T, P]
+	children []*MismatchNode[T, P]
+}
+
+// TreeLog records mismatches as a tree mirroring the pattern nesting. It is a Tracer, so it must be installed with a
+// tracing reader (see readers/trace), and a Logger, patterns logging to the tree attach their mismatch to their
+// attempt. Successful attempts without failed sub pattern attempts are not recorded
+type TreeLog[T, P any] struct {
+	Roots []*MismatchNode[T, P]
+	stack []*treeFrame[T, P]
+}
+
+// NewTreeLog creates a new tree log
+func NewTreeLog[T, P any]() *TreeLog[T, P] {
+	return &TreeLog[T, P]{}
+}
+
+func (l *TreeLog[T, P]) OnEnter(pattern Pattern[T, P], _ int, pos P) {
+	l.stack = append(l.stack, &treeFrame[T, P]{pattern: pattern, pos: pos})
+}
+
+func (l *TreeLog[T, P]) OnSuccess(_ Pattern[T, P], depth int, _ P, _ P) {
+	frame := l.pop()
+	if frame == nil || len(frame.children) == 0 {
+		return
+	}
+
+	node := l.node(frame, depth)
+	node.Matched = true
+
+	for _, child := range node.Children {
+		child.recover()
+	}
+
+	l.add(node)
+}
+
+func (l *TreeLog[T, P]) OnFail(_ Pattern[T, P], depth int, _ P, err error) {
+	frame := l.pop()
+	if frame == nil {
+		return
+	}
+
+	node := l.node(frame, depth)
+	node.Err = err
+
+	l.add(node)
+}
+
+// LogMismatch attaches the mismatch to the attempt of its pattern
+func (l *TreeLog[T, P]) LogMismatch(m *Mismatch[T, P]) {
+	if n := len(l.stack); n > 0 && l.stack[n-1].pattern == m.Pattern {
+		l.stack[n-1].mismatch = m
+		return
+	}
+
+	l.Roots = append(l.Roots, &MismatchNode[T, P]{Pattern: m.Pattern, Pos: m.Begin, Mismatch: m})
+}
+
+// Fatal returns the fatal attempts in pre-order
+func (l *TreeLog[T, P]) Fatal() []*MismatchNode[T, P] {
+	var fatal []*MismatchNode[T, P]
+
+	l.Walk(func(n *MismatchNode[T, P]) bool {
+		if n.Fatal() {
+			fatal = append(fatal, n)
+			return true
+		}
+
+		return false
+	})
+
+	return fatal
+}
+
+// Walk visits the nodes in pre-order, if fn returns false the children of the visited node are skipped
+func (l *TreeLog[T, P]) Walk(fn func(*MismatchNode[T, P]) bool) {
+	var walk func(*MismatchNode[T, P])
+
+	walk = func(n *MismatchNode[T, P]) {
+		if !fn(n) {
+			return
+		}
+
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+
+	for _, root := range l.Roots {
+		walk(root)
+	}
+}
+
+// Print writes the tree with one attempt per line, indented by depth. Failed attempts start with -, successful
+// attempts with +, fatal attempts are marked with !. Anonymous patterns are labeled with their EBNF
+func (l *TreeLog[T, P]) Print(w io.Writer) error {
+	var err error
+
+	l.Walk(func(n *MismatchNode[T, P]) bool {
+		if err != nil {
+			return false
+		}
+
+		mark := "-"
+
+		switch {
+		case n.Matched:
+			mark = "+"
+		case n.Fatal():
+			mark = "!"
+		}
+
+		label := Expectation(n.Pattern)
+		if label == "" {
+			var sb strings.Builder
+
+			_ = n.Pattern.Print(&sb)
+			label = sb.String()
+		}
+
+		_, err = fmt.Fprintf(w, "%s%s %s at %v\n", strings.Repeat("  ", n.Depth), mark, label, n.Pos)
+
+		return err == nil
+	})
+
+	return err
+}
+
+// Reset clears the log
+func (l *TreeLog[T, P]) Reset() {
+	l.Roots = nil
+	l.stack = nil
+}
+
+func (l *TreeLog[T, P]) pop() *treeFrame[T, P] {
+	n := len(l.stack)
+	if n == 0 {
+		return nil
+	}
+
+	frame := l.stack[n-1]
+	l.stack = l.stack[:n-1]
+
+	return frame
+}
+
+func (l *TreeLog[T, P]) node(frame *treeFrame[T, P], depth int) *MismatchNode[T, P] {
+	node := &MismatchNode[T, P]{
+		Pattern:  frame.pattern,
+		Depth:    depth,
+		Pos:      frame.pos,
+		Mismatch: frame.mismatch,
+		Children: frame.children,
+	}
+
+	for _, child := range node.Children {
+		child.Parent = node
+	}
+
+	return node
+}
+
+// add adds a node to the attempt in progress or to the roots
+func (l *TreeLog[T, P]) add(node *MismatchNode[T, P]) {
+	if n := len(l.stack); n > 0 {
+		l.stack[n-1].children = append(l.stack[n-1].children, node)
+		return
+	}
+
+	l.Roots = append(l.Roots, node)
+}
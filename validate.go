@@ -0,0 +1,130 @@
+package exbana
+
+// Validator is an optional extension of Reader, if Validating returns true patterns only validate the input, no
+// match trees, range values or mismatches are created and successful matches return a nil match
+type Validator interface {
+	Validating() bool
+}
+
+// IsValidating returns true if r implements Validator and is validating
+func IsValidating[T, P any](r Reader[T, P]) bool {
+	if v, ok := Lookup[Validator](r); ok {
+		return v.Validating()
+	}
+
+	return false
+}
+
+// Matches checks if pattern matches from the current position of r without building a match tree, this is the fast
+// path for checking if input conforms to a pattern. Like MatchPattern the reader is positioned after the match
+func Matches[T, P any](pattern Pattern[T, P], r Reader[T, P]) (bool, error) {
+	matched, _, err := MatchPattern[T, P](pattern, &validator[T, P]{src: r})
+	return matched, err
+}
+
+// Count counts the non overlapping matches of pattern in stream without building match trees or range values
+func Count[T, P any](stream Reader[T, P], pattern Pattern[T, P]) (int, error) {
+	var (
+		v     = &validator[T, P]{src: stream}
+		count int
+	)
+
+	for !stream.Finished() {
+		mark, err := NewMarker[T, P](v)
+		if IsStreamError(err) {
+			return count, err
+		}
+
+		matched, _, err := MatchPattern[T, P](pattern, v)
+		if err != nil {
+			mark.Discard()
+			return count, err
+		}
+
+		if matched {
+			mark.Discard()
+			count++
+
+			// Validating matches return no match tree, measure the match from the reader position
+			pos, err := stream.Position()
+			if IsStreamError(err) {
+				return count, err
+			}
+
+			if stream.Length(mark.Pos(), pos) > 0 {
+				continue
+			}
+		} else {
+			err = mark.Reset()
+			mark.Discard()
+
+			if IsStreamError(err) {
+				return count, err
+			}
+		}
+
+		_, err = stream.Skip(1)
+		if IsStreamError(err) {
+			return count, err
+		}
+	}
+
+	return count, nil
+}
+
+// validator is a reader middleware that puts patterns in validate only mode
+type validator[T, P any] struct {
+	src Reader[T, P]
+}
+
+func (v *validator[T, P]) Validating() bool {
+	return true
+}
+
+func (v *validator[T, P]) Unwrap() Reader[T, P] {
+	return v.src
+}
+
+func (v *validator[T, P]) MatchPattern(pattern Pattern[T, P], r Reader[T, P]) (bool, *Match[T, P], error) {
+	return MatchNext(pattern, v.src, r)
+}
+
+func (v *validator[T, P]) Peek1() (T, error) {
+	return v.src.Peek1()
+}
+
+func (v *validator[T, P]) Read1() (T, error) {
+	return v.src.Read1()
+}
+
+func (v *validator[T, P]) Peek(n int, buf []T) (int, error) {
+	return v.src.Peek(n, buf)
+}
+
+func (v *validator[T, P]) Read(n int, buf []T) (int, error) {
+	return v.src.Read(n, buf)
+}
+
+func (v *validator[T, P]) Skip(n int) (int, error) {
+	return v.src.Skip(n)
+}
+
+func (v *validator[T, P]) Finished() bool {
+	return v.src.Finished()
+}
+
+func (v *validator[T, P]) Position() (P, error) {
+	return v.src.Position()
+}
+
+func (v *validator[T, P]) SetPosition(p P) error {
+	return v.src.SetPosition(p)
+}
+
+func (v *validator[T, P]) Range(p1 P, p2 P) ([]T, error) {
+	return v.src.Range(p1, p2)
+}
+
+func (v *validator[T, P]) Length(p1 P, p2 P) int {
+	return v.src.Length(p1, p2)
+}
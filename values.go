@@ -0,0 +1,40 @@
+package exbana
+
+// Capturer is an optional extension of Reader, if Capturing returns false entities and vectors do not copy the
+// matched objects to Match.Value, the value is a nil slice. The objects can still be read with Range(Begin, End) as
+// long as the reader holds them, this saves allocations and copying when most values are never used (i.e.
+// tokenization)
+type Capturer interface {
+	Capturing() bool
+}
+
+// IsCapturing returns false if a reader in the middleware chain of r implements Capturer and does not capture values
+func IsCapturing[T, P any](r Reader[T, P]) bool {
+	if c, ok := Lookup[Capturer](r); ok {
+		return c.Capturing()
+	}
+
+	return true
+}
+
+// WithoutValues returns a reader middleware on top of r which turns off value capture
+func WithoutValues[T, P any](r Reader[T, P]) Reader[T, P] {
+	return &noValues[T, P]{Reader: r}
+}
+
+// noValues is a reader middleware that turns off value capture
+type noValues[T, P any] struct {
+	Reader[T, P]
+}
+
+func (n *noValues[T, P]) Capturing() bool {
+	return false
+}
+
+func (n *noValues[T, P]) Unwrap() Reader[T, P] {
+	return n.Reader
+}
+
+func (n *noValues[T, P]) MatchPattern(pattern Pattern[T, P], r Reader[T, P]) (bool, *Match[T, P], error) {
+	return MatchNext(pattern, n.Reader, r)
+}
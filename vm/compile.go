@@ -0,0 +1,354 @@
+package vm
+
+import (
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/end"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/exception"
+	"github.com/almerlucke/exbana/v2/patterns/hint"
+	"github.com/almerlucke/exbana/v2/patterns/reference"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	"io"
+	"strings"
+)
+
+type opcode uint8
+
+const (
+	// opEntity reads one object and tests it with funcs[arg]
+	opEntity opcode = iota
+	// opVector reads and compares the objects of vectors[arg]
+	opVector
+	// opEnd fails if the reader is not finished
+	opEnd
+	// opPattern matches patterns[arg] with the tree engine, used for patterns the compiler does not know
+	opPattern
+	// opChoice pushes a backtrack entry which continues at label
+	opChoice
+	// opCommit pops the top backtrack entry and jumps to label
+	opCommit
+	// opFailTwice pops the top backtrack entry and fails
+	opFailTwice
+	// opJump jumps to label
+	opJump
+	// opCall pushes the return address and jumps to label
+	opCall
+	// opReturn pops the return address and jumps to it
+	opReturn
+	// opLongest starts a longest match alternation at the current position
+	opLongest
+	// opRecord pops the backtrack entry of an alternative, records the end if it is the longest so far and returns
+	// to the start of the alternation
+	opRecord
+	// opLongestEnd continues at the end of the longest alternative, fails if no alternative matched
+	opLongestEnd
+	// opRepeat starts a repetition counter
+	opRepeat
+	// opRepeatCheck jumps to label if the reader is finished or arg (max) repetitions are matched
+	opRepeatCheck
+	// opRepeatInc increments the repetition counter
+	opRepeatInc
+	// opRepeatEnd pops the repetition counter, fails if less than arg (min) repetitions are matched
+	opRepeatEnd
+	// opHalt ends the program successfully
+	opHalt
+)
+
+var opcodeNames = [...]string{
+	opEntity:      "entity",
+	opVector:      "vector",
+	opEnd:         "end",
+	opPattern:     "pattern",
+	opChoice:      "choice",
+	opCommit:      "commit",
+	opFailTwice:   "failtwice",
+	opJump:        "jump",
+	opCall:        "call",
+	opReturn:      "return",
+	opLongest:     "longest",
+	opRecord:      "record",
+	opLongestEnd:  "longestend",
+	opRepeat:      "repeat",
+	opRepeatCheck: "repeatcheck",
+	opRepeatInc:   "repeatinc",
+	opRepeatEnd:   "repeatend",
+	opHalt:        "halt",
+}
+
+type instruction struct {
+	op    opcode
+	arg   int
+	label int
+}
+
+type vectorArg[T any] struct {
+	vector []T
+	eq     func(T, T) bool
+}
+
+// Program is a pattern compiled to a flat instruction program which is interpreted iteratively. A program is a
+// pattern itself, so it can be used with Scan, Matches, etc. Matching a program gives the same result as matching the
+// source pattern but only the match of the program itself is returned, sub matches, eval functions and loggers of the
+// source pattern are not used. Patterns unknown to the compiler are matched with the tree engine
+type Program[T, P any] struct {
+	*ebnf.BasePattern[T, P]
+	source   ebnf.Pattern[T, P]
+	code     []instruction
+	funcs    []func(T) bool
+	vectors  []vectorArg[T]
+	patterns ebnf.Patterns[T, P]
+}
+
+// Compile compiles a pattern tree to a program
+func Compile[T, P any](pattern ebnf.Pattern[T, P]) *Program[T, P] {
+	p := &Program[T, P]{
+		BasePattern: ebnf.NewBasePattern[T, P](),
+		source:      pattern,
+	}
+
+	p.SetSelf(p)
+
+	c := &compiler[T, P]{
+		program:  p,
+		routines: map[ebnf.Pattern[T, P]]int{},
+	}
+
+	c.compile(pattern)
+	c.emit(opHalt, 0, 0)
+
+	// Compile the referenced patterns as sub routines, compiling a routine can add new routines
+	for len(c.pending) > 0 {
+		target := c.pending[0]
+		c.pending = c.pending[1:]
+
+		start := len(p.code)
+
+		for _, call := range c.calls[target] {
+			p.code[call].label = start
+		}
+
+		c.routines[target] = start
+		c.compile(target)
+		c.emit(opReturn, 0, 0)
+	}
+
+	return p
+}
+
+// Source returns the compiled pattern
+func (p *Program[T, P]) Source() ebnf.Pattern[T, P] {
+	return p.source
+}
+
+// Generate generates the source pattern
+func (p *Program[T, P]) Generate(w ebnf.Writer[T]) error {
+	return ebnf.GeneratePattern(p.source, w)
+}
+
+// Print prints the source pattern
+func (p *Program[T, P]) Print(w io.Writer) error {
+	return p.source.Print(w)
+}
+
+// String returns a listing of the program instructions
+func (p *Program[T, P]) String() string {
+	var sb strings.Builder
+
+	for pc, inst := range p.code {
+		sb.WriteString(fmt.Sprintf("%04d %s", pc, opcodeNames[inst.op]))
+
+		switch inst.op {
+		case opEntity, opVector, opPattern:
+			sb.WriteString(fmt.Sprintf(" %d", inst.arg))
+		case opChoice, opCommit, opJump, opCall:
+			sb.WriteString(fmt.Sprintf(" %04d", inst.label))
+		case opRepeatCheck:
+			sb.WriteString(fmt.Sprintf(" %d %04d", inst.arg, inst.label))
+		case opRepeatEnd:
+			sb.WriteString(fmt.Sprintf(" %d", inst.arg))
+		}
+
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+type compiler[T, P any] struct {
+	program  *Program[T, P]
+	routines map[ebnf.Pattern[T, P]]int
+	calls    map[ebnf.Pattern[T, P]][]int
+	pending  ebnf.Patterns[T, P]
+}
+
+func (c *compiler[T, P]) emit(op opcode, arg int, label int) int {
+	c.program.code = append(c.program.code, instruction{op: op, arg: arg, label: label})
+	return len(c.program.code) - 1
+}
+
+func (c *compiler[T, P]) here() int {
+	return len(c.program.code)
+}
+
+func (c *compiler[T, P]) fallback(pattern ebnf.Pattern[T, P]) {
+	c.program.patterns = append(c.program.patterns, pattern)
+	c.emit(opPattern, len(c.program.patterns)-1, 0)
+}
+
+func (c *compiler[T, P]) call(target ebnf.Pattern[T, P]) {
+	if start, ok := c.routines[target]; ok {
+		c.emit(opCall, 0, start)
+		return
+	}
+
+	if c.calls == nil {
+		c.calls = map[ebnf.Pattern[T, P]][]int{}
+	}
+
+	if _, ok := c.calls[target]; !ok {
+		c.pending = append(c.pending, target)
+	}
+
+	// The label is patched when the routine is compiled
+	c.calls[target] = append(c.calls[target], c.emit(opCall, 0, 0))
+}
+
+func (c *compiler[T, P]) compile(pattern ebnf.Pattern[T, P]) {
+	switch pt := pattern.(type) {
+	case *entity.Entity[T, P]:
+		c.program.funcs = append(c.program.funcs, pt.MatchFunc())
+		c.emit(opEntity, len(c.program.funcs)-1, 0)
+	case *vector.Vector[T, P]:
+		c.program.vectors = append(c.program.vectors, vectorArg[T]{vector: pt.Vector(), eq: pt.Equal()})
+		c.emit(opVector, len(c.program.vectors)-1, 0)
+	case *end.End[T, P]:
+		c.emit(opEnd, 0, 0)
+	case *concatenation.Concatenation[T, P]:
+		for _, sub := range pt.Patterns() {
+			c.compile(sub)
+		}
+	case *alternation.Alternation[T, P]:
+		c.alternation(pt)
+	case *repetition.Repetition[T, P]:
+		c.repetition(pt)
+	case *exception.Exception[T, P]:
+		//   choice L1
+		//   <exception>
+		//   failtwice
+		// L1:
+		//   <must>
+		choice := c.emit(opChoice, 0, 0)
+		c.compile(pt.Exception())
+		c.emit(opFailTwice, 0, 0)
+		c.program.code[choice].label = c.here()
+		c.compile(pt.Must())
+	case *hint.Hint[T, P]:
+		c.compile(pt.Pattern())
+	case *reference.Reference[T, P]:
+		if pt.Pattern() == nil {
+			// Matching gives ErrUnresolvedReference
+			c.fallback(pt)
+			return
+		}
+
+		c.call(pt.Pattern())
+	default:
+		c.fallback(pattern)
+	}
+}
+
+func (c *compiler[T, P]) alternation(a *alternation.Alternation[T, P]) {
+	alternatives := a.Patterns()
+	if len(alternatives) == 0 {
+		c.fallback(a)
+		return
+	}
+
+	if a.IsOrthogonal() {
+		// The first matching alternative wins
+		//   choice L1
+		//   <alternative 1>
+		//   commit END
+		// L1:
+		//   ...
+		//   <alternative n>
+		// END:
+		var commits []int
+
+		for i, alternative := range alternatives {
+			if i == len(alternatives)-1 {
+				c.compile(alternative)
+				break
+			}
+
+			choice := c.emit(opChoice, 0, 0)
+			c.compile(alternative)
+			commits = append(commits, c.emit(opCommit, 0, 0))
+			c.program.code[choice].label = c.here()
+		}
+
+		for _, commit := range commits {
+			c.program.code[commit].label = c.here()
+		}
+
+		return
+	}
+
+	// The longest matching alternative wins
+	//   longest
+	//   choice L1
+	//   <alternative 1>
+	//   record
+	// L1:
+	//   ...
+	//   longestend
+	c.emit(opLongest, 0, 0)
+
+	for _, alternative := range alternatives {
+		choice := c.emit(opChoice, 0, 0)
+		c.compile(alternative)
+		c.emit(opRecord, 0, 0)
+		c.program.code[choice].label = c.here()
+	}
+
+	c.emit(opLongestEnd, 0, 0)
+}
+
+func (c *compiler[T, P]) repetition(rep *repetition.Repetition[T, P]) {
+	//   repeat
+	// LOOP:
+	//   repeatcheck max DONE
+	//   choice DONE
+	//   <pattern>
+	//   commit NEXT
+	// NEXT:
+	//   repeatinc
+	//   jump LOOP
+	// DONE:
+	//   repeatend min
+	c.emit(opRepeat, 0, 0)
+
+	loop := c.here()
+	check := c.emit(opRepeatCheck, rep.Max(), 0)
+	choice := c.emit(opChoice, 0, 0)
+
+	c.compile(rep.Pattern())
+
+	c.emit(opCommit, 0, c.here()+1)
+	c.emit(opRepeatInc, 0, 0)
+	c.emit(opJump, 0, loop)
+
+	c.program.code[check].label = c.here()
+	c.program.code[choice].label = c.here()
+
+	c.emit(opRepeatEnd, rep.Min(), 0)
+}
+
+// First reports if obj can start the source pattern
+func (p *Program[T, P]) First(obj T) (bool, bool) {
+	return ebnf.First(p.source, obj)
+}
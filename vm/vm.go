@@ -0,0 +1,232 @@
+package vm
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+)
+
+// entry is a backtrack entry, on failure the machine returns to pos and continues at pc with the stacks truncated
+// to the saved depths
+type entry[P any] struct {
+	pos      P
+	pc       int
+	calls    int
+	longest  int
+	counters int
+}
+
+// longest keeps track of the longest alternative of an alternation
+type longest[P any] struct {
+	start  P
+	end    P
+	length int
+}
+
+// machine holds the state of a single program run
+type machine[T, P any] struct {
+	program   *Program[T, P]
+	r         ebnf.Reader[T, P]
+	backtrack []entry[P]
+	calls     []int
+	longest   []longest[P]
+	counters  []int
+}
+
+// Match runs the program against a stream
+func (p *Program[T, P]) Match(r ebnf.Reader[T, P]) (bool, *ebnf.Match[T, P], error) {
+	begin, err := r.Position()
+	if ebnf.IsStreamError(err) {
+		return false, nil, err
+	}
+
+	m := &machine[T, P]{
+		program: p,
+		r:       r,
+	}
+
+	matched, err := m.run()
+	if err != nil || !matched {
+		return false, nil, err
+	}
+
+	if ebnf.IsValidating(r) {
+		return true, nil, nil
+	}
+
+	end, err := r.Position()
+	if ebnf.IsStreamError(err) {
+		return false, nil, err
+	}
+
+	return true, ebnf.NewMatch[T, P](p, begin, end, nil, nil), nil
+}
+
+// fail returns to the top backtrack entry, ok is false if there is no entry left
+func (m *machine[T, P]) fail() (int, bool, error) {
+	if len(m.backtrack) == 0 {
+		return 0, false, nil
+	}
+
+	e := m.backtrack[len(m.backtrack)-1]
+	m.backtrack = m.backtrack[:len(m.backtrack)-1]
+	m.calls = m.calls[:e.calls]
+	m.longest = m.longest[:e.longest]
+	m.counters = m.counters[:e.counters]
+
+	err := m.r.SetPosition(e.pos)
+	if ebnf.IsStreamError(err) {
+		return 0, false, err
+	}
+
+	return e.pc, true, nil
+}
+
+// step executes a single instruction and returns the next pc and whether the instruction succeeded
+func (m *machine[T, P]) step(pc int) (int, bool, error) {
+	inst := m.program.code[pc]
+
+	switch inst.op {
+	case opEntity:
+		obj, err := m.r.Read1()
+		if ebnf.IsStreamError(err) {
+			return 0, false, err
+		}
+
+		return pc + 1, err == nil && m.program.funcs[inst.arg](obj), nil
+	case opVector:
+		v := m.program.vectors[inst.arg]
+
+		for _, e1 := range v.vector {
+			e2, err := m.r.Read1()
+			if ebnf.IsStreamError(err) {
+				return 0, false, err
+			}
+
+			if err != nil || !v.eq(e1, e2) {
+				return 0, false, nil
+			}
+		}
+
+		return pc + 1, true, nil
+	case opEnd:
+		return pc + 1, m.r.Finished(), nil
+	case opPattern:
+		matched, _, err := ebnf.MatchPattern(m.program.patterns[inst.arg], m.r)
+		return pc + 1, matched, err
+	case opChoice:
+		pos, err := m.r.Position()
+		if ebnf.IsStreamError(err) {
+			return 0, false, err
+		}
+
+		m.backtrack = append(m.backtrack, entry[P]{
+			pos:      pos,
+			pc:       inst.label,
+			calls:    len(m.calls),
+			longest:  len(m.longest),
+			counters: len(m.counters),
+		})
+
+		return pc + 1, true, nil
+	case opCommit:
+		m.backtrack = m.backtrack[:len(m.backtrack)-1]
+		return inst.label, true, nil
+	case opFailTwice:
+		m.backtrack = m.backtrack[:len(m.backtrack)-1]
+		return 0, false, nil
+	case opJump:
+		return inst.label, true, nil
+	case opCall:
+		m.calls = append(m.calls, pc+1)
+		return inst.label, true, nil
+	case opReturn:
+		ret := m.calls[len(m.calls)-1]
+		m.calls = m.calls[:len(m.calls)-1]
+
+		return ret, true, nil
+	case opLongest:
+		pos, err := m.r.Position()
+		if ebnf.IsStreamError(err) {
+			return 0, false, err
+		}
+
+		m.longest = append(m.longest, longest[P]{start: pos, length: -1})
+
+		return pc + 1, true, nil
+	case opRecord:
+		m.backtrack = m.backtrack[:len(m.backtrack)-1]
+
+		pos, err := m.r.Position()
+		if ebnf.IsStreamError(err) {
+			return 0, false, err
+		}
+
+		l := &m.longest[len(m.longest)-1]
+		if length := m.r.Length(l.start, pos); length > l.length {
+			l.length = length
+			l.end = pos
+		}
+
+		err = m.r.SetPosition(l.start)
+		if ebnf.IsStreamError(err) {
+			return 0, false, err
+		}
+
+		return pc + 1, true, nil
+	case opLongestEnd:
+		l := m.longest[len(m.longest)-1]
+		m.longest = m.longest[:len(m.longest)-1]
+
+		if l.length < 0 {
+			return 0, false, nil
+		}
+
+		err := m.r.SetPosition(l.end)
+		if ebnf.IsStreamError(err) {
+			return 0, false, err
+		}
+
+		return pc + 1, true, nil
+	case opRepeat:
+		m.counters = append(m.counters, 0)
+		return pc + 1, true, nil
+	case opRepeatCheck:
+		if m.r.Finished() || (inst.arg != 0 && m.counters[len(m.counters)-1] == inst.arg) {
+			return inst.label, true, nil
+		}
+
+		return pc + 1, true, nil
+	case opRepeatInc:
+		m.counters[len(m.counters)-1]++
+		return pc + 1, true, nil
+	case opRepeatEnd:
+		n := m.counters[len(m.counters)-1]
+		m.counters = m.counters[:len(m.counters)-1]
+
+		return pc + 1, n >= inst.arg, nil
+	}
+
+	return pc + 1, true, nil
+}
+
+// run executes the program until it halts or fails without backtrack entries left
+func (m *machine[T, P]) run() (bool, error) {
+	pc := 0
+
+	for m.program.code[pc].op != opHalt {
+		next, ok, err := m.step(pc)
+		if err != nil {
+			return false, err
+		}
+
+		if !ok {
+			next, ok, err = m.fail()
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+
+		pc = next
+	}
+
+	return true, nil
+}
@@ -1,7 +1,169 @@
 package exbana
 
+import (
+	"math/rand"
+)
+
 // Writer interface to write generated objects
 type Writer[T any] interface {
 	Write(...T) error
 	Finish() error
 }
+
+// WriterUnwrapper is implemented by writer middleware, Unwrap returns the wrapped writer. The optional extensions of
+// Writer are looked up through the chain of middleware, so middleware only implements the extensions it changes
+type WriterUnwrapper[T any] interface {
+	Unwrap() Writer[T]
+}
+
+// LookupWriter returns the outermost writer in the middleware chain starting at w that implements I
+func LookupWriter[I, T any](w Writer[T]) (I, bool) {
+	for w != nil {
+		if i, ok := w.(I); ok {
+			return i, true
+		}
+
+		u, ok := w.(WriterUnwrapper[T])
+		if !ok {
+			break
+		}
+
+		w = u.Unwrap()
+	}
+
+	var zero I
+
+	return zero, false
+}
+
+// HintWriter is an optional extension of Writer which receives formatting hints emitted by patterns during
+// generation, BeginHint is called before and EndHint after the output of a hinted pattern
+type HintWriter interface {
+	BeginHint(string) error
+	EndHint(string) error
+}
+
+// BeginHint signals the start of hinted output if a writer in the middleware chain of w implements HintWriter
+func BeginHint[T any](w Writer[T], hint string) error {
+	if hw, ok := LookupWriter[HintWriter](w); ok {
+		return hw.BeginHint(hint)
+	}
+
+	return nil
+}
+
+// EndHint signals the end of hinted output if a writer in the middleware chain of w implements HintWriter
+func EndHint[T any](w Writer[T], hint string) error {
+	if hw, ok := LookupWriter[HintWriter](w); ok {
+		return hw.EndHint(hint)
+	}
+
+	return nil
+}
+
+// Generator is an optional extension of Writer which intercepts the generation of patterns. Patterns generate their
+// sub patterns with GeneratePattern, so a writer implementing Generator sees every (sub) pattern generation and can
+// track depth or limit output. Writer middleware can be chained, w is the outermost writer of the chain, a Generator
+// passes the generation on to the next writer in the chain with GenerateNext
+type Generator[T, P any] interface {
+	GeneratePattern(pattern Pattern[T, P], w Writer[T]) error
+}
+
+// GeneratePattern lets pattern generate to writer w, if w implements Generator the generation is delegated to the
+// writer
+func GeneratePattern[T, P any](pattern Pattern[T, P], w Writer[T]) error {
+	return GenerateNext(pattern, w, w)
+}
+
+// GenerateNext delegates the generation of pattern to next if next implements Generator, otherwise pattern generates
+// to the outermost writer w
+func GenerateNext[T, P any](pattern Pattern[T, P], next Writer[T], w Writer[T]) error {
+	if g, ok := next.(Generator[T, P]); ok {
+		return g.GeneratePattern(pattern, w)
+	}
+
+	return pattern.Generate(w)
+}
+
+// Chooser is an optional extension of Writer which chooses the alternative generated by an alternation pattern, a
+// negative result leaves the choice to the pattern
+type Chooser[T, P any] interface {
+	Choose(Pattern[T, P], Patterns[T, P]) int
+}
+
+// Choose returns the alternative chosen by the first Chooser in the middleware chain of w, otherwise -1
+func Choose[T, P any](w Writer[T], pattern Pattern[T, P], alternatives Patterns[T, P]) int {
+	if c, ok := LookupWriter[Chooser[T, P]](w); ok {
+		return c.Choose(pattern, alternatives)
+	}
+
+	return -1
+}
+
+// Repeater is an optional extension of Writer which chooses the number of times a repetition pattern generates,
+// min and max are the bounds of the repetition (max 0 is unbounded), a negative result leaves the choice to the
+// pattern
+type Repeater[T, P any] interface {
+	Repeat(pattern Pattern[T, P], min int, max int) int
+}
+
+// Repeat returns the number of repetitions chosen by the first Repeater in the middleware chain of w, otherwise -1
+func Repeat[T, P any](w Writer[T], pattern Pattern[T, P], min int, max int) int {
+	if r, ok := LookupWriter[Repeater[T, P]](w); ok {
+		return r.Repeat(pattern, min, max)
+	}
+
+	return -1
+}
+
+// Limiter is an optional extension of Writer which signals that the generation budget is exhausted, patterns stop
+// generating optional output as soon as possible
+type Limiter interface {
+	Exhausted() bool
+}
+
+// Exhausted returns true if a writer in the middleware chain of w implements Limiter and the generation budget is
+// exhausted
+func Exhausted[T any](w Writer[T]) bool {
+	if l, ok := LookupWriter[Limiter](w); ok {
+		return l.Exhausted()
+	}
+
+	return false
+}
+
+// Randomizer is an optional extension of Writer which supplies the random source of generation, so generated output
+// can be reproduced from a seed
+type Randomizer interface {
+	Rand() *rand.Rand
+}
+
+// globalSource is a rand.Source on top of the global source of math/rand
+type globalSource struct{}
+
+func (globalSource) Int63() int64 {
+	return rand.Int63()
+}
+
+func (globalSource) Uint64() uint64 {
+	return rand.Uint64()
+}
+
+func (globalSource) Seed(int64) {
+	/* the global source is not seeded */
+}
+
+// globalRand draws from the global source of math/rand, it is safe for concurrent use except for Read
+var globalRand = rand.New(globalSource{})
+
+// Rand returns the random source of the first Randomizer in the middleware chain of w if it has one, otherwise a
+// source drawing from the global source of math/rand
+func Rand[T any](w Writer[T]) *rand.Rand {
+	if r, ok := LookupWriter[Randomizer](w); ok {
+		if rnd := r.Rand(); rnd != nil {
+			return rnd
+		}
+	}
+
+	return globalRand
+}
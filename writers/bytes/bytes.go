@@ -0,0 +1,55 @@
+package bytes
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// Writer writes generated bytes to an io.Writer (i.e. os.File, net.Conn or bytes.Buffer), output is buffered and
+// flushed on Finish
+type Writer struct {
+	w      *bufio.Writer
+	offset int
+}
+
+// New creates a new byte writer on top of w
+func New(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Write writes bytes
+func (w *Writer) Write(data ...byte) error {
+	n, err := w.w.Write(data)
+	w.offset += n
+
+	return err
+}
+
+// Offset returns the number of bytes written
+func (w *Writer) Offset() int {
+	return w.offset
+}
+
+// Finish flushes the buffered output
+func (w *Writer) Finish() error {
+	return w.w.Flush()
+}
+
+// BufferWriter collects generated bytes in memory
+type BufferWriter struct {
+	*Writer
+	buf *bytes.Buffer
+}
+
+// NewBufferWriter creates a new writer that collects generated bytes, the result is available with Bytes after
+// Finish
+func NewBufferWriter() *BufferWriter {
+	buf := &bytes.Buffer{}
+	return &BufferWriter{Writer: New(buf), buf: buf}
+}
+
+// Bytes returns the collected output
+func (w *BufferWriter) Bytes() []byte {
+	return w.buf.Bytes()
+}
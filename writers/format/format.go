@@ -0,0 +1,133 @@
+package format
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+)
+
+// Rule describes how output hinted with a name is formatted
+type Rule[T any] struct {
+	// Before is written before the hinted output
+	Before []T
+	// After is written after the hinted output
+	After []T
+	// Separator is written between two directly consecutive outputs with the same hint
+	Separator []T
+	// Indent indents all lines of the hinted output one level deeper
+	Indent bool
+}
+
+// Config configures a formatting writer
+type Config[T comparable] struct {
+	// Rules maps hints to formatting rules, hints without a rule are ignored
+	Rules map[string]Rule[T]
+	// Newline is the object that ends a line, indentation is written at the start of each line
+	Newline T
+	// Indent is written once per indentation level
+	Indent []T
+}
+
+// Writer is a writer middleware that formats generated output based on hints emitted by patterns (see the hint
+// pattern), separators, newlines and indentation are inserted according to the configured rules
+type Writer[T comparable] struct {
+	w           ebnf.Writer[T]
+	config      Config[T]
+	depth       int
+	lineStart   bool
+	lastHint    string
+	hasLastHint bool
+}
+
+// New creates a new formatting writer on top of w
+func New[T comparable](w ebnf.Writer[T], config Config[T]) *Writer[T] {
+	return &Writer[T]{
+		w:      w,
+		config: config,
+	}
+}
+
+// write writes objects and indentation at the start of each line
+func (f *Writer[T]) write(objects ...T) error {
+	for _, obj := range objects {
+		if f.lineStart && obj != f.config.Newline {
+			for i := 0; i < f.depth; i++ {
+				if err := f.w.Write(f.config.Indent...); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := f.w.Write(obj); err != nil {
+			return err
+		}
+
+		f.lineStart = obj == f.config.Newline
+	}
+
+	return nil
+}
+
+// Write writes generated objects
+func (f *Writer[T]) Write(objects ...T) error {
+	if len(objects) > 0 {
+		f.hasLastHint = false
+	}
+
+	return f.write(objects...)
+}
+
+// BeginHint applies the Separator and Before part of the rule for hint
+func (f *Writer[T]) BeginHint(hint string) error {
+	rule, ok := f.config.Rules[hint]
+	if !ok {
+		return nil
+	}
+
+	if f.hasLastHint && f.lastHint == hint {
+		if err := f.write(rule.Separator...); err != nil {
+			return err
+		}
+	}
+
+	f.hasLastHint = false
+
+	if err := f.write(rule.Before...); err != nil {
+		return err
+	}
+
+	if rule.Indent {
+		f.depth++
+	}
+
+	return nil
+}
+
+// EndHint applies the After part of the rule for hint
+func (f *Writer[T]) EndHint(hint string) error {
+	rule, ok := f.config.Rules[hint]
+	if !ok {
+		return nil
+	}
+
+	if rule.Indent {
+		f.depth--
+	}
+
+	if err := f.write(rule.After...); err != nil {
+		return err
+	}
+
+	f.lastHint = hint
+	f.hasLastHint = true
+
+	return nil
+}
+
+// Finish finishes the underlying writer
+func (f *Writer[T]) Finish() error {
+	return f.w.Finish()
+}
+
+// Unwrap returns the underlying writer
+func (f *Writer[T]) Unwrap() ebnf.Writer[T] {
+	return f.w
+}
@@ -0,0 +1,180 @@
+package limit
+
+import (
+	ebnf "github.com/almerlucke/exbana/v2"
+	"github.com/almerlucke/exbana/v2/introspect"
+	"github.com/almerlucke/exbana/v2/patterns/alternation"
+	"github.com/almerlucke/exbana/v2/patterns/concatenation"
+	"github.com/almerlucke/exbana/v2/patterns/entity"
+	"github.com/almerlucke/exbana/v2/patterns/repetition"
+	"github.com/almerlucke/exbana/v2/patterns/vector"
+	"math"
+)
+
+// infinite is the size estimate of a pattern that can only be generated through recursion
+const infinite = math.MaxInt32
+
+// Limits configures the generation budget, zero values are unlimited
+type Limits struct {
+	// MaxObjects is the maximum number of generated objects
+	MaxObjects int
+	// MaxDepth is the maximum nesting depth of generated patterns
+	MaxDepth int
+}
+
+// Limit is a writer middleware which enforces a generation budget across the pattern tree. When the budget is
+// exhausted repetitions stop as soon as their minimum is satisfied and alternations choose the alternative that
+// generates the least output, so recursive grammars terminate and the output stays valid. The budget is soft, output
+// that is required to complete the pattern is still generated
+type Limit[T, P any] struct {
+	w       ebnf.Writer[T]
+	limits  Limits
+	objects int
+	depth   int
+	sizes   map[ebnf.Pattern[T, P]]int
+}
+
+// New creates a new limiting writer on top of w
+func New[T, P any](w ebnf.Writer[T], limits Limits) *Limit[T, P] {
+	return &Limit[T, P]{
+		w:      w,
+		limits: limits,
+		sizes:  map[ebnf.Pattern[T, P]]int{},
+	}
+}
+
+// Objects returns the number of objects written
+func (l *Limit[T, P]) Objects() int {
+	return l.objects
+}
+
+// Offset returns the offset of the underlying writer, or the number of objects written if the underlying writer
+// does not report its offset
+func (l *Limit[T, P]) Offset() int {
+	if offset, ok := ebnf.WriterOffset(l.w); ok {
+		return offset
+	}
+
+	return l.objects
+}
+
+// Exhausted returns true if the object or depth budget is exhausted
+func (l *Limit[T, P]) Exhausted() bool {
+	return (l.limits.MaxObjects > 0 && l.objects >= l.limits.MaxObjects) ||
+		(l.limits.MaxDepth > 0 && l.depth >= l.limits.MaxDepth) || ebnf.Exhausted(l.w)
+}
+
+// GeneratePattern generates pattern and keeps track of the depth
+func (l *Limit[T, P]) GeneratePattern(pattern ebnf.Pattern[T, P], w ebnf.Writer[T]) error {
+	l.depth++
+	err := ebnf.GenerateNext(pattern, l.w, w)
+	l.depth--
+
+	return err
+}
+
+// Choose chooses the alternative with the smallest output if the budget is exhausted
+func (l *Limit[T, P]) Choose(pattern ebnf.Pattern[T, P], alternatives ebnf.Patterns[T, P]) int {
+	if !l.Exhausted() {
+		return ebnf.Choose(l.w, pattern, alternatives)
+	}
+
+	choice, smallest := 0, infinite+1
+
+	for i, alternative := range alternatives {
+		if size := l.minSize(alternative); size < smallest {
+			choice, smallest = i, size
+		}
+	}
+
+	return choice
+}
+
+// Repeat chooses the minimum number of repetitions if the budget is exhausted
+func (l *Limit[T, P]) Repeat(pattern ebnf.Pattern[T, P], min int, max int) int {
+	if l.Exhausted() {
+		return min
+	}
+
+	return ebnf.Repeat(l.w, pattern, min, max)
+}
+
+// minSize returns an estimate of the minimum number of objects pattern generates
+func (l *Limit[T, P]) minSize(pattern ebnf.Pattern[T, P]) int {
+	size, _ := l.estimate(pattern, map[ebnf.Pattern[T, P]]bool{})
+	return size
+}
+
+// estimate returns the minimum size of pattern and whether the estimate was cut short by recursion, only complete
+// estimates are cached
+func (l *Limit[T, P]) estimate(pattern ebnf.Pattern[T, P], visiting map[ebnf.Pattern[T, P]]bool) (int, bool) {
+	if size, ok := l.sizes[pattern]; ok {
+		return size, false
+	}
+
+	if visiting[pattern] {
+		return infinite, true
+	}
+
+	visiting[pattern] = true
+	defer delete(visiting, pattern)
+
+	var (
+		size int
+		cut  bool
+	)
+
+	switch pt := pattern.(type) {
+	case *entity.Entity[T, P]:
+		size = 1
+	case *vector.Vector[T, P]:
+		size = len(pt.Vector())
+	case *alternation.Alternation[T, P]:
+		size = infinite
+
+		for _, alternative := range pt.Patterns() {
+			s, c := l.estimate(alternative, visiting)
+			size = min(size, s)
+			cut = cut || c
+		}
+	case *concatenation.Concatenation[T, P]:
+		for _, child := range pt.Patterns() {
+			s, c := l.estimate(child, visiting)
+			size = min(size+s, infinite)
+			cut = cut || c
+		}
+	case *repetition.Repetition[T, P]:
+		if pt.Min() > 0 {
+			s, c := l.estimate(pt.Pattern(), visiting)
+			size = min(s*pt.Min(), infinite)
+			cut = c
+		}
+	default:
+		// Exceptions, references, hints and unknown patterns generate their first child if any
+		if children := introspect.Children(pattern); len(children) > 0 {
+			size, cut = l.estimate(children[0], visiting)
+		}
+	}
+
+	if !cut {
+		l.sizes[pattern] = size
+	}
+
+	return size, cut
+}
+
+// Write writes objects and counts them
+func (l *Limit[T, P]) Write(objects ...T) error {
+	l.objects += len(objects)
+	return l.w.Write(objects...)
+}
+
+// Finish finishes the underlying writer
+func (l *Limit[T, P]) Finish() error {
+	return l.w.Finish()
+}
+
+// Unwrap returns the underlying writer
+func (l *Limit[T, P]) Unwrap() ebnf.Writer[T] {
+	return l.w
+}
@@ -0,0 +1,59 @@
+package runes
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Writer encodes generated runes as UTF-8 to an io.Writer, output is buffered and flushed on Finish
+type Writer struct {
+	w      *bufio.Writer
+	offset int
+}
+
+// New creates a new rune writer on top of w
+func New(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Write writes runes
+func (w *Writer) Write(runes ...rune) error {
+	for _, c := range runes {
+		if _, err := w.w.WriteRune(c); err != nil {
+			return err
+		}
+
+		w.offset++
+	}
+
+	return nil
+}
+
+// Offset returns the number of runes written
+func (w *Writer) Offset() int {
+	return w.offset
+}
+
+// Finish flushes the buffered output
+func (w *Writer) Finish() error {
+	return w.w.Flush()
+}
+
+// StringWriter collects generated runes in memory
+type StringWriter struct {
+	*Writer
+	builder *strings.Builder
+}
+
+// NewStringWriter creates a new writer that collects generated runes, the result is available with String after
+// Finish
+func NewStringWriter() *StringWriter {
+	builder := &strings.Builder{}
+	return &StringWriter{Writer: New(builder), builder: builder}
+}
+
+// String returns the collected output
+func (w *StringWriter) String() string {
+	return w.builder.String()
+}
@@ -0,0 +1,133 @@
+package tee
+
+import (
+	"errors"
+	"fmt"
+	ebnf "github.com/almerlucke/exbana/v2"
+)
+
+// SinkError is an error returned by a single sink
+type SinkError struct {
+	Index int
+	Err   error
+}
+
+func (e *SinkError) Error() string {
+	return fmt.Sprintf("sink %d: %v", e.Index, e.Err)
+}
+
+func (e *SinkError) Unwrap() error {
+	return e.Err
+}
+
+// Tee duplicates generated objects to multiple sinks. A sink that fails is not written to anymore, by default the
+// failure is returned once from the Write that failed, in best effort mode Write only fails when all sinks have
+// failed. Finish is always propagated to all sinks, including failed sinks, so resources are released
+type Tee[T any] struct {
+	sinks      []ebnf.Writer[T]
+	failed     []error
+	bestEffort bool
+}
+
+// New creates a new tee writer
+func New[T any](sinks ...ebnf.Writer[T]) *Tee[T] {
+	return &Tee[T]{
+		sinks:  sinks,
+		failed: make([]error, len(sinks)),
+	}
+}
+
+// SetBestEffort sets best effort mode
+func (t *Tee[T]) SetBestEffort(bestEffort bool) *Tee[T] {
+	t.bestEffort = bestEffort
+	return t
+}
+
+// Failed returns the error per sink, nil for sinks that did not fail
+func (t *Tee[T]) Failed() []error {
+	return t.failed
+}
+
+// each calls f for each sink that has not failed and records failures
+func (t *Tee[T]) each(f func(ebnf.Writer[T]) error) error {
+	var (
+		errs []error
+		live int
+	)
+
+	for i, sink := range t.sinks {
+		if t.failed[i] != nil {
+			continue
+		}
+
+		if err := f(sink); err != nil {
+			t.failed[i] = err
+			errs = append(errs, &SinkError{Index: i, Err: err})
+
+			continue
+		}
+
+		live++
+	}
+
+	if t.bestEffort && live > 0 {
+		return nil
+	}
+
+	return errors.Join(errs...)
+}
+
+// Write writes objects to all sinks
+func (t *Tee[T]) Write(objects ...T) error {
+	return t.each(func(sink ebnf.Writer[T]) error {
+		return sink.Write(objects...)
+	})
+}
+
+// BeginHint forwards the hint to all sinks
+func (t *Tee[T]) BeginHint(hint string) error {
+	return t.each(func(sink ebnf.Writer[T]) error {
+		return ebnf.BeginHint(sink, hint)
+	})
+}
+
+// EndHint forwards the hint to all sinks
+func (t *Tee[T]) EndHint(hint string) error {
+	return t.each(func(sink ebnf.Writer[T]) error {
+		return ebnf.EndHint(sink, hint)
+	})
+}
+
+// Finish finishes all sinks and returns the errors of all sinks that failed to finish
+func (t *Tee[T]) Finish() error {
+	var errs []error
+
+	for i, sink := range t.sinks {
+		if err := sink.Finish(); err != nil {
+			errs = append(errs, &SinkError{Index: i, Err: err})
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Slice is a sink that collects generated objects in memory
+type Slice[T any] struct {
+	Objects []T
+}
+
+// NewSlice creates a new slice sink
+func NewSlice[T any]() *Slice[T] {
+	return &Slice[T]{}
+}
+
+// Write appends objects
+func (s *Slice[T]) Write(objects ...T) error {
+	s.Objects = append(s.Objects, objects...)
+	return nil
+}
+
+// Finish does nothing
+func (s *Slice[T]) Finish() error {
+	return nil
+}